@@ -25,6 +25,8 @@ type testSchemaProvider struct {
 	updated        map[int]models.View
 	mismatchGroups map[int64]models.ColumnMismatchGroupWithItems
 	lastFilter     models.ColumnMismatchFilter
+	deadLetters    map[int64]models.DeadLetterEvent
+	deadFilter     models.DeadLetterFilter
 }
 
 func (m *testSchemaProvider) CreateTask(context.Context, string, string) error { return nil }
@@ -37,6 +39,123 @@ func (m *testSchemaProvider) ChangeStatusTask(context.Context, string, string, s
 func (m *testSchemaProvider) GetTasks(context.Context, models.TaskFilter) ([]models.Task, error) {
 	return nil, nil
 }
+func (m *testSchemaProvider) SaveTaskStage(context.Context, string, models.TaskStage) error {
+	return nil
+}
+func (m *testSchemaProvider) SaveTaskTableProgress(context.Context, string, models.TaskTableProgress) error {
+	return nil
+}
+func (m *testSchemaProvider) AddTaskTableRows(context.Context, string, models.TaskTableProgress) error {
+	return nil
+}
+func (m *testSchemaProvider) EnqueueETLJob(context.Context, string, int64, string, string, int) error {
+	return nil
+}
+func (m *testSchemaProvider) RequeueETLJob(context.Context, string, int64, string, int) error {
+	return nil
+}
+func (m *testSchemaProvider) SaveTaskChunks(context.Context, string, []models.TaskChunk) error {
+	return nil
+}
+func (m *testSchemaProvider) CompleteTaskChunk(context.Context, string, models.TaskChunk) error {
+	return nil
+}
+func (m *testSchemaProvider) ListTaskChunks(context.Context, string) ([]models.TaskChunk, error) {
+	return nil, nil
+}
+func (m *testSchemaProvider) RegisterTaskTempTables(context.Context, string, []string) error {
+	return nil
+}
+func (m *testSchemaProvider) GetTaskTempTables(context.Context, string) ([]string, error) {
+	return nil, nil
+}
+func (m *testSchemaProvider) ListTaskTempTables(context.Context, string, string, time.Time) ([]string, error) {
+	return nil, nil
+}
+func (m *testSchemaProvider) SaveTaskSnapshot(context.Context, string, models.TaskSnapshot) error {
+	return nil
+}
+func (m *testSchemaProvider) ListTaskSnapshots(context.Context, string) ([]models.TaskSnapshot, error) {
+	return nil, nil
+}
+func (m *testSchemaProvider) ListViewWatermarks(context.Context, int64) ([]models.ViewWatermark, error) {
+	return nil, nil
+}
+func (m *testSchemaProvider) SaveViewSnapshots(context.Context, int64, map[string]models.SnapshotPosition) error {
+	return nil
+}
+func (m *testSchemaProvider) ListViewSnapshots(context.Context) ([]models.ViewSnapshot, error) {
+	return nil, nil
+}
+func (m *testSchemaProvider) SaveViewWatermark(context.Context, int64, models.ViewWatermark) error {
+	return nil
+}
+func (m *testSchemaProvider) SaveDeadLetter(context.Context, models.DeadLetterEvent) (int64, error) {
+	return 0, nil
+}
+func (m *testSchemaProvider) ListDeadLetters(_ context.Context, filter models.DeadLetterFilter) ([]models.DeadLetterEvent, error) {
+	m.deadFilter = filter
+	var events []models.DeadLetterEvent
+	for _, event := range m.deadLetters {
+		if len(filter.IDs) > 0 && event.ID != filter.IDs[0] {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+func (m *testSchemaProvider) GetDeadLetter(_ context.Context, id int64) (models.DeadLetterEvent, error) {
+	if event, ok := m.deadLetters[id]; ok {
+		return event, nil
+	}
+	return models.DeadLetterEvent{}, storage.ErrDeadLetterNotFound
+}
+func (m *testSchemaProvider) ListDueDeadLetters(context.Context, time.Time, int) ([]models.DeadLetterEvent, error) {
+	return nil, nil
+}
+func (m *testSchemaProvider) HasDeadLetters(context.Context, string, string, string, string, int64) (bool, error) {
+	return false, nil
+}
+func (m *testSchemaProvider) UpdateDeadLetter(_ context.Context, event models.DeadLetterEvent) error {
+	m.deadLetters[event.ID] = event
+	return nil
+}
+func (m *testSchemaProvider) DeleteDeadLetters(_ context.Context, ids []int64) (int64, error) {
+	var deleted int64
+	for _, id := range ids {
+		if _, ok := m.deadLetters[id]; ok {
+			delete(m.deadLetters, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+func (m *testSchemaProvider) SavePendingEvent(context.Context, models.PendingCDCEvent) error {
+	return nil
+}
+func (m *testSchemaProvider) HasPendingEvents(context.Context, int64, string, string, string) (bool, error) {
+	return false, nil
+}
+func (m *testSchemaProvider) ListPendingEvents(context.Context, int64, string, string, string, int) ([]models.PendingCDCEvent, error) {
+	return nil, nil
+}
+func (m *testSchemaProvider) DeletePendingEvents(context.Context, []int64) error { return nil }
+func (m *testSchemaProvider) ClaimETLJob(context.Context, string, time.Duration) (*models.ETLJob, error) {
+	return nil, nil
+}
+func (m *testSchemaProvider) ExtendETLJobLease(context.Context, string, string, time.Duration) error {
+	return nil
+}
+func (m *testSchemaProvider) FinishETLJob(context.Context, string, string) error { return nil }
+func (m *testSchemaProvider) DeleteETLJob(context.Context, string) (models.ETLJob, error) {
+	return models.ETLJob{}, storage.ErrJobNotFound
+}
+func (m *testSchemaProvider) RecoverETLJobs(context.Context, int) ([]string, []string, error) {
+	return nil, nil, nil
+}
+func (m *testSchemaProvider) ListOrphanTasks(context.Context, string) ([]string, error) {
+	return nil, nil
+}
 
 func (m *testSchemaProvider) GetView(_ context.Context, idView int64) (models.View, error) {
 	if v, ok := m.views[int(idView)]; ok {
@@ -127,8 +246,15 @@ func (d *testDWH) RenameColumn(_ context.Context, query string) error {
 	d.renameCalls = append(d.renameCalls, query)
 	return nil
 }
-func (d *testDWH) DropColumn(context.Context, string) error      { return nil }
-func (d *testDWH) InsertDataToDWH(context.Context, string) error { return nil }
+func (d *testDWH) DropColumn(context.Context, string) error         { return nil }
+func (d *testDWH) SwapTables(context.Context, string, string) error { return nil }
+func (d *testDWH) DeleteChunkRows(context.Context, string, string, ...interface{}) error {
+	return nil
+}
+func (d *testDWH) ListTempTables(context.Context, string) ([]string, error) { return nil, nil }
+func (d *testDWH) InsertStream(context.Context, models.View, string, models.RowStream, int) (int64, error) {
+	return 0, nil
+}
 func (d *testDWH) GetColumnsTables(context.Context, string, string) ([]string, error) {
 	return nil, nil
 }
@@ -137,6 +263,15 @@ func (d *testDWH) ReplicaIdentityFull(context.Context, string) error { return ni
 func (d *testDWH) InsertOrUpdateTransactional(context.Context, string, map[string]interface{}, []string) error {
 	return nil
 }
+func (d *testDWH) UpsertBatch(context.Context, string, []map[string]interface{}, []string) error {
+	return nil
+}
+
+func (d *testDWH) DeleteRow(context.Context, string, map[string]interface{}) error     { return nil }
+func (d *testDWH) SoftDeleteRow(context.Context, string, map[string]interface{}) error { return nil }
+func (d *testDWH) ReplaceRows(context.Context, string, []map[string]interface{}, bool, []models.RowUpsert) error {
+	return nil
+}
 
 func setupHandlerForTests() (*DBHandlers, *testSchemaProvider, *testDWH) {
 	schemaProvider := &testSchemaProvider{
@@ -163,10 +298,12 @@ func setupHandlerForTests() (*DBHandlers, *testSchemaProvider, *testDWH) {
 
 	dwh := &testDWH{}
 	svc := &serviceanalytics.AnalyticsDataCenterService{
-		SchemaProvider:        schemaProvider,
-		ColumnMismatchStorage: schemaProvider,
-		DWHProvider:           dwh,
-		DWHDbName:             serviceanalytics.DbPostgres,
+		SchemaProvider:          schemaProvider,
+		RenameSuggestionStorage: schemaProvider,
+		ColumnMismatchStorage:   schemaProvider,
+		PendingEventStorage:     schemaProvider,
+		DWHProvider:             dwh,
+		DWHDbName:               serviceanalytics.DbPostgres,
 	}
 	logField := reflect.ValueOf(svc).Elem().FieldByName("log")
 	reflect.NewAt(logField.Type(), unsafe.Pointer(logField.UnsafeAddr())).Elem().Set(reflect.ValueOf(loggerpkg.New("test", "ru")))
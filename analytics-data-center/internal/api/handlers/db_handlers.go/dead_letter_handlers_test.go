@@ -0,0 +1,96 @@
+package dbhandlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDeadLetterRouter() (http.Handler, *testSchemaProvider) {
+	handler, schemaProvider, _ := setupHandlerForTests()
+	schemaProvider.deadLetters = map[int64]models.DeadLetterEvent{
+		1: {ID: 1, ViewID: ptr(int64(1)), DatabaseName: "db", SchemaName: "sch", TableName: "tbl", Op: "u",
+			Payload: json.RawMessage(`{"op":"u"}`), Error: "boom", Attempts: 3, Status: models.DeadLetterFailed},
+	}
+	handler.serviceAnalytics.DeadLetterStorage = schemaProvider
+
+	r := chi.NewRouter()
+	r.Get("/api/dead-letters", handler.GetDeadLetters)
+	r.Post("/api/dead-letters/replay", handler.ReplayDeadLetters)
+	r.Post("/api/dead-letters/discard", handler.DiscardDeadLetters)
+	r.Get("/api/dead-letters/{id}", handler.GetDeadLetter)
+	r.Post("/api/dead-letters/{id}/discard", handler.DiscardDeadLetter)
+	return r, schemaProvider
+}
+
+func TestGetDeadLetters(t *testing.T) {
+	r, schemaProvider := setupDeadLetterRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/dead-letters?viewId=1&table=tbl&status=failed&limit=10&offset=5", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp struct {
+		Items []models.DeadLetterEvent `json:"items"`
+		Limit int                      `json:"limit"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Len(t, resp.Items, 1)
+	require.Equal(t, 10, resp.Limit)
+	require.Equal(t, int64(1), *schemaProvider.deadFilter.ViewID)
+	require.Equal(t, "tbl", *schemaProvider.deadFilter.TableName)
+	require.Equal(t, models.DeadLetterFailed, *schemaProvider.deadFilter.Status)
+	require.Equal(t, 5, schemaProvider.deadFilter.Offset)
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/dead-letters?viewId=abc", nil))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestGetDeadLetter(t *testing.T) {
+	r, _ := setupDeadLetterRouter()
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/dead-letters/1", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var event models.DeadLetterEvent
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &event))
+	require.Equal(t, "boom", event.Error)
+	require.JSONEq(t, `{"op":"u"}`, string(event.Payload))
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/dead-letters/999", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestDiscardDeadLetters(t *testing.T) {
+	r, schemaProvider := setupDeadLetterRouter()
+
+	// без идентификаторов и фильтра массовая операция не выполняется
+	for _, path := range []string{"/api/dead-letters/replay", "/api/dead-letters/discard"} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{}`)))
+		require.Equal(t, http.StatusBadRequest, rr.Code, path)
+	}
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/dead-letters/discard", bytes.NewBufferString(`{"ids":[1]}`)))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp map[string]int64
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, int64(1), resp["discarded"])
+	require.Empty(t, schemaProvider.deadLetters)
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/dead-letters/1/discard", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
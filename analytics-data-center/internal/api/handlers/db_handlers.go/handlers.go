@@ -2,6 +2,8 @@ package dbhandlers
 
 import (
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/lib/joingraph"
+	"analyticDataCenter/analytics-data-center/internal/lib/rowfilter"
 	"analyticDataCenter/analytics-data-center/internal/lib/validate"
 	loggerpkg "analyticDataCenter/analytics-data-center/internal/logger"
 	serviceanalytics "analyticDataCenter/analytics-data-center/internal/services/analytics"
@@ -106,6 +108,15 @@ func (d *DBHandlers) UploadSchema(w http.ResponseWriter, r *http.Request) {
 	}
 
 	id, err := d.serviceAnalytics.UploadSchema(ctx, schemaView)
+	var validationErr *serviceanalytics.ViewValidationError
+	if errors.As(err, &validationErr) {
+		d.log.Error("некорректное описание view", slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(validationErr.Result); err != nil {
+			d.log.Error("failed to encode response", slog.String("error", err.Error()))
+		}
+		return
+	}
 	if err != nil {
 		d.log.Error("ошибка сервиса аналитики", slog.String("error", err.Error()))
 		http.Error(w, "internal error", http.StatusInternalServerError)
@@ -157,8 +168,31 @@ func (d *DBHandlers) StartETL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	taskID, err := d.serviceAnalytics.StartETLProcess(ctx, schemaID)
+	var taskID string
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "", models.ETLModeFull:
+		taskID, err = d.serviceAnalytics.StartETLProcess(ctx, schemaID)
+	case models.ETLModeIncremental:
+		taskID, err = d.serviceAnalytics.StartIncrementalETL(ctx, schemaID)
+	default:
+		http.Error(w, "unknown etl mode: "+mode, http.StatusBadRequest)
+		return
+	}
 	if err != nil {
+		if errors.Is(err, storage.ErrQueueFull) {
+			log.Warn("очередь ETL переполнена", slog.Int64("schema_id", schemaID))
+			http.Error(w, "etl queue is full, try again later", http.StatusTooManyRequests)
+			return
+		}
+		if errors.Is(err, serviceanalytics.ErrInvalidSchemID) {
+			http.Error(w, "schema not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, serviceanalytics.ErrIncrementalNotSupported) {
+			log.Warn("инкрементальная загрузка невозможна", slog.String("error", err.Error()))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		log.Error("ошибка запуска ETL", slog.String("error", err.Error()))
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
@@ -172,6 +206,137 @@ func (d *DBHandlers) StartETL(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (d *DBHandlers) PlanETL(w http.ResponseWriter, r *http.Request) {
+	const op = "DBHandlers.PlanETL"
+	log := d.log.With(slog.String("op", op))
+
+	w.Header().Set("Content-Type", "application/json")
+	ctx := r.Context()
+
+	schemaIDParam := chi.URLParam(r, "id")
+	schemaID, err := strconv.ParseInt(schemaIDParam, 10, 64)
+	if err != nil {
+		log.Error("invalid schema id", slog.String("error", err.Error()))
+		http.Error(w, "invalid schema id", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := d.serviceAnalytics.PlanETL(ctx, schemaID)
+	if err != nil {
+		if errors.Is(err, serviceanalytics.ErrInvalidSchemID) {
+			http.Error(w, "schema not found", http.StatusNotFound)
+			return
+		}
+		log.Error("ошибка формирования плана ETL", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (d *DBHandlers) PlanView(w http.ResponseWriter, r *http.Request) {
+	const op = "DBHandlers.PlanView"
+	log := d.log.With(slog.String("op", op))
+
+	w.Header().Set("Content-Type", "application/json")
+	ctx := r.Context()
+
+	var schemaView models.View
+	if err := json.NewDecoder(r.Body).Decode(&schemaView); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if _, err := validate.Validate(schemaView); err != nil {
+		log.Error("failed validate", slog.String("error", err.Error()))
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := d.serviceAnalytics.PlanView(ctx, schemaView)
+	if errors.Is(err, joingraph.ErrInvalidJoin) || errors.Is(err, rowfilter.ErrInvalidFilter) {
+		log.Error("некорректное описание view", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error("ошибка формирования плана ETL", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(plan); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (d *DBHandlers) ValidateSchema(w http.ResponseWriter, r *http.Request) {
+	const op = "DBHandlers.ValidateSchema"
+	log := d.log.With(slog.String("op", op))
+
+	w.Header().Set("Content-Type", "application/json")
+	ctx := r.Context()
+
+	schemaIDParam := chi.URLParam(r, "id")
+	schemaID, err := strconv.ParseInt(schemaIDParam, 10, 64)
+	if err != nil {
+		log.Error("invalid schema id", slog.String("error", err.Error()))
+		http.Error(w, "invalid schema id", http.StatusBadRequest)
+		return
+	}
+
+	validation, err := d.serviceAnalytics.ValidateSchema(ctx, schemaID)
+	if err != nil {
+		if errors.Is(err, serviceanalytics.ErrInvalidSchemID) {
+			http.Error(w, "schema not found", http.StatusNotFound)
+			return
+		}
+		log.Error("ошибка проверки view", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(validation); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (d *DBHandlers) ValidateView(w http.ResponseWriter, r *http.Request) {
+	const op = "DBHandlers.ValidateView"
+	log := d.log.With(slog.String("op", op))
+
+	w.Header().Set("Content-Type", "application/json")
+	ctx := r.Context()
+
+	var schemaView models.View
+	if err := json.NewDecoder(r.Body).Decode(&schemaView); err != nil {
+		log.Error("failed to decode request", slog.String("error", err.Error()))
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	if _, err := validate.Validate(schemaView); err != nil {
+		log.Error("failed validate", slog.String("error", err.Error()))
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	validation := d.serviceAnalytics.ValidateView(ctx, schemaView)
+	if err := json.NewEncoder(w).Encode(validation); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
+
 func (d *DBHandlers) GetColumnMismatchGroups(w http.ResponseWriter, r *http.Request) {
 	const op = "DBHandlers.GetColumnMismatchGroups"
 	log := d.log.With(slog.String("op", op))
@@ -454,3 +619,232 @@ func (d *DBHandlers) RejectColumnRenameSuggestion(w http.ResponseWriter, r *http
 		return
 	}
 }
+
+func (d *DBHandlers) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	const op = "DBHandlers.GetDeadLetters"
+	log := d.log.With(slog.String("op", op))
+
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	filter := models.DeadLetterFilter{}
+
+	if viewIDStr := query.Get("viewId"); viewIDStr != "" {
+		viewID, err := strconv.ParseInt(viewIDStr, 10, 64)
+		if err != nil {
+			log.Error("invalid viewId", slog.String("error", err.Error()))
+			http.Error(w, "invalid viewId", http.StatusBadRequest)
+			return
+		}
+		filter.ViewID = &viewID
+	}
+
+	if database := query.Get("database"); database != "" {
+		filter.DatabaseName = &database
+	}
+	if schema := query.Get("schema"); schema != "" {
+		filter.SchemaName = &schema
+	}
+	if table := query.Get("table"); table != "" {
+		filter.TableName = &table
+	}
+
+	// status: retrying | failed | all
+	if status := query.Get("status"); status != "" && status != "all" {
+		filter.Status = &status
+	}
+
+	filter.Limit = 50
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if v, err := strconv.Atoi(limitStr); err == nil && v > 0 {
+			filter.Limit = v
+		}
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if v, err := strconv.Atoi(offsetStr); err == nil && v >= 0 {
+			filter.Offset = v
+		}
+	}
+
+	events, err := d.serviceAnalytics.ListDeadLetters(ctx, filter)
+	if err != nil {
+		log.Error("failed to list dead letters", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"items":  events,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (d *DBHandlers) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	const op = "DBHandlers.GetDeadLetter"
+	log := d.log.With(slog.String("op", op))
+
+	ctx := r.Context()
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	event, err := d.serviceAnalytics.GetDeadLetter(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrDeadLetterNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		log.Error("failed to get dead letter", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(event); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (d *DBHandlers) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	const op = "DBHandlers.ReplayDeadLetter"
+	log := d.log.With(slog.String("op", op))
+
+	ctx := r.Context()
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := d.serviceAnalytics.ReplayDeadLetter(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrDeadLetterNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, serviceanalytics.ErrDeadLetterReplayFailed) || errors.Is(err, serviceanalytics.ErrDeadLetterBlocked) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Error("failed to replay dead letter", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "ok"}); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (d *DBHandlers) DiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	const op = "DBHandlers.DiscardDeadLetter"
+	log := d.log.With(slog.String("op", op))
+
+	ctx := r.Context()
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := d.serviceAnalytics.DiscardDeadLetter(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrDeadLetterNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		log.Error("failed to discard dead letter", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "ok"}); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (d *DBHandlers) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	const op = "DBHandlers.ReplayDeadLetters"
+	log := d.log.With(slog.String("op", op))
+
+	ctx := r.Context()
+	var selection models.DeadLetterSelection
+	if err := json.NewDecoder(r.Body).Decode(&selection); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	results, err := d.serviceAnalytics.ReplayDeadLetters(ctx, selection)
+	if err != nil {
+		if errors.Is(err, serviceanalytics.ErrDeadLetterSelectionEmpty) {
+			http.Error(w, "ids or filter required", http.StatusBadRequest)
+			return
+		}
+		log.Error("failed to replay dead letters", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	replayed := 0
+	for _, result := range results {
+		if result.Replayed {
+			replayed++
+		}
+	}
+	response := map[string]interface{}{
+		"items":    results,
+		"replayed": replayed,
+		"failed":   len(results) - replayed,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (d *DBHandlers) DiscardDeadLetters(w http.ResponseWriter, r *http.Request) {
+	const op = "DBHandlers.DiscardDeadLetters"
+	log := d.log.With(slog.String("op", op))
+
+	ctx := r.Context()
+	var selection models.DeadLetterSelection
+	if err := json.NewDecoder(r.Body).Decode(&selection); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	discarded, err := d.serviceAnalytics.DiscardDeadLetters(ctx, selection)
+	if err != nil {
+		if errors.Is(err, serviceanalytics.ErrDeadLetterSelectionEmpty) {
+			http.Error(w, "ids or filter required", http.StatusBadRequest)
+			return
+		}
+		log.Error("failed to discard dead letters", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]int64{"discarded": discarded}); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
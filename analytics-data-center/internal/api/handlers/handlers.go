@@ -19,16 +19,28 @@ type HandlersDB interface {
 	UploadSchema(w http.ResponseWriter, r *http.Request)
 	ListViews(w http.ResponseWriter, r *http.Request)
 	StartETL(w http.ResponseWriter, r *http.Request)
+	PlanETL(w http.ResponseWriter, r *http.Request)
+	PlanView(w http.ResponseWriter, r *http.Request)
+	ValidateSchema(w http.ResponseWriter, r *http.Request)
+	ValidateView(w http.ResponseWriter, r *http.Request)
 	GetColumnRenameSuggestions(w http.ResponseWriter, r *http.Request)
 	AcceptColumnRenameSuggestion(w http.ResponseWriter, r *http.Request)
 	RejectColumnRenameSuggestion(w http.ResponseWriter, r *http.Request)
 	GetColumnMismatchGroups(w http.ResponseWriter, r *http.Request)
 	GetColumnMismatchGroup(w http.ResponseWriter, r *http.Request)
 	ApplyColumnMismatchGroup(w http.ResponseWriter, r *http.Request)
+	GetDeadLetters(w http.ResponseWriter, r *http.Request)
+	GetDeadLetter(w http.ResponseWriter, r *http.Request)
+	ReplayDeadLetter(w http.ResponseWriter, r *http.Request)
+	DiscardDeadLetter(w http.ResponseWriter, r *http.Request)
+	ReplayDeadLetters(w http.ResponseWriter, r *http.Request)
+	DiscardDeadLetters(w http.ResponseWriter, r *http.Request)
 }
 
 type HandlersTasks interface {
 	GetTasks(w http.ResponseWriter, r *http.Request)
+	CancelTask(w http.ResponseWriter, r *http.Request)
+	ResumeTask(w http.ResponseWriter, r *http.Request)
 }
 
 type HandlersNotifications interface {
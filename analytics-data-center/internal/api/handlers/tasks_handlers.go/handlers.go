@@ -4,11 +4,16 @@ import (
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
 	"analyticDataCenter/analytics-data-center/internal/lib/validate"
 	serviceanalytics "analyticDataCenter/analytics-data-center/internal/services/analytics"
+	"analyticDataCenter/analytics-data-center/internal/storage"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
 	loggerpkg "analyticDataCenter/analytics-data-center/internal/logger"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 type TaskHandlers struct {
@@ -58,3 +63,75 @@ func (t *TaskHandlers) GetTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (t *TaskHandlers) CancelTask(w http.ResponseWriter, r *http.Request) {
+	const op = "TaskHandlers.CancelTask"
+	log := t.log.With(slog.String("op", op))
+
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	taskID := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(taskID); err != nil {
+		log.Error("invalid task id", slog.String("error", err.Error()))
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	if err := t.serviceAnalytics.CancelTask(ctx, taskID); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTaskNotFound):
+			http.Error(w, "task not found", http.StatusNotFound)
+		case errors.Is(err, serviceanalytics.ErrTaskFinished):
+			http.Error(w, "task already finished", http.StatusConflict)
+		default:
+			log.Error("failed to cancel task", slog.String("error", err.Error()))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]string{"task_id": taskID, "status": "cancelling"}); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (t *TaskHandlers) ResumeTask(w http.ResponseWriter, r *http.Request) {
+	const op = "TaskHandlers.ResumeTask"
+	log := t.log.With(slog.String("op", op))
+
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	taskID := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(taskID); err != nil {
+		log.Error("invalid task id", slog.String("error", err.Error()))
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	if err := t.serviceAnalytics.ResumeTask(ctx, taskID); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTaskNotFound):
+			http.Error(w, "task not found", http.StatusNotFound)
+		case errors.Is(err, serviceanalytics.ErrTaskNotResumable):
+			http.Error(w, "task cannot be resumed", http.StatusConflict)
+		case errors.Is(err, storage.ErrQueueFull):
+			http.Error(w, "etl queue is full", http.StatusTooManyRequests)
+		default:
+			log.Error("failed to resume task", slog.String("error", err.Error()))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]string{"task_id": taskID, "status": serviceanalytics.Progress}); err != nil {
+		log.Error("failed to encode response", slog.String("error", err.Error()))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -43,13 +43,25 @@ func NewRouter(logger *loggerpkg.Logger, serviceAnalytics *serviceanalytics.Anal
 		r.Post("/upload-schem", handlers.UploadSchema)
 		r.Get("/schemas", handlers.ListViews)
 		r.Post("/schemas/{id}/etl", handlers.StartETL)
+		r.Post("/schemas/{id}/plan", handlers.PlanETL)
+		r.Post("/schemas/plan", handlers.PlanView)
+		r.Post("/schemas/{id}/validate", handlers.ValidateSchema)
+		r.Post("/schemas/validate", handlers.ValidateView)
 		r.Post("/get-tasks", handlers.GetTasks)
+		r.Post("/tasks/{id}/cancel", handlers.CancelTask)
+		r.Post("/tasks/{id}/resume", handlers.ResumeTask)
 		r.Get("/column-rename-suggestions", handlers.GetColumnRenameSuggestions)
 		r.Post("/column-rename-suggestions/{id}/accept", handlers.AcceptColumnRenameSuggestion)
 		r.Post("/column-rename-suggestions/{id}/reject", handlers.RejectColumnRenameSuggestion)
 		r.Get("/column-mismatch-groups", handlers.GetColumnMismatchGroups)
 		r.Get("/column-mismatch-groups/{id}", handlers.GetColumnMismatchGroup)
 		r.Post("/column-mismatch-groups/{id}/apply", handlers.ApplyColumnMismatchGroup)
+		r.Get("/dead-letters", handlers.GetDeadLetters)
+		r.Post("/dead-letters/replay", handlers.ReplayDeadLetters)
+		r.Post("/dead-letters/discard", handlers.DiscardDeadLetters)
+		r.Get("/dead-letters/{id}", handlers.GetDeadLetter)
+		r.Post("/dead-letters/{id}/replay", handlers.ReplayDeadLetter)
+		r.Post("/dead-letters/{id}/discard", handlers.DiscardDeadLetter)
 	})
 
 	r.Get("/ws/notifications", handlers.NotificationsWS)
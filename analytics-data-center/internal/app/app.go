@@ -16,6 +16,7 @@ import (
 	clickhousedwh "analyticDataCenter/analytics-data-center/internal/storage/clickhouseDWH"
 	"analyticDataCenter/analytics-data-center/internal/storage/postgres"
 	postgresdwh "analyticDataCenter/analytics-data-center/internal/storage/postgresDWH"
+	"context"
 	"log/slog"
 
 	loggerpkg "analyticDataCenter/analytics-data-center/internal/logger"
@@ -42,10 +43,10 @@ type App struct {
 
 func New(log *loggerpkg.Logger, grpcPort int,
 	storagePath string, connectionStringOLTP string, connectionStringDWH string, OLTPName string, DWHName string, DWHPath string,
-	renameHeuristic bool,
+	renameHeuristic bool, etl config.ETLSetting, cdcSetting config.CDCSetting,
 	tokenTTL time.Duration, factoryOLTP []config.OLTPstorage, BootstrapServers string, GroupId string, AutoOffsetReset string, EnableAutoCommit string, SessionTimeoutMs string, ClientId string, KafkaConnect string, topicSubscriptionInterval time.Duration, hostSMTP string, portSMTP int, userNameSMTP string, passwordSMTP string, adminEmailSMTP string, fromEmailSMTP string) *App {
 	// TO DO переделать на cfg
-	statusEnum := []string{"In progress", "Execution error", "Completed"}
+	statusEnum := []string{"In progress", "Execution error", "Completed", "Cancelled"}
 	// var storageOLTP storage.OLTPDB
 	var storageDWH storage.DWHDB
 	storageSys, err := postgres.New(storagePath, log.Logger)
@@ -92,6 +93,60 @@ func New(log *loggerpkg.Logger, grpcPort int,
 	analyticsService := serviceanalytics.New(log, storage.DbSys, tasksserivce, storageDWH, oltpFactory, DWHName, DWHPath, OLTPName, renameHeuristic, *smtp)
 	notificationWorker := notifications.NewWorker(log)
 	analyticsService.SetNotifier(notificationWorker)
+	analyticsService.SetInsertBatchSize(etl.InsertBatchSize)
+	sourceTuning := make(map[string]serviceanalytics.SourceTuning, len(factoryOLTP))
+	for _, conn := range factoryOLTP {
+		throttle, err := serviceanalytics.ParseThrottleWindow(conn.ETL.ThrottleHours)
+		if err != nil {
+			panic("Некорректное окно ограничения источника " + conn.Name + ": " + err.Error())
+		}
+		sourceTuning[conn.Name] = serviceanalytics.SourceTuning{
+			Concurrency:   conn.ETL.Concurrency,
+			ChunkSize:     conn.ETL.ChunkSize,
+			RowsPerSecond: conn.ETL.RowsPerSecond,
+			Throttle:      throttle,
+		}
+	}
+	analyticsService.SetETLTuning(serviceanalytics.ETLTuningOptions{
+		ChunkSize:    etl.ChunkSize,
+		Concurrency:  etl.ChunkConcurrency,
+		WorkerBudget: etl.WorkerBudget,
+		Sources:      sourceTuning,
+	})
+	analyticsService.SetJobQueueOptions(serviceanalytics.JobQueueOptions{
+		Workers:      etl.Workers,
+		Limit:        etl.QueueLimit,
+		Lease:        etl.JobLease,
+		PollInterval: etl.PollInterval,
+		MaxAttempts:  etl.MaxAttempts,
+	})
+	analyticsService.StartETLWorkers()
+	analyticsService.SetTempTableJanitorOptions(serviceanalytics.TempTableJanitorOptions{
+		Interval:        etl.JanitorInterval,
+		GracePeriod:     etl.JanitorGracePeriod,
+		ResumeRetention: etl.ResumeRetention,
+	})
+	analyticsService.StartTempTableJanitor()
+	analyticsService.SetDeadLetterOptions(serviceanalytics.DeadLetterOptions{
+		MaxAttempts: cdcSetting.RetryMaxAttempts,
+		Backoff:     cdcSetting.RetryBackoff,
+		MaxBackoff:  cdcSetting.RetryMaxBackoff,
+		Interval:    cdcSetting.RetryInterval,
+	})
+	analyticsService.StartDeadLetterRetries()
+	analyticsService.SetEventWorkerOptions(serviceanalytics.EventWorkerOptions{
+		Workers:       cdcSetting.Workers,
+		QueueSize:     cdcSetting.QueueSize,
+		HandoffBuffer: cdcSetting.HandoffBuffer,
+	})
+	analyticsService.SetCDCBatchOptions(serviceanalytics.CDCBatchOptions{
+		Size:   cdcSetting.BatchSize,
+		Window: cdcSetting.BatchWindow,
+	})
+	if err := analyticsService.LoadViewSnapshots(context.Background()); err != nil {
+		panic("Не удалось загрузить снимки view: " + err.Error())
+	}
+	analyticsService.StartEventWorkers()
 	r := routes.NewRouter(log, analyticsService, notificationWorker)
 
 	kafkaEngine, err := kafkaengine.NewEngine(BootstrapServers, GroupId, AutoOffsetReset, EnableAutoCommit, SessionTimeoutMs, ClientId, storage.DbSys, log)
@@ -102,9 +157,10 @@ func New(log *loggerpkg.Logger, grpcPort int,
 	topicCron := topicsubscription.NewCron(log, storage.DbSys, kafkaEngine, topicSubscriptionInterval)
 	topicCron.Start()
 	kafkaConsumer := kafkaEngine.Consumer()
-	cdcListener := cdc.NewListener(kafkaConsumer, log, func(data []byte) {
-		cdc.Dispatch(data, log, analyticsService)
-	})
+	cdcListener := cdc.NewListener(kafkaConsumer, log, func(key []byte, data []byte, ack func()) {
+		cdc.Dispatch(key, data, ack, log, analyticsService)
+	}, analyticsService.EventQueueLoad)
+	kafkaEngine.SetRevokeHandler(cdcListener.RevokePartitions)
 	cdcListener.Start()
 	grpcServer := grpcapp.New(log, grpcPort, analyticsService)
 	return &App{
@@ -24,6 +24,61 @@ type Config struct {
 	KafkaConnect              string        `yaml:"kafka_connect" json:"kafka_connect,omitempty"`
 	TopicSubscriptionInterval time.Duration `yaml:"topic_subscription_interval" env-default:"5s" json:"topic_subscription_interval,omitempty"`
 	SMTP                      SMTPSetting   `yaml:"smtp_setting" json:"smtp_setting,omitempty"`
+	ETL                       ETLSetting    `yaml:"etl" json:"etl,omitempty"`
+	CDC                       CDCSetting    `yaml:"cdc" json:"cdc,omitempty"`
+}
+
+// ETLSetting — параметры первичной загрузки данных в DWH
+type ETLSetting struct {
+	// InsertBatchSize — сколько строк источника держится в памяти и вставляется одним запросом
+	InsertBatchSize int `yaml:"insert_batch_size" env:"ETL_INSERT_BATCH_SIZE" env-default:"10000" json:"insert_batch_size,omitempty"`
+	// Workers — сколько заданий ETL выполняется одновременно
+	Workers int `yaml:"workers" env:"ETL_WORKERS" env-default:"1" json:"workers,omitempty"`
+	// QueueLimit — сколько заданий может ждать в очереди, прежде чем новые запуски отклоняются
+	QueueLimit int `yaml:"queue_limit" env:"ETL_QUEUE_LIMIT" env-default:"100" json:"queue_limit,omitempty"`
+	// JobLease — срок аренды задания воркером; по истечении задание возвращается в очередь
+	JobLease time.Duration `yaml:"job_lease" env:"ETL_JOB_LEASE" env-default:"1m" json:"job_lease,omitempty"`
+	// PollInterval — как часто свободный воркер проверяет очередь
+	PollInterval time.Duration `yaml:"poll_interval" env:"ETL_POLL_INTERVAL" env-default:"2s" json:"poll_interval,omitempty"`
+	// MaxAttempts — сколько раз задание берётся в работу после сбоев воркера
+	MaxAttempts int `yaml:"max_attempts" env:"ETL_MAX_ATTEMPTS" env-default:"3" json:"max_attempts,omitempty"`
+	// JanitorInterval — как часто DWH проверяется на брошенные временные таблицы
+	JanitorInterval time.Duration `yaml:"janitor_interval" env:"ETL_JANITOR_INTERVAL" env-default:"10m" json:"janitor_interval,omitempty"`
+	// JanitorGracePeriod — сколько временная таблица остаётся ничьей, прежде чем её удалят
+	JanitorGracePeriod time.Duration `yaml:"janitor_grace_period" env:"ETL_JANITOR_GRACE_PERIOD" env-default:"1h" json:"janitor_grace_period,omitempty"`
+	// ResumeRetention — сколько временные таблицы упавшей задачи хранятся для её продолжения
+	ResumeRetention time.Duration `yaml:"resume_retention" env:"ETL_RESUME_RETENTION" env-default:"72h" json:"resume_retention,omitempty"`
+	// ChunkSize — сколько строк таблицы источника загружается одним чанком
+	ChunkSize int64 `yaml:"chunk_size" env:"ETL_CHUNK_SIZE" env-default:"500000" json:"chunk_size,omitempty"`
+	// ChunkConcurrency — сколько чанков одной задачи загружается одновременно; 0 — по объёму данных
+	ChunkConcurrency int `yaml:"chunk_concurrency" env:"ETL_CHUNK_CONCURRENCY" json:"chunk_concurrency,omitempty"`
+	// WorkerBudget — сколько чанков загружается одновременно во всех задачах
+	WorkerBudget int `yaml:"worker_budget" env:"ETL_WORKER_BUDGET" env-default:"16" json:"worker_budget,omitempty"`
+}
+
+// CDCSetting — параметры применения CDC-событий к view
+type CDCSetting struct {
+	// RetryMaxAttempts — сколько всего попыток записать событие во view, прежде чем оно ждёт оператора
+	RetryMaxAttempts int `yaml:"retry_max_attempts" env:"CDC_RETRY_MAX_ATTEMPTS" env-default:"5" json:"retry_max_attempts,omitempty"`
+	// RetryBackoff — пауза перед первым повтором события; каждая следующая вдвое больше
+	RetryBackoff time.Duration `yaml:"retry_backoff" env:"CDC_RETRY_BACKOFF" env-default:"30s" json:"retry_backoff,omitempty"`
+	// RetryMaxBackoff — предел паузы между повторами
+	RetryMaxBackoff time.Duration `yaml:"retry_max_backoff" env:"CDC_RETRY_MAX_BACKOFF" env-default:"30m" json:"retry_max_backoff,omitempty"`
+	// RetryInterval — как часто проверяются события, время повтора которых наступило
+	RetryInterval time.Duration `yaml:"retry_interval" env:"CDC_RETRY_INTERVAL" env-default:"10s" json:"retry_interval,omitempty"`
+	// Workers — сколько воркеров применяют события параллельно; события одной строки
+	// всегда применяет один воркер
+	Workers int `yaml:"workers" env:"CDC_WORKERS" env-default:"4" json:"workers,omitempty"`
+	// QueueSize — сколько событий может ждать в очереди каждого воркера; при заполнении
+	// чтение Kafka приостанавливается
+	QueueSize int `yaml:"queue_size" env:"CDC_QUEUE_SIZE" env-default:"100" json:"queue_size,omitempty"`
+	// HandoffBuffer — сколько событий view копится, пока она загружается; при заполнении
+	// чтение Kafka приостанавливается до публикации view
+	HandoffBuffer int `yaml:"handoff_buffer" env:"CDC_HANDOFF_BUFFER" env-default:"100000" json:"handoff_buffer,omitempty"`
+	// BatchSize — сколько строк воркер копит, прежде чем записать их во view одним запросом
+	BatchSize int `yaml:"batch_size" env:"CDC_BATCH_SIZE" env-default:"500" json:"batch_size,omitempty"`
+	// BatchWindow — сколько строка может ждать записи, если пачка не набралась
+	BatchWindow time.Duration `yaml:"batch_window" env:"CDC_BATCH_WINDOW" env-default:"200ms" json:"batch_window,omitempty"`
 }
 
 type SMTPSetting struct {
@@ -41,9 +96,22 @@ type GRPCSetting struct {
 }
 
 type OLTPstorage struct {
-	Name      string `yaml:"name"`
-	Path      string `yaml:"connection_string"`
-	PathKafka string `yaml:"connection_string_kafka"`
+	Name      string           `yaml:"name"`
+	Path      string           `yaml:"connection_string"`
+	PathKafka string           `yaml:"connection_string_kafka"`
+	ETL       SourceETLSetting `yaml:"etl"`
+}
+
+// SourceETLSetting — ограничения первичной загрузки из источника OLTP; нулевые поля не ограничивают
+type SourceETLSetting struct {
+	// Concurrency — сколько чанков источника читается одновременно во всех задачах
+	Concurrency int `yaml:"concurrency"`
+	// ChunkSize — размер чанка для таблиц источника
+	ChunkSize int64 `yaml:"chunk_size"`
+	// RowsPerSecond — сколько строк в секунду читается из источника во всех задачах
+	RowsPerSecond int `yaml:"rows_per_second"`
+	// ThrottleHours — окно вида "09:00-19:00", в котором действует RowsPerSecond; пусто — круглосуточно
+	ThrottleHours string `yaml:"throttle_hours"`
 }
 
 type KafkaSetting struct {
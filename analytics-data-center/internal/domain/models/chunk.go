@@ -0,0 +1,19 @@
+package models
+
+// ChunkKeyCtid — ключ чанка для таблиц без первичного ключа: физический адрес строки Postgres
+const ChunkKeyCtid = "ctid"
+
+// Chunk — диапазон строк таблицы источника для одной порции загрузки: Lower <= ключ < Upper.
+// Пустая граница означает начало или конец таблицы, чанк без границ — вся таблица.
+// Для таблиц без первичного ключа Columns = ["ctid"], а границы — номера страниц.
+type Chunk struct {
+	Index   int           `json:"index"`
+	Columns []string      `json:"columns,omitempty"`
+	Lower   []interface{} `json:"lower,omitempty"`
+	Upper   []interface{} `json:"upper,omitempty"`
+}
+
+// ByCtid сообщает, что чанк построен по ctid, а не по первичному ключу
+func (c Chunk) ByCtid() bool {
+	return len(c.Columns) == 1 && c.Columns[0] == ChunkKeyCtid
+}
@@ -7,3 +7,9 @@ type CountInsertData struct {
 	SchemaName    string `json:"schema_name,omitempty"`
 	TempTableName string `json:"temp_table_name,omitempty"`
 }
+
+// RowUpsert — строка, которая вставляется или обновляется по ключам ConflictColumns
+type RowUpsert struct {
+	Row             map[string]interface{}
+	ConflictColumns []string
+}
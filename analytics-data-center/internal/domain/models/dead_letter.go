@@ -0,0 +1,82 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// DeadLetterRetrying — ошибка временная, событие ждёт автоматического повтора
+	DeadLetterRetrying = "retrying"
+	// DeadLetterFailed — повторы исчерпаны или ошибка не временная; событие ждёт решения оператора
+	DeadLetterFailed = "failed"
+)
+
+// DeadLetterEvent — CDC-событие, которое не удалось записать во view
+type DeadLetterEvent struct {
+	ID int64 `db:"id" json:"id"`
+	// ViewID — view, на которой событие упало; nil — ошибка до записи во view
+	ViewID       *int64 `db:"view_id" json:"view_id,omitempty"`
+	DatabaseName string `db:"database_name" json:"database_name"`
+	SchemaName   string `db:"schema_name" json:"schema_name"`
+	TableName    string `db:"table_name" json:"table_name"`
+	// RowKey — ключ строки источника из сообщения Kafka; следующие события строки ждут
+	// в dead letter, пока это событие не будет записано или отброшено
+	RowKey string `db:"row_key" json:"row_key,omitempty"`
+	Op     string `db:"op" json:"op"`
+	// Payload — сообщение Kafka в том виде, в каком оно пришло
+	Payload     json.RawMessage `db:"payload" json:"payload,omitempty"`
+	Error       string          `db:"error" json:"error"`
+	Attempts    int             `db:"attempts" json:"attempts"`
+	Status      string          `db:"status" json:"status"`
+	NextRetryAt *time.Time      `db:"next_retry_at" json:"next_retry_at,omitempty"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+type DeadLetterFilter struct {
+	IDs          []int64
+	ViewID       *int64
+	DatabaseName *string
+	SchemaName   *string
+	TableName    *string
+	Status       *string
+
+	Limit  int
+	Offset int
+}
+
+// DeadLetterSelection — события для массового повтора или удаления: перечисленные
+// идентификаторы либо все события, подходящие под фильтр
+type DeadLetterSelection struct {
+	IDs          []int64 `json:"ids"`
+	ViewID       *int64  `json:"view_id"`
+	DatabaseName *string `json:"database"`
+	SchemaName   *string `json:"schema"`
+	TableName    *string `json:"table"`
+	Status       *string `json:"status"`
+}
+
+// Empty — ни идентификаторы, ни фильтр не заданы
+func (s DeadLetterSelection) Empty() bool {
+	return len(s.IDs) == 0 && s.ViewID == nil && s.DatabaseName == nil && s.SchemaName == nil &&
+		s.TableName == nil && s.Status == nil
+}
+
+func (s DeadLetterSelection) Filter() DeadLetterFilter {
+	return DeadLetterFilter{
+		IDs:          s.IDs,
+		ViewID:       s.ViewID,
+		DatabaseName: s.DatabaseName,
+		SchemaName:   s.SchemaName,
+		TableName:    s.TableName,
+		Status:       s.Status,
+	}
+}
+
+// DeadLetterReplayResult — итог повтора одного события
+type DeadLetterReplayResult struct {
+	ID       int64  `json:"id"`
+	Replayed bool   `json:"replayed"`
+	Error    string `json:"error,omitempty"`
+}
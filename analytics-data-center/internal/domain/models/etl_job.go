@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Статусы задания в очереди ETL
+const (
+	ETLJobQueued  = "queued"
+	ETLJobRunning = "running"
+)
+
+// ETLJob — задание очереди ETL, хранящееся в sys DB. Взятое в работу задание
+// закреплено за воркером до LeaseUntil; воркер периодически продлевает аренду.
+// Задание с Resume продолжает загрузку упавшей задачи по её чекпоинтам. Mode
+// выбирает полную пересборку или инкрементальную загрузку (ETLModeFull, ETLModeIncremental).
+type ETLJob struct {
+	TaskID     string     `json:"task_id"`
+	ViewID     int64      `json:"view_id"`
+	Status     string     `json:"status"`
+	Attempts   int        `json:"attempts"`
+	Resume     bool       `json:"resume"`
+	Mode       string     `json:"mode"`
+	WorkerID   *string    `json:"worker_id,omitempty"`
+	LeaseUntil *time.Time `json:"lease_until,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
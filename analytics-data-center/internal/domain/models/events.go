@@ -4,6 +4,13 @@ type CDCEvent struct {
 	Event string       `json:"event"`
 	ID    string       `json:"id"`
 	Data  CDCEventData `json:"data"`
+	// Ack подтверждает, что событие записано во все затронутые view, и разрешает
+	// коммит его offset'а; nil — событие пришло не из Kafka
+	Ack func() `json:"-"`
+	// Payload — исходное сообщение Kafka, из которого разобрано событие
+	Payload []byte `json:"-"`
+	// Key — ключ сообщения Kafka: первичный ключ строки источника
+	Key []byte `json:"-"`
 }
 
 type CDCEventData struct {
@@ -0,0 +1,39 @@
+package models
+
+// RowFilter — условие отбора строк таблицы. Узел либо сравнивает колонку со значением
+// (Column, Operator, Value), либо объединяет вложенные условия через And или Or.
+//
+//	{"and": [
+//	  {"column": "status", "operator": "<>", "value": "draft"},
+//	  {"or": [
+//	    {"column": "amount", "operator": ">=", "value": 100},
+//	    {"column": "vip", "operator": "=", "value": true}
+//	  ]}
+//	]}
+type RowFilter struct {
+	Column   string      `json:"column,omitempty"`
+	Operator string      `json:"operator,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+	And      []RowFilter `json:"and,omitempty"`
+	Or       []RowFilter `json:"or,omitempty"`
+}
+
+// Операторы RowFilter
+const (
+	FilterEq        = "="
+	FilterNotEq     = "<>"
+	FilterGt        = ">"
+	FilterGte       = ">="
+	FilterLt        = "<"
+	FilterLte       = "<="
+	FilterIn        = "in"
+	FilterNotIn     = "not_in"
+	FilterLike      = "like"
+	FilterIsNull    = "is_null"
+	FilterIsNotNull = "is_not_null"
+)
+
+// IsGroup сообщает, что узел объединяет вложенные условия
+func (f RowFilter) IsGroup() bool {
+	return len(f.And) > 0 || len(f.Or) > 0
+}
@@ -4,10 +4,13 @@ import "time"
 
 // Notification represents a message that can be delivered to UI clients via websockets.
 type Notification struct {
-	Type      string    `json:"type"`
-	Title     string    `json:"title"`
-	Message   string    `json:"message"`
-	TaskID    string    `json:"taskId,omitempty"`
-	Status    string    `json:"status,omitempty"`
-	CreatedAt time.Time `json:"createdAt"`
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Message   string      `json:"message"`
+	TaskID    string      `json:"taskId,omitempty"`
+	Status    string      `json:"status,omitempty"`
+	Stage     string      `json:"stage,omitempty"`
+	Stages    []TaskStage `json:"stages,omitempty"`
+	Tables    []string    `json:"tables,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
 }
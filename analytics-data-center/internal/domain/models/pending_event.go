@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PendingCDCEvent — CDC-событие, отложенное для view, пока по таблице события есть
+// нерешённое предложение переименования
+type PendingCDCEvent struct {
+	ID           int64     `db:"id" json:"id"`
+	ViewID       int64     `db:"view_id" json:"view_id"`
+	DatabaseName string    `db:"database_name" json:"database_name"`
+	SchemaName   string    `db:"schema_name" json:"schema_name"`
+	TableName    string    `db:"table_name" json:"table_name"`
+	Payload      []byte    `db:"payload" json:"-"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
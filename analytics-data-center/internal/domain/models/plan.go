@@ -0,0 +1,39 @@
+package models
+
+// Фазы плана ETL в порядке выполнения
+const (
+	PlanPhaseTempTables = StageTempTables
+	PlanPhaseCount      = "count"
+	PlanPhaseSelect     = "select"
+	PlanPhaseInsert     = "insert"
+	PlanPhaseMerge      = StageMerge
+	PlanPhaseIndexes    = StageIndexes
+)
+
+// ETLPlan — SQL, который ETL сгенерирует для view, без выполнения. Запросы
+// сгруппированы по фазам; то, что не удалось сгенерировать, попадает в Warnings.
+type ETLPlan struct {
+	View     string      `json:"view"`
+	DWH      string      `json:"dwh"`
+	OLTP     string      `json:"oltp"`
+	Phases   []PlanPhase `json:"phases"`
+	Warnings []string    `json:"warnings,omitempty"`
+}
+
+// PlanPhase — запросы одной фазы ETL
+type PlanPhase struct {
+	Name    string      `json:"name"`
+	Queries []PlanQuery `json:"queries"`
+}
+
+// PlanQuery — запрос плана. Target — таблица DWH, в которую пишет запрос;
+// Args — аргументы запроса, границы чанков подставляются символически.
+type PlanQuery struct {
+	Source      string        `json:"source,omitempty"`
+	Schema      string        `json:"schema,omitempty"`
+	Table       string        `json:"table,omitempty"`
+	Target      string        `json:"target,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Query       string        `json:"query"`
+	Args        []interface{} `json:"args,omitempty"`
+}
@@ -10,4 +10,5 @@ type Query struct {
 	TableName     string
 	BaseTableName string
 	Query         string
+	Args          []interface{}
 }
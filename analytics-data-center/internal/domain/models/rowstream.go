@@ -0,0 +1,11 @@
+package models
+
+// RowStream — построчное чтение результата выборки без материализации всего набора.
+// Использование как у sql.Rows: пока Next возвращает true, Row отдаёт текущую строку;
+// после завершения нужно проверить Err и вызвать Close.
+type RowStream interface {
+	Next() bool
+	Row() map[string]interface{}
+	Err() error
+	Close() error
+}
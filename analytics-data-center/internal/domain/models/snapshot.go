@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// SnapshotPosition — место согласованного снимка источника в потоке изменений:
+// позиция WAL на момент снимка и границы транзакций, видимых в нём
+// (формат txid_current_snapshot: xmin:xmax:xip).
+type SnapshotPosition struct {
+	LSN  int64   `json:"lsn"`
+	Xmin int64   `json:"xmin"`
+	Xmax int64   `json:"xmax"`
+	Xip  []int64 `json:"xip,omitempty"`
+}
+
+// Contains сообщает, что изменение CDC-события уже попало в снимок. Изменения с
+// LSN после снимка в нём нет. Изменение с LSN не позже снимка могло принадлежать
+// транзакции, зафиксированной уже после снимка, — такие транзакции отличаются по
+// границам снимка. События без позиции в снимок не попадают.
+func (p SnapshotPosition) Contains(source CDCSource) bool {
+	if p.LSN == 0 || source.LSN == 0 || source.LSN > p.LSN {
+		return false
+	}
+	if source.TxID == 0 {
+		return true
+	}
+	return p.visible(source.TxID)
+}
+
+// visible повторяет txid_visible_in_snapshot. Debezium передаёт 32-битный xid, поэтому
+// он достраивается до 64-битного txid по ближайшей к xmax эпохе.
+func (p SnapshotPosition) visible(xid int64) bool {
+	txid := p.Xmax + int64(int32(uint32(xid)-uint32(p.Xmax)))
+	if txid < p.Xmin {
+		return true
+	}
+	if txid >= p.Xmax {
+		return false
+	}
+	for _, active := range p.Xip {
+		if active == txid {
+			return false
+		}
+	}
+	return true
+}
+
+// OLTPSnapshot — экспортированный снимок источника: по ID его импортируют другие
+// соединения (SET TRANSACTION SNAPSHOT)
+type OLTPSnapshot struct {
+	ID       string
+	Position SnapshotPosition
+}
+
+// TaskSnapshot — снимок, из которого задача загрузила источник. CDC-события
+// источника, уже попавшие в снимок, к загруженной view не применяются.
+type TaskSnapshot struct {
+	Source   string           `json:"source"`
+	Position SnapshotPosition `json:"position"`
+	TakenAt  time.Time        `json:"taken_at"`
+}
+
+// ViewSnapshot — снимок источника, из которого загружена опубликованная view
+type ViewSnapshot struct {
+	ViewID   int64            `json:"view_id"`
+	Source   string           `json:"source"`
+	Position SnapshotPosition `json:"position"`
+}
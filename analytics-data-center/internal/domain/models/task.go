@@ -3,8 +3,61 @@ package models
 import "time"
 
 type Task struct {
-	ID         string    `json:"id"`
-	Status     string    `json:"status"`
-	CreateDate time.Time `json:"create_date,omitempty"`
-	Comment    *string   `json:"comment,omitempty"`
+	ID         string              `json:"id"`
+	Status     string              `json:"status"`
+	CreateDate time.Time           `json:"create_date,omitempty"`
+	Comment    *string             `json:"comment,omitempty"`
+	ViewID     *int64              `json:"view_id,omitempty"`
+	Stages     []TaskStage         `json:"stages,omitempty"`
+	Tables     []TaskTableProgress `json:"tables,omitempty"`
+	Snapshots  []TaskSnapshot      `json:"snapshots,omitempty"`
+}
+
+// Этапы ETL-задачи в порядке выполнения
+const (
+	StageTempTables  = "temp_tables"
+	StageExtract     = "extract"
+	StageMerge       = "merge"
+	StageIndexes     = "indexes"
+	StagePublish     = "publish"
+	StageReplication = "replication"
+)
+
+// Статусы этапа ETL-задачи
+const (
+	StageRunning   = "running"
+	StageCompleted = "completed"
+	StageFailed    = "failed"
+	StageCancelled = "cancelled"
+)
+
+// TaskStage — запись таймлайна задачи: когда этап начался, чем и когда закончился
+type TaskStage struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      *string    `json:"error,omitempty"`
+}
+
+// TaskTableProgress — прогресс загрузки одной таблицы источника: сколько строк
+// ожидалось по подсчёту и сколько уже записано во временную таблицу
+type TaskTableProgress struct {
+	Source   string `json:"source"`
+	Schema   string `json:"schema"`
+	Table    string `json:"table"`
+	Expected int64  `json:"expected"`
+	Loaded   int64  `json:"loaded"`
+}
+
+// TaskChunk — чекпоинт загрузки: чанк таблицы источника из плана задачи и отметка
+// о том, что его строки полностью записаны во временную таблицу
+type TaskChunk struct {
+	Source    string `json:"source"`
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	TempTable string `json:"temp_table"`
+	Chunk     Chunk  `json:"chunk"`
+	Done      bool   `json:"done"`
+	Rows      int64  `json:"rows"`
 }
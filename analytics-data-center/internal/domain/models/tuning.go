@@ -0,0 +1,10 @@
+package models
+
+// ETLTuning — параметры первичной загрузки view. Нулевые поля берутся из настроек
+// источника OLTP, а затем из глобальных настроек ETL.
+type ETLTuning struct {
+	// Concurrency — сколько чанков задачи загружается одновременно
+	Concurrency int `json:"concurrency,omitempty"`
+	// ChunkSize — сколько строк таблицы источника попадает в один чанк
+	ChunkSize int64 `json:"chunk_size,omitempty"`
+}
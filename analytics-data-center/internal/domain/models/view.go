@@ -4,7 +4,29 @@ type View struct {
 	Name    string   `json:"view_name"`
 	Sources []Source `json:"sources"`
 	Joins   []*Join  `json:"joins"`
+	// DeletePolicy определяет, что делать со строкой вью при удалении строки в OLTP:
+	// "hard" (по умолчанию) — удалить, "soft" — пометить is_deleted/deleted_at
+	DeletePolicy string `json:"delete_policy,omitempty"`
+	// Tuning переопределяет параметры первичной загрузки для этого view
+	Tuning *ETLTuning `json:"tuning,omitempty"`
 }
+
+const (
+	DeletePolicyHard = "hard"
+	DeletePolicySoft = "soft"
+)
+
+// Служебные колонки вью при DeletePolicy = "soft"
+const (
+	SoftDeleteFlagColumn = "is_deleted"
+	SoftDeleteTimeColumn = "deleted_at"
+)
+
+// IsSoftDelete сообщает, помечаются ли удалённые строки вместо физического удаления
+func (v View) IsSoftDelete() bool {
+	return v.DeletePolicy == DeletePolicySoft
+}
+
 type Source struct {
 	Name    string   `json:"name"`
 	Schemas []Schema `json:"schemas"`
@@ -18,6 +40,13 @@ type Schema struct {
 type Table struct {
 	Name    string   `json:"name"`
 	Columns []Column `json:"columns"`
+	// Filter ограничивает строки таблицы, попадающие во view
+	Filter *RowFilter `json:"filter,omitempty"`
+	// Watermark — колонка, значение которой растёт при каждом изменении строки
+	// (updated_at или монотонный id). По ней инкрементальная загрузка выбирает строки,
+	// изменившиеся после сохранённой отметки. Строки, зафиксированные с меньшим
+	// значением позже чтения отметки, и удаления строк такая загрузка не видит.
+	Watermark string `json:"watermark,omitempty"`
 }
 
 type Column struct {
@@ -69,19 +98,74 @@ type Reference struct {
 	Column string `json:"column"`
 }
 
+// Join описывает связь двух таблиц view. Заполняется ровно одно из условий,
+// его поле определяет тип джоина.
 type Join struct {
-	Inner *JoinCondition `json:"inner"`
-	// TO DO сделать другие джоины потом
+	Inner      *JoinCondition `json:"inner"`
+	LeftOuter  *JoinCondition `json:"left,omitempty"`
+	RightOuter *JoinCondition `json:"right,omitempty"`
+	FullOuter  *JoinCondition `json:"full,omitempty"`
+}
+
+type JoinType string
+
+const (
+	JoinInner JoinType = "INNER"
+	JoinLeft  JoinType = "LEFT"
+	JoinRight JoinType = "RIGHT"
+	JoinFull  JoinType = "FULL"
+)
+
+// Condition возвращает заполненное условие джоина и его тип (nil, "" если условия нет)
+func (j *Join) Condition() (*JoinCondition, JoinType) {
+	if j == nil {
+		return nil, ""
+	}
+	switch {
+	case j.Inner != nil:
+		return j.Inner, JoinInner
+	case j.LeftOuter != nil:
+		return j.LeftOuter, JoinLeft
+	case j.RightOuter != nil:
+		return j.RightOuter, JoinRight
+	case j.FullOuter != nil:
+		return j.FullOuter, JoinFull
+	}
+	return nil, ""
 }
 
+// JoinCondition связывает две таблицы. Для составного ключа (например, tenant_id + id)
+// пары колонок перечисляются в Columns, тогда Column в эндпоинтах не заполняется.
 type JoinCondition struct {
-	Left  JoinEndpoint `json:"left"`
-	Right JoinEndpoint `json:"right"`
+	Left    JoinEndpoint     `json:"left"`
+	Right   JoinEndpoint     `json:"right"`
+	Columns []JoinColumnPair `json:"columns,omitempty"`
+}
+
+// JoinColumnPair — пара колонок левой и правой таблицы, участвующая в условии джоина
+type JoinColumnPair struct {
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// ColumnPairs возвращает все пары колонок условия: Columns, а если они не заданы —
+// единственную пару из Left.Column и Right.Column
+func (c *JoinCondition) ColumnPairs() []JoinColumnPair {
+	if c == nil {
+		return nil
+	}
+	if len(c.Columns) > 0 {
+		return c.Columns
+	}
+	if c.Left.Column == "" && c.Right.Column == "" {
+		return nil
+	}
+	return []JoinColumnPair{{Left: c.Left.Column, Right: c.Right.Column}}
 }
 
 type JoinEndpoint struct {
 	Source string `json:"source"`
 	Schema string `json:"schema"`
 	Table  string `json:"table"`
-	Column string `json:"column"`
+	Column string `json:"column,omitempty"`
 }
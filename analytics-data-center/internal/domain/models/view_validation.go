@@ -0,0 +1,35 @@
+package models
+
+// Коды замечаний предварительной проверки view
+const (
+	ViewIssueUnknownSource      = "unknown_source"
+	ViewIssueUnknownTable       = "unknown_table"
+	ViewIssueUnknownColumn      = "unknown_column"
+	ViewIssueCatalogUnavailable = "catalog_unavailable"
+	ViewIssueTypeMismatch       = "type_mismatch"
+	ViewIssueDuplicateColumn    = "duplicate_column"
+	ViewIssueDuplicateOutput    = "duplicate_output"
+	ViewIssueInvalidFilter      = "invalid_filter"
+	ViewIssueInvalidJoin        = "invalid_join"
+	ViewIssueJoinColumn         = "join_column_not_selected"
+	ViewIssueJoinTypeMismatch   = "join_type_mismatch"
+	ViewIssueTableNotJoined     = "table_not_joined"
+	ViewIssueInvalidTuning      = "invalid_tuning"
+	ViewIssueInvalidWatermark   = "invalid_watermark"
+)
+
+// ViewIssue — замечание к полю описания view. Field — путь к полю в JSON view,
+// например sources[0].schemas[0].tables[1].columns[2].
+type ViewIssue struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ViewValidation — результат проверки view по каталогу OLTP и графу джоинов.
+// View с ошибками не загружается; предупреждения загрузку не блокируют.
+type ViewValidation struct {
+	Valid    bool        `json:"valid"`
+	Errors   []ViewIssue `json:"errors,omitempty"`
+	Warnings []ViewIssue `json:"warnings,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Режимы задания ETL
+const (
+	// ETLModeFull — полная пересборка view в новую таблицу
+	ETLModeFull = "full"
+	// ETLModeIncremental — дозагрузка строк, изменившихся после сохранённых отметок,
+	// в рабочую таблицу view по ключам обновления
+	ETLModeIncremental = "incremental"
+)
+
+// ViewWatermark — верхняя отметка таблицы view: наибольшее значение колонки Table.Watermark
+// среди строк, уже загруженных во view. Значение хранится текстом и передаётся в запрос
+// к источнику параметром, тип которого Postgres выводит из колонки.
+type ViewWatermark struct {
+	Source    string    `json:"source"`
+	Schema    string    `json:"schema"`
+	Table     string    `json:"table"`
+	Column    string    `json:"column"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -2,8 +2,11 @@ package analyticsdatacenter
 
 import (
 	"analyticDataCenter/analytics-data-center/internal/lib/validate"
+	"analyticDataCenter/analytics-data-center/internal/storage"
 	"context"
+	"errors"
 
+	analyticstasksv1 "analyticDataCenter/analytics-data-center/gen/go/analytics"
 	analyticsv1 "github.com/alexardishev/proto_auth/gen/go/analytics"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -12,6 +15,8 @@ import (
 
 type AnalyticsDataCenter interface {
 	StartETLProcess(ctx context.Context, idSchema int64) (taskID string, err error)
+	CancelTask(ctx context.Context, taskID string) error
+	ResumeTask(ctx context.Context, taskID string) error
 }
 
 const (
@@ -28,6 +33,7 @@ type CheckValidateStartETL struct {
 
 func RegisterServerAPI(gRPC *grpc.Server, analyticsDataCenter AnalyticsDataCenter) {
 	analyticsv1.RegisterAnalyticsServer(gRPC, &serverAPI{analyticsDataCenter: analyticsDataCenter})
+	analyticstasksv1.RegisterAnalyticsTasksServer(gRPC, &tasksServerAPI{analyticsDataCenter: analyticsDataCenter})
 }
 
 func (s *serverAPI) StartETLProcess(ctx context.Context, req *analyticsv1.StartETLProcessRequest) (*analyticsv1.StartETLProcessResponse, error) {
@@ -38,6 +44,9 @@ func (s *serverAPI) StartETLProcess(ctx context.Context, req *analyticsv1.StartE
 	}
 
 	taskID, err := s.analyticsDataCenter.StartETLProcess(ctx, req.GetShemaID())
+	if errors.Is(err, storage.ErrQueueFull) {
+		return nil, status.Error(codes.ResourceExhausted, "очередь ETL переполнена, повторите запуск позже")
+	}
 	if err != nil {
 		return nil, status.Error(codes.Internal, "процесс не удалось запустить!")
 	}
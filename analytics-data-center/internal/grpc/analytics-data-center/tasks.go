@@ -0,0 +1,61 @@
+package analyticsdatacenter
+
+import (
+	analyticstasksv1 "analyticDataCenter/analytics-data-center/gen/go/analytics"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"errors"
+
+	serviceanalytics "analyticDataCenter/analytics-data-center/internal/services/analytics"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tasksServerAPI — сервис analyticsgrpc.AnalyticsTasks из proto/analytics/tasks.proto:
+// отмена и продолжение задач ETL
+type tasksServerAPI struct {
+	analyticstasksv1.UnimplementedAnalyticsTasksServer
+	analyticsDataCenter AnalyticsDataCenter
+}
+
+func (s *tasksServerAPI) CancelTask(ctx context.Context, req *analyticstasksv1.CancelTaskRequest) (*analyticstasksv1.CancelTaskResponse, error) {
+	taskID := req.GetTaskID()
+	if _, err := uuid.Parse(taskID); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "некорректный идентификатор задачи")
+	}
+
+	err := s.analyticsDataCenter.CancelTask(ctx, taskID)
+	switch {
+	case err == nil:
+		return &analyticstasksv1.CancelTaskResponse{}, nil
+	case errors.Is(err, storage.ErrTaskNotFound):
+		return nil, status.Error(codes.NotFound, "задача не найдена")
+	case errors.Is(err, serviceanalytics.ErrTaskFinished):
+		return nil, status.Error(codes.FailedPrecondition, "задача уже завершена")
+	default:
+		return nil, status.Error(codes.Internal, "задачу не удалось отменить")
+	}
+}
+
+func (s *tasksServerAPI) ResumeTask(ctx context.Context, req *analyticstasksv1.ResumeTaskRequest) (*analyticstasksv1.ResumeTaskResponse, error) {
+	taskID := req.GetTaskID()
+	if _, err := uuid.Parse(taskID); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "некорректный идентификатор задачи")
+	}
+
+	err := s.analyticsDataCenter.ResumeTask(ctx, taskID)
+	switch {
+	case err == nil:
+		return &analyticstasksv1.ResumeTaskResponse{}, nil
+	case errors.Is(err, storage.ErrTaskNotFound):
+		return nil, status.Error(codes.NotFound, "задача не найдена")
+	case errors.Is(err, serviceanalytics.ErrTaskNotResumable):
+		return nil, status.Error(codes.FailedPrecondition, "задачу нельзя продолжить")
+	case errors.Is(err, storage.ErrQueueFull):
+		return nil, status.Error(codes.ResourceExhausted, "очередь ETL переполнена")
+	default:
+		return nil, status.Error(codes.Internal, "задачу не удалось продолжить")
+	}
+}
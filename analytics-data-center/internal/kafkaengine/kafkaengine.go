@@ -20,6 +20,7 @@ func NewKafkaConsumer(
 	SessionTimeoutMs string,
 	ClientId string,
 	topics []string,
+	rebalance kafka.RebalanceCb,
 	log *loggerpkg.Logger,
 ) (*kafka.Consumer, error) {
 	c, err := kafka.NewConsumer(&kafka.ConfigMap{
@@ -39,7 +40,7 @@ func NewKafkaConsumer(
 		log.WarnMsg(loggerpkg.MsgKafkaNoPatternTopics)
 	} else {
 		log.Info("Kafka consumer subscribing to topics", slog.Any("topics", topics))
-		err = c.SubscribeTopics(topics, RebalanceCallback)
+		err = c.SubscribeTopics(topics, rebalance)
 		if err != nil {
 			log.ErrorMsg(loggerpkg.MsgKafkaSubscribeError, slog.String("error", err.Error()))
 			return nil, err
@@ -85,6 +86,10 @@ func RebalanceCallback(c *kafka.Consumer, ev kafka.Event) error {
 	}
 }
 
+// RevokeHandler вызывается при отзыве разделов до снятия назначения, пока offset'ы
+// отзываемых разделов ещё можно закоммитить
+type RevokeHandler func(c *kafka.Consumer, partitions []kafka.TopicPartition)
+
 type TopicLister interface {
 	ListTopics(ctx context.Context) ([]string, error)
 }
@@ -95,6 +100,8 @@ type Engine struct {
 	topicCh    chan string
 	subscribed map[string]struct{}
 	mu         sync.Mutex
+	revokeMu   sync.RWMutex
+	onRevoke   RevokeHandler
 }
 
 func NewEngine(
@@ -117,17 +124,16 @@ func NewEngine(
 		}
 	}
 
-	c, err := NewKafkaConsumer(BootstrapServers, GroupId, AutoOffsetReset, EnableAutoCommit, SessionTimeoutMs, ClientId, topics, log)
-	if err != nil {
-		return nil, err
-	}
-
 	eng := &Engine{
-		consumer:   c,
 		log:        log,
 		topicCh:    make(chan string, 100),
 		subscribed: make(map[string]struct{}),
 	}
+	c, err := NewKafkaConsumer(BootstrapServers, GroupId, AutoOffsetReset, EnableAutoCommit, SessionTimeoutMs, ClientId, topics, eng.rebalance, log)
+	if err != nil {
+		return nil, err
+	}
+	eng.consumer = c
 	for _, t := range topics {
 		eng.subscribed[t] = struct{}{}
 	}
@@ -139,6 +145,27 @@ func NewEngine(
 // Вернет эземпляр консьюмера
 func (e *Engine) Consumer() *kafka.Consumer { return e.consumer }
 
+// SetRevokeHandler задаёт обработчик отзыва разделов
+func (e *Engine) SetRevokeHandler(handler RevokeHandler) {
+	e.revokeMu.Lock()
+	defer e.revokeMu.Unlock()
+	e.onRevoke = handler
+}
+
+// rebalance передаёт отзываемые разделы обработчику отзыва и затем назначает
+// разделы так же, как RebalanceCallback
+func (e *Engine) rebalance(c *kafka.Consumer, ev kafka.Event) error {
+	if revoked, ok := ev.(kafka.RevokedPartitions); ok {
+		e.revokeMu.RLock()
+		onRevoke := e.onRevoke
+		e.revokeMu.RUnlock()
+		if onRevoke != nil {
+			onRevoke(c, revoked.Partitions)
+		}
+	}
+	return RebalanceCallback(c, ev)
+}
+
 // Событие в очередь и в дальше в воркер
 func (e *Engine) EnqueueTopic(topic string) {
 	select {
@@ -157,7 +184,7 @@ func (e *Engine) subscriptionWorker() {
 			for t := range e.subscribed {
 				topics = append(topics, t)
 			}
-			if err := e.consumer.SubscribeTopics(topics, RebalanceCallback); err != nil {
+			if err := e.consumer.SubscribeTopics(topics, e.rebalance); err != nil {
 				e.log.ErrorMsg(loggerpkg.MsgKafkaSubscribeError, slog.String("error", err.Error()))
 			}
 		}
@@ -0,0 +1,285 @@
+package sqlgenerator
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// ClickhouseBatchValues раскладывает строку выборки по колонкам в порядке columns и
+// приводит значения к Go-типам, которые clickhouse-go ожидает для колонок таблицы:
+// columnTypes — типы ClickHouse (как в system.columns), например Nullable(Int32),
+// Array(Nullable(String)), Decimal(10, 2), DateTime64(6).
+func ClickhouseBatchValues(row map[string]interface{}, columns []string, columnTypes map[string]string) ([]interface{}, error) {
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		chType, ok := columnTypes[col]
+		if !ok {
+			return nil, fmt.Errorf("колонка %s отсутствует в таблице ClickHouse", col)
+		}
+		val, err := clickhouseValue(row[col], chType)
+		if err != nil {
+			return nil, fmt.Errorf("колонка %s: %w", col, err)
+		}
+		values[i] = val
+	}
+	return values, nil
+}
+
+func clickhouseValue(val interface{}, chType string) (interface{}, error) {
+	base, nullable := unwrapClickhouseType(chType)
+	if val == nil {
+		if nullable {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("NULL в колонке типа %s", chType)
+	}
+
+	switch {
+	case strings.HasPrefix(base, "Array("):
+		return clickhouseArray(val, innerClickhouseType(base))
+	case strings.HasPrefix(base, "Decimal"):
+		return clickhouseDecimal(val)
+	case strings.HasPrefix(base, "DateTime"), base == "Date", base == "Date32":
+		return clickhouseTime(val)
+	case base == "String", strings.HasPrefix(base, "FixedString"), base == "UUID":
+		return clickhouseString(val)
+	case base == "Bool":
+		return clickhouseBool(val)
+	case base == "Float32":
+		f, err := clickhouseFloat(val, 32)
+		return float32(f), err
+	case base == "Float64":
+		return clickhouseFloat(val, 64)
+	case strings.HasPrefix(base, "Int"), strings.HasPrefix(base, "UInt"):
+		return clickhouseInteger(val, base)
+	}
+	return nil, fmt.Errorf("неподдерживаемый тип колонки ClickHouse %s", chType)
+}
+
+// unwrapClickhouseType снимает обёртки LowCardinality(...) и Nullable(...)
+func unwrapClickhouseType(chType string) (string, bool) {
+	base := strings.TrimSpace(chType)
+	nullable := false
+	for {
+		switch {
+		case strings.HasPrefix(base, "LowCardinality("):
+			base = innerClickhouseType(base)
+		case strings.HasPrefix(base, "Nullable("):
+			base = innerClickhouseType(base)
+			nullable = true
+		default:
+			return base, nullable
+		}
+	}
+}
+
+func innerClickhouseType(chType string) string {
+	open := strings.Index(chType, "(")
+	if open < 0 || !strings.HasSuffix(chType, ")") {
+		return chType
+	}
+	return strings.TrimSpace(chType[open+1 : len(chType)-1])
+}
+
+// clickhouseArray принимает Go-срез или текстовый литерал массива Postgres ({a,b,NULL})
+func clickhouseArray(val interface{}, innerType string) (interface{}, error) {
+	var items []interface{}
+	switch v := val.(type) {
+	case []byte:
+		return clickhouseArray(string(v), innerType)
+	case string:
+		var parsed []sql.NullString
+		if err := pq.Array(&parsed).Scan(v); err != nil {
+			return nil, fmt.Errorf("не удалось разобрать массив %q: %w", v, err)
+		}
+		items = make([]interface{}, len(parsed))
+		for i, item := range parsed {
+			if item.Valid {
+				items[i] = item.String
+			}
+		}
+	default:
+		rv := reflect.ValueOf(val)
+		if rv.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("значение %T нельзя записать в массив", val)
+		}
+		items = make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+	}
+
+	result := make([]interface{}, len(items))
+	for i, item := range items {
+		converted, err := clickhouseValue(item, innerType)
+		if err != nil {
+			return nil, fmt.Errorf("элемент %d: %w", i, err)
+		}
+		result[i] = converted
+	}
+	return result, nil
+}
+
+func clickhouseDecimal(val interface{}) (decimal.Decimal, error) {
+	switch v := val.(type) {
+	case decimal.Decimal:
+		return v, nil
+	case []byte:
+		return decimal.NewFromString(string(v))
+	case string:
+		return decimal.NewFromString(strings.TrimSpace(v))
+	case float32:
+		return decimal.NewFromFloat32(v), nil
+	case float64:
+		return decimal.NewFromFloat(v), nil
+	case int:
+		return decimal.NewFromInt(int64(v)), nil
+	case int32:
+		return decimal.NewFromInt32(v), nil
+	case int64:
+		return decimal.NewFromInt(v), nil
+	}
+	return decimal.Decimal{}, fmt.Errorf("значение %T нельзя записать в Decimal", val)
+}
+
+var clickhouseTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+func clickhouseTime(val interface{}) (time.Time, error) {
+	switch v := val.(type) {
+	case time.Time:
+		return v, nil
+	case []byte:
+		return clickhouseTime(string(v))
+	case string:
+		for _, layout := range clickhouseTimeLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("не удалось разобрать дату %q", v)
+	}
+	return time.Time{}, fmt.Errorf("значение %T нельзя записать в дату", val)
+}
+
+func clickhouseString(val interface{}) (string, error) {
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case uuid.UUID:
+		return v.String(), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int32, int64, float32, float64:
+		return fmt.Sprintf("%v", v), nil
+	}
+	return "", fmt.Errorf("значение %T нельзя записать в строку", val)
+}
+
+func clickhouseBool(val interface{}) (bool, error) {
+	switch v := val.(type) {
+	case bool:
+		return v, nil
+	case []byte:
+		return strconv.ParseBool(string(v))
+	case string:
+		return strconv.ParseBool(v)
+	}
+	return false, fmt.Errorf("значение %T нельзя записать в Bool", val)
+}
+
+func clickhouseFloat(val interface{}, bitSize int) (float64, error) {
+	switch v := val.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case []byte:
+		return strconv.ParseFloat(string(v), bitSize)
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), bitSize)
+	}
+	return 0, fmt.Errorf("значение %T нельзя записать в Float%d", val, bitSize)
+}
+
+// clickhouseInteger приводит значение к целому типу нужной разрядности (Int32 → int32 и т.д.)
+// с проверкой диапазона; логические значения пишутся как 0/1 (UInt8 у булевых колонок)
+func clickhouseInteger(val interface{}, base string) (interface{}, error) {
+	unsigned := strings.HasPrefix(base, "UInt")
+	bitSize, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(base, "U"), "Int"))
+	if err != nil || bitSize > 64 {
+		return nil, fmt.Errorf("неподдерживаемый целочисленный тип %s", base)
+	}
+
+	var text string
+	switch v := val.(type) {
+	case bool:
+		text = "0"
+		if v {
+			text = "1"
+		}
+	case int, int32, int64, uint8, uint16, uint32, uint64:
+		text = fmt.Sprintf("%d", v)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case []byte:
+		text = string(v)
+	case string:
+		text = strings.TrimSpace(v)
+	default:
+		return nil, fmt.Errorf("значение %T нельзя записать в %s", val, base)
+	}
+
+	if unsigned {
+		n, err := strconv.ParseUint(text, 10, bitSize)
+		if err != nil {
+			return nil, fmt.Errorf("значение %q не помещается в %s: %w", text, base, err)
+		}
+		switch bitSize {
+		case 8:
+			return uint8(n), nil
+		case 16:
+			return uint16(n), nil
+		case 32:
+			return uint32(n), nil
+		}
+		return n, nil
+	}
+
+	n, err := strconv.ParseInt(text, 10, bitSize)
+	if err != nil {
+		return nil, fmt.Errorf("значение %q не помещается в %s: %w", text, base, err)
+	}
+	switch bitSize {
+	case 8:
+		return int8(n), nil
+	case 16:
+		return int16(n), nil
+	case 32:
+		return int32(n), nil
+	}
+	return n, nil
+}
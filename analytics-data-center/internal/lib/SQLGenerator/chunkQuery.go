@@ -0,0 +1,136 @@
+package sqlgenerator
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// chunkWhere собирает " WHERE ..." из фильтра таблицы и диапазона чанка.
+// Границы чанка передаются аргументами запроса, а не литералами.
+func chunkWhere(filter *models.RowFilter, chunk models.Chunk, dbType string) (string, []interface{}, error) {
+	var conditions []string
+
+	filterCondition, err := GenerateFilterCondition(filter, dbType)
+	if err != nil {
+		return "", nil, err
+	}
+	if filterCondition != "" {
+		conditions = append(conditions, filterCondition)
+	}
+
+	rangeCondition, args, err := chunkRangeCondition(chunk, dbType)
+	if err != nil {
+		return "", nil, err
+	}
+	if rangeCondition != "" {
+		conditions = append(conditions, rangeCondition)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// chunkRangeCondition формирует условие Lower <= ключ < Upper. Составной ключ
+// сравнивается как кортеж, что совпадает с порядком ORDER BY по тем же колонкам.
+func chunkRangeCondition(chunk models.Chunk, dbType string) (string, []interface{}, error) {
+	if len(chunk.Lower) == 0 && len(chunk.Upper) == 0 {
+		return "", nil, nil
+	}
+	if len(chunk.Columns) == 0 {
+		return "", nil, fmt.Errorf("у чанка %d не заданы ключевые колонки", chunk.Index)
+	}
+
+	var (
+		parts []string
+		args  []interface{}
+	)
+	placeholder := func() string {
+		if dbType == DbClickhouse {
+			return "?"
+		}
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if chunk.ByCtid() {
+		if dbType != DbPostgres {
+			return "", nil, fmt.Errorf("чанки по ctid поддерживаются только для Postgres")
+		}
+		for _, bound := range []struct {
+			values []interface{}
+			op     string
+		}{{chunk.Lower, ">="}, {chunk.Upper, "<"}} {
+			if len(bound.values) == 0 {
+				continue
+			}
+			args = append(args, fmt.Sprintf("(%v,0)", bound.values[0]))
+			parts = append(parts, fmt.Sprintf("ctid %s %s::tid", bound.op, placeholder()))
+		}
+		return strings.Join(parts, " AND "), args, nil
+	}
+
+	key := keyTuple(chunk.Columns)
+	for _, bound := range []struct {
+		values []interface{}
+		op     string
+	}{{chunk.Lower, ">="}, {chunk.Upper, "<"}} {
+		if len(bound.values) == 0 {
+			continue
+		}
+		if len(bound.values) != len(chunk.Columns) {
+			return "", nil, fmt.Errorf("у чанка %d граница не совпадает с ключом по числу колонок", chunk.Index)
+		}
+		placeholders := make([]string, 0, len(bound.values))
+		for _, v := range bound.values {
+			args = append(args, v)
+			placeholders = append(placeholders, placeholder())
+		}
+		value := placeholders[0]
+		if len(placeholders) > 1 {
+			value = "(" + strings.Join(placeholders, ", ") + ")"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", key, bound.op, value))
+	}
+	return strings.Join(parts, " AND "), args, nil
+}
+
+func keyTuple(columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quoted = append(quoted, pq.QuoteIdentifier(col))
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return "(" + strings.Join(quoted, ", ") + ")"
+}
+
+// GenerateChunkRowsCondition формирует условие, выделяющее строки чанка во временной
+// таблице: ключевые колонки источника заменяются их именами во временной таблице.
+// Чанки по ctid так выделить нельзя — физический адрес строки во временную таблицу не попадает.
+func GenerateChunkRowsCondition(table models.Table, chunk models.Chunk, dbType string) (string, []interface{}, error) {
+	if chunk.ByCtid() {
+		return "", nil, fmt.Errorf("строки чанка %d по ctid нельзя выделить во временной таблице", chunk.Index)
+	}
+
+	columns := make([]string, 0, len(chunk.Columns))
+	for _, name := range chunk.Columns {
+		resolved := ""
+		for _, col := range table.Columns {
+			if col.Name == name {
+				resolved = resolveInsertColumnName(col)
+				break
+			}
+		}
+		if resolved == "" {
+			return "", nil, fmt.Errorf("ключевая колонка %s чанка %d не найдена в таблице %s", name, chunk.Index, table.Name)
+		}
+		columns = append(columns, resolved)
+	}
+	chunk.Columns = columns
+	return chunkRangeCondition(chunk, dbType)
+}
@@ -0,0 +1,57 @@
+package sqlgenerator
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Колонки результата GenerateKeyRangeQuery
+const (
+	KeyRangeMinColumn = "min_key"
+	KeyRangeMaxColumn = "max_key"
+)
+
+// GenerateKeyRangeQuery возвращает минимальное и максимальное значение
+// целочисленного ключа таблицы с учётом её фильтра
+func GenerateKeyRangeQuery(tableName, column string, filter *models.RowFilter) (string, error) {
+	where, err := whereFilter(filter, DbPostgres)
+	if err != nil {
+		return "", err
+	}
+	key := pq.QuoteIdentifier(column)
+	return fmt.Sprintf("SELECT min(%s) AS %s, max(%s) AS %s FROM %s%s",
+		key, KeyRangeMinColumn, key, KeyRangeMaxColumn, tableName, where), nil
+}
+
+// GenerateKeyBoundariesQuery выбирает каждое step-е значение ключа в порядке сортировки,
+// начиная со step+1-й строки: это нижние границы всех чанков, кроме первого
+func GenerateKeyBoundariesQuery(tableName string, columns []string, filter *models.RowFilter, step int64) (string, error) {
+	if len(columns) == 0 {
+		return "", fmt.Errorf("не заданы ключевые колонки таблицы %s", tableName)
+	}
+	if step <= 0 {
+		return "", fmt.Errorf("некорректный размер чанка: %d", step)
+	}
+	where, err := whereFilter(filter, DbPostgres)
+	if err != nil {
+		return "", err
+	}
+
+	quoted := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quoted = append(quoted, pq.QuoteIdentifier(col))
+	}
+	keys := strings.Join(quoted, ", ")
+
+	return fmt.Sprintf("SELECT %s FROM (SELECT %s, row_number() OVER (ORDER BY %s) AS chunk_rn FROM %s%s) AS chunk_keys WHERE chunk_rn > 1 AND (chunk_rn - 1) %% %d = 0 ORDER BY %s",
+		keys, keys, keys, tableName, where, step, keys), nil
+}
+
+// GenerateRelationPagesQuery возвращает число страниц таблицы — основу чанков по ctid
+func GenerateRelationPagesQuery(tableName string) string {
+	return fmt.Sprintf("SELECT pg_relation_size(%s) / current_setting('block_size')::bigint",
+		pq.QuoteLiteral(pq.QuoteIdentifier(tableName)))
+}
@@ -0,0 +1,72 @@
+package sqlgenerator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+// PostgresCopyValues раскладывает строку выборки по колонкам в порядке columns и
+// приводит значения к типам, которые lib/pq передаёт в COPY без потерь:
+// bytea остаётся []byte, прочие []byte — текстом, массивы оборачиваются в pq.Array.
+func PostgresCopyValues(row map[string]interface{}, columns []string, columnTypes map[string]string) ([]interface{}, error) {
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		val, err := postgresCopyValue(row[col], strings.ToLower(columnTypes[col]))
+		if err != nil {
+			return nil, fmt.Errorf("колонка %s: %w", col, err)
+		}
+		values[i] = val
+	}
+	return values, nil
+}
+
+func postgresCopyValue(val interface{}, typeLower string) (interface{}, error) {
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case string, bool, int, int32, int64, float32, float64:
+		return v, nil
+	case []byte:
+		if isByteaType(typeLower) {
+			return v, nil
+		}
+		return string(v), nil
+	case uuid.UUID:
+		return v.String(), nil
+	case time.Time:
+		return postgresTimeValue(v, typeLower), nil
+	case []interface{}, []int, []int64, []float64, []string, []bool, [][]byte:
+		return pq.Array(v), nil
+	case []uuid.UUID:
+		arr := make([]string, len(v))
+		for i, item := range v {
+			arr[i] = item.String()
+		}
+		return pq.Array(arr), nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип значения (%T)", v)
+	}
+}
+
+// postgresTimeValue оставляет time.Time для дат и меток времени (lib/pq передаёт их
+// с микросекундами и смещением), а для time/timetz отдаёт только время суток
+func postgresTimeValue(t time.Time, typeLower string) interface{} {
+	switch {
+	case strings.Contains(typeLower, "timestamp"), strings.Contains(typeLower, "date"):
+		return t
+	case strings.Contains(typeLower, "timetz"), strings.Contains(typeLower, "with time zone"):
+		return t.Format("15:04:05.999999-07:00")
+	case strings.Contains(typeLower, "time"):
+		return t.Format("15:04:05.999999")
+	default:
+		return t
+	}
+}
+
+func isByteaType(typeLower string) bool {
+	return strings.Contains(typeLower, "bytea")
+}
@@ -41,3 +41,12 @@ func sanitizeIndexName(name string) string {
 	sanitized := re.ReplaceAllString(name, "_")
 	return strings.Trim(sanitized, "_")
 }
+
+// UniqueKeyIndexQuery формирует уникальный индекс по ключам обновления view, под
+// который CDC пишет пачки через INSERT ... ON CONFLICT. Номер n различает индексы
+// таблиц с разными наборами ключей.
+func UniqueKeyIndexQuery(schema string, table string, columns []string, n int) string {
+	timePrefix := time.Now().UTC().Format("20060102_150405")
+	name := fmt.Sprintf("uk_%s_%d_%s", timePrefix, n, sanitizeIndexName(table))
+	return fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s.%s (%s)", name, schema, table, strings.Join(columns, ", "))
+}
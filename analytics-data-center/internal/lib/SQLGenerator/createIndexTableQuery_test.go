@@ -25,3 +25,10 @@ func TestTransformIndexDefToSQLExpression_RenamesIndexWithTimestamp(t *testing.T
 	pattern := regexp.MustCompile(`CREATE INDEX idx_\d{8}_\d{6}_my_complex_index ON`)
 	require.True(t, pattern.MatchString(query), "index name should be prefixed with timestamp and sanitized")
 }
+
+func TestUniqueKeyIndexQuery(t *testing.T) {
+	query := UniqueKeyIndexQuery("public", "temp_sales__shadow", []string{"id", "tenant_id"}, 1)
+
+	pattern := regexp.MustCompile(`^CREATE UNIQUE INDEX uk_\d{8}_\d{6}_1_temp_sales__shadow ON public\.temp_sales__shadow \(id, tenant_id\)$`)
+	require.True(t, pattern.MatchString(query), query)
+}
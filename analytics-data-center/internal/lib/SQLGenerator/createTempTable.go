@@ -2,8 +2,12 @@ package sqlgenerator
 
 import (
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/lib/duplicate"
 	"fmt"
+	"hash/fnv"
 	"log/slog"
+	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -11,18 +15,120 @@ const (
 	DbClickhouse = "clickhouse"
 )
 
-// Универсальная функция выбора адаптера под базу
+// TempTablePrefix — префикс имён таблиц, которые ETL создаёт на время задачи
+const TempTablePrefix = "temp_"
+
+const (
+	// taskTagLength — сколько символов идентификатора задачи входит в имена её таблиц
+	taskTagLength = 12
+	// maxTableNameBytes — предел длины идентификатора PostgreSQL
+	maxTableNameBytes = 63
+)
+
+// TempTableName возвращает имя временной таблицы задачи для таблицы источника:
+// temp_<задача>_<source>_<schema>_<table>. Идентификатор задачи в имени не даёт задачам,
+// читающим один источник, удалять и перезаписывать временные таблицы друг друга.
+func TempTableName(taskID, source, schema, table string) string {
+	return taskTableName(taskID, fmt.Sprintf("%s_%s_%s", source, schema, table))
+}
+
+// TaskTablePrefix возвращает общий префикс имён временных и теневых таблиц задачи
+func TaskTablePrefix(taskID string) string {
+	return taskTableName(taskID, "")
+}
+
+// taskTableName возвращает имя таблицы задачи temp_<задача>_<name>; без задачи
+// (план ETL) — temp_<name>. Слишком длинное имя укорачивается с хэшем полного имени,
+// чтобы PostgreSQL не обрезал его сам и имя в DWH совпадало с закреплённым за задачей.
+func taskTableName(taskID, name string) string {
+	if tag := taskTag(taskID); tag != "" {
+		name = tag + "_" + name
+	}
+	return limitTableName(TempTablePrefix + name)
+}
+
+// taskTag возвращает метку задачи для имён таблиц: первые символы идентификатора без
+// дефисов и прочих знаков, в нижнем регистре
+func taskTag(taskID string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(taskID) {
+		if b.Len() == taskTagLength {
+			break
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func limitTableName(name string) string {
+	if len(name) <= maxTableNameBytes {
+		return name
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	suffix := fmt.Sprintf("_%08x", h.Sum32())
+	cut := maxTableNameBytes - len(suffix)
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return name[:cut] + suffix
+}
+
+// Универсальная функция выбора адаптера под базу. Временные таблицы именуются по
+// задаче taskID, см. TempTableName.
 func GenerateQueryCreateTempTable(
 	schema *models.View,
+	taskID string,
 	logger *slog.Logger,
 	dbName string,
 ) (models.Queries, []string, error) {
 	switch dbName {
 	case DbPostgres:
-		return GenerateQueryCreateTempTablePostgres(schema, logger, dbName)
+		return GenerateQueryCreateTempTablePostgres(schema, taskID, logger, dbName)
 	case DbClickhouse:
-		return GenerateQueryCreateTempTableClickhouse(schema, logger)
+		return GenerateQueryCreateTempTableClickhouse(schema, taskID, logger)
 	default:
 		return models.Queries{}, nil, fmt.Errorf("unsupported db: %s", dbName)
 	}
 }
+
+// tempTableColumns возвращает колонки временной таблицы: колонки таблицы источника и
+// колонки, порождённые трансформациями JSON и FieldTransform, без дублей по имени
+func tempTableColumns(tbl models.Table) ([]models.Column, []string) {
+	columns := append([]models.Column(nil), tbl.Columns...)
+	for _, clmn := range tbl.Columns {
+		if clmn.Transform == nil {
+			continue
+		}
+		if clmn.Transform.Type == transformTypeJSON {
+			for _, colmnMappingRow := range clmn.Transform.Mapping.MappingJSON {
+				for _, value := range colmnMappingRow.Mapping {
+					columns = append(columns, models.Column{
+						Name:       value,
+						Type:       colmnMappingRow.TypeField,
+						IsNullable: true,
+					})
+				}
+			}
+		}
+		if clmn.Transform.Type == transformTypeFieldTransform {
+			columns = append(columns, models.Column{
+				Name:       clmn.Transform.Mapping.AliasNewColumnTransform,
+				IsNullable: true,
+			})
+		}
+	}
+	return duplicate.RemoveDuplicateColumns(columns)
+}
+
+// TempTableColumnNames возвращает имена колонок временной таблицы в порядке их создания
+func TempTableColumnNames(tbl models.Table) []string {
+	columns, _ := tempTableColumns(tbl)
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, resolveInsertColumnName(col))
+	}
+	return names
+}
@@ -2,7 +2,6 @@ package sqlgenerator
 
 import (
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
-	"analyticDataCenter/analytics-data-center/internal/lib/duplicate"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -13,6 +12,8 @@ func MapTypeToClickhouse(pgType string) string {
 	switch strings.ToLower(pgType) {
 	case "text", "varchar", "character varying":
 		return "String"
+	case "smallint", "int2":
+		return "Int16"
 	case "int", "integer", "int4":
 		return "Int32"
 	case "bigint", "int8":
@@ -25,15 +26,65 @@ func MapTypeToClickhouse(pgType string) string {
 		return "UInt8"
 	case "date":
 		return "Date"
-	case "timestamp", "timestamp without time zone", "timestamptz":
-		return "DateTime"
+	case "timestamp", "timestamp without time zone", "timestamptz", "timestamp with time zone":
+		// Postgres хранит метки времени с микросекундами
+		return "DateTime64(6)"
 	default:
 		return "String"
 	}
 }
 
+// MapColumnTypeToClickhouse строит тип колонки временной таблицы ClickHouse по описанию
+// колонки Postgres: numeric с точностью → Decimal(P,S), массивы (_int4, text[]) →
+// Array(Nullable(T)), nullable-колонки оборачиваются в Nullable (кроме массивов,
+// которые в ClickHouse не бывают Nullable).
+func MapColumnTypeToClickhouse(col models.Column) string {
+	udtName := strings.ToLower(strings.TrimSpace(col.UdtName))
+	dataType := strings.ToLower(strings.TrimSpace(col.DataType))
+	legacyType := strings.ToLower(strings.TrimSpace(col.Type))
+
+	isArray := false
+	base := legacyType
+	switch {
+	case strings.HasPrefix(udtName, "_"):
+		isArray, base = true, strings.TrimPrefix(udtName, "_")
+	case strings.HasPrefix(legacyType, "_"):
+		isArray, base = true, strings.TrimPrefix(legacyType, "_")
+	case strings.HasSuffix(legacyType, "[]"):
+		isArray, base = true, strings.TrimSuffix(legacyType, "[]")
+	case udtName != "":
+		base = udtName
+	case dataType != "":
+		base = dataType
+	}
+
+	var mapped string
+	switch base {
+	case "numeric", "decimal":
+		mapped = "String"
+		if col.NumPrecision != nil && *col.NumPrecision > 0 && *col.NumPrecision <= 76 {
+			var scale int64
+			if col.NumScale != nil {
+				scale = *col.NumScale
+			}
+			mapped = fmt.Sprintf("Decimal(%d, %d)", *col.NumPrecision, scale)
+		}
+	default:
+		mapped = MapTypeToClickhouse(base)
+	}
+
+	switch {
+	case isArray:
+		return fmt.Sprintf("Array(Nullable(%s))", mapped)
+	case col.IsNullable:
+		return fmt.Sprintf("Nullable(%s)", mapped)
+	}
+	return mapped
+}
+
 func GenerateQueryCreateTempTableClickhouse(
 	schema *models.View,
+	taskID string,
 	logger *slog.Logger,
 ) (models.Queries, []string, error) {
 	const op = "sqlgenerator.GenerateQueryCreateTempTableClickhouse"
@@ -47,42 +98,13 @@ func GenerateQueryCreateTempTableClickhouse(
 			for _, tbl := range sch.Tables {
 				logger.Info("Table", slog.String("Table", tbl.Name))
 				var b strings.Builder
-				tableName := fmt.Sprintf("temp_%s_%s_%s", source.Name, sch.Name, tbl.Name)
+				tableName := TempTableName(taskID, source.Name, sch.Name, tbl.Name)
 				_, err := b.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", tableName))
 				if err != nil {
 					logger.Error("ошибка", slog.String("error", err.Error()))
 					return models.Queries{}, nil, err
 				}
-				for _, clmn := range tbl.Columns {
-					if clmn.Transform == nil {
-						continue
-					}
-					if clmn.Transform.Type == transformTypeJSON {
-						mapping := clmn.Transform.Mapping.MappingJSON
-						for _, colmnMappingRow := range mapping {
-							for _, value := range colmnMappingRow.Mapping {
-								colName := value
-								typeClm := colmnMappingRow.TypeField
-								column := &models.Column{
-									Name:       colName,
-									Type:       typeClm,
-									IsNullable: true,
-								}
-								tbl.Columns = append(tbl.Columns, *column)
-							}
-						}
-					}
-					if clmn.Transform.Type == transformTypeFieldTransform {
-						mapping := clmn.Transform.Mapping
-						colName := mapping.AliasNewColumnTransform
-						column := &models.Column{
-							Name:       colName,
-							IsNullable: true,
-						}
-						tbl.Columns = append(tbl.Columns, *column)
-					}
-				}
-				cleanList, duplicateList := duplicate.RemoveDuplicateColumns(tbl.Columns)
+				cleanList, duplicateList := tempTableColumns(tbl)
 				if len(duplicateList) > 0 {
 					logger.Warn("duplicate", slog.Any("Дублирующие имена колонок", duplicateList), slog.Any("в таблице", tbl.Name))
 					duplicateColumnNames = append(duplicateColumnNames, duplicateList...)
@@ -94,10 +116,7 @@ func GenerateQueryCreateTempTableClickhouse(
 					if colName == "" {
 						colName = col.Name
 					}
-					colType := MapTypeToClickhouse(col.Type)
-					if col.IsNullable {
-						colType = fmt.Sprintf("Nullable(%s)", colType)
-					}
+					colType := MapColumnTypeToClickhouse(col)
 					line := fmt.Sprintf("  %s %s", colName, colType)
 					if idx < len(cleanList)-1 {
 						line += ","
@@ -114,10 +133,12 @@ func GenerateQueryCreateTempTableClickhouse(
 					return models.Queries{}, nil, err
 				}
 				querySt := &models.Query{
-					TableName: tableName,
-					Query:     b.String(),
+					TableName:     tableName,
+					BaseTableName: tbl.Name,
+					SchemaName:    sch.Name,
+					SourceName:    source.Name,
+					Query:         b.String(),
 				}
-				fmt.Sprintln(querySt.Query)
 				queryObject = append(queryObject, *querySt)
 			}
 		}
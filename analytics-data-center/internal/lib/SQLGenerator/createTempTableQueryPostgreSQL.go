@@ -2,7 +2,6 @@ package sqlgenerator
 
 import (
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
-	"analyticDataCenter/analytics-data-center/internal/lib/duplicate"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -119,6 +118,7 @@ func MapTypeToPostgres(typ string) string {
 
 func GenerateQueryCreateTempTablePostgres(
 	schema *models.View,
+	taskID string,
 	logger *slog.Logger,
 	_ string,
 ) (models.Queries, []string, error) {
@@ -133,7 +133,7 @@ func GenerateQueryCreateTempTablePostgres(
 			for _, tbl := range sch.Tables {
 				logger.Info("Table", slog.String("Table", tbl.Name))
 				var b strings.Builder
-				tableName := fmt.Sprintf("temp_%s_%s_%s", source.Name, sch.Name, tbl.Name)
+				tableName := TempTableName(taskID, source.Name, sch.Name, tbl.Name)
 				quotedTable := pq.QuoteIdentifier(tableName)
 				_, err := b.WriteString(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", quotedTable))
 				if err != nil {
@@ -143,37 +143,7 @@ func GenerateQueryCreateTempTablePostgres(
 
 				linePrimary := ""
 
-				for _, clmn := range tbl.Columns {
-					if clmn.Transform == nil {
-						continue
-					}
-					if clmn.Transform.Type == transformTypeJSON {
-						mapping := clmn.Transform.Mapping.MappingJSON
-						for _, colmnMappingRow := range mapping {
-							for _, value := range colmnMappingRow.Mapping {
-								colName := value
-								typeClm := colmnMappingRow.TypeField
-								column := &models.Column{
-									Name:       colName,
-									Type:       typeClm,
-									IsNullable: true,
-								}
-								tbl.Columns = append(tbl.Columns, *column)
-							}
-						}
-					}
-					if clmn.Transform.Type == transformTypeFieldTransform {
-						mapping := clmn.Transform.Mapping
-						colName := mapping.AliasNewColumnTransform
-						column := &models.Column{
-							Name:       colName,
-							IsNullable: true,
-						}
-						tbl.Columns = append(tbl.Columns, *column)
-					}
-				}
-
-				cleanList, duplicateList := duplicate.RemoveDuplicateColumns(tbl.Columns)
+				cleanList, duplicateList := tempTableColumns(tbl)
 				if len(duplicateList) > 0 {
 					logger.Warn("duplicate", slog.Any("Дублирующие имена колонок", duplicateList), slog.Any("в таблице", tbl.Name))
 					duplicateColumnNames = append(duplicateColumnNames, duplicateList...)
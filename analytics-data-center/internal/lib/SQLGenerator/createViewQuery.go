@@ -24,6 +24,67 @@ func CreateViewQuery(
 	}
 }
 
+// shadowTableSuffix — суффикс теневой таблицы, в которую собирается view при пересборке
+const shadowTableSuffix = "__shadow"
+
+// ShadowTableName возвращает имя теневой таблицы, в которую задача taskID собирает view:
+// temp_<задача>_<view>__shadow. Имя в нижнем регистре, чтобы совпадать и с
+// идентификаторами в кавычках, и без них. Префикс временных таблиц отдаёт теневую
+// таблицу упавшей задачи сборщику мусора.
+func ShadowTableName(viewName string, taskID string) string {
+	return taskTableName(taskID, strings.ToLower(viewName)+shadowTableSuffix)
+}
+
+// joinKeyword возвращает ключевое слово джоина. reversed — к запросу присоединяется
+// левая таблица условия, поэтому LEFT и RIGHT меняются местами.
+func joinKeyword(joinType models.JoinType, reversed bool) string {
+	switch joinType {
+	case models.JoinLeft:
+		if reversed {
+			return "RIGHT JOIN"
+		}
+		return "LEFT JOIN"
+	case models.JoinRight:
+		if reversed {
+			return "LEFT JOIN"
+		}
+		return "RIGHT JOIN"
+	case models.JoinFull:
+		return "FULL JOIN"
+	default:
+		return "JOIN"
+	}
+}
+
+// joinOnClause формирует условие ON по всем парам колонок джоина. reversed — к запросу
+// присоединяется левая таблица, поэтому первой в сравнении идёт правая сторона.
+// quote экранирует идентификаторы (nil — без экранирования).
+func joinOnClause(pairs []models.JoinColumnPair, leftAlias, rightAlias string, reversed bool, quote func(string) string) string {
+	if quote == nil {
+		quote = func(s string) string { return s }
+	}
+	parts := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		left := fmt.Sprintf("%s.%s", quote(leftAlias), quote(pair.Left))
+		right := fmt.Sprintf("%s.%s", quote(rightAlias), quote(pair.Right))
+		if reversed {
+			left, right = right, left
+		}
+		parts = append(parts, fmt.Sprintf("%s = %s", left, right))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// hasOuterJoins сообщает, есть ли во view внешние джоины
+func hasOuterJoins(schema models.View) bool {
+	for _, join := range schema.Joins {
+		if cond, joinType := join.Condition(); cond != nil && joinType != models.JoinInner {
+			return true
+		}
+	}
+	return false
+}
+
 func CleanAndTrim(input string, maxLen int) string {
 	cleaned := strings.TrimSpace(input)
 	cleaned = strings.Join(strings.Fields(cleaned), "_")
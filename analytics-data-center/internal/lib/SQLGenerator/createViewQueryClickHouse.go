@@ -39,21 +39,25 @@ func CreateViewQueryClickhouse(schema models.View, viewJoin models.ViewJoinTable
 		rightTable models.TempTable
 		leftAlias  string
 		rightAlias string
-		leftCol    string
-		rightCol   string
+		columns    []models.JoinColumnPair
+		joinType   models.JoinType
 	}
 
 	rightKeys := make(map[string]struct{})
 
 	for _, join := range schema.Joins {
-		if join.Inner == nil {
+		cond, joinType := join.Condition()
+		if cond == nil {
 			continue
 		}
-		left, leftAlias, err := resolveTempTable(join.Inner.Left)
+		if len(cond.ColumnPairs()) == 0 {
+			return models.Query{}, fmt.Errorf("в условии джоина %s.%s — %s.%s не указаны колонки", cond.Left.Schema, cond.Left.Table, cond.Right.Schema, cond.Right.Table)
+		}
+		left, leftAlias, err := resolveTempTable(cond.Left)
 		if err != nil {
 			return models.Query{}, err
 		}
-		right, rightAlias, err := resolveTempTable(join.Inner.Right)
+		right, rightAlias, err := resolveTempTable(cond.Right)
 		if err != nil {
 			return models.Query{}, err
 		}
@@ -63,15 +67,15 @@ func CreateViewQueryClickhouse(schema models.View, viewJoin models.ViewJoinTable
 			rightTable models.TempTable
 			leftAlias  string
 			rightAlias string
-			leftCol    string
-			rightCol   string
+			columns    []models.JoinColumnPair
+			joinType   models.JoinType
 		}{
 			leftTable:  left,
 			rightTable: right,
 			leftAlias:  leftAlias,
 			rightAlias: rightAlias,
-			leftCol:    join.Inner.Left.Column,
-			rightCol:   join.Inner.Right.Column,
+			columns:    cond.ColumnPairs(),
+			joinType:   joinType,
 		})
 		rightKeys[fmt.Sprintf("%s|%s|%s", right.Source, right.Schema, right.Table)] = struct{}{}
 	}
@@ -119,10 +123,10 @@ func CreateViewQueryClickhouse(schema models.View, viewJoin models.ViewJoinTable
 			}
 
 			if leftKnown && !rightKnown {
-				joinClauses = append(joinClauses, fmt.Sprintf("JOIN %s %s ON %s.%s = %s.%s",
+				joinClauses = append(joinClauses, fmt.Sprintf("%s %s %s ON %s",
+					joinKeyword(j.joinType, false),
 					j.rightTable.TempTableName, j.rightAlias,
-					j.leftAlias, j.leftCol,
-					j.rightAlias, j.rightCol,
+					joinOnClause(j.columns, j.leftAlias, j.rightAlias, false, nil),
 				))
 				known[j.rightTable.TempTableName] = struct{}{}
 				processed[idx] = true
@@ -131,10 +135,10 @@ func CreateViewQueryClickhouse(schema models.View, viewJoin models.ViewJoinTable
 			}
 
 			if rightKnown && !leftKnown {
-				joinClauses = append(joinClauses, fmt.Sprintf("JOIN %s %s ON %s.%s = %s.%s",
+				joinClauses = append(joinClauses, fmt.Sprintf("%s %s %s ON %s",
+					joinKeyword(j.joinType, true),
 					j.leftTable.TempTableName, j.leftAlias,
-					j.rightAlias, j.rightCol,
-					j.leftAlias, j.leftCol,
+					joinOnClause(j.columns, j.leftAlias, j.rightAlias, true, nil),
 				))
 				known[j.leftTable.TempTableName] = struct{}{}
 				processed[idx] = true
@@ -157,6 +161,12 @@ func CreateViewQueryClickhouse(schema models.View, viewJoin models.ViewJoinTable
 			selectParts = append(selectParts, fmt.Sprintf("%s.%s", alias, col.ColumnName))
 		}
 	}
+	if schema.IsSoftDelete() {
+		selectParts = append(selectParts,
+			fmt.Sprintf("toUInt8(0) AS %s", models.SoftDeleteFlagColumn),
+			fmt.Sprintf("CAST(NULL, 'Nullable(DateTime)') AS %s", models.SoftDeleteTimeColumn),
+		)
+	}
 	selectParts = append(selectParts, "now() AS updated_at")
 
 	orderBy := "tuple()"
@@ -165,15 +175,25 @@ func CreateViewQueryClickhouse(schema models.View, viewJoin models.ViewJoinTable
 	}
 
 	engineClause := "ENGINE = ReplacingMergeTree(updated_at)"
+	// внешние джоины должны давать NULL, а не значения по умолчанию ClickHouse;
+	// ключ сортировки при этом может оказаться Nullable
+	outer := hasOuterJoins(schema)
+	tableSettings := ""
+	if outer {
+		tableSettings = " SETTINGS allow_nullable_key = 1"
+	}
 
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf(
-		"CREATE TABLE %s %s ORDER BY %s AS SELECT %s",
-		schema.Name, engineClause, orderBy, strings.Join(selectParts, ", "),
+		"CREATE TABLE %s %s ORDER BY %s%s AS SELECT %s",
+		schema.Name, engineClause, orderBy, tableSettings, strings.Join(selectParts, ", "),
 	))
 
 	fromClause := fmt.Sprintf(" FROM %s %s", rootTable.TempTableName, rootAlias)
 	finalQuery := fmt.Sprintf("%s%s %s", b.String(), fromClause, strings.Join(joinClauses, " "))
+	if outer {
+		finalQuery += " SETTINGS join_use_nulls = 1"
+	}
 
 	return models.Query{
 		Query:     finalQuery,
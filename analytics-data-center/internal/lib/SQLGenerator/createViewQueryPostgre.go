@@ -41,21 +41,25 @@ func CreateViewQueryPostgres(schema models.View, viewJoin models.ViewJoinTable,
 		rightTable models.TempTable
 		leftAlias  string
 		rightAlias string
-		leftCol    string
-		rightCol   string
+		columns    []models.JoinColumnPair
+		joinType   models.JoinType
 	}
 
 	rightKeys := make(map[string]struct{})
 
 	for _, join := range schema.Joins {
-		if join.Inner == nil {
+		cond, joinType := join.Condition()
+		if cond == nil {
 			continue
 		}
-		left, leftAlias, err := resolveTempTable(join.Inner.Left)
+		if len(cond.ColumnPairs()) == 0 {
+			return models.Query{}, fmt.Errorf("в условии джоина %s.%s — %s.%s не указаны колонки", cond.Left.Schema, cond.Left.Table, cond.Right.Schema, cond.Right.Table)
+		}
+		left, leftAlias, err := resolveTempTable(cond.Left)
 		if err != nil {
 			return models.Query{}, err
 		}
-		right, rightAlias, err := resolveTempTable(join.Inner.Right)
+		right, rightAlias, err := resolveTempTable(cond.Right)
 		if err != nil {
 			return models.Query{}, err
 		}
@@ -65,15 +69,15 @@ func CreateViewQueryPostgres(schema models.View, viewJoin models.ViewJoinTable,
 			rightTable models.TempTable
 			leftAlias  string
 			rightAlias string
-			leftCol    string
-			rightCol   string
+			columns    []models.JoinColumnPair
+			joinType   models.JoinType
 		}{
 			leftTable:  left,
 			rightTable: right,
 			leftAlias:  leftAlias,
 			rightAlias: rightAlias,
-			leftCol:    join.Inner.Left.Column,
-			rightCol:   join.Inner.Right.Column,
+			columns:    cond.ColumnPairs(),
+			joinType:   joinType,
 		})
 		rightKeys[fmt.Sprintf("%s|%s|%s", right.Source, right.Schema, right.Table)] = struct{}{}
 	}
@@ -121,10 +125,10 @@ func CreateViewQueryPostgres(schema models.View, viewJoin models.ViewJoinTable,
 			}
 
 			if leftKnown && !rightKnown {
-				joinClauses = append(joinClauses, fmt.Sprintf("JOIN %s %s ON %s.%s = %s.%s",
+				joinClauses = append(joinClauses, fmt.Sprintf("%s %s %s ON %s",
+					joinKeyword(j.joinType, false),
 					pq.QuoteIdentifier(j.rightTable.TempTableName), pq.QuoteIdentifier(j.rightAlias),
-					pq.QuoteIdentifier(j.leftAlias), pq.QuoteIdentifier(j.leftCol),
-					pq.QuoteIdentifier(j.rightAlias), pq.QuoteIdentifier(j.rightCol),
+					joinOnClause(j.columns, j.leftAlias, j.rightAlias, false, pq.QuoteIdentifier),
 				))
 				known[j.rightTable.TempTableName] = struct{}{}
 				processed[idx] = true
@@ -133,10 +137,10 @@ func CreateViewQueryPostgres(schema models.View, viewJoin models.ViewJoinTable,
 			}
 
 			if rightKnown && !leftKnown {
-				joinClauses = append(joinClauses, fmt.Sprintf("JOIN %s %s ON %s.%s = %s.%s",
+				joinClauses = append(joinClauses, fmt.Sprintf("%s %s %s ON %s",
+					joinKeyword(j.joinType, true),
 					pq.QuoteIdentifier(j.leftTable.TempTableName), pq.QuoteIdentifier(j.leftAlias),
-					pq.QuoteIdentifier(j.rightAlias), pq.QuoteIdentifier(j.rightCol),
-					pq.QuoteIdentifier(j.leftAlias), pq.QuoteIdentifier(j.leftCol),
+					joinOnClause(j.columns, j.leftAlias, j.rightAlias, true, pq.QuoteIdentifier),
 				))
 				known[j.leftTable.TempTableName] = struct{}{}
 				processed[idx] = true
@@ -159,6 +163,12 @@ func CreateViewQueryPostgres(schema models.View, viewJoin models.ViewJoinTable,
 			selectParts = append(selectParts, fmt.Sprintf("%s.%s", pq.QuoteIdentifier(alias), pq.QuoteIdentifier(col.ColumnName)))
 		}
 	}
+	if schema.IsSoftDelete() {
+		selectParts = append(selectParts,
+			fmt.Sprintf("FALSE AS %s", pq.QuoteIdentifier(models.SoftDeleteFlagColumn)),
+			fmt.Sprintf("NULL::timestamp AS %s", pq.QuoteIdentifier(models.SoftDeleteTimeColumn)),
+		)
+	}
 
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("CREATE TABLE %s AS SELECT %s", pq.QuoteIdentifier(schema.Name), strings.Join(selectParts, ",")))
@@ -0,0 +1,115 @@
+package sqlgenerator
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/lib/rowfilter"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// GenerateFilterCondition компилирует фильтр строк таблицы в условие WHERE (без самого WHERE).
+// Для пустого фильтра возвращается пустая строка.
+func GenerateFilterCondition(filter *models.RowFilter, dbType string) (string, error) {
+	if filter == nil {
+		return "", nil
+	}
+	if err := rowfilter.Validate(filter); err != nil {
+		return "", err
+	}
+	return filterNodeSQL(*filter, dbType)
+}
+
+// whereFilter возвращает " WHERE <условие>" для фильтра таблицы или пустую строку
+func whereFilter(filter *models.RowFilter, dbType string) (string, error) {
+	condition, err := GenerateFilterCondition(filter, dbType)
+	if err != nil || condition == "" {
+		return "", err
+	}
+	return " WHERE " + condition, nil
+}
+
+func filterNodeSQL(f models.RowFilter, dbType string) (string, error) {
+	if f.IsGroup() {
+		group, sep := f.And, " AND "
+		if len(f.Or) > 0 {
+			group, sep = f.Or, " OR "
+		}
+		parts := make([]string, 0, len(group))
+		for _, child := range group {
+			part, err := filterNodeSQL(child, dbType)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+		return "(" + strings.Join(parts, sep) + ")", nil
+	}
+
+	column := pq.QuoteIdentifier(f.Column)
+	switch f.Operator {
+	case models.FilterIsNull:
+		return column + " IS NULL", nil
+	case models.FilterIsNotNull:
+		return column + " IS NOT NULL", nil
+	case models.FilterIn, models.FilterNotIn:
+		values, _ := f.Value.([]interface{})
+		literals := make([]string, 0, len(values))
+		for _, v := range values {
+			lit, err := sqlLiteral(v, dbType)
+			if err != nil {
+				return "", err
+			}
+			literals = append(literals, lit)
+		}
+		keyword := "IN"
+		if f.Operator == models.FilterNotIn {
+			keyword = "NOT IN"
+		}
+		return fmt.Sprintf("%s %s (%s)", column, keyword, strings.Join(literals, ", ")), nil
+	case models.FilterLike:
+		lit, err := sqlLiteral(f.Value, dbType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s LIKE %s", column, lit), nil
+	default:
+		lit, err := sqlLiteral(f.Value, dbType)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s %s", column, f.Operator, lit), nil
+	}
+}
+
+// sqlLiteral записывает скалярное значение фильтра литералом SQL
+func sqlLiteral(v interface{}, dbType string) (string, error) {
+	switch val := v.(type) {
+	case string:
+		escaped := strings.ReplaceAll(val, "'", "''")
+		if dbType == DbClickhouse {
+			escaped = strings.ReplaceAll(escaped, `\`, `\\`)
+		}
+		return "'" + escaped + "'", nil
+	case bool:
+		if val {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32), nil
+	case int, int32, int64, uint32, uint64:
+		return fmt.Sprintf("%d", val), nil
+	case json.Number:
+		if _, err := val.Float64(); err != nil {
+			return "", fmt.Errorf("%w: некорректное число %s", rowfilter.ErrInvalidFilter, val)
+		}
+		return val.String(), nil
+	}
+	return "", fmt.Errorf("%w: неподдерживаемое значение %v (%T)", rowfilter.ErrInvalidFilter, v, v)
+}
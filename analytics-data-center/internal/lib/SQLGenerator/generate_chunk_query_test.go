@@ -0,0 +1,93 @@
+package sqlgenerator_test
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chunkTestView(filter *models.RowFilter) models.View {
+	return models.View{
+		Sources: []models.Source{{
+			Name: "db1",
+			Schemas: []models.Schema{{
+				Name: "public",
+				Tables: []models.Table{{
+					Name:    "orders",
+					Columns: []models.Column{{Name: "tenant_id", IsPrimaryKey: true}, {Name: "id", IsPrimaryKey: true}, {Name: "status"}},
+					Filter:  filter,
+				}},
+			}},
+		}},
+	}
+}
+
+func TestGenerateSelectInsertDataQuery_CompositeKeyChunk(t *testing.T) {
+	view := chunkTestView(&models.RowFilter{Column: "status", Operator: models.FilterNotEq, Value: "draft"})
+	chunk := models.Chunk{
+		Index:   1,
+		Columns: []string{"tenant_id", "id"},
+		Lower:   []interface{}{int64(1), int64(500)},
+		Upper:   []interface{}{int64(2), int64(10)},
+	}
+
+	pg, err := sqlgenerator.GenerateSelectInsertDataQuery(view, chunk, "orders", getTestLogger(), "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT tenant_id, id, status FROM orders WHERE "status" <> 'draft' AND ("tenant_id", "id") >= ($1, $2) AND ("tenant_id", "id") < ($3, $4)`, pg.Query)
+	assert.Equal(t, []interface{}{int64(1), int64(500), int64(2), int64(10)}, pg.Args)
+
+	ch, err := sqlgenerator.GenerateSelectInsertDataQuery(view, chunk, "orders", getTestLogger(), "clickhouse")
+	require.NoError(t, err)
+	assert.Contains(t, ch.Query, `("tenant_id", "id") >= (?, ?) AND ("tenant_id", "id") < (?, ?)`)
+}
+
+func TestGenerateSelectInsertDataQuery_OpenEndedAndCtidChunks(t *testing.T) {
+	view := chunkTestView(nil)
+
+	last, err := sqlgenerator.GenerateSelectInsertDataQuery(view, models.Chunk{Columns: []string{"id"}, Lower: []interface{}{int64(100)}}, "orders", getTestLogger(), "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT tenant_id, id, status FROM orders WHERE "id" >= $1`, last.Query)
+
+	ctid := models.Chunk{Columns: []string{models.ChunkKeyCtid}, Lower: []interface{}{int64(128)}, Upper: []interface{}{int64(256)}}
+	pg, err := sqlgenerator.GenerateSelectInsertDataQuery(view, ctid, "orders", getTestLogger(), "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT tenant_id, id, status FROM orders WHERE ctid >= $1::tid AND ctid < $2::tid`, pg.Query)
+	assert.Equal(t, []interface{}{"(128,0)", "(256,0)"}, pg.Args)
+
+	_, err = sqlgenerator.GenerateSelectInsertDataQuery(view, ctid, "orders", getTestLogger(), "clickhouse")
+	require.Error(t, err)
+}
+
+func TestGenerateChunkBoundaryQueries(t *testing.T) {
+	filter := &models.RowFilter{Column: "status", Operator: models.FilterNotEq, Value: "draft"}
+
+	rangeQuery, err := sqlgenerator.GenerateKeyRangeQuery("orders", "id", filter)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT min("id") AS min_key, max("id") AS max_key FROM orders WHERE "status" <> 'draft'`, rangeQuery)
+
+	boundaries, err := sqlgenerator.GenerateKeyBoundariesQuery("orders", []string{"tenant_id", "id"}, nil, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT "tenant_id", "id" FROM (SELECT "tenant_id", "id", row_number() OVER (ORDER BY "tenant_id", "id") AS chunk_rn FROM orders) AS chunk_keys WHERE chunk_rn > 1 AND (chunk_rn - 1) % 1000 = 0 ORDER BY "tenant_id", "id"`, boundaries)
+
+	assert.Equal(t, `SELECT pg_relation_size('"orders"') / current_setting('block_size')::bigint`, sqlgenerator.GenerateRelationPagesQuery("orders"))
+}
+
+func TestGenerateChunkRowsCondition_UsesTempTableColumns(t *testing.T) {
+	table := models.Table{
+		Name:    "orders",
+		Columns: []models.Column{{Name: "tenant_id", Alias: "tenant", IsPrimaryKey: true}, {Name: "id", IsPrimaryKey: true}},
+	}
+	chunk := models.Chunk{Index: 2, Columns: []string{"tenant_id", "id"}, Lower: []interface{}{int64(1), int64(5)}}
+
+	cond, args, err := sqlgenerator.GenerateChunkRowsCondition(table, chunk, "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, `("tenant", "id") >= ($1, $2)`, cond)
+	assert.Equal(t, []interface{}{int64(1), int64(5)}, args)
+
+	ctid := models.Chunk{Columns: []string{models.ChunkKeyCtid}, Lower: []interface{}{int64(128)}}
+	_, _, err = sqlgenerator.GenerateChunkRowsCondition(table, ctid, "postgres")
+	require.Error(t, err)
+}
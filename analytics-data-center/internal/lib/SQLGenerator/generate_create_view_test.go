@@ -113,3 +113,152 @@ func TestCreateViewQuery_DisconnectedGraph(t *testing.T) {
 		t.Fatalf("expected error for disconnected joins")
 	}
 }
+
+func TestCreateViewQuery_SoftDeleteColumns(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{}))
+	schema := models.View{Name: "users_view", DeletePolicy: models.DeletePolicySoft}
+	viewJoin := models.ViewJoinTable{
+		TempTables: []models.TempTable{{
+			TempTableName: "temp_db_public_users",
+			Source:        "db",
+			Schema:        "public",
+			Table:         "users",
+			TempColumns:   []models.TempColumn{{ColumnName: "id"}},
+		}},
+	}
+
+	pgQuery, err := sqlgenerator.CreateViewQuery(schema, viewJoin, logger, "postgres")
+	if err != nil {
+		t.Fatalf("error generating view query: %v", err)
+	}
+	if !strings.Contains(pgQuery.Query, `FALSE AS "is_deleted"`) || !strings.Contains(pgQuery.Query, `NULL::timestamp AS "deleted_at"`) {
+		t.Fatalf("expected soft delete columns, got: %s", pgQuery.Query)
+	}
+
+	chQuery, err := sqlgenerator.CreateViewQuery(schema, viewJoin, logger, "clickhouse")
+	if err != nil {
+		t.Fatalf("error generating view query: %v", err)
+	}
+	if !strings.Contains(chQuery.Query, "toUInt8(0) AS is_deleted") {
+		t.Fatalf("expected soft delete columns, got: %s", chQuery.Query)
+	}
+}
+
+func TestCreateViewQuery_OuterJoins(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{}))
+	schema := models.View{
+		Name: "user_basic_info",
+		Joins: []*models.Join{
+			{LeftOuter: &models.JoinCondition{
+				Left:  models.JoinEndpoint{Source: "db", Schema: "public", Table: "users", Column: "id"},
+				Right: models.JoinEndpoint{Source: "db", Schema: "public", Table: "profiles", Column: "user_id"},
+			}},
+			{FullOuter: &models.JoinCondition{
+				Left:  models.JoinEndpoint{Source: "db", Schema: "public", Table: "profiles", Column: "profile_id"},
+				Right: models.JoinEndpoint{Source: "db", Schema: "public", Table: "sessions", Column: "profile_id"},
+			}},
+		},
+	}
+	viewJoin := models.ViewJoinTable{
+		TempTables: []models.TempTable{
+			{TempTableName: "temp_db_public_users", Source: "db", Schema: "public", Table: "users", TempColumns: []models.TempColumn{{ColumnName: "id"}}},
+			{TempTableName: "temp_db_public_profiles", Source: "db", Schema: "public", Table: "profiles", TempColumns: []models.TempColumn{{ColumnName: "profile_id"}}},
+			{TempTableName: "temp_db_public_sessions", Source: "db", Schema: "public", Table: "sessions", TempColumns: []models.TempColumn{{ColumnName: "session_id"}}},
+		},
+	}
+
+	pgQuery, err := sqlgenerator.CreateViewQuery(schema, viewJoin, logger, "postgres")
+	if err != nil {
+		t.Fatalf("error generating view query: %v", err)
+	}
+	if !strings.Contains(pgQuery.Query, `LEFT JOIN "temp_db_public_profiles" "t2" ON "t1"."id" = "t2"."user_id"`) {
+		t.Fatalf("expected left join, got: %s", pgQuery.Query)
+	}
+	if !strings.Contains(pgQuery.Query, `FULL JOIN "temp_db_public_sessions" "t3"`) {
+		t.Fatalf("expected full join, got: %s", pgQuery.Query)
+	}
+
+	chQuery, err := sqlgenerator.CreateViewQuery(schema, viewJoin, logger, "clickhouse")
+	if err != nil {
+		t.Fatalf("error generating view query: %v", err)
+	}
+	if !strings.Contains(chQuery.Query, "LEFT JOIN temp_db_public_profiles t2") || !strings.HasSuffix(chQuery.Query, "SETTINGS join_use_nulls = 1") {
+		t.Fatalf("expected clickhouse outer join with join_use_nulls, got: %s", chQuery.Query)
+	}
+}
+
+func TestCreateViewQuery_LeftJoinReversedBecomesRight(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{}))
+	schema := models.View{
+		Name: "orders_view",
+		Joins: []*models.Join{
+			{Inner: &models.JoinCondition{
+				Left:  models.JoinEndpoint{Source: "db", Schema: "public", Table: "users", Column: "id"},
+				Right: models.JoinEndpoint{Source: "db", Schema: "public", Table: "orders", Column: "user_id"},
+			}},
+			{LeftOuter: &models.JoinCondition{
+				Left:  models.JoinEndpoint{Source: "db", Schema: "public", Table: "coupons", Column: "order_id"},
+				Right: models.JoinEndpoint{Source: "db", Schema: "public", Table: "orders", Column: "id"},
+			}},
+		},
+	}
+	viewJoin := models.ViewJoinTable{
+		TempTables: []models.TempTable{
+			{TempTableName: "temp_db_public_users", Source: "db", Schema: "public", Table: "users"},
+			{TempTableName: "temp_db_public_orders", Source: "db", Schema: "public", Table: "orders"},
+			{TempTableName: "temp_db_public_coupons", Source: "db", Schema: "public", Table: "coupons"},
+		},
+	}
+
+	result, err := sqlgenerator.CreateViewQuery(schema, viewJoin, logger, "postgres")
+	if err != nil {
+		t.Fatalf("error generating view query: %v", err)
+	}
+	if !strings.Contains(result.Query, `RIGHT JOIN "temp_db_public_coupons" "t3"`) {
+		t.Fatalf("expected reversed left join to become right join, got: %s", result.Query)
+	}
+}
+
+func TestCreateViewQuery_CompositeJoinKeys(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{}))
+	schema := models.View{
+		Name: "tenant_orders",
+		Joins: []*models.Join{
+			{Inner: &models.JoinCondition{
+				Left:  models.JoinEndpoint{Source: "db", Schema: "public", Table: "users"},
+				Right: models.JoinEndpoint{Source: "db", Schema: "public", Table: "orders"},
+				Columns: []models.JoinColumnPair{
+					{Left: "tenant_id", Right: "tenant_id"},
+					{Left: "id", Right: "user_id"},
+				},
+			}},
+		},
+	}
+	viewJoin := models.ViewJoinTable{
+		TempTables: []models.TempTable{
+			{TempTableName: "temp_db_public_users", Source: "db", Schema: "public", Table: "users", TempColumns: []models.TempColumn{{ColumnName: "id"}}},
+			{TempTableName: "temp_db_public_orders", Source: "db", Schema: "public", Table: "orders", TempColumns: []models.TempColumn{{ColumnName: "order_id"}}},
+		},
+	}
+
+	pgQuery, err := sqlgenerator.CreateViewQuery(schema, viewJoin, logger, "postgres")
+	if err != nil {
+		t.Fatalf("error generating view query: %v", err)
+	}
+	if !strings.Contains(pgQuery.Query, `JOIN "temp_db_public_orders" "t2" ON "t1"."tenant_id" = "t2"."tenant_id" AND "t1"."id" = "t2"."user_id"`) {
+		t.Fatalf("expected composite join condition, got: %s", pgQuery.Query)
+	}
+
+	chQuery, err := sqlgenerator.CreateViewQuery(schema, viewJoin, logger, "clickhouse")
+	if err != nil {
+		t.Fatalf("error generating view query: %v", err)
+	}
+	if !strings.Contains(chQuery.Query, "JOIN temp_db_public_orders t2 ON t1.tenant_id = t2.tenant_id AND t1.id = t2.user_id") {
+		t.Fatalf("expected composite join condition, got: %s", chQuery.Query)
+	}
+
+	schema.Joins[0].Inner.Columns = nil
+	if _, err := sqlgenerator.CreateViewQuery(schema, viewJoin, logger, "postgres"); err == nil {
+		t.Fatalf("expected error for join without columns")
+	}
+}
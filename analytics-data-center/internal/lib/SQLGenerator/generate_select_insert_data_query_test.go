@@ -79,13 +79,13 @@ func TestGenerateSelectInsertDataQuery_WithJSONAndFieldTransform(t *testing.T) {
 		},
 	}
 
-	query, err := sqlgenerator.GenerateSelectInsertDataQuery(view, 0, 100, "users", logger, "postgres")
+	query, err := sqlgenerator.GenerateSelectInsertDataQuery(view, models.Chunk{}, "users", logger, "postgres")
 	t.Logf(query.Query)
 
 	require.NoError(t, err)
 	require.Contains(t, query.Query, "metadata->>'city' AS user_city")
 	require.Contains(t, query.Query, "CASE WHEN status = '1' THEN 'Создано' WHEN status = '2' THEN 'В обработке' END as status_label")
-	require.Contains(t, query.Query, "ORDER BY id")
+	require.NotContains(t, query.Query, "OFFSET")
 	t.Logf(query.Query)
 
 }
@@ -114,9 +114,9 @@ func TestGenerateSelectInsertDataQuery_WithAlias(t *testing.T) {
 		},
 	}
 
-	query, err := sqlgenerator.GenerateSelectInsertDataQuery(view, 0, 10, "routes", getTestLogger(), "postgres")
+	query, err := sqlgenerator.GenerateSelectInsertDataQuery(view, models.Chunk{}, "routes", getTestLogger(), "postgres")
 	require.NoError(t, err)
-	require.Contains(t, query.Query, "SELECT id AS route_id, name FROM routes ORDER BY id OFFSET 0 LIMIT 10")
+	require.Contains(t, query.Query, "SELECT id AS route_id, name FROM routes")
 }
 
 func TestGenerateSelectInsertDataQuery_Success(t *testing.T) {
@@ -142,12 +142,12 @@ func TestGenerateSelectInsertDataQuery_Success(t *testing.T) {
 		},
 	}
 
-	query, err := sqlgenerator.GenerateSelectInsertDataQuery(view, 0, 100, "users", getTestLogger(), "postgres")
+	query, err := sqlgenerator.GenerateSelectInsertDataQuery(view, models.Chunk{}, "users", getTestLogger(), "postgres")
 	t.Logf(query.Query)
 	assert.NoError(t, err)
 	assert.Equal(t, "users", query.TableName)
 	assert.Equal(t, "db1", query.SourceName)
-	assert.Contains(t, query.Query, "SELECT id, name FROM users OFFSET 0 LIMIT 100")
+	assert.Contains(t, query.Query, "SELECT id, name FROM users")
 }
 
 func TestGenerateSelectInsertDataQuery_DuplicateColumns(t *testing.T) {
@@ -173,7 +173,7 @@ func TestGenerateSelectInsertDataQuery_DuplicateColumns(t *testing.T) {
 		},
 	}
 
-	_, err := sqlgenerator.GenerateSelectInsertDataQuery(view, 0, 50, "users", getTestLogger(), "postgres")
+	_, err := sqlgenerator.GenerateSelectInsertDataQuery(view, models.Chunk{}, "users", getTestLogger(), "postgres")
 
 	assert.Error(t, err)
 	assert.EqualError(t, err, "колонки с одинаковыми именами недопустимы")
@@ -201,8 +201,52 @@ func TestGenerateSelectInsertDataQuery_TableNotFound(t *testing.T) {
 		},
 	}
 
-	_, err := sqlgenerator.GenerateSelectInsertDataQuery(view, 0, 20, "orders", getTestLogger(), "postgres")
+	_, err := sqlgenerator.GenerateSelectInsertDataQuery(view, models.Chunk{}, "orders", getTestLogger(), "postgres")
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "таблица orders не найдена")
 }
+
+func TestGenerateQueries_WithTableFilter(t *testing.T) {
+	view := models.View{
+		Name: "orders_view",
+		Sources: []models.Source{{
+			Name: "postgres",
+			Schemas: []models.Schema{{
+				Name: "public",
+				Tables: []models.Table{{
+					Name: "orders",
+					Columns: []models.Column{
+						{Name: "id", IsPrimaryKey: true},
+						{Name: "status"},
+					},
+					Filter: &models.RowFilter{And: []models.RowFilter{
+						{Column: "status", Operator: models.FilterNotEq, Value: "draft"},
+						{Or: []models.RowFilter{
+							{Column: "amount", Operator: models.FilterGte, Value: float64(100)},
+							{Column: "region", Operator: models.FilterIn, Value: []interface{}{"eu", "o'hara"}},
+						}},
+					}},
+				}},
+			}},
+		}},
+	}
+	where := ` WHERE ("status" <> 'draft' AND ("amount" >= 100 OR "region" IN ('eu', 'o''hara')))`
+
+	counts, err := sqlgenerator.GenerateCountQueries(view, getTestLogger())
+	require.NoError(t, err)
+	require.Len(t, counts.Queries, 1)
+	assert.Equal(t, "SELECT COUNT(*) FROM orders"+where, counts.Queries[0].Query)
+
+	pg, err := sqlgenerator.GenerateSelectInsertDataQuery(view, models.Chunk{}, "orders", getTestLogger(), "postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id, status FROM orders"+where, pg.Query)
+
+	ch, err := sqlgenerator.GenerateSelectInsertDataQuery(view, models.Chunk{}, "orders", getTestLogger(), "clickhouse")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT id, status FROM orders"+where, ch.Query)
+
+	view.Sources[0].Schemas[0].Tables[0].Filter = &models.RowFilter{Column: "status", Operator: "between", Value: "a"}
+	_, err = sqlgenerator.GenerateCountQueries(view, getTestLogger())
+	require.Error(t, err)
+}
@@ -5,7 +5,10 @@ import (
 	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestGenerateQueryCreateTempTablePostgres(t *testing.T) {
@@ -81,7 +84,7 @@ func TestGenerateQueryCreateTempTablePostgres(t *testing.T) {
 		},
 	}
 
-	queries, duplicates, err := sqlgenerator.GenerateQueryCreateTempTablePostgres(view, logger, "postgres")
+	queries, duplicates, err := sqlgenerator.GenerateQueryCreateTempTablePostgres(view, "task-1", logger, "postgres")
 	t.Log(duplicates)
 	if err != nil {
 		t.Fatalf("ошибка генерации: %v", err)
@@ -95,3 +98,43 @@ func TestGenerateQueryCreateTempTablePostgres(t *testing.T) {
 		t.Logf("Query %d:\n%s", i+1, query)
 	}
 }
+
+func TestMapColumnTypeToClickhouse(t *testing.T) {
+	precision, scale := int64(10), int64(2)
+	cases := []struct {
+		col  models.Column
+		want string
+	}{
+		{models.Column{Type: "integer"}, "Int32"},
+		{models.Column{Type: "integer", IsNullable: true}, "Nullable(Int32)"},
+		{models.Column{DataType: "numeric", UdtName: "numeric", NumPrecision: &precision, NumScale: &scale}, "Decimal(10, 2)"},
+		{models.Column{DataType: "numeric", UdtName: "numeric"}, "String"},
+		{models.Column{DataType: "timestamp without time zone", UdtName: "timestamp", IsNullable: true}, "Nullable(DateTime64(6))"},
+		{models.Column{DataType: "ARRAY", UdtName: "_int4", IsNullable: true}, "Array(Nullable(Int32))"},
+		{models.Column{Type: "text[]"}, "Array(Nullable(String))"},
+	}
+	for _, tc := range cases {
+		if got := sqlgenerator.MapColumnTypeToClickhouse(tc.col); got != tc.want {
+			t.Errorf("MapColumnTypeToClickhouse(%+v) = %s, want %s", tc.col, got, tc.want)
+		}
+	}
+}
+
+func TestTempTableName_UniquePerTask(t *testing.T) {
+	first := sqlgenerator.TempTableName("3f2b8c1e-9d4a-4b7e-8c2f-1a2b3c4d5e6f", "db1", "public", "users")
+	second := sqlgenerator.TempTableName("7a6b5c4d-3e2f-4a1b-9c8d-7e6f5a4b3c2d", "db1", "public", "users")
+
+	require.Equal(t, "temp_3f2b8c1e9d4a_db1_public_users", first)
+	require.NotEqual(t, first, second)
+	require.Equal(t, "temp_db1_public_users", sqlgenerator.TempTableName("", "db1", "public", "users"))
+	require.Equal(t, "temp_3f2b8c1e9d4a_sales__shadow", sqlgenerator.ShadowTableName("Sales", "3f2b8c1e-9d4a-4b7e-8c2f-1a2b3c4d5e6f"))
+}
+
+func TestTempTableName_FitsPostgresIdentifier(t *testing.T) {
+	long := strings.Repeat("x", 60)
+	first := sqlgenerator.TempTableName("task-1", "db1", "public", long+"a")
+	second := sqlgenerator.TempTableName("task-1", "db1", "public", long+"b")
+
+	require.LessOrEqual(t, len(first), 63)
+	require.NotEqual(t, first, second)
+}
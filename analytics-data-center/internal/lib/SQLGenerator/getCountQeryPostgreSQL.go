@@ -23,7 +23,13 @@ func GenerateCountQueries(view models.View, logger *slog.Logger) (queriesCreateT
 
 				var b strings.Builder
 
-				_, err := b.WriteString(fmt.Sprintf("SELECT COUNT(*) FROM %s", tbl.Name))
+				where, err := whereFilter(tbl.Filter, DbPostgres)
+				if err != nil {
+					logger.Error("некорректный фильтр таблицы", slog.String("table", tbl.Name), slog.String("error", err.Error()))
+					return models.Queries{}, err
+				}
+
+				_, err = b.WriteString(fmt.Sprintf("SELECT COUNT(*) FROM %s%s", tbl.Name, where))
 				if err != nil {
 					logger.Error("ошибка", slog.String("error", err.Error()))
 					return models.Queries{}, err
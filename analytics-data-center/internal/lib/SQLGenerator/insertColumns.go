@@ -0,0 +1,97 @@
+package sqlgenerator
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// InsertColumns возвращает отсортированный список колонок временной таблицы,
+// которые присутствуют в строке выборки: колонки view (с учётом алиасов) и
+// колонки, порождённые трансформациями JSON и FieldTransform.
+func InsertColumns(view models.View, row map[string]interface{}) ([]string, error) {
+	columnNames := make(map[string]struct{})
+	for _, src := range view.Sources {
+		for _, sch := range src.Schemas {
+			for _, tbl := range sch.Tables {
+				for _, clmn := range tbl.Columns {
+					columnNames[resolveInsertColumnName(clmn)] = struct{}{}
+					if clmn.Transform == nil || clmn.Transform.Mapping == nil {
+						continue
+					}
+					switch clmn.Transform.Type {
+					case transformTypeJSON:
+						for _, mapping := range clmn.Transform.Mapping.MappingJSON {
+							for _, outputCol := range mapping.Mapping {
+								columnNames[outputCol] = struct{}{}
+							}
+						}
+					case transformTypeFieldTransform:
+						aliasName := clmn.Transform.Mapping.AliasNewColumnTransform
+						if aliasName == "" {
+							aliasName = clmn.Name + "_transformed"
+						}
+						columnNames[aliasName] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+
+	var columns []string
+	for colName := range columnNames {
+		if _, ok := row[colName]; ok {
+			columns = append(columns, colName)
+		}
+	}
+	sort.Strings(columns)
+
+	if len(columns) == 0 {
+		return nil, errors.New("не найдены подходящие колонки для вставки")
+	}
+	return columns, nil
+}
+
+// ColumnTypes сопоставляет имени колонки временной таблицы её тип из описания view
+func ColumnTypes(view models.View) map[string]string {
+	res := make(map[string]string)
+	for _, src := range view.Sources {
+		for _, sch := range src.Schemas {
+			for _, tbl := range sch.Tables {
+				for _, col := range tbl.Columns {
+					res[resolveInsertColumnName(col)] = col.Type
+				}
+			}
+		}
+	}
+	return res
+}
+
+func resolveInsertColumnName(col models.Column) string {
+	if col.Alias != "" {
+		return col.Alias
+	}
+	return col.Name
+}
+
+// GenerateInsertTemplate возвращает оператор пакетной вставки во временную таблицу:
+// COPY для Postgres и INSERT без VALUES для батча clickhouse-go. Значения строк
+// передаются драйверу отдельно.
+func GenerateInsertTemplate(tableName string, columns []string, dbType string) (string, error) {
+	switch dbType {
+	case DbPostgres:
+		return pq.CopyIn(tableName, columns...), nil
+	case DbClickhouse:
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = "`" + strings.ReplaceAll(col, "`", "\\`") + "`"
+		}
+		return fmt.Sprintf("INSERT INTO %s (%s)", tableName, strings.Join(quoted, ", ")), nil
+	default:
+		return "", fmt.Errorf("unsupported db: %s", dbType)
+	}
+}
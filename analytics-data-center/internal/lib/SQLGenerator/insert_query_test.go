@@ -3,17 +3,16 @@ package sqlgenerator_test
 import (
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
 	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
-	"log/slog"
-	"os"
+	"database/sql/driver"
 	"testing"
 	"time"
 
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 )
 
-func TestGeneratetInsertDataQuery(t *testing.T) {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-
+func TestPostgresCopyValues(t *testing.T) {
 	view := models.View{
 		Sources: []models.Source{
 			{
@@ -25,10 +24,15 @@ func TestGeneratetInsertDataQuery(t *testing.T) {
 							{
 								Name: "temp_test_table",
 								Columns: []models.Column{
-									{Name: "id"},
-									{Name: "name"},
-									{Name: "active"},
-									{Name: "created_at"},
+									{Name: "id", Type: "integer"},
+									{Name: "name", Type: "text"},
+									{Name: "active", Type: "boolean"},
+									{Name: "created_at", Type: "timestamp"},
+									{Name: "opened_at", Type: "time"},
+									{Name: "avatar", Type: "bytea"},
+									{Name: "price", Type: "numeric(10,2)"},
+									{Name: "tags", Type: "text[]"},
+									{Name: "note", Type: "text"},
 								},
 							},
 						},
@@ -38,35 +42,53 @@ func TestGeneratetInsertDataQuery(t *testing.T) {
 		},
 	}
 
-	selectData := []map[string]interface{}{
-		{
-			"id":         1,
-			"name":       "Alice",
-			"active":     true,
-			"created_at": time.Date(2023, 3, 15, 14, 30, 0, 0, time.UTC),
-		},
-		{
-			"id":         2,
-			"name":       "Bob",
-			"active":     false,
-			"created_at": time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
-		},
+	createdAt := time.Date(2023, 3, 15, 14, 30, 0, 123456000, time.UTC)
+	row := map[string]interface{}{
+		"id":         int64(1),
+		"name":       "O'Hara \\ \"quoted\"\ttab",
+		"active":     true,
+		"created_at": createdAt,
+		"opened_at":  time.Date(0, 1, 1, 9, 15, 30, 0, time.UTC),
+		"avatar":     []byte{0x00, 0xff},
+		"price":      []byte("12.50"),
+		"tags":       []string{"a,b", "c"},
+		"note":       nil,
 	}
 
-	query, err := sqlgenerator.GenerateInsertDataQuery(view, selectData, "temp_test_table", logger, "postgres")
+	columns, err := sqlgenerator.InsertColumns(view, row)
+	require.NoError(t, err)
+	require.Equal(t, []string{"active", "avatar", "created_at", "id", "name", "note", "opened_at", "price", "tags"}, columns)
 
+	values, err := sqlgenerator.PostgresCopyValues(row, columns, sqlgenerator.ColumnTypes(view))
 	require.NoError(t, err)
-	t.Log("Generated query:\n" + query.Query)
 
-	require.Contains(t, query.Query, "INSERT INTO temp_test_table")
-	require.Contains(t, query.Query, "TRUE")
-	require.Contains(t, query.Query, "FALSE")
-	require.Contains(t, query.Query, "'2023-03-15")
+	require.Equal(t, true, values[0])
+	require.Equal(t, []byte{0x00, 0xff}, values[1])
+	require.Equal(t, createdAt, values[2])
+	require.Equal(t, int64(1), values[3])
+	require.Equal(t, "O'Hara \\ \"quoted\"\ttab", values[4])
+	require.Nil(t, values[5])
+	require.Equal(t, "09:15:30", values[6])
+	require.Equal(t, "12.50", values[7])
+
+	tags, ok := values[8].(driver.Valuer)
+	require.True(t, ok)
+	tagsValue, err := tags.Value()
+	require.NoError(t, err)
+	require.Equal(t, `{"a,b","c"}`, tagsValue)
 }
 
-func TestGenerateInsertDataQuery_WithAlias(t *testing.T) {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+func TestPostgresCopyValues_UUIDAndUnsupported(t *testing.T) {
+	id := uuid.Must(uuid.FromString("6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+	values, err := sqlgenerator.PostgresCopyValues(map[string]interface{}{"id": id}, []string{"id"}, map[string]string{"id": "uuid"})
+	require.NoError(t, err)
+	require.Equal(t, id.String(), values[0])
+
+	_, err = sqlgenerator.PostgresCopyValues(map[string]interface{}{"meta": map[string]interface{}{"a": 1}}, []string{"meta"}, nil)
+	require.ErrorContains(t, err, "meta")
+}
 
+func TestInsertColumns_WithAlias(t *testing.T) {
 	view := models.View{
 		Sources: []models.Source{
 			{
@@ -89,15 +111,86 @@ func TestGenerateInsertDataQuery_WithAlias(t *testing.T) {
 		},
 	}
 
-	selectData := []map[string]interface{}{
-		{
-			"route_id": 123,
-			"title":    "Route 123",
-		},
+	columns, err := sqlgenerator.InsertColumns(view, map[string]interface{}{
+		"route_id": 123,
+		"title":    "Route 123",
+		"extra":    "not in view",
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"route_id", "title"}, columns)
+}
+
+func TestInsertColumns_NoMatchingColumns(t *testing.T) {
+	view := models.View{Sources: []models.Source{{Schemas: []models.Schema{{Tables: []models.Table{{Columns: []models.Column{{Name: "id"}}}}}}}}}
+
+	_, err := sqlgenerator.InsertColumns(view, map[string]interface{}{"other": 1})
+	require.Error(t, err)
+}
+
+func TestClickhouseBatchValues(t *testing.T) {
+	createdAt := time.Date(2023, 3, 15, 14, 30, 0, 123456000, time.UTC)
+	row := map[string]interface{}{
+		"id":         int64(7),
+		"small":      int64(-3),
+		"active":     true,
+		"price":      []byte("12.50"),
+		"created_at": createdAt,
+		"name":       "O'Hara \\ 'quoted'",
+		"tags":       []byte(`{"a,b",NULL,c}`),
+		"scores":     []int64{1, 2},
+		"comment":    nil,
+		"ratio":      []byte("0.25"),
+	}
+	columnTypes := map[string]string{
+		"id":         "Int32",
+		"small":      "Nullable(Int16)",
+		"active":     "UInt8",
+		"price":      "Nullable(Decimal(10, 2))",
+		"created_at": "DateTime64(6)",
+		"name":       "LowCardinality(String)",
+		"tags":       "Array(Nullable(String))",
+		"scores":     "Array(Nullable(Int32))",
+		"comment":    "Nullable(String)",
+		"ratio":      "Float64",
 	}
+	columns := []string{"id", "small", "active", "price", "created_at", "name", "tags", "scores", "comment", "ratio"}
+
+	values, err := sqlgenerator.ClickhouseBatchValues(row, columns, columnTypes)
+	require.NoError(t, err)
+
+	require.Equal(t, int32(7), values[0])
+	require.Equal(t, int16(-3), values[1])
+	require.Equal(t, uint8(1), values[2])
+	require.Equal(t, "12.5", values[3].(decimal.Decimal).String())
+	require.Equal(t, createdAt, values[4])
+	require.Equal(t, "O'Hara \\ 'quoted'", values[5])
+	require.Equal(t, []interface{}{"a,b", nil, "c"}, values[6])
+	require.Equal(t, []interface{}{int32(1), int32(2)}, values[7])
+	require.Nil(t, values[8])
+	require.Equal(t, 0.25, values[9])
+}
+
+func TestClickhouseBatchValues_Errors(t *testing.T) {
+	_, err := sqlgenerator.ClickhouseBatchValues(map[string]interface{}{"id": nil}, []string{"id"}, map[string]string{"id": "Int32"})
+	require.ErrorContains(t, err, "NULL")
 
-	query, err := sqlgenerator.GenerateInsertDataQuery(view, selectData, "temp_test_table", logger, "postgres")
+	_, err = sqlgenerator.ClickhouseBatchValues(map[string]interface{}{"id": int64(1 << 40)}, []string{"id"}, map[string]string{"id": "Int32"})
+	require.ErrorContains(t, err, "Int32")
+
+	_, err = sqlgenerator.ClickhouseBatchValues(map[string]interface{}{"id": int64(1)}, []string{"id"}, map[string]string{})
+	require.ErrorContains(t, err, "отсутствует")
+}
 
+func TestGenerateInsertTemplate(t *testing.T) {
+	query, err := sqlgenerator.GenerateInsertTemplate("temp_db_public_users", []string{"id", "na`me"}, sqlgenerator.DbClickhouse)
 	require.NoError(t, err)
-	require.Contains(t, query.Query, "INSERT INTO temp_test_table (route_id, title) VALUES (123, 'Route 123')")
+	require.Equal(t, "INSERT INTO temp_db_public_users (`id`, `na\\`me`)", query)
+
+	query, err = sqlgenerator.GenerateInsertTemplate("temp_db_public_users", []string{"id", "name"}, sqlgenerator.DbPostgres)
+	require.NoError(t, err)
+	require.Equal(t, `COPY "temp_db_public_users" ("id", "name") FROM STDIN`, query)
+
+	_, err = sqlgenerator.GenerateInsertTemplate("t", []string{"id"}, "oracle")
+	require.Error(t, err)
 }
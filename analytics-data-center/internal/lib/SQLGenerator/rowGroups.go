@@ -0,0 +1,36 @@
+package sqlgenerator
+
+import (
+	"sort"
+	"strings"
+)
+
+// RowGroup — строки пачки с одинаковым набором колонок
+type RowGroup struct {
+	// Columns — колонки строк группы в алфавитном порядке
+	Columns []string
+	Rows    []map[string]interface{}
+}
+
+// GroupRowsByColumns делит строки пачки по набору колонок, чтобы каждую группу записать
+// одним многострочным INSERT. Порядок групп и строк внутри группы сохраняется.
+func GroupRowsByColumns(rows []map[string]interface{}) []RowGroup {
+	var groups []RowGroup
+	index := make(map[string]int)
+	for _, row := range rows {
+		columns := make([]string, 0, len(row))
+		for col := range row {
+			columns = append(columns, col)
+		}
+		sort.Strings(columns)
+		key := strings.Join(columns, "\x00")
+		idx, ok := index[key]
+		if !ok {
+			idx = len(groups)
+			index[key] = idx
+			groups = append(groups, RowGroup{Columns: columns})
+		}
+		groups[idx].Rows = append(groups[idx].Rows, row)
+	}
+	return groups
+}
@@ -0,0 +1,24 @@
+package sqlgenerator_test
+
+import (
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupRowsByColumns(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": 1, "name": "a"},
+		{"id": 2},
+		{"name": "c", "id": 3},
+	}
+
+	groups := sqlgenerator.GroupRowsByColumns(rows)
+
+	assert.Equal(t, []sqlgenerator.RowGroup{
+		{Columns: []string{"id", "name"}, Rows: []map[string]interface{}{rows[0], rows[2]}},
+		{Columns: []string{"id"}, Rows: []map[string]interface{}{rows[1]}},
+	}, groups)
+	assert.Empty(t, sqlgenerator.GroupRowsByColumns(nil))
+}
@@ -13,17 +13,16 @@ const (
 
 func GenerateSelectInsertDataQuery(
 	view models.View,
-	start int64,
-	end int64,
+	chunk models.Chunk,
 	tableName string,
 	logger *slog.Logger,
 	dbtype string,
 ) (models.Query, error) {
 	switch dbtype {
 	case DbPostgres:
-		return GenerateSelectInsertDataQueryPostgres(view, start, end, tableName, logger)
+		return GenerateSelectInsertDataQueryPostgres(view, chunk, tableName, logger)
 	case DbClickhouse:
-		return GenerateSelectInsertDataQueryClickhouse(view, start, end, tableName, logger)
+		return GenerateSelectInsertDataQueryClickhouse(view, chunk, tableName, logger)
 	default:
 		return models.Query{}, fmt.Errorf("неизвестный тип базы: %s", dbtype)
 	}
@@ -5,13 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 )
 
 func GenerateSelectInsertDataQueryClickhouse(
 	view models.View,
-	start int64,
-	end int64,
+	chunk models.Chunk,
 	tableName string,
 	logger *slog.Logger,
 ) (models.Query, error) {
@@ -20,7 +20,6 @@ func GenerateSelectInsertDataQueryClickhouse(
 	logger.Info("start operation")
 
 	var b strings.Builder
-	var primaryColumn string
 
 	resolveColumnName := func(column models.Column) string {
 		if column.Alias != "" {
@@ -71,7 +70,13 @@ func GenerateSelectInsertDataQueryClickhouse(
 						mapping := column.Transform.Mapping
 						var a strings.Builder
 						a.WriteString("CASE ")
-						for key, value := range mapping.Mapping {
+						keys := make([]string, 0, len(mapping.Mapping))
+						for key := range mapping.Mapping {
+							keys = append(keys, key)
+						}
+						sort.Strings(keys)
+						for _, key := range keys {
+							value := mapping.Mapping[key]
 							safeKey := strings.ReplaceAll(key, "'", "''")
 							safeValue := strings.ReplaceAll(value, "'", "''")
 							a.WriteString(fmt.Sprintf("WHEN %s = '%s' THEN '%s' ", column.Name, safeKey, safeValue))
@@ -83,35 +88,25 @@ func GenerateSelectInsertDataQueryClickhouse(
 						a.WriteString(fmt.Sprintf("END as %s", alias))
 						columns = append(columns, a.String())
 					}
-
-					if column.IsPrimaryKey && primaryColumn == "" {
-						primaryColumn = column.Name
-					}
 				}
 
-				// В ClickHouse — LIMIT <limit> OFFSET <offset>
-				limit := end - start
-				if primaryColumn != "" {
-					b.WriteString(fmt.Sprintf("SELECT %s FROM %s ORDER BY %s LIMIT %d OFFSET %d",
-						strings.Join(columns, ", "),
-						tableName,
-						primaryColumn,
-						limit,
-						start,
-					))
-				} else {
-					b.WriteString(fmt.Sprintf("SELECT %s FROM %s LIMIT %d OFFSET %d",
-						strings.Join(columns, ", "),
-						tableName,
-						limit,
-						start,
-					))
+				where, args, err := chunkWhere(tbl.Filter, chunk, DbClickhouse)
+				if err != nil {
+					logger.Error("ошибка формирования условия чанка", slog.String("table", tbl.Name), slog.String("error", err.Error()))
+					return models.Query{}, err
 				}
 
+				b.WriteString(fmt.Sprintf("SELECT %s FROM %s%s",
+					strings.Join(columns, ", "),
+					tableName,
+					where,
+				))
+
 				return models.Query{
 					TableName:  tbl.Name,
 					SourceName: source.Name,
 					Query:      b.String(),
+					Args:       args,
 				}, nil
 			}
 		}
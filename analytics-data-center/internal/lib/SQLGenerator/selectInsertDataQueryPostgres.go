@@ -5,13 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 )
 
 func GenerateSelectInsertDataQueryPostgres(
 	view models.View,
-	start int64,
-	end int64,
+	chunk models.Chunk,
 	tableName string,
 	logger *slog.Logger,
 ) (models.Query, error) {
@@ -20,7 +20,6 @@ func GenerateSelectInsertDataQueryPostgres(
 	logger.Info("start operation")
 
 	var b strings.Builder
-	var primaryColumn string
 
 	resolveColumnName := func(column models.Column) string {
 		if column.Alias != "" {
@@ -69,7 +68,13 @@ func GenerateSelectInsertDataQueryPostgres(
 						mapping := column.Transform.Mapping
 						var a strings.Builder
 						a.WriteString("CASE ")
-						for key, value := range mapping.Mapping {
+						keys := make([]string, 0, len(mapping.Mapping))
+						for key := range mapping.Mapping {
+							keys = append(keys, key)
+						}
+						sort.Strings(keys)
+						for _, key := range keys {
+							value := mapping.Mapping[key]
 							safeKey := strings.ReplaceAll(key, "'", "''")
 							safeValue := strings.ReplaceAll(value, "'", "''")
 							a.WriteString(fmt.Sprintf("WHEN %s = '%s' THEN '%s' ", column.Name, safeKey, safeValue))
@@ -81,33 +86,25 @@ func GenerateSelectInsertDataQueryPostgres(
 						a.WriteString(fmt.Sprintf("END as %s", alias))
 						columns = append(columns, a.String())
 					}
-
-					if column.IsPrimaryKey && primaryColumn == "" {
-						primaryColumn = column.Name
-					}
 				}
 
-				if primaryColumn != "" {
-					b.WriteString(fmt.Sprintf("SELECT %s FROM %s ORDER BY %s OFFSET %d LIMIT %d",
-						strings.Join(columns, ", "),
-						tableName,
-						primaryColumn,
-						start,
-						end-start,
-					))
-				} else {
-					b.WriteString(fmt.Sprintf("SELECT %s FROM %s OFFSET %d LIMIT %d",
-						strings.Join(columns, ", "),
-						tableName,
-						start,
-						end-start,
-					))
+				where, args, err := chunkWhere(tbl.Filter, chunk, DbPostgres)
+				if err != nil {
+					logger.Error("ошибка формирования условия чанка", slog.String("table", tbl.Name), slog.String("error", err.Error()))
+					return models.Query{}, err
 				}
 
+				b.WriteString(fmt.Sprintf("SELECT %s FROM %s%s",
+					strings.Join(columns, ", "),
+					tableName,
+					where,
+				))
+
 				return models.Query{
 					TableName:  tbl.Name,
 					SourceName: source.Name,
 					Query:      b.String(),
+					Args:       args,
 				}, nil
 			}
 		}
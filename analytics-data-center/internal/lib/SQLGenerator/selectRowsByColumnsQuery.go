@@ -0,0 +1,52 @@
+package sqlgenerator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// GenerateSelectRowsByColumnsQuery формирует параметризованную выборку строк OLTP-таблицы
+// по значениям набора колонок: WHERE ("c1", "c2") IN (($1, $2), ($3, $4)).
+// Используется CDC для поиска строк, связанных джоинами view.
+func GenerateSelectRowsByColumnsQuery(schemaName, tableName string, columns []string, tuples int) (string, error) {
+	if len(columns) == 0 {
+		return "", fmt.Errorf("не заданы колонки для выборки из %s.%s", schemaName, tableName)
+	}
+	if tuples <= 0 {
+		return "", fmt.Errorf("не заданы значения для выборки из %s.%s", schemaName, tableName)
+	}
+
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = pq.QuoteIdentifier(col)
+	}
+
+	placeholder := 1
+	values := make([]string, 0, tuples)
+	for t := 0; t < tuples; t++ {
+		parts := make([]string, len(columns))
+		for i := range columns {
+			parts[i] = fmt.Sprintf("$%d", placeholder)
+			placeholder++
+		}
+		if len(columns) == 1 {
+			values = append(values, parts[0])
+		} else {
+			values = append(values, "("+strings.Join(parts, ", ")+")")
+		}
+	}
+
+	target := quoted[0]
+	if len(quoted) > 1 {
+		target = "(" + strings.Join(quoted, ", ") + ")"
+	}
+
+	return fmt.Sprintf("SELECT * FROM %s.%s WHERE %s IN (%s)",
+		pq.QuoteIdentifier(schemaName),
+		pq.QuoteIdentifier(tableName),
+		target,
+		strings.Join(values, ", "),
+	), nil
+}
@@ -0,0 +1,60 @@
+package sqlgenerator
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// WatermarkMaxColumn — колонка результата GenerateWatermarkMaxQuery
+const WatermarkMaxColumn = "max_watermark"
+
+// GenerateWatermarkMaxQuery возвращает наибольшее значение колонки отметки таблицы с учётом
+// её фильтра: до этого значения включительно строки загружены полной пересборкой
+func GenerateWatermarkMaxQuery(schemaName string, table models.Table) (string, error) {
+	if table.Watermark == "" {
+		return "", fmt.Errorf("у таблицы %s.%s не задана колонка отметки", schemaName, table.Name)
+	}
+	where, err := whereFilter(table.Filter, DbPostgres)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("SELECT max(%s) AS %s FROM %s.%s%s",
+		pq.QuoteIdentifier(table.Watermark),
+		WatermarkMaxColumn,
+		pq.QuoteIdentifier(schemaName),
+		pq.QuoteIdentifier(table.Name),
+		where,
+	), nil
+}
+
+// GenerateWatermarkRowsQuery выбирает строки таблицы в порядке колонки отметки. Без
+// отметки выбираются строки, прошедшие фильтр таблицы. Если afterMark, выбираются строки
+// со значением не меньше $1 и без фильтра: строку, которая после изменения вышла из
+// фильтра, нужно убрать из view. Строки с самим значением $1 читаются повторно — так не
+// теряются строки с тем же значением, зафиксированные после прошлого чтения, а уже
+// записанные перезаписываются по ключам обновления.
+func GenerateWatermarkRowsQuery(schemaName string, table models.Table, afterMark bool) (string, error) {
+	if table.Watermark == "" {
+		return "", fmt.Errorf("у таблицы %s.%s не задана колонка отметки", schemaName, table.Name)
+	}
+	watermark := pq.QuoteIdentifier(table.Watermark)
+	condition := fmt.Sprintf("%s >= $1", watermark)
+	if !afterMark {
+		var err error
+		if condition, err = GenerateFilterCondition(table.Filter, DbPostgres); err != nil {
+			return "", err
+		}
+	}
+	where := ""
+	if condition != "" {
+		where = " WHERE " + condition
+	}
+	return fmt.Sprintf("SELECT * FROM %s.%s%s ORDER BY %s",
+		pq.QuoteIdentifier(schemaName),
+		pq.QuoteIdentifier(table.Name),
+		where,
+		watermark,
+	), nil
+}
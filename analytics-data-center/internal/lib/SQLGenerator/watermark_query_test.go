@@ -0,0 +1,42 @@
+package sqlgenerator_test
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateWatermarkQueries(t *testing.T) {
+	table := models.Table{
+		Name:      "orders",
+		Columns:   []models.Column{{Name: "id", IsUpdateKey: true}, {Name: "updated_at"}},
+		Filter:    &models.RowFilter{Column: "status", Operator: models.FilterNotEq, Value: "draft"},
+		Watermark: "updated_at",
+	}
+
+	query, err := sqlgenerator.GenerateWatermarkMaxQuery("public", table)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT max("updated_at") AS max_watermark FROM "public"."orders" WHERE "status" <> 'draft'`, query)
+
+	// после отметки строки читаются без фильтра: вышедшие из него убираются из view
+	query, err = sqlgenerator.GenerateWatermarkRowsQuery("public", table, true)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "public"."orders" WHERE "updated_at" >= $1 ORDER BY "updated_at"`, query)
+
+	query, err = sqlgenerator.GenerateWatermarkRowsQuery("public", table, false)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "public"."orders" WHERE "status" <> 'draft' ORDER BY "updated_at"`, query)
+
+	// без сохранённой отметки выбираются все строки таблицы
+	table.Filter = nil
+	query, err = sqlgenerator.GenerateWatermarkRowsQuery("public", table, false)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "public"."orders" ORDER BY "updated_at"`, query)
+
+	table.Watermark = ""
+	_, err = sqlgenerator.GenerateWatermarkRowsQuery("public", table, true)
+	require.Error(t, err)
+}
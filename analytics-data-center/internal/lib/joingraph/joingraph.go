@@ -0,0 +1,311 @@
+package joingraph
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoJoins      = errors.New("во view не описаны джоины")
+	ErrDisconnected = errors.New("невозможно связать все джоины: отсутствуют исходные таблицы")
+	ErrInvalidJoin  = errors.New("некорректное описание джоина")
+)
+
+// TableRef идентифицирует таблицу источника внутри view
+type TableRef struct {
+	Source string
+	Schema string
+	Table  string
+}
+
+func RefFromEndpoint(endpoint models.JoinEndpoint) TableRef {
+	return TableRef{Source: endpoint.Source, Schema: endpoint.Schema, Table: endpoint.Table}
+}
+
+func (t TableRef) String() string {
+	return fmt.Sprintf("%s.%s.%s", t.Source, t.Schema, t.Table)
+}
+
+// Edge — одно условие джоина между двумя таблицами view. LeftColumns[i]
+// сравнивается с RightColumns[i]; для составного ключа колонок несколько.
+type Edge struct {
+	Left         TableRef
+	Right        TableRef
+	LeftColumns  []string
+	RightColumns []string
+	Type         models.JoinType
+}
+
+// Step — переход по ребру от уже известной таблицы к соседней
+type Step struct {
+	From        TableRef
+	To          TableRef
+	FromColumns []string
+	ToColumns   []string
+	Edge        Edge
+}
+
+func (e Edge) forward() Step {
+	return Step{From: e.Left, To: e.Right, FromColumns: e.LeftColumns, ToColumns: e.RightColumns, Edge: e}
+}
+
+func (e Edge) backward() Step {
+	return Step{From: e.Right, To: e.Left, FromColumns: e.RightColumns, ToColumns: e.LeftColumns, Edge: e}
+}
+
+// Reversed сообщает, что шаг идёт от правой таблицы джоина к левой
+func (s Step) Reversed() bool {
+	return s.From == s.Edge.Right && s.To == s.Edge.Left && s.Edge.Left != s.Edge.Right
+}
+
+// Kind — тип джоина с точки зрения шага From → To: при обратном проходе
+// LEFT и RIGHT меняются местами
+func (s Step) Kind() models.JoinType {
+	if !s.Reversed() {
+		return s.Edge.Type
+	}
+	switch s.Edge.Type {
+	case models.JoinLeft:
+		return models.JoinRight
+	case models.JoinRight:
+		return models.JoinLeft
+	}
+	return s.Edge.Type
+}
+
+// Optional сообщает, что строки From сохраняются и без пары в To (To заполняется NULL)
+func (s Step) Optional() bool {
+	kind := s.Kind()
+	return kind == models.JoinLeft || kind == models.JoinFull
+}
+
+// Graph — граф джоинов view с корневой таблицей (FROM в запросе слияния)
+type Graph struct {
+	Root   TableRef
+	Edges  []Edge
+	tables map[TableRef]struct{}
+}
+
+// Build строит граф по Joins view. Корнем считается левая таблица первого
+// джоина, которая не встречается справа ни в одном другом джоине —
+// так же выбирается FROM при генерации запроса слияния.
+func Build(view models.View) (Graph, error) {
+	g := Graph{tables: make(map[TableRef]struct{})}
+
+	rightKeys := make(map[TableRef]struct{})
+	for _, join := range view.Joins {
+		cond, joinType := join.Condition()
+		if cond == nil {
+			continue
+		}
+		edge := Edge{
+			Left:  RefFromEndpoint(cond.Left),
+			Right: RefFromEndpoint(cond.Right),
+			Type:  joinType,
+		}
+		for _, pair := range cond.ColumnPairs() {
+			edge.LeftColumns = append(edge.LeftColumns, pair.Left)
+			edge.RightColumns = append(edge.RightColumns, pair.Right)
+		}
+		g.Edges = append(g.Edges, edge)
+		g.tables[edge.Left] = struct{}{}
+		g.tables[edge.Right] = struct{}{}
+		rightKeys[edge.Right] = struct{}{}
+	}
+
+	if len(g.Edges) == 0 {
+		return Graph{}, ErrNoJoins
+	}
+
+	g.Root = g.Edges[0].Left
+	for _, edge := range g.Edges {
+		if _, ok := rightKeys[edge.Left]; !ok {
+			g.Root = edge.Left
+			break
+		}
+	}
+
+	if len(g.Walk(g.Root)) != len(g.Edges) {
+		return Graph{}, ErrDisconnected
+	}
+
+	return g, nil
+}
+
+// Contains сообщает, участвует ли таблица в джоинах
+func (g Graph) Contains(t TableRef) bool {
+	_, ok := g.tables[t]
+	return ok
+}
+
+// Walk обходит граф в ширину от start и возвращает шаги в порядке присоединения
+// таблиц. Каждое ребро используется ровно один раз: если обе его таблицы уже
+// известны, оно попадает в список как дополнительное условие (To уже посещена).
+func (g Graph) Walk(start TableRef) []Step {
+	known := map[TableRef]struct{}{start: {}}
+	processed := make(map[int]bool)
+	var steps []Step
+
+	for len(processed) < len(g.Edges) {
+		progress := false
+		for idx, edge := range g.Edges {
+			if processed[idx] {
+				continue
+			}
+			_, leftKnown := known[edge.Left]
+			_, rightKnown := known[edge.Right]
+
+			switch {
+			case leftKnown:
+				steps = append(steps, edge.forward())
+				known[edge.Right] = struct{}{}
+			case rightKnown:
+				steps = append(steps, edge.backward())
+				known[edge.Left] = struct{}{}
+			default:
+				continue
+			}
+			processed[idx] = true
+			progress = true
+		}
+		if !progress {
+			break
+		}
+	}
+
+	return steps
+}
+
+// PathTo возвращает кратчайшую цепочку шагов от from до to
+func (g Graph) PathTo(from, to TableRef) ([]Step, bool) {
+	if from == to {
+		return nil, true
+	}
+
+	prev := map[TableRef]Step{}
+	visited := map[TableRef]struct{}{from: {}}
+	queue := []TableRef{from}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, step := range g.neighbours(current) {
+			if _, ok := visited[step.To]; ok {
+				continue
+			}
+			visited[step.To] = struct{}{}
+			prev[step.To] = step
+			if step.To == to {
+				var path []Step
+				for at := to; at != from; {
+					s := prev[at]
+					path = append([]Step{s}, path...)
+					at = s.From
+				}
+				return path, true
+			}
+			queue = append(queue, step.To)
+		}
+	}
+
+	return nil, false
+}
+
+func (g Graph) neighbours(t TableRef) []Step {
+	var steps []Step
+	for _, edge := range g.Edges {
+		if edge.Left == t {
+			steps = append(steps, edge.forward())
+		}
+		if edge.Right == t {
+			steps = append(steps, edge.backward())
+		}
+	}
+	return steps
+}
+
+// FindTable возвращает описание таблицы view по ссылке
+func FindTable(view models.View, ref TableRef) (models.Table, bool) {
+	for _, source := range view.Sources {
+		if source.Name != ref.Source {
+			continue
+		}
+		for _, sch := range source.Schemas {
+			if sch.Name != ref.Schema {
+				continue
+			}
+			for _, table := range sch.Tables {
+				if table.Name == ref.Table {
+					return table, true
+				}
+			}
+		}
+	}
+	return models.Table{}, false
+}
+
+// HasOuterJoins сообщает, есть ли в графе внешние джоины
+func (g Graph) HasOuterJoins() bool {
+	for _, edge := range g.Edges {
+		if edge.Type != models.JoinInner {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate проверяет описание джоинов view: у каждого джоина ровно одно условие,
+// таблицы условий есть среди источников view, колонки заданы либо одной парой
+// (Left.Column/Right.Column), либо списком Columns, и все таблицы связаны в один граф.
+func Validate(view models.View) error {
+	if len(view.Joins) == 0 {
+		return nil
+	}
+
+	for idx, join := range view.Joins {
+		if join == nil {
+			return fmt.Errorf("%w: джоин #%d пустой", ErrInvalidJoin, idx+1)
+		}
+		filled := 0
+		for _, cond := range []*models.JoinCondition{join.Inner, join.LeftOuter, join.RightOuter, join.FullOuter} {
+			if cond != nil {
+				filled++
+			}
+		}
+		if filled != 1 {
+			return fmt.Errorf("%w: в джоине #%d должно быть задано ровно одно условие (inner, left, right или full)", ErrInvalidJoin, idx+1)
+		}
+
+		cond, _ := join.Condition()
+		for _, endpoint := range []models.JoinEndpoint{cond.Left, cond.Right} {
+			if _, ok := FindTable(view, RefFromEndpoint(endpoint)); !ok {
+				return fmt.Errorf("%w: таблица %s из джоина #%d не описана в источниках view", ErrInvalidJoin, RefFromEndpoint(endpoint), idx+1)
+			}
+		}
+
+		if len(cond.Columns) > 0 && (cond.Left.Column != "" || cond.Right.Column != "") {
+			return fmt.Errorf("%w: в джоине #%d колонки заданы и в column, и в columns", ErrInvalidJoin, idx+1)
+		}
+		pairs := cond.ColumnPairs()
+		if len(pairs) == 0 {
+			return fmt.Errorf("%w: в джоине #%d не указаны колонки", ErrInvalidJoin, idx+1)
+		}
+		seen := make(map[models.JoinColumnPair]struct{}, len(pairs))
+		for _, pair := range pairs {
+			if pair.Left == "" || pair.Right == "" {
+				return fmt.Errorf("%w: в джоине #%d у пары колонок не заполнена одна из сторон", ErrInvalidJoin, idx+1)
+			}
+			if _, dup := seen[pair]; dup {
+				return fmt.Errorf("%w: в джоине #%d пара колонок %s = %s указана дважды", ErrInvalidJoin, idx+1, pair.Left, pair.Right)
+			}
+			seen[pair] = struct{}{}
+		}
+	}
+
+	if _, err := Build(view); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidJoin, err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package joingraph
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func chainView() models.View {
+	return models.View{
+		Name: "user_basic_info",
+		Joins: []*models.Join{
+			{Inner: &models.JoinCondition{
+				Left:  models.JoinEndpoint{Source: "db", Schema: "public", Table: "users", Column: "id"},
+				Right: models.JoinEndpoint{Source: "db", Schema: "public", Table: "profiles", Column: "user_id"},
+			}},
+			{Inner: &models.JoinCondition{
+				Left:  models.JoinEndpoint{Source: "db", Schema: "public", Table: "profiles", Column: "profile_id"},
+				Right: models.JoinEndpoint{Source: "db", Schema: "public", Table: "sessions", Column: "profile_id"},
+			}},
+		},
+	}
+}
+
+func TestBuild_RootAndWalk(t *testing.T) {
+	g, err := Build(chainView())
+	require.NoError(t, err)
+
+	users := TableRef{Source: "db", Schema: "public", Table: "users"}
+	sessions := TableRef{Source: "db", Schema: "public", Table: "sessions"}
+	require.Equal(t, users, g.Root)
+	require.True(t, g.Contains(sessions))
+
+	steps := g.Walk(g.Root)
+	require.Len(t, steps, 2)
+	require.Equal(t, "profiles", steps[0].To.Table)
+	require.Equal(t, "sessions", steps[1].To.Table)
+}
+
+func TestPathTo_FromLeafToRoot(t *testing.T) {
+	g, err := Build(chainView())
+	require.NoError(t, err)
+
+	path, ok := g.PathTo(TableRef{Source: "db", Schema: "public", Table: "sessions"}, g.Root)
+	require.True(t, ok)
+	require.Len(t, path, 2)
+	require.Equal(t, []string{"profile_id"}, path[0].FromColumns)
+	require.Equal(t, "profiles", path[0].To.Table)
+	require.Equal(t, []string{"user_id"}, path[1].FromColumns)
+	require.Equal(t, []string{"id"}, path[1].ToColumns)
+}
+
+func TestBuild_Disconnected(t *testing.T) {
+	view := chainView()
+	view.Joins[1].Inner.Left.Table = "orders"
+
+	_, err := Build(view)
+	require.ErrorIs(t, err, ErrDisconnected)
+}
+
+func TestStep_KindAndOptional(t *testing.T) {
+	view := chainView()
+	view.Joins[0].LeftOuter, view.Joins[0].Inner = view.Joins[0].Inner, nil
+
+	g, err := Build(view)
+	require.NoError(t, err)
+
+	forward := g.Walk(g.Root)[0]
+	require.Equal(t, models.JoinLeft, forward.Kind())
+	require.True(t, forward.Optional())
+
+	path, ok := g.PathTo(TableRef{Source: "db", Schema: "public", Table: "profiles"}, g.Root)
+	require.True(t, ok)
+	require.Equal(t, models.JoinRight, path[0].Kind())
+	require.False(t, path[0].Optional())
+	require.True(t, g.HasOuterJoins())
+}
+
+func validView() models.View {
+	view := chainView()
+	view.Sources = []models.Source{{
+		Name: "db",
+		Schemas: []models.Schema{{
+			Name: "public",
+			Tables: []models.Table{
+				{Name: "users"},
+				{Name: "profiles"},
+				{Name: "sessions"},
+			},
+		}},
+	}}
+	return view
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(validView()))
+
+	composite := validView()
+	composite.Joins[0].Inner.Left.Column, composite.Joins[0].Inner.Right.Column = "", ""
+	composite.Joins[0].Inner.Columns = []models.JoinColumnPair{
+		{Left: "tenant_id", Right: "tenant_id"},
+		{Left: "id", Right: "user_id"},
+	}
+	require.NoError(t, Validate(composite))
+
+	cases := map[string]func(v *models.View){
+		"two conditions": func(v *models.View) { v.Joins[0].LeftOuter = v.Joins[0].Inner },
+		"unknown table":  func(v *models.View) { v.Joins[1].Inner.Right.Table = "orders" },
+		"no columns":     func(v *models.View) { v.Joins[0].Inner.Left.Column, v.Joins[0].Inner.Right.Column = "", "" },
+		"half pair": func(v *models.View) {
+			v.Joins[0].Inner.Left.Column, v.Joins[0].Inner.Right.Column = "", ""
+			v.Joins[0].Inner.Columns = []models.JoinColumnPair{{Left: "tenant_id"}}
+		},
+		"column and columns": func(v *models.View) {
+			v.Joins[0].Inner.Columns = []models.JoinColumnPair{{Left: "tenant_id", Right: "tenant_id"}}
+		},
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			view := validView()
+			mutate(&view)
+			require.ErrorIs(t, Validate(view), ErrInvalidJoin)
+		})
+	}
+}
+
+func TestBuild_CompositeColumns(t *testing.T) {
+	view := chainView()
+	view.Joins[0].Inner.Left.Column, view.Joins[0].Inner.Right.Column = "", ""
+	view.Joins[0].Inner.Columns = []models.JoinColumnPair{
+		{Left: "tenant_id", Right: "tenant_id"},
+		{Left: "id", Right: "user_id"},
+	}
+
+	g, err := Build(view)
+	require.NoError(t, err)
+
+	path, ok := g.PathTo(TableRef{Source: "db", Schema: "public", Table: "profiles"}, g.Root)
+	require.True(t, ok)
+	require.Equal(t, []string{"tenant_id", "user_id"}, path[0].FromColumns)
+	require.Equal(t, []string{"tenant_id", "id"}, path[0].ToColumns)
+}
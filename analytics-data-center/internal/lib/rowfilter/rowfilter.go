@@ -0,0 +1,307 @@
+package rowfilter
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFilter = errors.New("некорректный фильтр строк")
+
+// Validate проверяет структуру фильтра: узел либо группа (and/or), либо сравнение
+// колонки со значением, у оператора значение подходящего вида
+func Validate(f *models.RowFilter) error {
+	if f == nil {
+		return nil
+	}
+	return validateNode(*f, "filter")
+}
+
+func validateNode(f models.RowFilter, path string) error {
+	if f.IsGroup() {
+		if f.Column != "" || f.Operator != "" || f.Value != nil {
+			return fmt.Errorf("%w: %s: группа and/or не может содержать column, operator или value", ErrInvalidFilter, path)
+		}
+		if len(f.And) > 0 && len(f.Or) > 0 {
+			return fmt.Errorf("%w: %s: and и or нужно описывать разными узлами", ErrInvalidFilter, path)
+		}
+		group, name := f.And, "and"
+		if len(f.Or) > 0 {
+			group, name = f.Or, "or"
+		}
+		for idx, child := range group {
+			if err := validateNode(child, fmt.Sprintf("%s.%s[%d]", path, name, idx)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if f.Column == "" {
+		return fmt.Errorf("%w: %s: не указана колонка", ErrInvalidFilter, path)
+	}
+
+	switch f.Operator {
+	case models.FilterIsNull, models.FilterIsNotNull:
+		if f.Value != nil {
+			return fmt.Errorf("%w: %s: оператор %s не принимает значение", ErrInvalidFilter, path, f.Operator)
+		}
+	case models.FilterIn, models.FilterNotIn:
+		values, ok := f.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return fmt.Errorf("%w: %s: оператору %s нужен непустой список значений", ErrInvalidFilter, path, f.Operator)
+		}
+		for _, v := range values {
+			if !isScalar(v) {
+				return fmt.Errorf("%w: %s: в списке %s допустимы только строки, числа и логические значения", ErrInvalidFilter, path, f.Operator)
+			}
+		}
+	case models.FilterLike:
+		if _, ok := f.Value.(string); !ok {
+			return fmt.Errorf("%w: %s: оператору like нужен строковый шаблон", ErrInvalidFilter, path)
+		}
+	case models.FilterEq, models.FilterNotEq, models.FilterGt, models.FilterGte, models.FilterLt, models.FilterLte:
+		if !isScalar(f.Value) {
+			return fmt.Errorf("%w: %s: оператору %s нужно значение-строка, число или логическое значение", ErrInvalidFilter, path, f.Operator)
+		}
+	default:
+		return fmt.Errorf("%w: %s: неизвестный оператор %q", ErrInvalidFilter, path, f.Operator)
+	}
+	return nil
+}
+
+// ValidateView проверяет фильтры всех таблиц view
+func ValidateView(view models.View) error {
+	for _, source := range view.Sources {
+		for _, sch := range source.Schemas {
+			for _, table := range sch.Tables {
+				if err := Validate(table.Filter); err != nil {
+					return fmt.Errorf("таблица %s.%s.%s: %w", source.Name, sch.Name, table.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Columns возвращает колонки, на которые ссылается фильтр, без повторов
+func Columns(f *models.RowFilter) []string {
+	if f == nil {
+		return nil
+	}
+	seen := make(map[string]struct{})
+	var columns []string
+	var walk func(node models.RowFilter)
+	walk = func(node models.RowFilter) {
+		if node.Column != "" {
+			if _, ok := seen[node.Column]; !ok {
+				seen[node.Column] = struct{}{}
+				columns = append(columns, node.Column)
+			}
+		}
+		for _, child := range node.And {
+			walk(child)
+		}
+		for _, child := range node.Or {
+			walk(child)
+		}
+	}
+	walk(*f)
+	return columns
+}
+
+// Match вычисляет фильтр на образе строки. Сравнение с отсутствующим значением
+// (NULL) ложно, как и в SQL; пустой фильтр пропускает любую строку.
+func Match(f *models.RowFilter, row map[string]interface{}) bool {
+	if f == nil {
+		return true
+	}
+	return matchNode(*f, row)
+}
+
+func matchNode(f models.RowFilter, row map[string]interface{}) bool {
+	if len(f.And) > 0 {
+		for _, child := range f.And {
+			if !matchNode(child, row) {
+				return false
+			}
+		}
+		return true
+	}
+	if len(f.Or) > 0 {
+		for _, child := range f.Or {
+			if matchNode(child, row) {
+				return true
+			}
+		}
+		return false
+	}
+
+	val := row[f.Column]
+	switch f.Operator {
+	case models.FilterIsNull:
+		return val == nil
+	case models.FilterIsNotNull:
+		return val != nil
+	}
+	if val == nil {
+		return false
+	}
+
+	switch f.Operator {
+	case models.FilterEq:
+		cmp, ok := compare(val, f.Value)
+		return ok && cmp == 0
+	case models.FilterNotEq:
+		cmp, ok := compare(val, f.Value)
+		return ok && cmp != 0
+	case models.FilterGt:
+		cmp, ok := compare(val, f.Value)
+		return ok && cmp > 0
+	case models.FilterGte:
+		cmp, ok := compare(val, f.Value)
+		return ok && cmp >= 0
+	case models.FilterLt:
+		cmp, ok := compare(val, f.Value)
+		return ok && cmp < 0
+	case models.FilterLte:
+		cmp, ok := compare(val, f.Value)
+		return ok && cmp <= 0
+	case models.FilterIn, models.FilterNotIn:
+		values, _ := f.Value.([]interface{})
+		found := false
+		for _, v := range values {
+			if cmp, ok := compare(val, v); ok && cmp == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (f.Operator == models.FilterIn)
+	case models.FilterLike:
+		pattern, _ := f.Value.(string)
+		return likeRegexp(pattern).MatchString(fmt.Sprintf("%v", val))
+	}
+	return false
+}
+
+// compare сравнивает значение из строки со значением фильтра. Числа сравниваются
+// как числа (Debezium присылает numeric строкой), время — как время, остальное — как строки.
+func compare(val, filterVal interface{}) (int, bool) {
+	if fv, ok := toFloat(filterVal); ok {
+		if v, ok := toFloat(val); ok {
+			return compareFloat(v, fv), true
+		}
+		if s, ok := val.(string); ok {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return compareFloat(v, fv), true
+			}
+		}
+		return 0, false
+	}
+
+	if fb, ok := filterVal.(bool); ok {
+		switch v := val.(type) {
+		case bool:
+			if v == fb {
+				return 0, true
+			}
+			if fb {
+				return -1, true
+			}
+			return 1, true
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return 0, false
+			}
+			return compare(b, fb)
+		}
+		return 0, false
+	}
+
+	fs := fmt.Sprintf("%v", filterVal)
+	if t, ok := val.(time.Time); ok {
+		ft, ok := parseTime(fs)
+		if !ok {
+			return 0, false
+		}
+		return t.Compare(ft), true
+	}
+	return strings.Compare(fmt.Sprintf("%v", val), fs), true
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, bool:
+		return true
+	}
+	_, ok := toFloat(v)
+	return ok
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// likeRegexp переводит SQL-шаблон LIKE (% и _) в регулярное выражение
+func likeRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile("(?s)" + b.String())
+}
@@ -0,0 +1,87 @@
+package rowfilter
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	valid := &models.RowFilter{And: []models.RowFilter{
+		{Column: "status", Operator: models.FilterNotEq, Value: "draft"},
+		{Or: []models.RowFilter{
+			{Column: "amount", Operator: models.FilterGte, Value: float64(100)},
+			{Column: "region", Operator: models.FilterIn, Value: []interface{}{"eu", "us"}},
+			{Column: "closed_at", Operator: models.FilterIsNull},
+		}},
+	}}
+	require.NoError(t, Validate(valid))
+	require.NoError(t, Validate(nil))
+
+	invalid := map[string]*models.RowFilter{
+		"empty node":        {},
+		"unknown operator":  {Column: "status", Operator: "~", Value: "x"},
+		"missing value":     {Column: "status", Operator: models.FilterEq},
+		"null with value":   {Column: "status", Operator: models.FilterIsNull, Value: "x"},
+		"empty in":          {Column: "status", Operator: models.FilterIn, Value: []interface{}{}},
+		"like with number":  {Column: "status", Operator: models.FilterLike, Value: float64(1)},
+		"group with column": {Column: "status", And: []models.RowFilter{{Column: "a", Operator: models.FilterIsNull}}},
+		"and and or":        {And: []models.RowFilter{{Column: "a", Operator: models.FilterIsNull}}, Or: []models.RowFilter{{Column: "b", Operator: models.FilterIsNull}}},
+		"nested invalid":    {Or: []models.RowFilter{{Column: "a", Operator: "between", Value: "x"}}},
+		"object as value":   {Column: "status", Operator: models.FilterEq, Value: map[string]interface{}{"a": 1}},
+	}
+	for name, f := range invalid {
+		t.Run(name, func(t *testing.T) {
+			require.ErrorIs(t, Validate(f), ErrInvalidFilter)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	notDraft := &models.RowFilter{Column: "status", Operator: models.FilterNotEq, Value: "draft"}
+	require.True(t, Match(notDraft, map[string]interface{}{"status": "paid"}))
+	require.False(t, Match(notDraft, map[string]interface{}{"status": "draft"}))
+	require.False(t, Match(notDraft, map[string]interface{}{"status": nil}), "сравнение с NULL ложно")
+
+	amount := &models.RowFilter{Column: "amount", Operator: models.FilterGte, Value: float64(100)}
+	require.True(t, Match(amount, map[string]interface{}{"amount": "150.50"}), "numeric приходит строкой")
+	require.True(t, Match(amount, map[string]interface{}{"amount": int64(100)}))
+	require.False(t, Match(amount, map[string]interface{}{"amount": float64(99.9)}))
+
+	group := &models.RowFilter{Or: []models.RowFilter{
+		{Column: "region", Operator: models.FilterIn, Value: []interface{}{"eu", "us"}},
+		{Column: "email", Operator: models.FilterLike, Value: "%@example.com"},
+	}}
+	require.True(t, Match(group, map[string]interface{}{"region": "eu"}))
+	require.True(t, Match(group, map[string]interface{}{"region": "asia", "email": "a@example.com"}))
+	require.False(t, Match(group, map[string]interface{}{"region": "asia", "email": "a@example.org"}))
+
+	notIn := &models.RowFilter{Column: "id", Operator: models.FilterNotIn, Value: []interface{}{float64(1), float64(2)}}
+	require.True(t, Match(notIn, map[string]interface{}{"id": float64(3)}))
+	require.False(t, Match(notIn, map[string]interface{}{"id": float64(2)}))
+
+	active := &models.RowFilter{Column: "active", Operator: models.FilterEq, Value: true}
+	require.True(t, Match(active, map[string]interface{}{"active": true}))
+	require.False(t, Match(active, map[string]interface{}{"active": false}))
+
+	since := &models.RowFilter{Column: "created_at", Operator: models.FilterGt, Value: "2024-01-01"}
+	require.True(t, Match(since, map[string]interface{}{"created_at": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}))
+	require.False(t, Match(since, map[string]interface{}{"created_at": time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)}))
+
+	require.True(t, Match(&models.RowFilter{Column: "closed_at", Operator: models.FilterIsNull}, map[string]interface{}{}))
+	require.True(t, Match(nil, map[string]interface{}{"status": "draft"}))
+}
+
+func TestColumns(t *testing.T) {
+	filter := &models.RowFilter{And: []models.RowFilter{
+		{Column: "status", Operator: models.FilterNotEq, Value: "draft"},
+		{Or: []models.RowFilter{
+			{Column: "amount", Operator: models.FilterGte, Value: float64(100)},
+			{Column: "status", Operator: models.FilterIsNull},
+		}},
+	}}
+	require.Equal(t, []string{"status", "amount"}, Columns(filter))
+	require.Nil(t, Columns(nil))
+}
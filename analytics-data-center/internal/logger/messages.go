@@ -52,12 +52,17 @@ var (
 	}
 
 	// Tasks service messages
-	MsgCreateTaskStart    = Message{RU: "Создание задачи", EN: "Create task start", CN: "开始创建任务"}
-	MsgCreateTaskFailed   = Message{RU: "не удалось создать задачу", EN: "failed to create task", CN: "创建任务失败"}
-	MsgChangeStatusStart  = Message{RU: "Изменение статуса задачи", EN: "Change task status", CN: "更改任务状态"}
-	MsgChangeStatusFailed = Message{RU: "не изменить статус у задачи", EN: "failed to change task status", CN: "无法更改任务状态"}
-	MsgGetTaskStart       = Message{RU: "Получение задачи", EN: "Get task start", CN: "开始获取任务"}
-	MsgGetTaskFailed      = Message{RU: "не удалось получить задачу", EN: "failed to get task", CN: "获取任务失败"}
+	MsgCreateTaskStart          = Message{RU: "Создание задачи", EN: "Create task start", CN: "开始创建任务"}
+	MsgCreateTaskFailed         = Message{RU: "не удалось создать задачу", EN: "failed to create task", CN: "创建任务失败"}
+	MsgChangeStatusStart        = Message{RU: "Изменение статуса задачи", EN: "Change task status", CN: "更改任务状态"}
+	MsgChangeStatusFailed       = Message{RU: "не изменить статус у задачи", EN: "failed to change task status", CN: "无法更改任务状态"}
+	MsgGetTaskStart             = Message{RU: "Получение задачи", EN: "Get task start", CN: "开始获取任务"}
+	MsgGetTaskFailed            = Message{RU: "не удалось получить задачу", EN: "failed to get task", CN: "获取任务失败"}
+	MsgSaveStageFailed          = Message{RU: "не удалось сохранить этап задачи", EN: "failed to save task stage", CN: "保存任务阶段失败"}
+	MsgSaveProgressFailed       = Message{RU: "не удалось сохранить прогресс загрузки таблицы", EN: "failed to save table load progress", CN: "保存表加载进度失败"}
+	MsgSaveChunkFailed          = Message{RU: "не удалось сохранить чекпоинт чанка", EN: "failed to save chunk checkpoint", CN: "保存分块检查点失败"}
+	MsgRegisterTempTablesFailed = Message{RU: "не удалось закрепить временные таблицы за задачей", EN: "failed to register task temp tables", CN: "登记任务临时表失败"}
+	MsgSaveSnapshotFailed       = Message{RU: "не удалось сохранить снимок источника задачи", EN: "failed to save task source snapshot", CN: "保存任务数据源快照失败"}
 
 	// Analytics service messages
 	MsgETLWorkerStart          = Message{RU: "Начало обработки задачи", EN: "task processing start", CN: "开始处理任务"}
@@ -68,9 +73,12 @@ var (
 	MsgCountRowsFailed         = Message{RU: "не удалось получить количество", EN: "failed to get count", CN: "获取数量失败"}
 	MsgInsertDataFailed        = Message{RU: "не удалось получить данные для вставки", EN: "failed to get insert data", CN: "获取插入数据失败"}
 	MsgTransferIndexesFailed   = Message{RU: "не удалось перенести индексы", EN: "failed to transfer indexes", CN: "转移索引失败"}
+	MsgPublishViewFailed       = Message{RU: "не удалось опубликовать новую версию вью", EN: "failed to publish new view version", CN: "发布新版本视图失败"}
 	MsgEnableReplicationFailed = Message{RU: "не удалось включить полную репликацию", EN: "failed to enable replication", CN: "启用复制失败"}
 	MsgReplicationEnabled      = Message{RU: "Репликация для вью включена", EN: "replication enabled", CN: "视图复制已启用"}
 	MsgTableRecordCount        = Message{RU: "количество записей в таблице", EN: "table record count", CN: "表记录数"}
+	MsgETLStageStart           = Message{RU: "Начало этапа задачи", EN: "task stage start", CN: "任务阶段开始"}
+	MsgETLStageFailed          = Message{RU: "этап задачи завершился с ошибкой", EN: "task stage failed", CN: "任务阶段失败"}
 
 	// Analytics event worker messages
 	MsgEventWorkerReceived  = Message{RU: "Событие пришло в eventWorker", EN: "event received in worker", CN: "事件已到达worker"}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
 	loggerpkg "analyticDataCenter/analytics-data-center/internal/logger"
@@ -31,6 +32,7 @@ const (
 	Progress  = "In progress"
 	Error     = "Execution error"
 	Completed = "Completed"
+	Cancelled = "Cancelled"
 )
 
 const (
@@ -39,12 +41,11 @@ const (
 	ErrorSelectInsertData    = "Не удалось получить данный для вставки"
 	ErrorReplicaFullData     = "Не удалось включиь полную репликацию таблицы"
 	CompletedTask            = "Задача завершена успешно"
+	CancelledTask            = "Задача отменена"
 )
 
-type TaskETL struct {
-	ViewID int64
-	TaskID string
-}
+// defaultInsertBatchSize — размер порции вставки, если он не задан в конфигурации
+const defaultInsertBatchSize = 10_000
 
 // TopicNotifier allows to send information about new topics to subscribe.
 type TopicNotifier interface {
@@ -57,17 +58,47 @@ type AnalyticsDataCenterService struct {
 	RenameSuggestionStorage storage.ColumnRenameSuggestionStorage
 	ColumnMismatchStorage   storage.ColumnMismatchStorage
 	TaskService             TaskService
+	JobStorage              storage.ETLJobStorage
+	WatermarkStorage        storage.WatermarkStorage
+	ViewSnapshotStorage     storage.ViewSnapshotStorage
+	DeadLetterStorage       storage.DeadLetterStorage
+	PendingEventStorage     storage.PendingEventStorage
 	DWHProvider             storage.DWHDB
 	OLTPFactory             storage.OLTPFactory
 	DWHDbName               string
 	DWHDbPath               string
 	OLTPDbName              string
 	RenameHeuristicEnabled  bool
-	jobQueue                chan TaskETL
-	eventQueue              chan models.CDCEvent
+	jobSignal               chan struct{}
+	jobQueueOptions         JobQueueOptions
+	eventQueues             []chan models.CDCEvent
+	eventWorkerOpts         EventWorkerOptions
+	cdcBatchOpts            CDCBatchOptions
 	SMTPClient              smtpsender.SMTP
 	topicNotifier           TopicNotifier
 	notifier                notifications.Notifier
+	insertBatchSize         int
+
+	runningMu sync.Mutex
+	running   map[string]context.CancelFunc
+
+	limits *loadLimits
+
+	// joinLocks упорядочивает пересборку строк view с джоинами по опорным ключам
+	joinLocks keyLocks
+
+	handoffMu       sync.Mutex
+	handoffs        map[string]*viewHandoff
+	loadedPositions map[int64]map[string]models.SnapshotPosition
+
+	deadLetterOpts DeadLetterOptions
+
+	// pendingMu упорядочивает откладывание событий и применение отложенных
+	pendingMu sync.Mutex
+
+	janitorOptions TempTableJanitorOptions
+	janitorMu      sync.Mutex
+	orphanSince    map[string]time.Time
 }
 
 type TaskService interface {
@@ -75,6 +106,17 @@ type TaskService interface {
 	GetTask(ctx context.Context, taskID string) (models.Task, error)
 	ChangeStatusTask(ctx context.Context, taskID string, status string, comment string) error
 	GetTasks(ctx context.Context, taskFilter models.TaskFilter) ([]models.Task, error)
+	SaveTaskStage(ctx context.Context, taskID string, stage models.TaskStage) error
+	SaveTaskTableProgress(ctx context.Context, taskID string, progress models.TaskTableProgress) error
+	AddTaskTableRows(ctx context.Context, taskID string, progress models.TaskTableProgress) error
+	SaveTaskChunks(ctx context.Context, taskID string, chunks []models.TaskChunk) error
+	CompleteTaskChunk(ctx context.Context, taskID string, chunk models.TaskChunk) error
+	ListTaskChunks(ctx context.Context, taskID string) ([]models.TaskChunk, error)
+	RegisterTaskTempTables(ctx context.Context, taskID string, tables []string) error
+	GetTaskTempTables(ctx context.Context, taskID string) ([]string, error)
+	ListTaskTempTables(ctx context.Context, activeStatus string, failedStatus string, failedSince time.Time) ([]string, error)
+	SaveTaskSnapshot(ctx context.Context, taskID string, snapshot models.TaskSnapshot) error
+	ListTaskSnapshots(ctx context.Context, taskID string) ([]models.TaskSnapshot, error)
 }
 
 func New(
@@ -96,18 +138,22 @@ func New(
 		RenameSuggestionStorage: schemaProvider,
 		ColumnMismatchStorage:   schemaProvider,
 		TaskService:             taskService,
+		JobStorage:              schemaProvider,
+		WatermarkStorage:        schemaProvider,
+		ViewSnapshotStorage:     schemaProvider,
+		DeadLetterStorage:       schemaProvider,
+		PendingEventStorage:     schemaProvider,
 		DWHProvider:             dwhProvider,
 		OLTPFactory:             OLTPFactory,
 		DWHDbName:               DWHDbName,
 		DWHDbPath:               DWHDbPath,
 		OLTPDbName:              OLTPDbName,
 		RenameHeuristicEnabled:  renameHeuristic,
-		jobQueue:                make(chan TaskETL, 100),
-		eventQueue:              make(chan models.CDCEvent, 100),
+		jobSignal:               make(chan struct{}, 1),
 		SMTPClient:              SMTPClient,
+		running:                 make(map[string]context.CancelFunc),
 	}
-	go service.etlWorker()
-	go service.eventWorker()
+	service.SetETLTuning(ETLTuningOptions{})
 	return service
 }
 
@@ -121,20 +167,38 @@ func (a *AnalyticsDataCenterService) SetNotifier(notifier notifications.Notifier
 	a.notifier = notifier
 }
 
+// SetInsertBatchSize задаёт, сколько строк источника вставляется в DWH одной порцией.
+func (a *AnalyticsDataCenterService) SetInsertBatchSize(size int) {
+	a.insertBatchSize = size
+}
+
+func (a *AnalyticsDataCenterService) batchSize() int {
+	if a.insertBatchSize <= 0 {
+		return defaultInsertBatchSize
+	}
+	return a.insertBatchSize
+}
+
+// StartETLProcess ставит пересборку view в персистентную очередь. Если очередь уже
+// заполнена до лимита, возвращает storage.ErrQueueFull.
 func (a *AnalyticsDataCenterService) StartETLProcess(ctx context.Context, idView int64) (taskID string, err error) {
+	return a.enqueueETL(ctx, idView, models.ETLModeFull)
+}
+
+func (a *AnalyticsDataCenterService) enqueueETL(ctx context.Context, idView int64, mode string) (taskID string, err error) {
 	taskID = uuid.NewString()
 
-	err = a.TaskService.CreateTask(ctx, taskID, Progress)
+	err = a.JobStorage.EnqueueETLJob(ctx, taskID, idView, mode, Progress, a.queueOptions().Limit)
 	if err != nil {
 		return "", err
 	}
 
-	a.jobQueue <- TaskETL{
-		TaskID: taskID,
-		ViewID: idView,
+	// будим свободный воркер, не дожидаясь очередного опроса очереди
+	select {
+	case a.jobSignal <- struct{}{}:
+	default:
 	}
 	return taskID, nil
-
 }
 
 func (a *AnalyticsDataCenterService) ListViews(ctx context.Context) ([]models.SchemaInfo, error) {
@@ -145,25 +209,51 @@ func (a *AnalyticsDataCenterService) ListViews(ctx context.Context) ([]models.Sc
 	return views, nil
 }
 
-func (a *AnalyticsDataCenterService) etlWorker() {
-	for job := range a.jobQueue {
-		log := a.log.With(
-			slog.String("task", job.TaskID),
-			slog.String("component", "ETLWorker"),
-		)
-		log.InfoMsg(loggerpkg.MsgETLWorkerStart)
-
-		ctx := context.Background()
-		err := a.runETL(ctx, job.ViewID, job.TaskID)
-		if err != nil {
-			log.ErrorMsg(loggerpkg.MsgInsertDataFailed, slog.String("error", err.Error()))
-			a.TaskService.ChangeStatusTask(ctx, job.TaskID, Error, err.Error())
-			a.notifyTaskStatus(job.TaskID, Error, err.Error())
-		}
+// processETLJob выполняет задачу и записывает её итоговый статус. Если контекст
+// задачи отменён через CancelTask, задача получает статус Cancelled.
+func (a *AnalyticsDataCenterService) processETLJob(ctx context.Context, job models.ETLJob, log *loggerpkg.Logger) {
+	// статус пишется и после отмены, поэтому не зависит от контекста задачи
+	statusCtx := context.WithoutCancel(ctx)
+	tracker := a.newTaskTracker(job.TaskID)
+
+	tracker.resume = job.Resume
+	run := a.runETL
+	if job.Mode == models.ETLModeIncremental {
+		// инкрементальная загрузка пишет в рабочую таблицу наравне с CDC: копить события не нужно
+		run = a.runIncrementalETL
+	} else {
+		// CDC-события view копятся с самого начала загрузки, до экспорта снимков
+		tracker.handoff, tracker.handoffContinued = a.beginHandoff(job.TaskID, job.ViewID, job.Resume)
+	}
+	err := run(ctx, job.ViewID, tracker)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		log.Info("задача отменена", slog.String("error", err.Error()))
+		a.finishHandoff(statusCtx, tracker.handoff, handoffCancelled)
+		tracker.cancel(statusCtx)
+		a.TaskService.ChangeStatusTask(statusCtx, job.TaskID, Cancelled, CancelledTask)
+		tracker.notify(Cancelled, CancelledTask)
+	case err != nil:
+		log.ErrorMsg(loggerpkg.MsgInsertDataFailed, slog.String("error", err.Error()))
+		a.finishHandoff(statusCtx, tracker.handoff, handoffFailed)
+		tracker.fail(statusCtx, err)
+		a.TaskService.ChangeStatusTask(statusCtx, job.TaskID, Error, err.Error())
+		tracker.notify(Error, err.Error())
+	default:
+		// задача завершена, когда новая таблица догнала CDC
+		a.finishHandoff(statusCtx, tracker.handoff, handoffPublished)
+		tracker.complete(statusCtx)
+		a.TaskService.ChangeStatusTask(statusCtx, job.TaskID, Completed, CompletedTask)
+		tracker.notify(Completed, CompletedTask)
 	}
 }
 
-func (a *AnalyticsDataCenterService) runETL(ctx context.Context, idView int64, taskID string) error {
+// runETL выполняет полную пересборку view по этапам: временные таблицы, выгрузка
+// из источников, сборка теневой таблицы, перенос индексов, публикация и включение
+// репликации. Каждый этап фиксируется в tracker; задача считается выполненной,
+// только когда runETL вернулся без ошибки. Временные таблицы упавшей задачи
+// сохраняются вместе с чекпоинтами чанков, чтобы её можно было продолжить.
+func (a *AnalyticsDataCenterService) runETL(ctx context.Context, idView int64, tracker *taskTracker) (err error) {
 	const op = "analytics.StartETLProcess"
 	var queriesInit models.Queries
 	var tempTables []string
@@ -172,23 +262,20 @@ func (a *AnalyticsDataCenterService) runETL(ctx context.Context, idView int64, t
 		slog.Int64("idSchema", idView),
 	)
 	log.InfoMsg(loggerpkg.MsgETLStart)
+
+	tracker.enter(ctx, models.StageTempTables)
 	viewSchema, err := a.SchemaProvider.GetView(ctx, idView)
 	if err != nil {
+		a.log.Warn("view not found", slog.String("error", err.Error()))
 		if errors.Is(err, storage.ErrSchemaNotFound) {
-			a.log.Warn("view not found", slog.String("error", err.Error()))
-			a.TaskService.ChangeStatusTask(ctx, taskID, Error, ErrorCreateTemplateTable)
 			return fmt.Errorf("%s:%s", op, ErrInvalidSchemID)
-
 		}
-		a.TaskService.ChangeStatusTask(ctx, taskID, Error, ErrorCreateTemplateTable)
-		a.log.Warn("view not found", slog.String("error", err.Error()))
 		return fmt.Errorf("%s:%s", op, err)
 	}
-	queries, duplicates, err := sqlgenerator.GenerateQueryCreateTempTable(&viewSchema, log.Logger, a.DWHDbName)
+	queries, duplicates, err := sqlgenerator.GenerateQueryCreateTempTable(&viewSchema, tracker.id(), log.Logger, a.DWHDbName)
 	if err != nil {
 		log.ErrorMsg(loggerpkg.MsgGenerateQueriesFailed, slog.String("error", err.Error()))
-		a.TaskService.ChangeStatusTask(ctx, taskID, Error, ErrorCreateTemplateTable)
-		return fmt.Errorf("%s:%s", op, err)
+		return fmt.Errorf("%s: %s: %w", op, ErrorCreateTemplateTable, err)
 	}
 
 	queriesInit = queries
@@ -201,65 +288,92 @@ func (a *AnalyticsDataCenterService) runETL(ctx context.Context, idView int64, t
 		tempTables = append(tempTables, tempTable.TableName)
 		log.InfoMsg(loggerpkg.MsgTempTable, slog.String("table", tempTable.TableName))
 	}
-	err = a.createTempTables(ctx, queriesInit)
-	if err != nil {
-		log.ErrorMsg(loggerpkg.MsgCreateTempTablesFailed, slog.String("error", err.Error()))
-		a.TaskService.ChangeStatusTask(ctx, taskID, Error, ErrorCreateTemplateTable)
-		return fmt.Errorf("%s:%s", op, err)
-	}
-
-	countInsertData, err := a.getCountInsertData(ctx, viewSchema, tempTables)
-	if err != nil {
-		log.ErrorMsg(loggerpkg.MsgCountRowsFailed, slog.String("error", err.Error()))
-		a.TaskService.ChangeStatusTask(ctx, taskID, Error, ErrorCountInsertData)
-		return fmt.Errorf("%s:%s", op, err)
-	}
-	backgroundCtx := context.Background() // независимый от вызова клиента
-
-	go func() {
-		_, err := a.prepareAndInsertData(backgroundCtx, &countInsertData, &viewSchema)
-		if err != nil {
-			log.ErrorMsg(loggerpkg.MsgInsertDataFailed, slog.String("error", err.Error()))
-			a.TaskService.ChangeStatusTask(ctx, taskID, Error, ErrorSelectInsertData)
-			return
+	if tracker.resuming() {
+		// временные таблицы остались от прошлого запуска и уже содержат загруженные чанки
+		if err := tracker.loadCheckpoints(ctx); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
 		}
-
-		err = a.transferIndixesAndConstraint(ctx, &viewSchema, a.DWHDbName)
+		if err := tracker.dropStaleCheckpoints(ctx); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		owns, err := a.ownsResumeTables(ctx, tracker, tempTables)
 		if err != nil {
-			log.ErrorMsg(loggerpkg.MsgTransferIndexesFailed, slog.String("error", err.Error()))
-			a.TaskService.ChangeStatusTask(ctx, taskID, Error, ErrorSelectInsertData)
+			return fmt.Errorf("%s: %w", op, err)
 		}
-		//Нужно для постгри, если DWH и OLTP одна БД.
-		if a.DWHDbName == DbPostgres {
-			err = a.DWHProvider.ReplicaIdentityFull(ctx, strings.ToLower(viewSchema.Name))
+		if !owns {
+			tracker.restartLoad()
+		}
+	}
+	if tracker.resuming() {
+		log.Info("продолжение задачи: временные таблицы переиспользуются")
+	} else {
+		// таблицы закрепляются за задачей до создания, чтобы сборщик мусора их не тронул;
+		// теневая таблица создаётся позже, но закрепляется вместе с временными
+		owned := append(append([]string(nil), tempTables...), tracker.shadowTable(viewSchema.Name))
+		if err := tracker.registerTempTables(ctx, owned); err != nil {
+			return fmt.Errorf("%s: %s: %w", op, ErrorCreateTemplateTable, err)
 		}
+		err = a.createTempTables(ctx, queriesInit)
 		if err != nil {
-			log.ErrorMsg(loggerpkg.MsgEnableReplicationFailed, slog.String("error", err.Error()))
-			a.TaskService.ChangeStatusTask(ctx, taskID, Error, ErrorSelectInsertData)
+			log.ErrorMsg(loggerpkg.MsgCreateTempTablesFailed, slog.String("error", err.Error()))
+			return fmt.Errorf("%s: %s: %w", op, ErrorCreateTemplateTable, err)
+		}
+	}
+	// после успеха или отмены временные таблицы удаляются, после ошибки — остаются для продолжения
+	defer func() {
+		if err == nil || ctx.Err() != nil {
+			a.DeleteTempTables(ctx, tempTables)
 		}
-		log.InfoMsg(loggerpkg.MsgReplicationEnabled)
-
 	}()
 
+	tracker.enter(ctx, models.StageExtract)
+	// подсчёт, план чанков и выгрузка читают источники из снимков, позиции которых
+	// отделяют загруженные изменения от тех, что догонит CDC
+	defer tracker.releaseSnapshots()
+	if err := a.exportSnapshots(ctx, tracker, viewSchema); err != nil {
+		log.Error("не удалось экспортировать снимки источников", slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %s: %w", op, ErrorSelectInsertData, err)
+	}
+	countInsertData, err := a.getCountInsertData(ctx, tracker, viewSchema, tempTables)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgCountRowsFailed, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %s: %w", op, ErrorCountInsertData, err)
+	}
 	log.InfoMsg(loggerpkg.MsgTableRecordCount, slog.Any("count", countInsertData))
-	a.TaskService.ChangeStatusTask(ctx, taskID, Completed, CompletedTask)
-	a.notifyTaskStatus(taskID, Completed, CompletedTask)
-	return nil
-}
+	// при продолжении прогресс таблиц уже учитывает загруженные чанки
+	var watermarks []models.ViewWatermark
+	if !tracker.resuming() {
+		for _, item := range countInsertData {
+			tracker.expectRows(ctx, item)
+		}
+		// часть чанков продолжаемой задачи загружена из прежнего снимка, поэтому отметки
+		// снимаются только при обычном запуске
+		if watermarks, err = a.captureWatermarks(ctx, tracker, viewSchema); err != nil {
+			log.Warn("не удалось прочитать отметки таблиц", slog.String("error", err.Error()))
+			watermarks = nil
+		}
+	}
 
-func (a *AnalyticsDataCenterService) notifyTaskStatus(taskID, status, message string) {
-	if a.notifier == nil {
-		return
+	// этап merge начинается внутри prepareAndInsertData, когда все чанки загружены
+	if _, err := a.prepareAndInsertData(ctx, tracker, &countInsertData, &viewSchema); err != nil {
+		log.ErrorMsg(loggerpkg.MsgInsertDataFailed, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %s: %w", op, ErrorSelectInsertData, err)
 	}
 
-	notification := models.Notification{
-		Type:      "task_status",
-		Title:     "ETL задача",
-		Message:   message,
-		TaskID:    taskID,
-		Status:    status,
-		CreatedAt: time.Now(),
+	if err := a.publishView(ctx, tracker, &viewSchema); err != nil {
+		log.ErrorMsg(loggerpkg.MsgPublishViewFailed, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	a.saveWatermarks(ctx, idView, watermarks)
 
-	a.notifier.Publish(notification)
+	tracker.enter(ctx, models.StageReplication)
+	//Нужно для постгри, если DWH и OLTP одна БД.
+	if a.DWHDbName == DbPostgres {
+		if err := a.DWHProvider.ReplicaIdentityFull(ctx, strings.ToLower(viewSchema.Name)); err != nil {
+			log.ErrorMsg(loggerpkg.MsgEnableReplicationFailed, slog.String("error", err.Error()))
+			return fmt.Errorf("%s: %s: %w", op, ErrorReplicaFullData, err)
+		}
+	}
+	log.InfoMsg(loggerpkg.MsgReplicationEnabled)
+	return nil
 }
@@ -17,7 +17,7 @@ func TestGetCountInsertData(t *testing.T) {
 	svc := &AnalyticsDataCenterService{log: getTestLogger(), OLTPFactory: factory}
 
 	view := models.View{Sources: []models.Source{{Name: "db1", Schemas: []models.Schema{{Tables: []models.Table{{Name: "users"}}}}}}}
-	res, err := svc.getCountInsertData(context.Background(), view, []string{"tmp"})
+	res, err := svc.getCountInsertData(context.Background(), nil, view, []string{"tmp"})
 
 	require.NoError(t, err)
 	require.Len(t, res, 1)
@@ -30,7 +30,7 @@ func TestGetCountInsertDataStorageError(t *testing.T) {
 	svc := &AnalyticsDataCenterService{log: getTestLogger(), OLTPFactory: factory}
 	view := models.View{Sources: []models.Source{{Name: "db1", Schemas: []models.Schema{{Tables: []models.Table{{Name: "users"}}}}}}}
 
-	_, err := svc.getCountInsertData(context.Background(), view, []string{"tmp"})
+	_, err := svc.getCountInsertData(context.Background(), nil, view, []string{"tmp"})
 
 	require.Error(t, err)
 }
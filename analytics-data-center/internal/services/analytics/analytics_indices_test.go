@@ -18,8 +18,9 @@ func TestTransferIndices(t *testing.T) {
 	view := &models.View{Sources: []models.Source{{Name: "db1", Schemas: []models.Schema{{Name: "public", Tables: []models.Table{{Name: "t1"}}}}}}}
 	svc := &AnalyticsDataCenterService{log: getTestLogger(), OLTPFactory: factory, DWHProvider: dwh}
 
-	err := svc.transferIndixesAndConstraint(context.Background(), view, "postgres")
+	err := svc.transferIndixesAndConstraint(context.Background(), view, "v__shadow", "postgres")
 
 	require.NoError(t, err)
 	require.NotEmpty(t, dwh.indexCalls)
+	require.Contains(t, dwh.indexCalls[0], "ON public.v__shadow")
 }
@@ -33,11 +33,58 @@ func TestPrepareAndInsertData(t *testing.T) {
 	svc := &AnalyticsDataCenterService{log: getTestLogger(), OLTPFactory: factory, DWHProvider: dwh, DWHDbName: DbPostgres, OLTPDbName: DbPostgres}
 	data := []models.CountInsertData{{TableName: "users", Count: 1, DataBaseName: "db1", TempTableName: "tmp_users"}}
 
-	ok, err := svc.prepareAndInsertData(context.Background(), &data, view)
+	ok, err := svc.prepareAndInsertData(context.Background(), nil, &data, view)
 
 	require.True(t, ok)
 	require.NoError(t, err)
 	require.NotEmpty(t, dwh.insertCalls)
 	require.Equal(t, 1, dwh.mergeCalls)
-	require.Equal(t, []string{"tmp_users"}, dwh.deleteCalls)
+	require.Contains(t, dwh.mergeQueries[0], `CREATE TABLE "temp_v__shadow"`)
+	// остаток прошлой сборки удаляется до мерджа; временные таблицы удаляет runETL
+	require.Equal(t, []string{"temp_v__shadow"}, dwh.deleteCalls)
+}
+
+func TestPrepareAndInsertData_InsertsInBatches(t *testing.T) {
+	rows := make([]map[string]interface{}, 0, 5)
+	for i := 1; i <= 5; i++ {
+		rows = append(rows, map[string]interface{}{"id": i, "name": "A"})
+	}
+	oltp := &mockOLTP{selectResult: rows}
+	dwh := &mockDWH{columns: map[string][]string{"tmp_users": {"id", "name"}}}
+	view := &models.View{
+		Name: "v",
+		Sources: []models.Source{{
+			Name: "db1",
+			Schemas: []models.Schema{{
+				Name: "public",
+				Tables: []models.Table{{
+					Name:    "users",
+					Columns: []models.Column{{Name: "id", IsPrimaryKey: true}, {Name: "name"}},
+				}},
+			}},
+		}},
+	}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHProvider: dwh,
+		DWHDbName:   DbPostgres,
+		OLTPDbName:  DbPostgres,
+	}
+	svc.SetInsertBatchSize(2)
+	data := []models.CountInsertData{{TableName: "users", Count: 5, DataBaseName: "db1", TempTableName: "tmp_users"}}
+
+	ok, err := svc.prepareAndInsertData(context.Background(), nil, &data, view)
+
+	require.True(t, ok)
+	require.NoError(t, err)
+	require.Len(t, oltp.streamCalls, 1)
+	require.Equal(t, []int{2, 2, 1}, dwh.batchSizes)
+}
+
+func TestPrepareAndInsertData_DefaultBatchSize(t *testing.T) {
+	svc := &AnalyticsDataCenterService{}
+	require.Equal(t, defaultInsertBatchSize, svc.batchSize())
+	svc.SetInsertBatchSize(500)
+	require.Equal(t, 500, svc.batchSize())
 }
@@ -3,6 +3,8 @@ package serviceanalytics
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	loggerpkg "analyticDataCenter/analytics-data-center/internal/logger"
 
@@ -15,6 +17,7 @@ func getTestLogger() *loggerpkg.Logger {
 }
 
 type mockDWH struct {
+	mu           sync.Mutex
 	createCalls  []string
 	deleteCalls  []string
 	columns      map[string][]string
@@ -22,14 +25,28 @@ type mockDWH struct {
 	deleteErrors map[string]error
 	insertCalls  []string
 	insertErr    error
+	batchSizes   []int
 	mergeCalls   int
+	mergeQueries []string
 	mergeErr     error
+	swapCalls    [][2]string
+	swapErr      error
+	chunkDeletes []mockChunkDelete
+	tempTables   []string
 	indexCalls   []string
 	indexErr     error
 	renameCalls  []string
 	renameErr    error
 	dropCalls    []string
 	dropErr      error
+
+	upsertCalls     []mockRowCall
+	upsertBatches   []int
+	upsertErr       error
+	rowDeleteCalls  []mockRowCall
+	softDeleteCalls []mockRowCall
+	rowDeleteErr    error
+	replaceCalls    int
 }
 
 func (m *mockDWH) CreateTempTable(_ context.Context, _ string, name string) error {
@@ -37,6 +54,7 @@ func (m *mockDWH) CreateTempTable(_ context.Context, _ string, name string) erro
 	if err, ok := m.createErrors[name]; ok {
 		return err
 	}
+	m.tempTables = append(m.tempTables, name)
 	return nil
 }
 
@@ -45,6 +63,12 @@ func (m *mockDWH) DeleteTempTable(_ context.Context, name string) error {
 	if err, ok := m.deleteErrors[name]; ok {
 		return err
 	}
+	for i, table := range m.tempTables {
+		if table == name {
+			m.tempTables = append(m.tempTables[:i:i], m.tempTables[i+1:]...)
+			break
+		}
+	}
 	return nil
 }
 
@@ -53,9 +77,33 @@ func (m *mockDWH) CreateIndex(_ context.Context, query string) error {
 	return m.indexErr
 }
 func (m *mockDWH) CreateConstraint(_ context.Context, _ string) error { return nil }
-func (m *mockDWH) InsertDataToDWH(_ context.Context, query string) error {
-	m.insertCalls = append(m.insertCalls, query)
-	return m.insertErr
+func (m *mockDWH) InsertStream(_ context.Context, _ models.View, table string, rows models.RowStream, batchSize int) (int64, error) {
+	var inserted int64
+	batch := 0
+	flush := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.insertCalls = append(m.insertCalls, table)
+		m.batchSizes = append(m.batchSizes, batch)
+		inserted += int64(batch)
+		batch = 0
+	}
+	for rows.Next() {
+		batch++
+		if batch == batchSize {
+			if m.insertErr != nil {
+				return inserted, m.insertErr
+			}
+			flush()
+		}
+	}
+	if batch > 0 {
+		if m.insertErr != nil {
+			return inserted, m.insertErr
+		}
+		flush()
+	}
+	return inserted, rows.Err()
 }
 func (m *mockDWH) RenameColumn(_ context.Context, query string) error {
 	m.renameCalls = append(m.renameCalls, query)
@@ -71,38 +119,153 @@ func (m *mockDWH) GetColumnsTables(_ context.Context, _ string, table string) ([
 	}
 	return nil, fmt.Errorf("no columns")
 }
-func (m *mockDWH) MergeTempTables(_ context.Context, _ string) error {
+func (m *mockDWH) MergeTempTables(_ context.Context, query string) error {
 	m.mergeCalls++
+	m.mergeQueries = append(m.mergeQueries, query)
 	return m.mergeErr
 }
+func (m *mockDWH) SwapTables(_ context.Context, shadow string, target string) error {
+	m.swapCalls = append(m.swapCalls, [2]string{shadow, target})
+	return m.swapErr
+}
+func (m *mockDWH) DeleteChunkRows(_ context.Context, table string, condition string, args ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chunkDeletes = append(m.chunkDeletes, mockChunkDelete{table: table, condition: condition, args: args})
+	return nil
+}
+func (m *mockDWH) ListTempTables(context.Context, string) ([]string, error) {
+	return m.tempTables, nil
+}
 func (m *mockDWH) ReplicaIdentityFull(context.Context, string) error { return nil }
-func (m *mockDWH) InsertOrUpdateTransactional(context.Context, string, map[string]interface{}, []string) error {
+func (m *mockDWH) InsertOrUpdateTransactional(_ context.Context, table string, row map[string]interface{}, conflict []string) error {
+	m.upsertCalls = append(m.upsertCalls, mockRowCall{table: table, row: row, keys: conflict})
+	return m.upsertErr
+}
+func (m *mockDWH) UpsertBatch(_ context.Context, table string, rows []map[string]interface{}, conflict []string) error {
+	m.upsertBatches = append(m.upsertBatches, len(rows))
+	for _, row := range rows {
+		m.upsertCalls = append(m.upsertCalls, mockRowCall{table: table, row: row, keys: conflict})
+	}
+	return m.upsertErr
+}
+func (m *mockDWH) DeleteRow(_ context.Context, table string, keys map[string]interface{}) error {
+	m.rowDeleteCalls = append(m.rowDeleteCalls, mockRowCall{table: table, row: keys})
+	return m.rowDeleteErr
+}
+func (m *mockDWH) SoftDeleteRow(_ context.Context, table string, keys map[string]interface{}) error {
+	m.softDeleteCalls = append(m.softDeleteCalls, mockRowCall{table: table, row: keys})
+	return m.rowDeleteErr
+}
+
+func (m *mockDWH) ReplaceRows(_ context.Context, table string, removeKeys []map[string]interface{}, softDelete bool, rows []models.RowUpsert) error {
+	m.replaceCalls++
+	// как в транзакции: при ошибке таблица не меняется
+	if m.rowDeleteErr != nil {
+		return m.rowDeleteErr
+	}
+	if m.upsertErr != nil {
+		return m.upsertErr
+	}
+	for _, keys := range removeKeys {
+		if softDelete {
+			m.softDeleteCalls = append(m.softDeleteCalls, mockRowCall{table: table, row: keys})
+		} else {
+			m.rowDeleteCalls = append(m.rowDeleteCalls, mockRowCall{table: table, row: keys})
+		}
+	}
+	for _, row := range rows {
+		m.upsertCalls = append(m.upsertCalls, mockRowCall{table: table, row: row.Row, keys: row.ConflictColumns})
+	}
 	return nil
 }
 
+type mockRowCall struct {
+	table string
+	row   map[string]interface{}
+	keys  []string
+}
+
+type mockChunkDelete struct {
+	table     string
+	condition string
+	args      []interface{}
+}
+
 // ---- OLTP mocks ----
 type mockOLTP struct {
+	mu           sync.Mutex
 	countResult  int64
 	countErr     error
+	countHook    func(ctx context.Context)
 	selectResult []map[string]interface{}
 	selectErr    error
 	indexResult  models.Indexes
 	indexErr     error
 	columns      []models.Column
 	columnsErr   error
+	// tableColumns и columnInfo задают каталог по таблицам; без них GetColumns отдаёт columns
+	tableColumns map[string][]models.Column
+	columnInfo   map[string]models.ColumnInfo
+
+	selectRows      func(query string, args []interface{}) ([]map[string]interface{}, error)
+	selectRowsCalls []string
+	streamCalls     []models.Query
+
+	// snapshot — позиция, которую отдаёт ExportSnapshot; released — сколько снимков закрыто
+	snapshot    models.SnapshotPosition
+	snapshotErr error
+	exported    int
+	released    int
 }
 
-func (m *mockOLTP) GetCountInsertData(context.Context, string) (int64, error) {
+// sliceRowStream отдаёт заранее подготовленные строки как models.RowStream
+type sliceRowStream struct {
+	rows   []map[string]interface{}
+	pos    int
+	closed bool
+}
+
+func (s *sliceRowStream) Next() bool {
+	if s.closed || s.pos >= len(s.rows) {
+		return false
+	}
+	s.pos++
+	return true
+}
+func (s *sliceRowStream) Row() map[string]interface{} { return s.rows[s.pos-1] }
+func (s *sliceRowStream) Err() error                  { return nil }
+func (s *sliceRowStream) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (m *mockOLTP) GetCountInsertData(ctx context.Context, _ string) (int64, error) {
+	if m.countHook != nil {
+		m.countHook(ctx)
+	}
 	if m.countErr != nil {
 		return 0, m.countErr
 	}
 	return m.countResult, nil
 }
-func (m *mockOLTP) SelectDataToInsert(context.Context, string) ([]map[string]interface{}, error) {
+func (m *mockOLTP) StreamRows(_ context.Context, query string, args ...interface{}) (models.RowStream, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.streamCalls = append(m.streamCalls, models.Query{Query: query, Args: args})
 	if m.selectErr != nil {
 		return nil, m.selectErr
 	}
-	return m.selectResult, nil
+	return &sliceRowStream{rows: m.selectResult}, nil
+}
+func (m *mockOLTP) SelectRows(_ context.Context, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	m.mu.Lock()
+	m.selectRowsCalls = append(m.selectRowsCalls, query)
+	m.mu.Unlock()
+	if m.selectRows != nil {
+		return m.selectRows(query, args)
+	}
+	return nil, nil
 }
 func (m *mockOLTP) GetIndexes(context.Context, string, string) (models.Indexes, error) {
 	if m.indexErr != nil {
@@ -113,10 +276,13 @@ func (m *mockOLTP) GetIndexes(context.Context, string, string) (models.Indexes,
 func (m *mockOLTP) GetConstraint(context.Context, string, string) (models.Constraints, error) {
 	return models.Constraints{}, nil
 }
-func (m *mockOLTP) GetColumns(context.Context, string, string) ([]models.Column, error) {
+func (m *mockOLTP) GetColumns(_ context.Context, _ string, table string) ([]models.Column, error) {
 	if m.columnsErr != nil {
 		return nil, m.columnsErr
 	}
+	if m.tableColumns != nil {
+		return m.tableColumns[table], nil
+	}
 	return m.columns, nil
 }
 
@@ -130,14 +296,32 @@ func (m *mockOLTP) GetTablesPaginated(context.Context, string, int, int) ([]mode
 	return []models.Table{}, nil
 }
 
-func (m *mockOLTP) GetColumnInfo(context.Context, string, string) (models.ColumnInfo, error) {
-	return models.ColumnInfo{}, nil
+func (m *mockOLTP) ExportSnapshot(context.Context) (models.OLTPSnapshot, func() error, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.snapshotErr != nil {
+		return models.OLTPSnapshot{}, nil, m.snapshotErr
+	}
+	m.exported++
+	release := func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.released++
+		return nil
+	}
+	return models.OLTPSnapshot{ID: fmt.Sprintf("snapshot-%d", m.exported), Position: m.snapshot}, release, nil
+}
+
+func (m *mockOLTP) GetColumnInfo(_ context.Context, table string, column string) (models.ColumnInfo, error) {
+	return m.columnInfo[table+"."+column], nil
 }
 
 // ---- factory ----
 type mockFactory struct {
 	store map[string]storage.OLTPDB
 	err   error
+	// snapshotReads — снимки, для которых запрашивались хранилища загрузки
+	snapshotReads []string
 }
 
 func (m *mockFactory) GetOLTPStorage(_ context.Context, name string) (storage.OLTPDB, error) {
@@ -150,5 +334,225 @@ func (m *mockFactory) GetOLTPStorage(_ context.Context, name string) (storage.OL
 	}
 	return st, nil
 }
-func (m *mockFactory) CloseAll() error                                  { return nil }
-func (m *mockFactory) GetOLTPStrings(context.Context) map[string]string { return nil }
+func (m *mockFactory) GetOLTPSnapshotStorage(ctx context.Context, name string, snapshotID string) (storage.OLTPDB, error) {
+	m.snapshotReads = append(m.snapshotReads, name+"@"+snapshotID)
+	return m.GetOLTPStorage(ctx, name)
+}
+func (m *mockFactory) CloseAll() error { return nil }
+func (m *mockFactory) GetOLTPStrings(context.Context) map[string]string {
+	res := make(map[string]string, len(m.store))
+	for name := range m.store {
+		res[name] = name
+	}
+	return res
+}
+
+// ---- task service ----
+type mockTaskService struct {
+	mu          sync.Mutex
+	task        models.Task
+	taskErr     error
+	statuses    []string
+	comments    []string
+	stages      []models.TaskStage
+	expected    []models.TaskTableProgress
+	loaded      []models.TaskTableProgress
+	plans       []models.TaskChunk
+	done        []models.TaskChunk
+	chunks      []models.TaskChunk
+	owned       []string
+	register    []string
+	registerErr error
+	snapshots   []models.TaskSnapshot
+}
+
+func (m *mockTaskService) CreateTask(context.Context, string, string) error { return nil }
+func (m *mockTaskService) GetTask(context.Context, string) (models.Task, error) {
+	return m.task, m.taskErr
+}
+func (m *mockTaskService) ChangeStatusTask(_ context.Context, _ string, status string, comment string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statuses = append(m.statuses, status)
+	m.comments = append(m.comments, comment)
+	return nil
+}
+func (m *mockTaskService) GetTasks(context.Context, models.TaskFilter) ([]models.Task, error) {
+	return nil, nil
+}
+func (m *mockTaskService) SaveTaskStage(_ context.Context, _ string, stage models.TaskStage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stages = append(m.stages, stage)
+	return nil
+}
+func (m *mockTaskService) SaveTaskTableProgress(_ context.Context, _ string, progress models.TaskTableProgress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expected = append(m.expected, progress)
+	return nil
+}
+func (m *mockTaskService) AddTaskTableRows(_ context.Context, _ string, progress models.TaskTableProgress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loaded = append(m.loaded, progress)
+	return nil
+}
+
+func (m *mockTaskService) SaveTaskChunks(_ context.Context, _ string, chunks []models.TaskChunk) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.plans = append(m.plans, chunks...)
+	return nil
+}
+func (m *mockTaskService) CompleteTaskChunk(_ context.Context, _ string, chunk models.TaskChunk) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.done = append(m.done, chunk)
+	return nil
+}
+func (m *mockTaskService) ListTaskChunks(context.Context, string) ([]models.TaskChunk, error) {
+	return m.chunks, nil
+}
+
+func (m *mockTaskService) RegisterTaskTempTables(_ context.Context, _ string, tables []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.registerErr != nil {
+		return m.registerErr
+	}
+	m.register = append(m.register, tables...)
+	return nil
+}
+func (m *mockTaskService) GetTaskTempTables(context.Context, string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.register...), nil
+}
+func (m *mockTaskService) ListTaskTempTables(context.Context, string, string, time.Time) ([]string, error) {
+	return m.owned, nil
+}
+
+func (m *mockTaskService) SaveTaskSnapshot(_ context.Context, _ string, snapshot models.TaskSnapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.snapshots {
+		if m.snapshots[i].Source == snapshot.Source {
+			m.snapshots[i] = snapshot
+			return nil
+		}
+	}
+	m.snapshots = append(m.snapshots, snapshot)
+	return nil
+}
+func (m *mockTaskService) ListTaskSnapshots(context.Context, string) ([]models.TaskSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.TaskSnapshot(nil), m.snapshots...), nil
+}
+
+// finalStages сворачивает записи этапов в последнее состояние каждого этапа по порядку начала
+func (m *mockTaskService) finalStages() []models.TaskStage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []models.TaskStage
+	index := map[string]int{}
+	for _, stage := range m.stages {
+		if i, ok := index[stage.Name]; ok {
+			result[i] = stage
+			continue
+		}
+		index[stage.Name] = len(result)
+		result = append(result, stage)
+	}
+	return result
+}
+
+// ---- job queue ----
+type mockJobStorage struct {
+	mu        sync.Mutex
+	jobs      []models.ETLJob
+	limit     int
+	requeued  []string
+	failed    []string
+	orphans   []string
+	finished  []string
+	leaseLost bool
+}
+
+func (m *mockJobStorage) EnqueueETLJob(_ context.Context, taskID string, viewID int64, mode string, _ string, maxBacklog int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limit = maxBacklog
+	queued := 0
+	for _, job := range m.jobs {
+		if job.Status == models.ETLJobQueued {
+			queued++
+		}
+	}
+	if queued >= maxBacklog {
+		return storage.ErrQueueFull
+	}
+	m.jobs = append(m.jobs, models.ETLJob{TaskID: taskID, ViewID: viewID, Status: models.ETLJobQueued, Mode: mode})
+	return nil
+}
+func (m *mockJobStorage) RequeueETLJob(_ context.Context, taskID string, viewID int64, _ string, _ int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs = append(m.jobs, models.ETLJob{TaskID: taskID, ViewID: viewID, Status: models.ETLJobQueued, Resume: true})
+	return nil
+}
+func (m *mockJobStorage) ClaimETLJob(_ context.Context, workerID string, _ time.Duration) (*models.ETLJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.jobs {
+		if m.jobs[i].Status == models.ETLJobQueued {
+			m.jobs[i].Status = models.ETLJobRunning
+			m.jobs[i].WorkerID = &workerID
+			m.jobs[i].Attempts++
+			job := m.jobs[i]
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockJobStorage) ExtendETLJobLease(context.Context, string, string, time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.leaseLost {
+		return storage.ErrJobLost
+	}
+	return nil
+}
+func (m *mockJobStorage) FinishETLJob(_ context.Context, taskID string, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finished = append(m.finished, taskID)
+	m.removeLocked(taskID)
+	return nil
+}
+func (m *mockJobStorage) DeleteETLJob(_ context.Context, taskID string) (models.ETLJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.TaskID == taskID {
+			m.removeLocked(taskID)
+			return job, nil
+		}
+	}
+	return models.ETLJob{}, storage.ErrJobNotFound
+}
+func (m *mockJobStorage) RecoverETLJobs(context.Context, int) ([]string, []string, error) {
+	return m.requeued, m.failed, nil
+}
+func (m *mockJobStorage) ListOrphanTasks(context.Context, string) ([]string, error) {
+	return m.orphans, nil
+}
+func (m *mockJobStorage) removeLocked(taskID string) {
+	for i, job := range m.jobs {
+		if job.TaskID == taskID {
+			m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+			return
+		}
+	}
+}
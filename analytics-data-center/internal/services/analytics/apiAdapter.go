@@ -4,6 +4,7 @@ import (
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
 	"context"
 	"fmt"
+	"log/slog"
 	"net/url"
 )
 
@@ -77,6 +78,12 @@ func (a *AnalyticsDataCenterService) GetDBInformations(ctx context.Context, conn
 
 func (a *AnalyticsDataCenterService) UploadSchema(ctx context.Context, schema models.View) (int64, error) {
 	var id int64
+	// ошибки описания всплывают до загрузки, а не посреди runETL
+	if validation := a.ValidateView(ctx, schema); !validation.Valid {
+		err := &ViewValidationError{Result: validation}
+		a.log.Warn("view не прошёл проверку", slog.String("error", err.Error()))
+		return 0, err
+	}
 	id, err := a.SchemaProvider.UploadView(ctx, schema)
 	if err != nil {
 		a.log.Error("Ошибка работы с базой данных")
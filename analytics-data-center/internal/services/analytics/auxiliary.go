@@ -40,9 +40,9 @@ func (a *AnalyticsDataCenterService) createTempTables(ctx context.Context, qurie
 	}
 	if errorCreate != nil {
 		for _, tableQuery := range quries.Queries {
-			err := a.DWHProvider.DeleteTempTable(ctx, tableQuery.TableName)
+			err := a.DWHProvider.DeleteTempTable(context.WithoutCancel(ctx), tableQuery.TableName)
 			if err != nil {
-				//TO DO сделать worker , который раз в какое-то время запускается и чистит темп таблицы на такие случаи.
+				// оставшуюся таблицу удалит сборщик временных таблиц (janitor.go)
 				log.Error("не удалось удалить временную таблицу",
 					slog.String("table", tableQuery.TableName),
 					slog.String("error", err.Error()),
@@ -57,7 +57,7 @@ func (a *AnalyticsDataCenterService) createTempTables(ctx context.Context, qurie
 
 }
 
-func (a *AnalyticsDataCenterService) getCountInsertData(ctx context.Context, viewSchema models.View, tempTables []string) ([]models.CountInsertData, error) {
+func (a *AnalyticsDataCenterService) getCountInsertData(ctx context.Context, tracker *taskTracker, viewSchema models.View, tempTables []string) ([]models.CountInsertData, error) {
 	const op = "analytics.getCountInsertData"
 	var sliceCountInsertData []models.CountInsertData
 	log := a.log.With(
@@ -71,7 +71,7 @@ func (a *AnalyticsDataCenterService) getCountInsertData(ctx context.Context, vie
 	}
 
 	for idx, query := range queries.Queries {
-		oltpStorage, err := a.OLTPFactory.GetOLTPStorage(ctx, query.SourceName)
+		oltpStorage, err := a.sourceStorage(ctx, tracker, query.SourceName)
 		if err != nil {
 			log.Error("Невозможно подключиться к OLTP хранилищу", slog.String("error", err.Error()))
 			return []models.CountInsertData{}, err
@@ -104,32 +104,39 @@ func (a *AnalyticsDataCenterService) getCountInsertData(ctx context.Context, vie
 
 	return sliceCountInsertData, nil
 }
-func (a *AnalyticsDataCenterService) prepareAndInsertData(ctx context.Context, countData *[]models.CountInsertData, viewSchema *models.View) (bool, error) {
+func (a *AnalyticsDataCenterService) prepareAndInsertData(ctx context.Context, tracker *taskTracker, countData *[]models.CountInsertData, viewSchema *models.View) (bool, error) {
 	const op = "analytics.prepareDataForInsert"
 	log := a.log.With(slog.String("op", op))
 
 	var (
-		tempTbl  []string
 		tempMeta []models.TempTable
 		wg       sync.WaitGroup
 		hasError bool
 		mu       sync.Mutex
 	)
 
-	// максимум 3 горутины одновременно
-	const maxConcurrentWorkers = 3
-	sem := make(chan struct{}, maxConcurrentWorkers)
-
-	// размер чанка (универсально для Postgres/ClickHouse)
-	const chunkLimit int64 = 500_000
+	// одновременно загружаемые чанки ограничены настройками view, источника и общим бюджетом
+	var totalRows int64
+	for _, item := range *countData {
+		totalRows += item.Count
+	}
+	concurrency := a.taskConcurrency(*viewSchema, totalRows)
+	log.Info("параметры загрузки", slog.Int("concurrency", concurrency), slog.Int64("rows", totalRows))
+	sem := make(chan struct{}, concurrency)
 
 	for _, tempTableInsert := range *countData {
+		if ctx.Err() != nil {
+			log.Warn("загрузка прервана: задача отменена")
+			mu.Lock()
+			hasError = true
+			mu.Unlock()
+			break
+		}
 		if tempTableInsert.Count <= 0 {
 			continue
 		}
 
 		log.Info("запуск вставки и подготовки данных", slog.String("Таблица", tempTableInsert.TableName))
-		tempTbl = append(tempTbl, tempTableInsert.TempTableName)
 		tempMeta = append(tempMeta, models.TempTable{
 			TempTableName: tempTableInsert.TempTableName,
 			Source:        tempTableInsert.DataBaseName,
@@ -137,7 +144,7 @@ func (a *AnalyticsDataCenterService) prepareAndInsertData(ctx context.Context, c
 			Table:         tempTableInsert.TableName,
 		})
 
-		oltpStorage, err := a.OLTPFactory.GetOLTPStorage(ctx, tempTableInsert.DataBaseName)
+		oltpStorage, err := a.sourceStorage(ctx, tracker, tempTableInsert.DataBaseName)
 		if err != nil {
 			log.Error("Невозможно подключиться к OLTP хранилищу", slog.String("error", err.Error()))
 			return false, err
@@ -146,33 +153,48 @@ func (a *AnalyticsDataCenterService) prepareAndInsertData(ctx context.Context, c
 		tableName := tempTableInsert.TableName
 		sourceName := tempTableInsert.DataBaseName
 		tempTableName := tempTableInsert.TempTableName
-		total := tempTableInsert.Count
 
-		// идём по таблице батчами по 500к (или меньше на хвосте)
-		for start := int64(0); start < total; start += chunkLimit {
-			end := start + chunkLimit
-			if end > total {
-				end = total
+		// делим таблицу на диапазоны ключа; при продолжении задачи остаются только
+		// незагруженные чанки
+		chunkSize := a.chunkSize(*viewSchema, sourceName)
+		chunks, err := a.loadPlan(ctx, tracker, oltpStorage, *viewSchema, tempTableInsert, chunkSize)
+		if err != nil {
+			// уже запущенные чанки других таблиц дожидаемся ниже
+			log.Error("ошибка разбиения таблицы на чанки", slog.String("table", tableName), slog.String("error", err.Error()))
+			mu.Lock()
+			hasError = true
+			mu.Unlock()
+			break
+		}
+		log.Info("таблица разбита на чанки", slog.String("table", tableName), slog.Int("chunks", len(chunks)), slog.Int64("chunkSize", chunkSize))
+
+		for _, chunk := range chunks {
+			// после отмены задачи новые чанки не запускаются
+			select {
+			case sem <- struct{}{}: // занять слот
+			case <-ctx.Done():
 			}
-			limit := end - start
-			if limit <= 0 {
-				continue
+			if ctx.Err() != nil {
+				break
+			}
+			release, err := a.acquireLoadSlot(ctx, sourceName)
+			if err != nil {
+				<-sem
+				break
 			}
-
 			wg.Add(1)
-			sem <- struct{}{} // занять слот
 
-			go func(start, limit int64, tableName, sourceName, tempTableName string, oltpStorage storage.OLTPDB) {
+			go func(chunk models.Chunk, item models.CountInsertData, tableName, sourceName, tempTableName string, oltpStorage storage.OLTPDB) {
 				defer wg.Done()
 				defer func() { <-sem }() // освободить слот
+				defer release()
 
 				log.Info("Горутина запущена",
 					slog.String("Для таблицы", tableName),
-					slog.Int64("offset", start),
-					slog.Int64("limit", limit),
+					slog.Int("chunk", chunk.Index),
 				)
 
-				query, err := sqlgenerator.GenerateSelectInsertDataQuery(*viewSchema, start, start+limit, tableName, log.Logger, a.OLTPDbName)
+				query, err := sqlgenerator.GenerateSelectInsertDataQuery(*viewSchema, chunk, tableName, log.Logger, a.OLTPDbName)
 				if err != nil {
 					log.Error("ошибка генерации SQL", slog.String("error", err.Error()))
 					mu.Lock()
@@ -181,7 +203,7 @@ func (a *AnalyticsDataCenterService) prepareAndInsertData(ctx context.Context, c
 					return
 				}
 
-				insertData, err := oltpStorage.SelectDataToInsert(ctx, query.Query)
+				stream, err := oltpStorage.StreamRows(ctx, query.Query, query.Args...)
 				if err != nil {
 					log.Error("ошибка при SELECT из OLTP", slog.String("error", err.Error()))
 					mu.Lock()
@@ -189,21 +211,12 @@ func (a *AnalyticsDataCenterService) prepareAndInsertData(ctx context.Context, c
 					mu.Unlock()
 					return
 				}
+				defer stream.Close()
+				rows := a.throttleRows(ctx, sourceName, stream)
 
-				log.Info("получены данные", slog.Int("rows", len(insertData)))
-
-				queryInsert, err := sqlgenerator.GenerateInsertDataQuery(*viewSchema, insertData, tempTableName, log.Logger, a.DWHDbName)
-				if err != nil {
-					log.Error("ошибка при генерации INSERT запроса", slog.String("error", err.Error()))
-					mu.Lock()
-					hasError = true
-					mu.Unlock()
-					return
-				}
-
-				err = a.DWHProvider.InsertDataToDWH(ctx, queryInsert.Query)
+				inserted, err := a.DWHProvider.InsertStream(ctx, *viewSchema, tempTableName, rows, a.batchSize())
 				if err != nil {
-					log.Error("ошибка при вставке данных в таблицу", slog.String("error", err.Error()))
+					log.Error("ошибка при вставке данных в таблицу", slog.Int64("rows", inserted), slog.String("error", err.Error()))
 					mu.Lock()
 					hasError = true
 					mu.Unlock()
@@ -212,36 +225,50 @@ func (a *AnalyticsDataCenterService) prepareAndInsertData(ctx context.Context, c
 
 				log.Info("Вставка данных завершена",
 					slog.String("таблица", tempTableName),
-					slog.Int64("offset", start),
-					slog.Int64("limit", limit),
+					slog.Int("chunk", chunk.Index),
+					slog.Int64("rows", inserted),
 				)
-			}(start, limit, tableName, sourceName, tempTableName, oltpStorage)
+				tracker.chunkDone(ctx, item, chunk, inserted)
+			}(chunk, tempTableInsert, tableName, sourceName, tempTableName, oltpStorage)
 		}
 	}
 
 	wg.Wait()
+	// выгрузка закончена: транзакции снимков больше не нужны
+	tracker.releaseSnapshots()
 
-	if hasError {
-		_ = a.DeleteTempTables(ctx, tempTbl)
+	// временные таблицы остаются: загруженные чанки переиспользуются при продолжении задачи
+	if hasError || ctx.Err() != nil {
 		return false, fmt.Errorf("одна или несколько горутин завершились с ошибкой")
 	}
 
+	tracker.enter(ctx, models.StageMerge)
+
 	viewJoin, err := a.prepareViewJoin(ctx, tempMeta, "public")
 	if err != nil {
-		_ = a.DeleteTempTables(ctx, tempTbl)
 		log.Error("Ошибка", slog.String("error", err.Error()))
 		return false, err
 	}
 
-	query, err := sqlgenerator.CreateViewQuery(*viewSchema, *viewJoin, log.Logger, a.DWHDbName)
+	// view собирается в теневую таблицу; рабочая таблица подменяется ею в publishView
+	shadowView := *viewSchema
+	shadowView.Name = tracker.shadowTable(viewSchema.Name)
+	if err := a.DWHProvider.DeleteTempTable(ctx, shadowView.Name); err != nil {
+		log.Error("не удалось удалить теневую таблицу прошлой сборки", slog.String("error", err.Error()))
+		return false, err
+	}
+
+	query, err := sqlgenerator.CreateViewQuery(shadowView, *viewJoin, log.Logger, a.DWHDbName)
 	if err != nil {
-		_ = a.DeleteTempTables(ctx, tempTbl)
 		log.Error("Ошибка", slog.String("error", err.Error()))
 		return false, err
 	}
 	log.Info("Запрос на мердж", slog.String("Запрос", query.Query))
-	a.DWHProvider.MergeTempTables(ctx, query.Query)
-	_ = a.DeleteTempTables(ctx, tempTbl)
+	if err := a.DWHProvider.MergeTempTables(ctx, query.Query); err != nil {
+		a.dropShadowTable(ctx, shadowView.Name)
+		log.Error("не удалось собрать теневую таблицу", slog.String("error", err.Error()))
+		return false, err
+	}
 	return true, nil
 }
 
@@ -310,6 +337,8 @@ func (a *AnalyticsDataCenterService) DeleteTempTables(ctx context.Context, tempT
 	log := a.log.With(
 		slog.String("op", op),
 	)
+	// очистка выполняется и после отмены задачи
+	ctx = context.WithoutCancel(ctx)
 	for _, temp := range tempTbl {
 		err := a.DWHProvider.DeleteTempTable(ctx, temp)
 		if err != nil {
@@ -321,21 +350,38 @@ func (a *AnalyticsDataCenterService) DeleteTempTables(ctx context.Context, tempT
 
 }
 
-func (a *AnalyticsDataCenterService) transferIndixesAndConstraint(ctx context.Context, viewSchema *models.View, dbName string) error {
-	const op = "analytics.transferIndicesAndConstraint"
-	log := a.log.With(
-		slog.String("op", op),
-	)
+// transferIndixesAndConstraint переносит индексы таблиц источников на таблицу targetTable
+// (при пересборке — на теневую таблицу view)
+func (a *AnalyticsDataCenterService) transferIndixesAndConstraint(ctx context.Context, viewSchema *models.View, targetTable string, dbName string) error {
 	if dbName == DbClickhouse {
 		return nil
 	}
+	queries, err := a.indexQueries(ctx, viewSchema, targetTable)
+	if err != nil {
+		return err
+	}
+	for _, query := range queries {
+		if err := a.DWHProvider.CreateIndex(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexQueries читает индексы таблиц источников и формирует запросы на их создание
+// на таблице targetTable. В DWH ничего не выполняется.
+func (a *AnalyticsDataCenterService) indexQueries(ctx context.Context, viewSchema *models.View, targetTable string) ([]string, error) {
+	const op = "analytics.indexQueries"
+	log := a.log.With(
+		slog.String("op", op),
+	)
 	var OLTPSourceName []string
 	var indexTransfers IndexTransfers
 	for _, src := range viewSchema.Sources {
 		storage, err := a.OLTPFactory.GetOLTPStorage(ctx, src.Name)
 		if err != nil {
 			log.Error("Невозможно получить хранилище OLTP", slog.String("error", err.Error()))
-			return err
+			return nil, err
 		}
 		OLTPSourceName = append(OLTPSourceName, src.Name)
 		var indexTransfer *IndexTransfer
@@ -361,12 +407,13 @@ func (a *AnalyticsDataCenterService) transferIndixesAndConstraint(ctx context.Co
 		}
 	}
 
+	var queries []string
 	for _, transferTable := range indexTransfers.IndexTransfers {
 		storage := transferTable.storage
 		indexes, err := storage.GetIndexes(ctx, transferTable.IndexTransfer.TableName, transferTable.IndexTransfer.SchemaName)
 		if err != nil {
 			log.Error("Невозможно получить индексы таблиц", slog.String("error", err.Error()))
-			return err
+			return nil, err
 		}
 		// constraints, err := storage.GetConstraint(ctx, transferTable.IndexTransfer.TableName, transferTable.IndexTransfer.SchemaName)
 		// if err != nil {
@@ -383,16 +430,13 @@ func (a *AnalyticsDataCenterService) transferIndixesAndConstraint(ctx context.Co
 		// }
 		for _, index := range indexes.Indexes {
 			// TO DO инжектировать в сервис DWH схему, если она нужна через config
-			query, err := sqlgenerator.TransformIndexDefToSQLExpression(index, transferTable.IndexTransfer.SchemaName, strings.ToLower(transferTable.IndexTransfer.TableName), "public", viewSchema.Name, a.log.Logger)
+			query, err := sqlgenerator.TransformIndexDefToSQLExpression(index, transferTable.IndexTransfer.SchemaName, strings.ToLower(transferTable.IndexTransfer.TableName), "public", targetTable, a.log.Logger)
 			if err != nil {
 				log.Error("Невозможно сформировать запрос на создание индексов", slog.String("error", err.Error()))
-				return err
-			}
-			err = a.DWHProvider.CreateIndex(ctx, query)
-			if err != nil {
-				return err
+				return nil, err
 			}
+			queries = append(queries, query)
 		}
 	}
-	return nil
+	return queries, nil
 }
@@ -0,0 +1,90 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var (
+	// ErrTaskFinished — задача уже завершилась и отменить её нельзя
+	ErrTaskFinished = errors.New("задача уже завершена")
+)
+
+// CancelTask останавливает ETL-задачу. Выполняющаяся задача получает отмену контекста:
+// прерываются выборки из OLTP и вставки в DWH, временные таблицы удаляются, а статус
+// Cancelled воркер записывает после очистки. Задание, ещё ждущее в очереди, удаляется
+// из неё, и задача отменяется сразу. Если задание выполняет другой экземпляр сервиса,
+// его воркер обнаружит удаление задания при продлении аренды.
+func (a *AnalyticsDataCenterService) CancelTask(ctx context.Context, taskID string) error {
+	const op = "analytics.CancelTask"
+	log := a.log.With(slog.String("op", op), slog.String("task", taskID))
+
+	task, err := a.TaskService.GetTask(ctx, taskID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if task.Status != Progress {
+		return fmt.Errorf("%s: %w: %s", op, ErrTaskFinished, task.Status)
+	}
+
+	if a.cancelRun(taskID) {
+		log.Info("отмена выполняющейся задачи")
+		return nil
+	}
+
+	job, err := a.JobStorage.DeleteETLJob(ctx, taskID)
+	switch {
+	case errors.Is(err, storage.ErrJobNotFound):
+		log.Warn("у задачи нет задания в очереди, задача отменяется")
+	case err != nil:
+		return fmt.Errorf("%s: %w", op, err)
+	case job.Status == models.ETLJobRunning:
+		log.Info("задание выполняется другим воркером, отмена передана через очередь")
+		return nil
+	default:
+		log.Info("отмена задачи в очереди")
+	}
+
+	if err := a.TaskService.ChangeStatusTask(ctx, taskID, Cancelled, CancelledTask); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.newTaskTracker(taskID).notify(Cancelled, CancelledTask)
+	return nil
+}
+
+// startRun регистрирует выполнение задачи и возвращает её отменяемый контекст
+func (a *AnalyticsDataCenterService) startRun(taskID string) context.Context {
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.running[taskID] = cancel
+	return ctx
+}
+
+// cancelRun отменяет контекст задачи, если она выполняется в этом экземпляре
+func (a *AnalyticsDataCenterService) cancelRun(taskID string) bool {
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+
+	cancel, ok := a.running[taskID]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// finishRun снимает задачу с учёта и освобождает её контекст
+func (a *AnalyticsDataCenterService) finishRun(taskID string) {
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+
+	if cancel, ok := a.running[taskID]; ok {
+		cancel()
+		delete(a.running, taskID)
+	}
+}
@@ -0,0 +1,99 @@
+package serviceanalytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newCancelTestService(tasks *mockTaskService, jobs *mockJobStorage) *AnalyticsDataCenterService {
+	return &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		TaskService: tasks,
+		JobStorage:  jobs,
+		running:     make(map[string]context.CancelFunc),
+	}
+}
+
+func TestCancelTask_Running(t *testing.T) {
+	tasks := &mockTaskService{task: models.Task{ID: "task-1", Status: Progress}}
+	svc := newCancelTestService(tasks, &mockJobStorage{})
+	ctx := svc.startRun("task-1")
+
+	require.NoError(t, svc.CancelTask(context.Background(), "task-1"))
+
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
+	// статус Cancelled запишет воркер после очистки
+	require.Empty(t, tasks.statuses)
+	svc.finishRun("task-1")
+	require.Empty(t, svc.running)
+}
+
+func TestCancelTask_Queued(t *testing.T) {
+	tasks := &mockTaskService{task: models.Task{ID: "task-1", Status: Progress}}
+	jobs := &mockJobStorage{jobs: []models.ETLJob{{TaskID: "task-1", Status: models.ETLJobQueued}}}
+	svc := newCancelTestService(tasks, jobs)
+
+	require.NoError(t, svc.CancelTask(context.Background(), "task-1"))
+
+	require.Equal(t, []string{Cancelled}, tasks.statuses)
+	require.Empty(t, jobs.jobs)
+}
+
+func TestCancelTask_RunningElsewhere(t *testing.T) {
+	tasks := &mockTaskService{task: models.Task{ID: "task-1", Status: Progress}}
+	jobs := &mockJobStorage{jobs: []models.ETLJob{{TaskID: "task-1", Status: models.ETLJobRunning}}}
+	svc := newCancelTestService(tasks, jobs)
+
+	require.NoError(t, svc.CancelTask(context.Background(), "task-1"))
+
+	// задание удалено из очереди, статус запишет воркер, потерявший аренду
+	require.Empty(t, jobs.jobs)
+	require.Empty(t, tasks.statuses)
+}
+
+func TestCancelTask_Errors(t *testing.T) {
+	finished := newCancelTestService(&mockTaskService{task: models.Task{ID: "task-1", Status: Completed}}, &mockJobStorage{})
+	require.ErrorIs(t, finished.CancelTask(context.Background(), "task-1"), ErrTaskFinished)
+
+	missing := newCancelTestService(&mockTaskService{taskErr: storage.ErrTaskNotFound}, &mockJobStorage{})
+	require.ErrorIs(t, missing.CancelTask(context.Background(), "task-1"), storage.ErrTaskNotFound)
+}
+
+func TestProcessETLJob_CancelledCleansUp(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	tasks := &mockTaskService{}
+	svc := newETLTestService(dwh, tasks)
+	ctx, cancel := context.WithCancel(context.Background())
+	// задачу отменяют, пока идёт выгрузка
+	svc.OLTPFactory.(*mockFactory).store["db1"].(*mockOLTP).countHook = func(context.Context) { cancel() }
+
+	svc.processETLJob(ctx, models.ETLJob{TaskID: "task-1", ViewID: 1}, getTestLogger())
+
+	require.Equal(t, []string{Cancelled}, tasks.statuses)
+	require.Contains(t, dwh.deleteCalls, "temp_task1_db1_public_users")
+	require.Zero(t, dwh.mergeCalls)
+	stages := tasks.finalStages()
+	require.Equal(t, models.StageExtract, stages[len(stages)-1].Name)
+	require.Equal(t, models.StageCancelled, stages[len(stages)-1].Status)
+}
+
+func TestProcessETLJob_ErrorStatus(t *testing.T) {
+	dwh := &mockDWH{
+		columns:  map[string][]string{"temp_task1_db1_public_users": {"id", "name"}},
+		mergeErr: errors.New("disk full"),
+	}
+	tasks := &mockTaskService{}
+	svc := newETLTestService(dwh, tasks)
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1}, getTestLogger())
+
+	require.Equal(t, []string{Error}, tasks.statuses)
+	// временные таблицы упавшей задачи остаются для её продолжения
+	require.NotContains(t, dwh.deleteCalls, "temp_task1_db1_public_users")
+}
@@ -5,21 +5,143 @@ import (
 	loggerpkg "analyticDataCenter/analytics-data-center/internal/logger"
 	"context"
 	"log/slog"
+	"sync"
+	"time"
 )
 
-func (a *AnalyticsDataCenterService) eventWorker() {
-	for event := range a.eventQueue {
-		log := a.log.With(
-			slog.String("component", "EventWorker"),
-		)
-		log.InfoMsg(loggerpkg.MsgEventWorkerReceived)
-		ctx := context.Background()
-		err := a.handlerCDCFunc(ctx, event)
-		if err != nil {
-			log.ErrorMsg(loggerpkg.MsgEventWorkerError, slog.String("error", err.Error()))
+// eventWorker применяет события своей очереди. Строки view копятся в пачке воркера и
+// записываются, когда пачка наберёт Size строк или первая строка прождёт Window.
+func (a *AnalyticsDataCenterService) eventWorker(queue <-chan models.CDCEvent) {
+	opts := a.cdcBatchOptions()
+	batch := &cdcBatch{}
+	ctx := context.WithValue(context.Background(), cdcBatchKey{}, batch)
+
+	timer := time.NewTimer(opts.Window)
+	timer.Stop()
+	var window <-chan time.Time
+	for {
+		select {
+		case event, ok := <-queue:
+			if !ok {
+				a.flushCDCBatch(ctx)
+				return
+			}
+			a.applyCDCEvent(ctx, event)
+			switch {
+			case batch.rows >= opts.Size:
+				a.flushCDCBatch(ctx)
+			case window == nil && batch.rows > 0:
+				timer.Reset(opts.Window)
+				window = timer.C
+			}
+		case <-window:
+			a.flushCDCBatch(ctx)
+		}
+		if batch.rows == 0 && window != nil {
+			timer.Stop()
+			window = nil
 		}
 	}
 }
+
+// applyCDCEvent применяет событие из очереди и подтверждает его, когда оно записано
+// во все затронутые view или сохранено в dead letter
+func (a *AnalyticsDataCenterService) applyCDCEvent(ctx context.Context, event models.CDCEvent) {
+	log := a.log.With(
+		slog.String("component", "EventWorker"),
+	)
+	log.InfoMsg(loggerpkg.MsgEventWorkerReceived)
+	ack := newEventAck(event.Ack)
+	ctx = context.WithValue(ctx, eventAckKey{}, ack)
+	ctx = context.WithValue(ctx, cdcEventKey{}, &event)
+	held, err := a.holdBehindDeadLetter(ctx, event)
+	if err != nil {
+		// offset события не коммитится: после перезапуска оно будет прочитано снова
+		ack.fail()
+		log.Error("не удалось проверить dead letter строки", slog.String("error", err.Error()))
+		return
+	}
+	if held {
+		ack.release()
+		return
+	}
+	err = a.handlerCDCFunc(ctx, event)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgEventWorkerError, slog.String("error", err.Error()))
+		if batch := cdcBatchFrom(ctx); batch != nil {
+			// событие уходит в dead letter вместе с пачкой: после более ранних событий и один раз
+			batch.fail(ctx, err)
+			a.flushCDCBatch(ctx)
+		} else if dlErr := a.deadLetter(ctx, event, err); dlErr != nil {
+			// offset события не коммитится: после перезапуска оно будет прочитано снова
+			ack.fail()
+			log.Error("не удалось сохранить событие в dead letter", slog.String("error", dlErr.Error()))
+			return
+		}
+	}
+	ack.release()
+}
+
+// eventAckKey — ключ контекста с подтверждением обрабатываемого CDC-события
+type eventAckKey struct{}
+
+// cdcEventKey — ключ контекста с обрабатываемым CDC-событием в том виде, в каком оно
+// пришло из очереди
+type cdcEventKey struct{}
+
+// eventAck — подтверждение CDC-события. Событие подтверждается, когда записано во
+// все затронутые view; отложенное до публикации view держит подтверждение, пока не
+// будет применено к новой таблице.
+type eventAck struct {
+	mu      sync.Mutex
+	pending int
+	failed  bool
+	done    func()
+}
+
+func newEventAck(done func()) *eventAck {
+	return &eventAck{pending: 1, done: done}
+}
+
+// holdEventAck откладывает подтверждение события из контекста до вызова release;
+// без подтверждения в контексте release ничего не делает
+func holdEventAck(ctx context.Context) (release func()) {
+	ack := heldEventAck(ctx)
+	if ack == nil {
+		return func() {}
+	}
+	return ack.release
+}
+
+// heldEventAck откладывает подтверждение события из контекста и возвращает его: запись
+// завершается вызовом release или fail и release; nil — в контексте нет подтверждения
+func heldEventAck(ctx context.Context) *eventAck {
+	ack, ok := ctx.Value(eventAckKey{}).(*eventAck)
+	if !ok {
+		return nil
+	}
+	ack.mu.Lock()
+	ack.pending++
+	ack.mu.Unlock()
+	return ack
+}
+
+func (e *eventAck) release() {
+	e.mu.Lock()
+	e.pending--
+	done := e.pending == 0 && !e.failed && e.done != nil
+	e.mu.Unlock()
+	if done {
+		e.done()
+	}
+}
+
+// fail отменяет подтверждение: событие записано не во все view
+func (e *eventAck) fail() {
+	e.mu.Lock()
+	e.failed = true
+	e.mu.Unlock()
+}
 func (a *AnalyticsDataCenterService) EventPreprocessing(evt models.CDCEvent) {
 	const op = "EventPreprocessing"
 	log := a.log.With(
@@ -28,7 +150,8 @@ func (a *AnalyticsDataCenterService) EventPreprocessing(evt models.CDCEvent) {
 	)
 
 	log.InfoMsg(loggerpkg.MsgForwardCDCEvent)
-	a.eventQueue <- evt
+	// очередь воркера не переполняется: слушатель приостанавливает чтение Kafka по EventQueueLoad
+	a.eventQueues[eventWorkerIndex(evt, len(a.eventQueues))] <- evt
 }
 
 func (a *AnalyticsDataCenterService) handlerCDCFunc(ctx context.Context, evt models.CDCEvent) error {
@@ -89,6 +212,10 @@ func (a *AnalyticsDataCenterService) eventDispathFunction(ctx context.Context, t
 		}
 		return "u", nil
 	case "d":
+		err := a.deleteRowAfterListenEventInDWH(ctx, eventData)
+		if err != nil {
+			return "", err
+		}
 		return "d", nil
 	case "r":
 		return "r", nil
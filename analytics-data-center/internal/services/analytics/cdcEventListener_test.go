@@ -0,0 +1,86 @@
+package serviceanalytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ackedEvent — событие users, которое считает свои подтверждения
+func ackedEvent(id int, lsn int64, txID int64, acks *int) models.CDCEvent {
+	evt := usersEvent(id, lsn, txID)
+	evt.Ack = func() { *acks++ }
+	return evt
+}
+
+func TestApplyCDCEvent_AcksOnlyWrittenEvents(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}}
+	svc, _ := newHandoffTestService(dwh, &mockTaskService{})
+
+	acks := 0
+	svc.applyCDCEvent(context.Background(), ackedEvent(1, 90, 12, &acks))
+	require.Equal(t, 1, acks)
+
+	// событие, не записанное ни во view, ни в dead letter, не подтверждается
+	dwh.upsertErr = errors.New("dwh down")
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+	schemas.deadLetterErr = errors.New("sys db down")
+	svc.applyCDCEvent(context.Background(), ackedEvent(2, 95, 13, &acks))
+	require.Equal(t, 1, acks)
+
+	// сохранённое в dead letter событие подтверждается
+	schemas.deadLetterErr = nil
+	svc.applyCDCEvent(context.Background(), ackedEvent(3, 96, 14, &acks))
+	require.Equal(t, 2, acks)
+	require.Len(t, schemas.deadLetters, 1)
+
+	// событие без подтверждения тоже применяется
+	dwh.upsertErr = nil
+	svc.applyCDCEvent(context.Background(), usersEvent(4, 100, 15))
+	require.Equal(t, []interface{}{1, 2, 3, 4}, upsertedIDs(dwh))
+	require.Equal(t, 2, acks)
+}
+
+func TestApplyCDCEvent_DeferredEventAckedAfterReplay(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	tasks := &mockTaskService{}
+	svc, oltp := newHandoffTestService(dwh, tasks)
+
+	inSnapshot, afterSnapshot := 0, 0
+	oltp.countHook = func(ctx context.Context) {
+		svc.applyCDCEvent(context.Background(), ackedEvent(1, 90, 12, &inSnapshot))
+		svc.applyCDCEvent(context.Background(), ackedEvent(2, 120, 25, &afterSnapshot))
+		// пока view загружается, отложенные события не подтверждаются
+		require.Zero(t, inSnapshot)
+		require.Zero(t, afterSnapshot)
+	}
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	require.Equal(t, []interface{}{2}, upsertedIDs(dwh))
+	// событие из снимка подтверждается вместе с применёнными после публикации
+	require.Equal(t, 1, inSnapshot)
+	require.Equal(t, 1, afterSnapshot)
+}
+
+func TestApplyCDCEvent_FailedReplayKeepsOffset(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	tasks := &mockTaskService{}
+	svc, oltp := newHandoffTestService(dwh, tasks)
+
+	acks := 0
+	oltp.countHook = func(ctx context.Context) {
+		svc.applyCDCEvent(context.Background(), ackedEvent(1, 120, 25, &acks))
+		dwh.upsertErr = errors.New("dwh down")
+	}
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	require.Zero(t, acks)
+}
@@ -0,0 +1,333 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Значения пакетной записи CDC-событий по умолчанию
+const (
+	defaultCDCBatchSize   = 500
+	defaultCDCBatchWindow = 200 * time.Millisecond
+)
+
+// CDCBatchOptions — параметры пакетной записи CDC-событий во view. Нулевые поля
+// заменяются значениями по умолчанию.
+type CDCBatchOptions struct {
+	// Size — сколько строк воркер копит, прежде чем записать их во view; изменение
+	// строки view с джоинами считается одной строкой
+	Size int
+	// Window — сколько строка может ждать записи, если пачка не набралась
+	Window time.Duration
+}
+
+// SetCDCBatchOptions задаёт параметры пакетной записи; вызывается до StartEventWorkers.
+func (a *AnalyticsDataCenterService) SetCDCBatchOptions(opts CDCBatchOptions) {
+	a.cdcBatchOpts = opts
+}
+
+func (a *AnalyticsDataCenterService) cdcBatchOptions() CDCBatchOptions {
+	opts := a.cdcBatchOpts
+	if opts.Size <= 0 {
+		opts.Size = defaultCDCBatchSize
+	}
+	if opts.Window <= 0 {
+		opts.Window = defaultCDCBatchWindow
+	}
+	return opts
+}
+
+// cdcBatchKey — ключ контекста с пачкой строк воркера
+type cdcBatchKey struct{}
+
+// cdcBatch — строки view, накопленные CDC-воркером для пакетной записи. Пачка
+// принадлежит одному воркеру, а события одной строки всегда у одного воркера, поэтому
+// порядок изменений строки сохраняется. Изменения строк view с джоинами копятся
+// отдельно и пересобираются при записи пачки.
+type cdcBatch struct {
+	groups map[string]*cdcBatchGroup
+	order  []string
+	joins  map[string]*cdcJoinGroup
+	// joinOrder — view с джоинами в порядке первого изменения
+	joinOrder []string
+	rows      int
+	// sources — события, строки которых лежат в пачке, в порядке поступления
+	sources []*cdcBatchSource
+	byEvent map[*models.CDCEvent]*cdcBatchSource
+}
+
+// cdcBatchSource — событие из очереди, строки которого лежат в пачке. Событие может
+// попасть в несколько групп, но в dead letter уходит один раз.
+type cdcBatchSource struct {
+	event models.CDCEvent
+	// failed — ошибка первой группы события, которую не удалось записать
+	failed error
+	// lost — событие не записано и не сохранено в dead letter
+	lost bool
+	// ack — подтверждение события, упавшего до записи пачки; держится до её записи
+	ack *eventAck
+}
+
+// cdcBatchGroup — строки одной view с одинаковыми ключами обновления. Для каждого
+// ключа остаётся одна строка: значения последнего события перекрывают предыдущие.
+type cdcBatchGroup struct {
+	view     eventView
+	conflict []string
+	rows     []map[string]interface{}
+	keys     map[string]int
+	// sources и acks — события строк группы и их подтверждения, которые держатся до записи
+	sources []*cdcBatchSource
+	acks    []*eventAck
+}
+
+// cdcJoinGroup — изменения строк одной view с джоинами, ждущие пересборки
+type cdcJoinGroup struct {
+	view    eventView
+	events  []models.CDCEventData
+	sources []*cdcBatchSource
+	acks    []*eventAck
+}
+
+func cdcBatchFrom(ctx context.Context) *cdcBatch {
+	batch, _ := ctx.Value(cdcBatchKey{}).(*cdcBatch)
+	return batch
+}
+
+// add кладёт строку view в пачку; подтверждение события держится до записи пачки
+func (b *cdcBatch) add(ctx context.Context, ev eventView, row map[string]interface{}, conflict []string, evtData models.CDCEventData) {
+	conflict = append([]string(nil), conflict...)
+	sort.Strings(conflict)
+	groupKey := ev.view.Name + "\x00" + strings.Join(conflict, "\x00")
+
+	if b.groups == nil {
+		b.groups = make(map[string]*cdcBatchGroup)
+	}
+	group, ok := b.groups[groupKey]
+	if !ok {
+		group = &cdcBatchGroup{view: ev, conflict: conflict, keys: make(map[string]int)}
+		b.groups[groupKey] = group
+		b.order = append(b.order, groupKey)
+	}
+	group.sources = append(group.sources, b.source(ctx, evtData))
+	group.acks = append(group.acks, heldEventAck(ctx))
+
+	if len(conflict) > 0 {
+		key := rowConflictKey(row, conflict)
+		if idx, ok := group.keys[key]; ok {
+			for k, v := range row {
+				group.rows[idx][k] = v
+			}
+			return
+		}
+		group.keys[key] = len(group.rows)
+	}
+	group.rows = append(group.rows, row)
+	b.rows++
+}
+
+// addJoin кладёт в пачку изменение строки view с джоинами; подтверждение события
+// держится до пересборки
+func (b *cdcBatch) addJoin(ctx context.Context, ev eventView, evtData models.CDCEventData) {
+	if b.joins == nil {
+		b.joins = make(map[string]*cdcJoinGroup)
+	}
+	group, ok := b.joins[ev.view.Name]
+	if !ok {
+		group = &cdcJoinGroup{view: ev}
+		b.joins[ev.view.Name] = group
+		b.joinOrder = append(b.joinOrder, ev.view.Name)
+	}
+	group.events = append(group.events, evtData)
+	group.sources = append(group.sources, b.source(ctx, evtData))
+	group.acks = append(group.acks, heldEventAck(ctx))
+	b.rows++
+}
+
+// source возвращает событие из очереди, к которому относится строка. Без события в
+// контексте строка считается отдельным событием.
+func (b *cdcBatch) source(ctx context.Context, evtData models.CDCEventData) *cdcBatchSource {
+	evt, ok := ctx.Value(cdcEventKey{}).(*models.CDCEvent)
+	if !ok {
+		source := &cdcBatchSource{event: models.CDCEvent{Data: evtData}}
+		b.sources = append(b.sources, source)
+		return source
+	}
+	if source, ok := b.byEvent[evt]; ok {
+		return source
+	}
+	if b.byEvent == nil {
+		b.byEvent = make(map[*models.CDCEvent]*cdcBatchSource)
+	}
+	source := &cdcBatchSource{event: *evt}
+	b.byEvent[evt] = source
+	b.sources = append(b.sources, source)
+	return source
+}
+
+// fail отмечает событие из контекста упавшим: оно уйдёт в dead letter при записи пачки
+func (b *cdcBatch) fail(ctx context.Context, cause error) {
+	evt, _ := ctx.Value(cdcEventKey{}).(*models.CDCEvent)
+	var evtData models.CDCEventData
+	if evt != nil {
+		evtData = evt.Data
+	}
+	source := b.source(ctx, evtData)
+	if source.failed == nil {
+		source.failed = cause
+	}
+	if source.ack == nil {
+		source.ack = heldEventAck(ctx)
+	}
+}
+
+func rowConflictKey(row map[string]interface{}, conflict []string) string {
+	parts := make([]string, len(conflict))
+	for i, col := range conflict {
+		parts[i] = fmt.Sprintf("%v", row[col])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// upsertViewRow записывает строку во view: в пачку воркера, если событие пришло из
+// очереди, иначе сразу
+func (a *AnalyticsDataCenterService) upsertViewRow(ctx context.Context, ev eventView, row map[string]interface{}, conflict []string, evtData models.CDCEventData) error {
+	if batch := cdcBatchFrom(ctx); batch != nil {
+		batch.add(ctx, ev, row, conflict, evtData)
+		return nil
+	}
+	return a.DWHProvider.InsertOrUpdateTransactional(ctx, ev.view.Name, row, conflict)
+}
+
+// refreshJoinedViewRow пересобирает строки view с джоинами, затронутые изменением: при
+// записи пачки воркера, если событие пришло из очереди, иначе сразу
+func (a *AnalyticsDataCenterService) refreshJoinedViewRow(ctx context.Context, ev eventView, evtData models.CDCEventData) error {
+	if batch := cdcBatchFrom(ctx); batch != nil {
+		batch.addJoin(ctx, ev, evtData)
+		return nil
+	}
+	return a.refreshJoinedView(ctx, ev, evtData)
+}
+
+// flushCDCBatch записывает пачку воркера из контекста и пересобирает накопленные в ней
+// строки view с джоинами — каждую один раз. Записанные события подтверждаются; события группы, которую не удалось записать, уходят в dead letter —
+// каждое один раз и целиком, как пришло из очереди. Более поздние события тех же строк
+// встают за ними в dead letter, чтобы повтор не затёр их изменения.
+// Вызывается и перед удалениями строк, чтобы они не обогнали накопленные вставки.
+func (a *AnalyticsDataCenterService) flushCDCBatch(ctx context.Context) {
+	const op = "analytics.flushCDCBatch"
+	batch := cdcBatchFrom(ctx)
+	if batch == nil || (batch.rows == 0 && len(batch.sources) == 0) {
+		return
+	}
+	log := a.log.With(slog.String("op", op))
+
+	for _, key := range batch.order {
+		group := batch.groups[key]
+		viewName := group.view.view.Name
+		err := a.DWHProvider.UpsertBatch(ctx, viewName, group.rows, group.conflict)
+		switch {
+		case err == nil:
+			log.Info("пачка записана во view", slog.String("view", viewName), slog.Int("rows", len(group.rows)))
+		case isRelationDoesNotExist(err):
+			log.Warn("таблица отсутствует в DWH, пропускаю вставку", slog.String("view", viewName))
+			err = nil
+		default:
+			log.Error("ошибка пакетной вставки/обновления", slog.String("view", viewName), slog.String("error", err.Error()))
+		}
+
+		if err != nil {
+			failBatchSources(group.sources, group.view, err)
+		}
+	}
+
+	for _, name := range batch.joinOrder {
+		group := batch.joins[name]
+		if err := a.refreshJoinedView(ctx, group.view, group.events...); err != nil {
+			log.Error("ошибка пересборки строк view", slog.String("view", name), slog.String("error", err.Error()))
+			failBatchSources(group.sources, group.view, err)
+		}
+	}
+
+	failedRows := make(map[string]struct{})
+	for _, source := range batch.sources {
+		row := deadLetterRowKey(source.event)
+		var dlErr error
+		switch {
+		case source.failed != nil:
+			dlErr = a.deadLetter(ctx, source.event, source.failed)
+			if row != "" {
+				failedRows[row] = struct{}{}
+			}
+		case row != "" && hasKey(failedRows, row):
+			dlErr = a.holdDeadLetter(ctx, source.event)
+		}
+		if dlErr != nil {
+			log.Error("не удалось сохранить событие в dead letter", slog.String("error", dlErr.Error()))
+			source.lost = true
+		}
+	}
+
+	for _, key := range batch.order {
+		group := batch.groups[key]
+		releaseBatchAcks(group.acks, group.sources)
+	}
+	for _, name := range batch.joinOrder {
+		group := batch.joins[name]
+		releaseBatchAcks(group.acks, group.sources)
+	}
+	for _, source := range batch.sources {
+		if source.ack == nil {
+			continue
+		}
+		if source.lost {
+			source.ack.fail()
+		}
+		source.ack.release()
+	}
+
+	batch.groups = nil
+	batch.order = nil
+	batch.joins = nil
+	batch.joinOrder = nil
+	batch.rows = 0
+	batch.sources = nil
+	batch.byEvent = nil
+}
+
+// failBatchSources отмечает события упавшими на view, если они не упали раньше
+func failBatchSources(sources []*cdcBatchSource, ev eventView, err error) {
+	for _, source := range sources {
+		if source.failed == nil {
+			source.failed = viewEventFailed(ev, err)
+		}
+	}
+}
+
+// releaseBatchAcks отпускает подтверждения строк пачки; событие, не записанное и не
+// сохранённое в dead letter, не подтверждается
+func releaseBatchAcks(acks []*eventAck, sources []*cdcBatchSource) {
+	for i, ack := range acks {
+		if ack == nil {
+			continue
+		}
+		if sources[i].lost {
+			ack.fail()
+		}
+		ack.release()
+	}
+}
+
+// deadLetterRowKey — строка источника события для очереди в dead letter; пустая, если
+// у события нет ключа
+func deadLetterRowKey(evt models.CDCEvent) string {
+	if len(evt.Key) == 0 {
+		return ""
+	}
+	src := evt.Data.Source
+	return src.DB + "\x00" + src.Schema + "\x00" + src.Table + "\x00" + string(evt.Key)
+}
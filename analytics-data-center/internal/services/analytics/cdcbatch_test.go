@@ -0,0 +1,247 @@
+package serviceanalytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newBatchTestService — сервис, view которого обновляется по ключу id таблицы users,
+// и контекст воркера с пустой пачкой
+func newBatchTestService(dwh *mockDWH) (*AnalyticsDataCenterService, context.Context, *cdcBatch) {
+	svc, _ := newHandoffTestService(dwh, &mockTaskService{})
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+	view := schemas.views[1]
+	view.Sources[0].Schemas[0].Tables[0].Columns[0].IsUpdateKey = true
+	schemas.views[1] = view
+
+	batch := &cdcBatch{}
+	return svc, context.WithValue(context.Background(), cdcBatchKey{}, batch), batch
+}
+
+func namedUsersEvent(id int, name string, acks *int) models.CDCEvent {
+	evt := ackedEvent(id, 90, 12, acks)
+	evt.Data.After = map[string]interface{}{"id": id, "name": name}
+	return evt
+}
+
+func TestCDCBatch_LatestEventPerKeyWins(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}}
+	svc, ctx, batch := newBatchTestService(dwh)
+
+	acks := 0
+	svc.applyCDCEvent(ctx, namedUsersEvent(1, "first", &acks))
+	svc.applyCDCEvent(ctx, namedUsersEvent(2, "other", &acks))
+	svc.applyCDCEvent(ctx, namedUsersEvent(1, "second", &acks))
+
+	// до записи пачки события не подтверждаются
+	require.Empty(t, dwh.upsertCalls)
+	require.Zero(t, acks)
+	require.Equal(t, 2, batch.rows)
+
+	svc.flushCDCBatch(ctx)
+
+	require.Equal(t, []int{2}, dwh.upsertBatches)
+	require.Equal(t, map[string]interface{}{"id": 1, "name": "second"}, dwh.upsertCalls[0].row)
+	require.Equal(t, map[string]interface{}{"id": 2, "name": "other"}, dwh.upsertCalls[1].row)
+	require.Equal(t, []string{"id"}, dwh.upsertCalls[0].keys)
+	require.Equal(t, 3, acks)
+	require.Zero(t, batch.rows)
+}
+
+func TestCDCBatch_DeleteFlushesBatchFirst(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}}
+	svc, ctx, batch := newBatchTestService(dwh)
+
+	svc.applyCDCEvent(ctx, usersEvent(1, 90, 12))
+	svc.applyCDCEvent(ctx, deleteUsersEvent(1))
+
+	// вставка записана до удаления той же строки
+	require.Equal(t, []int{1}, dwh.upsertBatches)
+	require.Len(t, dwh.rowDeleteCalls, 1)
+	require.Zero(t, batch.rows)
+}
+
+func TestCDCBatch_FailedFlushDeadLettersEvents(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}, upsertErr: errors.New("dwh down")}
+	svc, ctx, _ := newBatchTestService(dwh)
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+
+	acks := 0
+	svc.applyCDCEvent(ctx, namedUsersEvent(1, "a", &acks))
+	svc.applyCDCEvent(ctx, namedUsersEvent(2, "b", &acks))
+	svc.flushCDCBatch(ctx)
+
+	// события сохранены в dead letter с view, в которую не записались
+	require.Len(t, schemas.deadLetters, 2)
+	require.Equal(t, int64(1), *schemas.deadLetters[0].ViewID)
+	require.Equal(t, 2, acks)
+
+	// событие, не сохранённое и в dead letter, не подтверждается
+	schemas.deadLetterErr = errors.New("sys db down")
+	svc.applyCDCEvent(ctx, namedUsersEvent(3, "c", &acks))
+	svc.flushCDCBatch(ctx)
+	require.Equal(t, 2, acks)
+}
+
+// batchEventContext — контекст воркера с событием из очереди и его подтверждением
+func batchEventContext(ctx context.Context, evt *models.CDCEvent) (context.Context, *eventAck) {
+	ack := newEventAck(evt.Ack)
+	ctx = context.WithValue(ctx, eventAckKey{}, ack)
+	return context.WithValue(ctx, cdcEventKey{}, evt), ack
+}
+
+func TestCDCBatch_EventInSeveralGroupsDeadLetteredOnce(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}, upsertErr: errors.New("dwh down")}
+	svc, ctx, batch := newBatchTestService(dwh)
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+
+	acks := 0
+	evt := namedUsersEvent(1, "a", &acks)
+	evt.Key = []byte(`{"id":1}`)
+	evt.Payload = []byte(`{"op":"u","after":{"id":1,"name":"a"}}`)
+	evtCtx, ack := batchEventContext(ctx, &evt)
+
+	// событие пишет строку в две view
+	row := map[string]interface{}{"id": 1, "name": "a"}
+	batch.add(evtCtx, eventView{id: 1, view: models.View{Name: "v"}}, row, []string{"id"}, evt.Data)
+	batch.add(evtCtx, eventView{id: 2, view: models.View{Name: "v2"}}, row, []string{"id"}, evt.Data)
+	ack.release()
+	svc.flushCDCBatch(ctx)
+
+	// в dead letter одно событие в том виде, в каком оно пришло из очереди
+	require.Len(t, schemas.deadLetters, 1)
+	require.JSONEq(t, string(evt.Payload), string(schemas.deadLetters[0].Payload))
+	require.Equal(t, `{"id":1}`, schemas.deadLetters[0].RowKey)
+	require.Equal(t, 1, acks)
+}
+
+func TestCDCBatch_FailedEventGoesAfterEarlierRowsOfBatch(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}}
+	svc, ctx, batch := newBatchTestService(dwh)
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+	view := eventView{id: 1, view: models.View{Name: "v"}}
+
+	acks := 0
+	rowEvent := func(name string) *models.CDCEvent {
+		evt := namedUsersEvent(1, name, &acks)
+		evt.Key = []byte(`{"id":1}`)
+		return &evt
+	}
+	first, second, third := rowEvent("first"), rowEvent("second"), rowEvent("third")
+
+	firstCtx, firstAck := batchEventContext(ctx, first)
+	batch.add(firstCtx, view, map[string]interface{}{"id": 1, "name": "first"}, []string{"id"}, first.Data)
+	firstAck.release()
+
+	// событие упало до записи пачки, например при пересборке view с джоинами
+	secondCtx, secondAck := batchEventContext(ctx, second)
+	batch.fail(secondCtx, errors.New("dwh down"))
+	secondAck.release()
+
+	thirdCtx, thirdAck := batchEventContext(ctx, third)
+	batch.add(thirdCtx, view, map[string]interface{}{"id": 1, "name": "third"}, []string{"id"}, third.Data)
+	thirdAck.release()
+	require.Zero(t, acks)
+
+	svc.flushCDCBatch(ctx)
+
+	// более позднее событие строки ждёт за упавшим, хоть его строка и записана
+	require.Len(t, schemas.deadLetters, 2)
+	require.Equal(t, 1, schemas.deadLetters[0].Attempts)
+	require.Zero(t, schemas.deadLetters[1].Attempts)
+	require.Equal(t, ErrDeadLetterBlocked.Error(), schemas.deadLetters[1].Error)
+	require.Less(t, schemas.deadLetters[0].ID, schemas.deadLetters[1].ID)
+	require.Equal(t, 3, acks)
+}
+
+func TestCDCBatch_JoinedViewRowsRebuiltOncePerFlush(t *testing.T) {
+	oltp := &mockOLTP{selectRows: tableRowsSelector(map[string][]map[string]interface{}{
+		"users": {{"id": int64(1), "email": "a@example.com"}},
+		"profiles": {
+			{"profile_id": int64(10), "user_id": int64(1), "age": int64(30)},
+			{"profile_id": int64(11), "user_id": int64(1), "age": int64(31)},
+		},
+	})}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		DWHProvider: dwh,
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHDbName:   DbPostgres,
+	}
+	batch := &cdcBatch{}
+	ctx := context.WithValue(context.Background(), cdcBatchKey{}, batch)
+
+	acks := 0
+	plain := namedUsersEvent(2, "b", &acks)
+	plainCtx, plainAck := batchEventContext(ctx, &plain)
+	batch.add(plainCtx, eventView{id: 2, view: models.View{Name: "v"}}, plain.Data.After, []string{"id"}, plain.Data)
+	plainAck.release()
+
+	ev := eventView{id: 1, view: joinedTestView()}
+	for _, profileID := range []float64{10, 11} {
+		evt := ackedEvent(0, 0, 0, &acks)
+		evt.Data = models.CDCEventData{
+			Op:     "u",
+			After:  map[string]interface{}{"profile_id": profileID, "user_id": float64(1), "age": float64(40)},
+			Source: models.CDCSource{DB: "db1", Schema: "public", Table: "profiles"},
+		}
+		evtCtx, ack := batchEventContext(ctx, &evt)
+		require.NoError(t, svc.refreshJoinedViewRow(evtCtx, ev, evt.Data))
+		ack.release()
+	}
+
+	// изменения view с джоинами копятся в пачке и не выталкивают строки других view
+	require.Zero(t, dwh.replaceCalls)
+	require.Empty(t, dwh.upsertBatches)
+	require.Zero(t, acks)
+	require.Equal(t, 3, batch.rows)
+
+	svc.flushCDCBatch(ctx)
+
+	// строка пользователя, затронутая обоими изменениями, пересобрана один раз
+	require.Equal(t, []int{1}, dwh.upsertBatches)
+	require.Equal(t, 1, dwh.replaceCalls)
+	require.Len(t, dwh.upsertCalls, 3)
+	require.Equal(t, []mockRowCall{{table: "user_basic_info", row: map[string]interface{}{"id": int64(1)}}}, dwh.rowDeleteCalls)
+	require.Equal(t, 3, acks)
+	require.Zero(t, batch.rows)
+}
+
+func TestEventWorker_FlushesBySize(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}}
+	svc, _, _ := newBatchTestService(dwh)
+	svc.SetCDCBatchOptions(CDCBatchOptions{Size: 2, Window: time.Hour})
+
+	queue := make(chan models.CDCEvent, 2)
+	acked := make(chan int, 2)
+	for id := 1; id <= 2; id++ {
+		evt := usersEvent(id, 90, 12)
+		evt.Ack = func() { acked <- id }
+		queue <- evt
+	}
+	done := make(chan struct{})
+	go func() {
+		svc.eventWorker(queue)
+		close(done)
+	}()
+
+	for want := 1; want <= 2; want++ {
+		select {
+		case got := <-acked:
+			require.Equal(t, want, got)
+		case <-time.After(time.Second):
+			t.Fatal("пачка не записана по размеру")
+		}
+	}
+	close(queue)
+	<-done
+	require.Equal(t, []int{2}, dwh.upsertBatches)
+}
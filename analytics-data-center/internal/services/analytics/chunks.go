@@ -0,0 +1,248 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// planChunks делит таблицу источника на диапазоны для параллельной загрузки.
+// Границы считаются заранее: для одиночного целочисленного первичного ключа — по
+// min/max, для прочих (в том числе составных) ключей — выборкой каждого chunkSize-го
+// значения ключа. У таблиц без первичного ключа чанки строятся по страницам ctid.
+func (a *AnalyticsDataCenterService) planChunks(
+	ctx context.Context,
+	oltpStorage storage.OLTPDB,
+	view models.View,
+	item models.CountInsertData,
+	chunkSize int64,
+) ([]models.Chunk, error) {
+	const op = "analytics.planChunks"
+	log := a.log.With(slog.String("op", op), slog.String("table", item.TableName))
+
+	whole := []models.Chunk{{}}
+	if chunkSize <= 0 || item.Count <= chunkSize {
+		return whole, nil
+	}
+
+	table, ok := findCountTable(view, item)
+	if !ok {
+		return nil, fmt.Errorf("%s: таблица %s не найдена в представлении", op, item.TableName)
+	}
+	chunksCount := (item.Count + chunkSize - 1) / chunkSize
+
+	strategy, keys := a.chunkStrategyFor(table)
+	switch strategy {
+	case chunkByIntegerKey:
+		log.Info("чанки по диапазону целочисленного ключа", slog.String("key", keys[0]))
+		return a.planIntegerKeyChunks(ctx, oltpStorage, table, keys[0], chunksCount)
+	case chunkBySampledKey:
+		log.Info("чанки по выборке границ ключа", slog.String("key", strings.Join(keys, ",")))
+		return a.planSampledKeyChunks(ctx, oltpStorage, table, keys, chunkSize)
+	case chunkByCtid:
+		log.Warn("у таблицы нет первичного ключа, чанки строятся по ctid")
+		return a.planCtidChunks(ctx, oltpStorage, table, chunksCount)
+	default:
+		log.Warn("у таблицы нет первичного ключа, загружаю одним чанком")
+		return whole, nil
+	}
+}
+
+// chunkStrategy — способ деления таблицы источника на чанки
+type chunkStrategy int
+
+const (
+	chunkWhole chunkStrategy = iota
+	chunkByIntegerKey
+	chunkBySampledKey
+	chunkByCtid
+)
+
+// chunkStrategyFor выбирает способ деления таблицы на чанки и возвращает колонки ключа
+func (a *AnalyticsDataCenterService) chunkStrategyFor(table models.Table) (chunkStrategy, []string) {
+	var keyColumns []models.Column
+	for _, column := range table.Columns {
+		if column.IsPrimaryKey {
+			keyColumns = append(keyColumns, column)
+		}
+	}
+
+	switch {
+	case len(keyColumns) == 1 && isIntegerColumn(keyColumns[0]):
+		return chunkByIntegerKey, []string{keyColumns[0].Name}
+	case len(keyColumns) > 0:
+		names := make([]string, 0, len(keyColumns))
+		for _, column := range keyColumns {
+			names = append(names, column.Name)
+		}
+		return chunkBySampledKey, names
+	case a.OLTPDbName == DbPostgres:
+		return chunkByCtid, []string{models.ChunkKeyCtid}
+	default:
+		return chunkWhole, nil
+	}
+}
+
+func (a *AnalyticsDataCenterService) planIntegerKeyChunks(
+	ctx context.Context,
+	oltpStorage storage.OLTPDB,
+	table models.Table,
+	column string,
+	chunksCount int64,
+) ([]models.Chunk, error) {
+	query, err := sqlgenerator.GenerateKeyRangeQuery(table.Name, column, table.Filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := oltpStorage.SelectRows(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || rows[0][sqlgenerator.KeyRangeMinColumn] == nil {
+		return []models.Chunk{{}}, nil
+	}
+	minKey, okMin := toInt64(rows[0][sqlgenerator.KeyRangeMinColumn])
+	maxKey, okMax := toInt64(rows[0][sqlgenerator.KeyRangeMaxColumn])
+	if !okMin || !okMax {
+		return nil, fmt.Errorf("не удалось разобрать границы ключа %s таблицы %s", column, table.Name)
+	}
+
+	width := (maxKey - minKey + chunksCount) / chunksCount
+	if width < 1 {
+		width = 1
+	}
+
+	var chunks []models.Chunk
+	for lower := minKey; lower <= maxKey; lower += width {
+		chunk := models.Chunk{Index: len(chunks), Columns: []string{column}}
+		if lower != minKey {
+			chunk.Lower = []interface{}{lower}
+		}
+		if upper := lower + width; upper <= maxKey {
+			chunk.Upper = []interface{}{upper}
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+func (a *AnalyticsDataCenterService) planSampledKeyChunks(
+	ctx context.Context,
+	oltpStorage storage.OLTPDB,
+	table models.Table,
+	columns []string,
+	chunkSize int64,
+) ([]models.Chunk, error) {
+	query, err := sqlgenerator.GenerateKeyBoundariesQuery(table.Name, columns, table.Filter, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := oltpStorage.SelectRows(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	boundaries := make([][]interface{}, 0, len(rows))
+	for _, row := range rows {
+		normalizeOLTPRow(table, row)
+		tuple := make([]interface{}, 0, len(columns))
+		for _, column := range columns {
+			tuple = append(tuple, row[column])
+		}
+		boundaries = append(boundaries, tuple)
+	}
+	return rangeChunks(columns, boundaries), nil
+}
+
+func (a *AnalyticsDataCenterService) planCtidChunks(
+	ctx context.Context,
+	oltpStorage storage.OLTPDB,
+	table models.Table,
+	chunksCount int64,
+) ([]models.Chunk, error) {
+	pages, err := oltpStorage.GetCountInsertData(ctx, sqlgenerator.GenerateRelationPagesQuery(table.Name))
+	if err != nil {
+		return nil, err
+	}
+	pagesPerChunk := (pages + chunksCount - 1) / chunksCount
+	if pagesPerChunk < 1 {
+		pagesPerChunk = 1
+	}
+
+	var boundaries [][]interface{}
+	for page := pagesPerChunk; page < pages; page += pagesPerChunk {
+		boundaries = append(boundaries, []interface{}{page})
+	}
+	return rangeChunks([]string{models.ChunkKeyCtid}, boundaries), nil
+}
+
+// rangeChunks превращает упорядоченные границы в чанки [nil, b1), [b1, b2), ..., [bn, nil)
+func rangeChunks(columns []string, boundaries [][]interface{}) []models.Chunk {
+	chunks := make([]models.Chunk, 0, len(boundaries)+1)
+	var lower []interface{}
+	for _, boundary := range boundaries {
+		chunks = append(chunks, models.Chunk{Index: len(chunks), Columns: columns, Lower: lower, Upper: boundary})
+		lower = boundary
+	}
+	return append(chunks, models.Chunk{Index: len(chunks), Columns: columns, Lower: lower})
+}
+
+// findCountTable находит таблицу view, к которой относится результат подсчёта строк
+func findCountTable(view models.View, item models.CountInsertData) (models.Table, bool) {
+	for _, source := range view.Sources {
+		if source.Name != item.DataBaseName {
+			continue
+		}
+		for _, sch := range source.Schemas {
+			if item.SchemaName != "" && sch.Name != item.SchemaName {
+				continue
+			}
+			for _, table := range sch.Tables {
+				if table.Name == item.TableName {
+					return table, true
+				}
+			}
+		}
+	}
+	return models.Table{}, false
+}
+
+func isIntegerColumn(column models.Column) bool {
+	typ := strings.ToLower(strings.TrimSpace(column.DataType))
+	if typ == "" {
+		typ = strings.ToLower(strings.TrimSpace(column.UdtName))
+	}
+	if typ == "" {
+		typ = strings.ToLower(strings.TrimSpace(column.Type))
+	}
+	switch typ {
+	case "smallint", "integer", "bigint", "int", "int2", "int4", "int8", "smallserial", "serial", "bigserial":
+		return true
+	}
+	return false
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	case []byte:
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		return i, err == nil
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,116 @@
+package serviceanalytics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+func chunkedView(columns ...models.Column) models.View {
+	return models.View{
+		Name: "v",
+		Sources: []models.Source{{
+			Name: "db1",
+			Schemas: []models.Schema{{
+				Name:   "public",
+				Tables: []models.Table{{Name: "orders", Columns: columns}},
+			}},
+		}},
+	}
+}
+
+func chunkedItem(count int64) models.CountInsertData {
+	return models.CountInsertData{TableName: "orders", Count: count, DataBaseName: "db1", SchemaName: "public", TempTableName: "tmp_orders"}
+}
+
+func TestPlanChunks_SmallTableIsSingleChunk(t *testing.T) {
+	svc := &AnalyticsDataCenterService{log: getTestLogger(), OLTPDbName: DbPostgres}
+	chunks, err := svc.planChunks(context.Background(), &mockOLTP{}, chunkedView(models.Column{Name: "id", IsPrimaryKey: true}), chunkedItem(10), 100)
+	require.NoError(t, err)
+	require.Equal(t, []models.Chunk{{}}, chunks)
+}
+
+func TestPlanChunks_IntegerKeyUsesMinMax(t *testing.T) {
+	oltp := &mockOLTP{selectRows: func(query string, _ []interface{}) ([]map[string]interface{}, error) {
+		require.Contains(t, query, `min("id")`)
+		return []map[string]interface{}{{"min_key": int64(1), "max_key": int64(1000)}}, nil
+	}}
+	svc := &AnalyticsDataCenterService{log: getTestLogger(), OLTPDbName: DbPostgres}
+
+	chunks, err := svc.planChunks(context.Background(), oltp, chunkedView(models.Column{Name: "id", IsPrimaryKey: true, DataType: "bigint"}), chunkedItem(1000), 300)
+	require.NoError(t, err)
+	require.Len(t, chunks, 4)
+	require.Nil(t, chunks[0].Lower)
+	require.Equal(t, []interface{}{int64(251)}, chunks[0].Upper)
+	require.Equal(t, []interface{}{int64(251)}, chunks[1].Lower)
+	require.Equal(t, []interface{}{int64(751)}, chunks[3].Lower)
+	require.Nil(t, chunks[3].Upper)
+}
+
+func TestPlanChunks_CompositeKeyUsesSampledBoundaries(t *testing.T) {
+	oltp := &mockOLTP{selectRows: func(query string, _ []interface{}) ([]map[string]interface{}, error) {
+		require.Contains(t, query, "row_number() OVER")
+		return []map[string]interface{}{
+			{"tenant_id": int64(1), "id": []byte("b")},
+			{"tenant_id": int64(2), "id": []byte("a")},
+		}, nil
+	}}
+	svc := &AnalyticsDataCenterService{log: getTestLogger(), OLTPDbName: DbPostgres}
+	view := chunkedView(models.Column{Name: "tenant_id", IsPrimaryKey: true}, models.Column{Name: "id", IsPrimaryKey: true, DataType: "uuid"})
+
+	chunks, err := svc.planChunks(context.Background(), oltp, view, chunkedItem(25), 10)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+	require.Equal(t, []string{"tenant_id", "id"}, chunks[1].Columns)
+	require.Equal(t, []interface{}{int64(1), "b"}, chunks[1].Lower)
+	require.Equal(t, []interface{}{int64(2), "a"}, chunks[1].Upper)
+	require.Nil(t, chunks[2].Upper)
+}
+
+func TestPlanChunks_NoPrimaryKeyFallsBackToCtid(t *testing.T) {
+	oltp := &mockOLTP{countResult: 100}
+	svc := &AnalyticsDataCenterService{log: getTestLogger(), OLTPDbName: DbPostgres}
+
+	chunks, err := svc.planChunks(context.Background(), oltp, chunkedView(models.Column{Name: "payload"}), chunkedItem(1000), 400)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+	for _, chunk := range chunks {
+		require.True(t, chunk.ByCtid())
+	}
+	require.Equal(t, []interface{}{int64(34)}, chunks[0].Upper)
+	require.Equal(t, []interface{}{int64(68)}, chunks[2].Lower)
+}
+
+func TestPrepareAndInsertData_LoadsEveryChunk(t *testing.T) {
+	oltp := &mockOLTP{
+		selectResult: []map[string]interface{}{{"id": 1}},
+		selectRows: func(string, []interface{}) ([]map[string]interface{}, error) {
+			return []map[string]interface{}{{"min_key": int64(1), "max_key": int64(1_200_000)}}, nil
+		},
+	}
+	dwh := &mockDWH{columns: map[string][]string{"tmp_orders": {"id"}}}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHProvider: dwh,
+		DWHDbName:   DbPostgres,
+		OLTPDbName:  DbPostgres,
+	}
+	view := chunkedView(models.Column{Name: "id", IsPrimaryKey: true, DataType: "integer"})
+	data := []models.CountInsertData{chunkedItem(1_200_000)}
+
+	ok, err := svc.prepareAndInsertData(context.Background(), nil, &data, &view)
+	require.True(t, ok)
+	require.NoError(t, err)
+
+	require.Len(t, oltp.streamCalls, 3)
+	for _, call := range oltp.streamCalls {
+		require.False(t, strings.Contains(call.Query, "OFFSET"))
+	}
+	require.Len(t, dwh.insertCalls, 3)
+}
@@ -116,6 +116,12 @@ func (a *AnalyticsDataCenterService) ApplyColumnMismatchResolution(ctx context.C
 		return err
 	}
 
+	renames := make(map[string]string, len(resolution.Renames))
+	for _, decision := range resolution.Renames {
+		renames[decision.OldName] = decision.NewName
+	}
+	a.releasePendingEvents(ctx, group.Group.SchemaID, group.Group.DatabaseName, group.Group.SchemaName, group.Group.TableName, renames)
+
 	return nil
 }
 
@@ -18,11 +18,13 @@ func TestApplyColumnMismatchResolution_IgnoresOnly(t *testing.T) {
 	dwh := &mockDWH{}
 
 	svc := &AnalyticsDataCenterService{
-		log:                   getTestLogger(),
-		SchemaProvider:        schemaProvider,
-		ColumnMismatchStorage: schemaProvider,
-		DWHProvider:           dwh,
-		DWHDbName:             DbPostgres,
+		log:                     getTestLogger(),
+		SchemaProvider:          schemaProvider,
+		RenameSuggestionStorage: schemaProvider,
+		PendingEventStorage:     schemaProvider,
+		ColumnMismatchStorage:   schemaProvider,
+		DWHProvider:             dwh,
+		DWHDbName:               DbPostgres,
 	}
 
 	err := svc.ApplyColumnMismatchResolution(context.Background(), 1, models.ColumnMismatchResolution{
@@ -45,11 +47,13 @@ func TestApplyColumnMismatchResolution_Renames(t *testing.T) {
 	dwh := &mockDWH{}
 
 	svc := &AnalyticsDataCenterService{
-		log:                   getTestLogger(),
-		SchemaProvider:        schemaProvider,
-		ColumnMismatchStorage: schemaProvider,
-		DWHProvider:           dwh,
-		DWHDbName:             DbPostgres,
+		log:                     getTestLogger(),
+		SchemaProvider:          schemaProvider,
+		RenameSuggestionStorage: schemaProvider,
+		PendingEventStorage:     schemaProvider,
+		ColumnMismatchStorage:   schemaProvider,
+		DWHProvider:             dwh,
+		DWHDbName:               DbPostgres,
 	}
 
 	err := svc.ApplyColumnMismatchResolution(context.Background(), 1, models.ColumnMismatchResolution{
@@ -74,11 +78,13 @@ func TestApplyColumnMismatchResolution_Deletes(t *testing.T) {
 	dwh := &mockDWH{}
 
 	svc := &AnalyticsDataCenterService{
-		log:                   getTestLogger(),
-		SchemaProvider:        schemaProvider,
-		ColumnMismatchStorage: schemaProvider,
-		DWHProvider:           dwh,
-		DWHDbName:             DbPostgres,
+		log:                     getTestLogger(),
+		SchemaProvider:          schemaProvider,
+		RenameSuggestionStorage: schemaProvider,
+		PendingEventStorage:     schemaProvider,
+		ColumnMismatchStorage:   schemaProvider,
+		DWHProvider:             dwh,
+		DWHDbName:               DbPostgres,
 	}
 
 	err := svc.ApplyColumnMismatchResolution(context.Background(), 1, models.ColumnMismatchResolution{
@@ -102,11 +108,13 @@ func TestApplyColumnMismatchResolution_DropInDWH(t *testing.T) {
 	dwh := &mockDWH{}
 
 	svc := &AnalyticsDataCenterService{
-		log:                   getTestLogger(),
-		SchemaProvider:        schemaProvider,
-		ColumnMismatchStorage: schemaProvider,
-		DWHProvider:           dwh,
-		DWHDbName:             DbPostgres,
+		log:                     getTestLogger(),
+		SchemaProvider:          schemaProvider,
+		RenameSuggestionStorage: schemaProvider,
+		PendingEventStorage:     schemaProvider,
+		ColumnMismatchStorage:   schemaProvider,
+		DWHProvider:             dwh,
+		DWHDbName:               DbPostgres,
 	}
 
 	err := svc.ApplyColumnMismatchResolution(context.Background(), 1, models.ColumnMismatchResolution{
@@ -131,11 +139,13 @@ func TestApplyColumnMismatchResolution_Mixed(t *testing.T) {
 	dwh := &mockDWH{}
 
 	svc := &AnalyticsDataCenterService{
-		log:                   getTestLogger(),
-		SchemaProvider:        schemaProvider,
-		ColumnMismatchStorage: schemaProvider,
-		DWHProvider:           dwh,
-		DWHDbName:             DbPostgres,
+		log:                     getTestLogger(),
+		SchemaProvider:          schemaProvider,
+		RenameSuggestionStorage: schemaProvider,
+		PendingEventStorage:     schemaProvider,
+		ColumnMismatchStorage:   schemaProvider,
+		DWHProvider:             dwh,
+		DWHDbName:               DbPostgres,
 	}
 
 	err := svc.ApplyColumnMismatchResolution(context.Background(), 1, models.ColumnMismatchResolution{
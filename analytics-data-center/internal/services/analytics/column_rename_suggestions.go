@@ -61,6 +61,9 @@ func (a *AnalyticsDataCenterService) AcceptColumnRenameSuggestion(ctx context.Co
 		return err
 	}
 
+	a.releasePendingEvents(ctx, suggestion.SchemaID, suggestion.DatabaseName, suggestion.SchemaName, suggestion.TableName,
+		map[string]string{suggestion.OldColumnName: suggestion.NewColumnName})
+
 	return nil
 }
 
@@ -68,6 +71,15 @@ func (a *AnalyticsDataCenterService) RejectColumnRenameSuggestion(ctx context.Co
 	const op = "AnalyticsDataCenterService.RejectColumnRenameSuggestion"
 	log := a.log.With(slog.String("op", op), slog.Int64("suggestion_id", id))
 
+	suggestion, err := a.RenameSuggestionStorage.GetSuggestionByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrSuggestionNotFound) {
+			return err
+		}
+		log.Error("failed to get suggestion", slog.String("error", err.Error()))
+		return err
+	}
+
 	if err := a.RenameSuggestionStorage.DeleteSuggestionByID(ctx, id); err != nil {
 		if errors.Is(err, storage.ErrSuggestionNotFound) {
 			return err
@@ -76,6 +88,9 @@ func (a *AnalyticsDataCenterService) RejectColumnRenameSuggestion(ctx context.Co
 		return err
 	}
 
+	// колонка не переименована: отложенные события применяются как есть
+	a.releasePendingEvents(ctx, suggestion.SchemaID, suggestion.DatabaseName, suggestion.SchemaName, suggestion.TableName, nil)
+
 	return nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
 	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
 	renameheuristics "analyticDataCenter/analytics-data-center/internal/lib/renameheuristics"
+	"analyticDataCenter/analytics-data-center/internal/lib/rowfilter"
 	"analyticDataCenter/analytics-data-center/internal/storage"
 	"context"
 	"encoding/json"
@@ -17,11 +18,6 @@ import (
 
 func (a *AnalyticsDataCenterService) createRowAfterListenEventInDWH(ctx context.Context, evtData models.CDCEventData) error {
 	const op = "createRowAfterListenEventInDWH"
-	type viewInfo struct {
-		id   int
-		view models.View
-	}
-	var schems []viewInfo
 
 	log := a.log.With(slog.String("op", op))
 
@@ -45,121 +41,52 @@ func (a *AnalyticsDataCenterService) createRowAfterListenEventInDWH(ctx context.
 	}
 
 	// 3. Забираем сами view
-	for _, schemaId := range schemaIds {
-		schema, err := a.SchemaProvider.GetView(ctx, int64(schemaId))
-		if err != nil {
-			if errors.Is(err, storage.ErrSchemaNotFound) {
-				log.Warn("view not found", slog.String("error", err.Error()))
-				return fmt.Errorf("%s: %s", op, ErrInvalidSchemID)
-			}
-			log.Warn("ошибка получения схемы", slog.String("error", err.Error()))
-			return fmt.Errorf("%s: %s", op, err)
-		}
-		schems = append(schems, viewInfo{id: schemaId, view: schema})
+	schems, err := a.loadEventViews(ctx, schemaIds)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	// view на загрузке получат событие после публикации новой таблицы
+	schems = a.routeEventViews(ctx, schems, evtData)
 
 	// 4. Для КАЖДОЙ view отдельно собираем finalRow и conflictKeys
 	for _, schema := range schems {
 		viewName := schema.view.Name
 
-		hasSuggestion, err := a.RenameSuggestionStorage.HasSuggestion(ctx, int64(schema.id), databaseEvt, schemaEvt, tableEvt)
+		// пока есть предложение по переименованию, событие ждёт решения в буфере view
+		deferred, err := a.deferViewEvent(ctx, schema, evtData)
 		if err != nil {
-			log.Error("ошибка проверки предложений переименования", slog.String("error", err.Error()), slog.String("view", viewName))
-			return err
+			return viewEventFailed(schema, err)
 		}
-
-		if hasSuggestion {
-			log.Warn("Пропускаю вставку — есть предложение по переименованию", slog.String("view", viewName), slog.String("table", tableEvt))
+		if deferred {
 			continue
 		}
 
-		finalRow := make(map[string]interface{})
-		conflictKeys := make(map[string]struct{})
-		hasData := false
-
-		for _, source := range schema.view.Sources {
-			if source.Name != databaseEvt {
-				continue
+		if len(schema.view.Joins) > 0 {
+			if err := a.refreshJoinedViewRow(ctx, schema, evtData); err != nil {
+				return viewEventFailed(schema, err)
 			}
-			for _, sch := range source.Schemas {
-				if sch.Name != schemaEvt {
-					continue
-				}
-				for _, table := range sch.Tables {
-					if table.Name != tableEvt {
-						continue
-					}
-
-					for _, column := range table.Columns {
-						val, ok := after[column.Name]
-						if !ok {
-							continue
-						}
-						hasData = true
-
-						// SAFE: конвертация time-полей (если Debezium прислал микросекунды)
-						if a.DWHDbName == "postgres" && isTimeColumn(column) {
-							val = convertDebeziumTemporal(column, val, log.Logger)
-						}
-
-						targetColumnName := column.Name
-						if column.Alias != "" {
-							targetColumnName = column.Alias
-						}
-
-						// всегда кладём по имени колонки (с учётом алиаса)
-						finalRow[targetColumnName] = val
-
-						// если ключевая — добавляем её в conflictKeys
-						if column.IsUpdateKey {
-							conflictKeys[targetColumnName] = struct{}{}
-							if column.ViewKey != "" && column.ViewKey != targetColumnName {
-								finalRow[column.ViewKey] = val
-								conflictKeys[column.ViewKey] = struct{}{}
-							}
-						}
+			continue
+		}
 
-						// === FieldTransform ===
-						if column.Transform != nil && column.Transform.Type == "FieldTransform" && column.Transform.Mapping != nil {
-							rawStr := fmt.Sprintf("%v", val)
-							if transformed, ok := column.Transform.Mapping.Mapping[rawStr]; ok {
-								outputColumn := column.Transform.Mapping.AliasNewColumnTransform
-								if outputColumn == "" {
-									outputColumn = column.Name + "_transformed"
-								}
-								finalRow[outputColumn] = transformed
-							}
-						}
+		tables := eventTables(schema.view, databaseEvt, schemaEvt, tableEvt)
 
-						// === JSON Transform ===
-						if column.Transform != nil && column.Transform.Type == "JSON" && column.Transform.Mapping != nil {
-							valStr, ok := val.(string)
-							if !ok {
-								log.Warn("Ожидалась строка JSON, но получено другое",
-									slog.String("column", column.Name),
-									slog.String("view", viewName))
-								continue
-							}
+		// строка не проходит фильтр таблицы: во view её быть не должно
+		if matched, filtered := a.eventMatchesFilters(tables, after); !matched {
+			// удаление не должно обогнать вставки строки, накопленные в пачке
+			a.flushCDCBatch(ctx)
+			if err := a.removeFilteredRow(ctx, schema.view, tables, evtData, filtered, log.Logger); err != nil {
+				return viewEventFailed(schema, err)
+			}
+			continue
+		}
 
-							var jsonMap map[string]interface{}
-							if err := json.Unmarshal([]byte(valStr), &jsonMap); err != nil {
-								log.Warn("Ошибка парсинга JSON-строки",
-									slog.String("column", column.Name),
-									slog.String("view", viewName),
-									slog.String("error", err.Error()))
-								continue
-							}
+		finalRow := make(map[string]interface{})
+		conflictKeys := make(map[string]struct{})
+		hasData := false
 
-							for _, mappingJSON := range column.Transform.Mapping.MappingJSON {
-								for jsonField, outputColumn := range mappingJSON.Mapping {
-									if extractedVal, exists := jsonMap[jsonField]; exists {
-										finalRow[outputColumn] = extractedVal
-									}
-								}
-							}
-						}
-					}
-				}
+		for _, table := range tables {
+			if a.fillViewRow(viewName, table, after, finalRow, conflictKeys, log.Logger) {
+				hasData = true
 			}
 		}
 
@@ -169,17 +96,16 @@ func (a *AnalyticsDataCenterService) createRowAfterListenEventInDWH(ctx context.
 			continue
 		}
 
+		// строка снова существует в источнике — снимаем пометку удаления
+		resetSoftDelete(schema.view, finalRow)
+
 		var conflictColumns []string
 		for k := range conflictKeys {
 			conflictColumns = append(conflictColumns, k)
 		}
 
-		if err := a.DWHProvider.InsertOrUpdateTransactional(
-			ctx,
-			viewName, // таблица в DWH = имя view
-			finalRow,
-			conflictColumns,
-		); err != nil {
+		// таблица в DWH = имя view
+		if err := a.upsertViewRow(ctx, schema, finalRow, conflictColumns, evtData); err != nil {
 			if isRelationDoesNotExist(err) {
 				log.Warn("таблица отсутствует в DWH, пропускаю вставку",
 					slog.String("view", viewName))
@@ -188,7 +114,7 @@ func (a *AnalyticsDataCenterService) createRowAfterListenEventInDWH(ctx context.
 			log.Error("ошибка вставки/обновления",
 				slog.String("error", err.Error()),
 				slog.String("view", viewName))
-			return err
+			return viewEventFailed(schema, err)
 		}
 	}
 
@@ -196,6 +122,198 @@ func (a *AnalyticsDataCenterService) createRowAfterListenEventInDWH(ctx context.
 	return nil
 }
 
+// eventMatchesFilters проверяет образ строки фильтрами таблиц события.
+// Вторым значением возвращается таблица, чей фильтр строку не пропустил.
+func (a *AnalyticsDataCenterService) eventMatchesFilters(tables []models.Table, image map[string]interface{}) (bool, models.Table) {
+	for _, table := range tables {
+		if !a.rowMatchesFilter(table, image) {
+			return false, table
+		}
+	}
+	return true, models.Table{}
+}
+
+// rowMatchesFilter вычисляет фильтр таблицы на образе строки. Временные колонки
+// перед сравнением приводятся из формата Debezium ко времени.
+func (a *AnalyticsDataCenterService) rowMatchesFilter(table models.Table, image map[string]interface{}) bool {
+	if table.Filter == nil {
+		return true
+	}
+	normalized := make(map[string]interface{}, len(image))
+	for k, v := range image {
+		normalized[k] = v
+	}
+	for _, column := range table.Columns {
+		if val, ok := normalized[column.Name]; ok && val != nil && isTimeColumn(column) {
+			normalized[column.Name] = convertDebeziumTemporal(column, val, a.log.Logger)
+		}
+	}
+	return rowfilter.Match(table.Filter, normalized)
+}
+
+// removeFilteredRow убирает из view строку, которая после изменения вышла из фильтра.
+// Если старый образ строки фильтр тоже не проходил, строки во view нет и удалять нечего.
+func (a *AnalyticsDataCenterService) removeFilteredRow(
+	ctx context.Context,
+	view models.View,
+	tables []models.Table,
+	evtData models.CDCEventData,
+	filtered models.Table,
+	log *slog.Logger,
+) error {
+	if evtData.Op == "c" || evtData.Op == "r" {
+		log.Info("строка не проходит фильтр таблицы, вставка пропущена",
+			slog.String("view", view.Name), slog.String("table", filtered.Name))
+		return nil
+	}
+	if len(evtData.Before) > 0 {
+		if matched, _ := a.eventMatchesFilters(tables, evtData.Before); !matched {
+			return nil
+		}
+	}
+
+	image := evtData.Before
+	if len(image) == 0 {
+		image = evtData.After
+	}
+	keys := make(map[string]interface{})
+	for _, table := range tables {
+		for k, v := range a.updateKeyValues(table, image, log) {
+			keys[k] = v
+		}
+	}
+	if len(keys) == 0 {
+		log.Warn("строка вышла из фильтра, но ключей обновления нет, удаление пропущено",
+			slog.String("view", view.Name))
+		return nil
+	}
+
+	log.Info("строка вышла из фильтра таблицы, удаляю её из view",
+		slog.String("view", view.Name), slog.String("table", filtered.Name))
+	return a.removeViewRow(ctx, view, keys, log)
+}
+
+// fillViewRow раскладывает образ строки таблицы в колонки view (алиасы, ключи
+// обновления, FieldTransform и JSON-трансформации). Возвращает true, если
+// в образе нашлась хотя бы одна колонка таблицы.
+func (a *AnalyticsDataCenterService) fillViewRow(
+	viewName string,
+	table models.Table,
+	image map[string]interface{},
+	finalRow map[string]interface{},
+	conflictKeys map[string]struct{},
+	log *slog.Logger,
+) bool {
+	hasData := false
+
+	for _, column := range table.Columns {
+		val, ok := image[column.Name]
+		if !ok {
+			continue
+		}
+		hasData = true
+
+		// SAFE: конвертация time-полей (если Debezium прислал микросекунды)
+		if a.DWHDbName == "postgres" && isTimeColumn(column) {
+			val = convertDebeziumTemporal(column, val, log)
+		}
+
+		targetColumnName := column.Name
+		if column.Alias != "" {
+			targetColumnName = column.Alias
+		}
+
+		// всегда кладём по имени колонки (с учётом алиаса)
+		finalRow[targetColumnName] = val
+
+		// если ключевая — добавляем её в conflictKeys
+		if column.IsUpdateKey {
+			conflictKeys[targetColumnName] = struct{}{}
+			if column.ViewKey != "" && column.ViewKey != targetColumnName {
+				finalRow[column.ViewKey] = val
+				conflictKeys[column.ViewKey] = struct{}{}
+			}
+		}
+
+		// === FieldTransform ===
+		if column.Transform != nil && column.Transform.Type == "FieldTransform" && column.Transform.Mapping != nil {
+			rawStr := fmt.Sprintf("%v", val)
+			if transformed, ok := column.Transform.Mapping.Mapping[rawStr]; ok {
+				outputColumn := column.Transform.Mapping.AliasNewColumnTransform
+				if outputColumn == "" {
+					outputColumn = column.Name + "_transformed"
+				}
+				finalRow[outputColumn] = transformed
+			}
+		}
+
+		// === JSON Transform ===
+		if column.Transform != nil && column.Transform.Type == "JSON" && column.Transform.Mapping != nil {
+			valStr, ok := val.(string)
+			if !ok {
+				log.Warn("Ожидалась строка JSON, но получено другое",
+					slog.String("column", column.Name),
+					slog.String("view", viewName))
+				continue
+			}
+
+			var jsonMap map[string]interface{}
+			if err := json.Unmarshal([]byte(valStr), &jsonMap); err != nil {
+				log.Warn("Ошибка парсинга JSON-строки",
+					slog.String("column", column.Name),
+					slog.String("view", viewName),
+					slog.String("error", err.Error()))
+				continue
+			}
+
+			for _, mappingJSON := range column.Transform.Mapping.MappingJSON {
+				for jsonField, outputColumn := range mappingJSON.Mapping {
+					if extractedVal, exists := jsonMap[jsonField]; exists {
+						finalRow[outputColumn] = extractedVal
+					}
+				}
+			}
+		}
+	}
+
+	return hasData
+}
+
+// resetSoftDelete снимает пометку удаления со строки, которая снова есть в источнике
+func resetSoftDelete(view models.View, row map[string]interface{}) {
+	if !view.IsSoftDelete() {
+		return
+	}
+	row[models.SoftDeleteFlagColumn] = false
+	row[models.SoftDeleteTimeColumn] = nil
+}
+
+// eventView — view, затронутая CDC-событием, вместе с её идентификатором в sys-БД
+type eventView struct {
+	id   int
+	view models.View
+}
+
+func (a *AnalyticsDataCenterService) loadEventViews(ctx context.Context, schemaIds []int) ([]eventView, error) {
+	const op = "loadEventViews"
+	log := a.log.With(slog.String("op", op))
+
+	views := make([]eventView, 0, len(schemaIds))
+	for _, schemaId := range schemaIds {
+		schema, err := a.SchemaProvider.GetView(ctx, int64(schemaId))
+		if err != nil {
+			if errors.Is(err, storage.ErrSchemaNotFound) {
+				log.Warn("view not found", slog.String("error", err.Error()))
+				return nil, ErrInvalidSchemID
+			}
+			log.Warn("ошибка получения схемы", slog.String("error", err.Error()))
+			return nil, err
+		}
+		views = append(views, eventView{id: schemaId, view: schema})
+	}
+	return views, nil
+}
+
 func (a *AnalyticsDataCenterService) checkColumnInTables(
 	ctx context.Context,
 	before map[string]interface{},
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
 	smtpsender "analyticDataCenter/analytics-data-center/internal/services/smtrsender"
@@ -22,6 +23,14 @@ type mockSchemaProvider struct {
 	hasSuggestionErr error
 	mismatchGroups   map[int64]models.ColumnMismatchGroupWithItems
 	mismatchSeq      int64
+	watermarks       map[int64][]models.ViewWatermark
+	viewSnapshots    map[int64]map[string]models.SnapshotPosition
+	viewSnapshotErr  error
+	deadLetters      []models.DeadLetterEvent
+	deadLetterSeq    int64
+	deadLetterErr    error
+	pendingEvents    []models.PendingCDCEvent
+	pendingSeq       int64
 }
 
 func (m *mockSchemaProvider) CreateTask(context.Context, string, string) error { return nil }
@@ -34,6 +43,222 @@ func (m *mockSchemaProvider) ChangeStatusTask(context.Context, string, string, s
 func (m *mockSchemaProvider) GetTasks(context.Context, models.TaskFilter) ([]models.Task, error) {
 	return nil, nil
 }
+func (m *mockSchemaProvider) SaveTaskStage(context.Context, string, models.TaskStage) error {
+	return nil
+}
+func (m *mockSchemaProvider) SaveTaskTableProgress(context.Context, string, models.TaskTableProgress) error {
+	return nil
+}
+func (m *mockSchemaProvider) AddTaskTableRows(context.Context, string, models.TaskTableProgress) error {
+	return nil
+}
+func (m *mockSchemaProvider) EnqueueETLJob(context.Context, string, int64, string, string, int) error {
+	return nil
+}
+func (m *mockSchemaProvider) RequeueETLJob(context.Context, string, int64, string, int) error {
+	return nil
+}
+func (m *mockSchemaProvider) SaveTaskChunks(context.Context, string, []models.TaskChunk) error {
+	return nil
+}
+func (m *mockSchemaProvider) CompleteTaskChunk(context.Context, string, models.TaskChunk) error {
+	return nil
+}
+func (m *mockSchemaProvider) ListTaskChunks(context.Context, string) ([]models.TaskChunk, error) {
+	return nil, nil
+}
+func (m *mockSchemaProvider) RegisterTaskTempTables(context.Context, string, []string) error {
+	return nil
+}
+func (m *mockSchemaProvider) GetTaskTempTables(context.Context, string) ([]string, error) {
+	return nil, nil
+}
+func (m *mockSchemaProvider) ListTaskTempTables(context.Context, string, string, time.Time) ([]string, error) {
+	return nil, nil
+}
+func (m *mockSchemaProvider) SaveTaskSnapshot(context.Context, string, models.TaskSnapshot) error {
+	return nil
+}
+func (m *mockSchemaProvider) ListTaskSnapshots(context.Context, string) ([]models.TaskSnapshot, error) {
+	return nil, nil
+}
+func (m *mockSchemaProvider) ClaimETLJob(context.Context, string, time.Duration) (*models.ETLJob, error) {
+	return nil, nil
+}
+func (m *mockSchemaProvider) ExtendETLJobLease(context.Context, string, string, time.Duration) error {
+	return nil
+}
+func (m *mockSchemaProvider) FinishETLJob(context.Context, string, string) error { return nil }
+func (m *mockSchemaProvider) DeleteETLJob(context.Context, string) (models.ETLJob, error) {
+	return models.ETLJob{}, storage.ErrJobNotFound
+}
+func (m *mockSchemaProvider) RecoverETLJobs(context.Context, int) ([]string, []string, error) {
+	return nil, nil, nil
+}
+func (m *mockSchemaProvider) ListOrphanTasks(context.Context, string) ([]string, error) {
+	return nil, nil
+}
+func (m *mockSchemaProvider) ListViewWatermarks(_ context.Context, viewID int64) ([]models.ViewWatermark, error) {
+	return m.watermarks[viewID], nil
+}
+func (m *mockSchemaProvider) SaveDeadLetter(_ context.Context, event models.DeadLetterEvent) (int64, error) {
+	if m.deadLetterErr != nil {
+		return 0, m.deadLetterErr
+	}
+	m.deadLetterSeq++
+	event.ID = m.deadLetterSeq
+	m.deadLetters = append(m.deadLetters, event)
+	return event.ID, nil
+}
+func (m *mockSchemaProvider) ListDeadLetters(_ context.Context, filter models.DeadLetterFilter) ([]models.DeadLetterEvent, error) {
+	var events []models.DeadLetterEvent
+	for i := len(m.deadLetters) - 1; i >= 0; i-- {
+		event := m.deadLetters[i]
+		if filter.Status != nil && event.Status != *filter.Status {
+			continue
+		}
+		if filter.ViewID != nil && (event.ViewID == nil || *event.ViewID != *filter.ViewID) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+func (m *mockSchemaProvider) GetDeadLetter(_ context.Context, id int64) (models.DeadLetterEvent, error) {
+	for _, event := range m.deadLetters {
+		if event.ID == id {
+			return event, nil
+		}
+	}
+	return models.DeadLetterEvent{}, storage.ErrDeadLetterNotFound
+}
+func (m *mockSchemaProvider) ListDueDeadLetters(_ context.Context, now time.Time, limit int) ([]models.DeadLetterEvent, error) {
+	var events []models.DeadLetterEvent
+	for _, event := range m.deadLetters {
+		if event.Status == models.DeadLetterRetrying && !event.NextRetryAt.After(now) && len(events) < limit &&
+			!m.hasDeadLetters(event.DatabaseName, event.SchemaName, event.TableName, event.RowKey, event.ID) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+func (m *mockSchemaProvider) HasDeadLetters(_ context.Context, database, schema, table, rowKey string, beforeID int64) (bool, error) {
+	if m.deadLetterErr != nil {
+		return false, m.deadLetterErr
+	}
+	return m.hasDeadLetters(database, schema, table, rowKey, beforeID), nil
+}
+func (m *mockSchemaProvider) hasDeadLetters(database, schema, table, rowKey string, beforeID int64) bool {
+	if rowKey == "" {
+		return false
+	}
+	for _, event := range m.deadLetters {
+		if event.DatabaseName == database && event.SchemaName == schema && event.TableName == table &&
+			event.RowKey == rowKey && (beforeID == 0 || event.ID < beforeID) {
+			return true
+		}
+	}
+	return false
+}
+func (m *mockSchemaProvider) UpdateDeadLetter(_ context.Context, event models.DeadLetterEvent) error {
+	for i := range m.deadLetters {
+		if m.deadLetters[i].ID == event.ID {
+			m.deadLetters[i] = event
+			return nil
+		}
+	}
+	return storage.ErrDeadLetterNotFound
+}
+func (m *mockSchemaProvider) DeleteDeadLetters(_ context.Context, ids []int64) (int64, error) {
+	var deleted int64
+	kept := m.deadLetters[:0]
+	for _, event := range m.deadLetters {
+		if containsID(ids, event.ID) {
+			deleted++
+			continue
+		}
+		kept = append(kept, event)
+	}
+	m.deadLetters = kept
+	return deleted, nil
+}
+
+func (m *mockSchemaProvider) SavePendingEvent(_ context.Context, event models.PendingCDCEvent) error {
+	m.pendingSeq++
+	event.ID = m.pendingSeq
+	m.pendingEvents = append(m.pendingEvents, event)
+	return nil
+}
+func (m *mockSchemaProvider) HasPendingEvents(ctx context.Context, viewID int64, database, schema, table string) (bool, error) {
+	events, err := m.ListPendingEvents(ctx, viewID, database, schema, table, 1)
+	return len(events) > 0, err
+}
+func (m *mockSchemaProvider) ListPendingEvents(_ context.Context, viewID int64, database, schema, table string, limit int) ([]models.PendingCDCEvent, error) {
+	var events []models.PendingCDCEvent
+	for _, event := range m.pendingEvents {
+		if event.ViewID == viewID && event.DatabaseName == database && event.SchemaName == schema && event.TableName == table {
+			events = append(events, event)
+		}
+		if len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+func (m *mockSchemaProvider) DeletePendingEvents(_ context.Context, ids []int64) error {
+	kept := m.pendingEvents[:0]
+	for _, event := range m.pendingEvents {
+		if !containsID(ids, event.ID) {
+			kept = append(kept, event)
+		}
+	}
+	m.pendingEvents = kept
+	return nil
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+func (m *mockSchemaProvider) SaveViewSnapshots(_ context.Context, viewID int64, positions map[string]models.SnapshotPosition) error {
+	if m.viewSnapshotErr != nil {
+		return m.viewSnapshotErr
+	}
+	if m.viewSnapshots == nil {
+		m.viewSnapshots = make(map[int64]map[string]models.SnapshotPosition)
+	}
+	m.viewSnapshots[viewID] = positions
+	return nil
+}
+func (m *mockSchemaProvider) ListViewSnapshots(context.Context) ([]models.ViewSnapshot, error) {
+	if m.viewSnapshotErr != nil {
+		return nil, m.viewSnapshotErr
+	}
+	var snapshots []models.ViewSnapshot
+	for viewID, positions := range m.viewSnapshots {
+		for source, position := range positions {
+			snapshots = append(snapshots, models.ViewSnapshot{ViewID: viewID, Source: source, Position: position})
+		}
+	}
+	return snapshots, nil
+}
+func (m *mockSchemaProvider) SaveViewWatermark(_ context.Context, viewID int64, watermark models.ViewWatermark) error {
+	if m.watermarks == nil {
+		m.watermarks = make(map[int64][]models.ViewWatermark)
+	}
+	for i, w := range m.watermarks[viewID] {
+		if w.Source == watermark.Source && w.Schema == watermark.Schema && w.Table == watermark.Table {
+			m.watermarks[viewID][i] = watermark
+			return nil
+		}
+	}
+	m.watermarks[viewID] = append(m.watermarks[viewID], watermark)
+	return nil
+}
 
 func (m *mockSchemaProvider) GetView(_ context.Context, idView int64) (models.View, error) {
 	if v, ok := m.views[int(idView)]; ok {
@@ -54,6 +279,9 @@ func (m *mockSchemaProvider) UpdateView(_ context.Context, view models.View, sch
 		m.updated = make(map[int]models.View)
 	}
 	m.updated[schemaId] = view
+	if m.views != nil {
+		m.views[schemaId] = view
+	}
 	return nil
 }
 
@@ -62,7 +290,11 @@ func (m *mockSchemaProvider) UploadView(context.Context, models.View) (int64, er
 }
 
 func (m *mockSchemaProvider) ListViews(context.Context) ([]models.SchemaInfo, error) {
-	return nil, nil
+	var views []models.SchemaInfo
+	for id, view := range m.views {
+		views = append(views, models.SchemaInfo{ID: int64(id), Name: view.Name})
+	}
+	return views, nil
 }
 
 func (m *mockSchemaProvider) ListTopics(context.Context) ([]string, error) { return nil, nil }
@@ -198,6 +430,7 @@ func TestCheckColumnInTables_RenameFromOLTP(t *testing.T) {
 		log:                     getTestLogger(),
 		SchemaProvider:          schemaProvider,
 		RenameSuggestionStorage: schemaProvider,
+		PendingEventStorage:     schemaProvider,
 		ColumnMismatchStorage:   schemaProvider,
 		DWHProvider:             dwh,
 		OLTPFactory:             factory,
@@ -251,6 +484,7 @@ func TestCheckColumnInTables_SetDeletedWhenMissingInOLTP(t *testing.T) {
 		log:                     getTestLogger(),
 		SchemaProvider:          schemaProvider,
 		RenameSuggestionStorage: schemaProvider,
+		PendingEventStorage:     schemaProvider,
 		ColumnMismatchStorage:   schemaProvider,
 		DWHProvider:             dwh,
 		OLTPFactory:             factory,
@@ -302,6 +536,7 @@ func TestCheckColumnInTables_IgnoresOtherTablesColumns(t *testing.T) {
 		log:                     getTestLogger(),
 		SchemaProvider:          schemaProvider,
 		RenameSuggestionStorage: schemaProvider,
+		PendingEventStorage:     schemaProvider,
 		ColumnMismatchStorage:   schemaProvider,
 		DWHProvider:             dwh,
 		OLTPFactory:             factory,
@@ -323,3 +558,76 @@ func columnNames(view models.View) []string {
 	}
 	return names
 }
+
+func filteredOrdersView() models.View {
+	return models.View{
+		Name: "orders_view",
+		Sources: []models.Source{{
+			Name: "db1",
+			Schemas: []models.Schema{{
+				Name: "public",
+				Tables: []models.Table{{
+					Name: "orders",
+					Columns: []models.Column{
+						{Name: "id", IsUpdateKey: true},
+						{Name: "status"},
+					},
+					Filter: &models.RowFilter{Column: "status", Operator: models.FilterNotEq, Value: "draft"},
+				}},
+			}},
+		}},
+	}
+}
+
+func TestRemoveFilteredRow(t *testing.T) {
+	view := filteredOrdersView()
+	tables := eventTables(view, "db1", "public", "orders")
+	source := models.CDCSource{DB: "db1", Schema: "public", Table: "orders"}
+
+	cases := []struct {
+		name    string
+		evt     models.CDCEventData
+		deleted bool
+	}{
+		{
+			name:    "update leaves filter",
+			evt:     models.CDCEventData{Op: "u", Before: map[string]interface{}{"id": float64(1), "status": "paid"}, After: map[string]interface{}{"id": float64(1), "status": "draft"}, Source: source},
+			deleted: true,
+		},
+		{
+			name:    "update without before image",
+			evt:     models.CDCEventData{Op: "u", After: map[string]interface{}{"id": float64(1), "status": "draft"}, Source: source},
+			deleted: true,
+		},
+		{
+			name: "row was already filtered out",
+			evt:  models.CDCEventData{Op: "u", Before: map[string]interface{}{"id": float64(1), "status": "draft"}, After: map[string]interface{}{"id": float64(1), "status": "draft"}, Source: source},
+		},
+		{
+			name: "insert of filtered row",
+			evt:  models.CDCEventData{Op: "c", After: map[string]interface{}{"id": float64(1), "status": "draft"}, Source: source},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dwh := &mockDWH{}
+			svc := &AnalyticsDataCenterService{log: getTestLogger(), DWHProvider: dwh, DWHDbName: DbPostgres}
+
+			matched, filtered := svc.eventMatchesFilters(tables, tc.evt.After)
+			require.False(t, matched)
+			require.NoError(t, svc.removeFilteredRow(context.Background(), view, tables, tc.evt, filtered, getTestLogger().Logger))
+
+			if tc.deleted {
+				require.Len(t, dwh.rowDeleteCalls, 1)
+				require.Equal(t, map[string]interface{}{"id": float64(1)}, dwh.rowDeleteCalls[0].row)
+			} else {
+				require.Empty(t, dwh.rowDeleteCalls)
+			}
+		})
+	}
+
+	svc := &AnalyticsDataCenterService{log: getTestLogger(), DWHDbName: DbPostgres}
+	matched, _ := svc.eventMatchesFilters(tables, map[string]interface{}{"id": float64(1), "status": "paid"})
+	require.True(t, matched)
+}
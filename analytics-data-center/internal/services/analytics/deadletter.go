@@ -0,0 +1,430 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrDeadLetterSelectionEmpty — для массовой операции не заданы ни события, ни фильтр
+	ErrDeadLetterSelectionEmpty = errors.New("не заданы события dead letter")
+	// ErrDeadLetterReplayFailed — событие снова не удалось записать во view
+	ErrDeadLetterReplayFailed = errors.New("не удалось повторить событие dead letter")
+	// ErrDeadLetterBlocked — перед событием в dead letter есть более раннее событие той же строки
+	ErrDeadLetterBlocked = errors.New("событие ждёт более раннего события строки в dead letter")
+)
+
+// Значения повторов dead letter по умолчанию
+const (
+	defaultDeadLetterMaxAttempts = 5
+	defaultDeadLetterBackoff     = 30 * time.Second
+	defaultDeadLetterMaxBackoff  = 30 * time.Minute
+	defaultDeadLetterInterval    = 10 * time.Second
+	deadLetterRetryBatch         = 100
+)
+
+// DeadLetterOptions — параметры автоматических повторов событий, которые не удалось
+// записать во view. Нулевые поля заменяются значениями по умолчанию.
+type DeadLetterOptions struct {
+	// MaxAttempts — сколько всего попыток записать событие, включая первую
+	MaxAttempts int
+	// Backoff — пауза перед первым повтором; каждая следующая вдвое больше
+	Backoff time.Duration
+	// MaxBackoff — предел паузы между повторами
+	MaxBackoff time.Duration
+	// Interval — как часто проверяются события, время повтора которых наступило
+	Interval time.Duration
+}
+
+// SetDeadLetterOptions задаёт параметры повторов; вызывается до StartDeadLetterRetries.
+func (a *AnalyticsDataCenterService) SetDeadLetterOptions(opts DeadLetterOptions) {
+	a.deadLetterOpts = opts
+}
+
+func (a *AnalyticsDataCenterService) deadLetterOptions() DeadLetterOptions {
+	opts := a.deadLetterOpts
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultDeadLetterMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultDeadLetterBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultDeadLetterMaxBackoff
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultDeadLetterInterval
+	}
+	return opts
+}
+
+// viewEventError — ошибка записи CDC-события в конкретную view
+type viewEventError struct {
+	viewID int64
+	err    error
+}
+
+func (e *viewEventError) Error() string { return e.err.Error() }
+func (e *viewEventError) Unwrap() error { return e.err }
+
+func viewEventFailed(ev eventView, err error) error {
+	return &viewEventError{viewID: int64(ev.id), err: err}
+}
+
+// failedView возвращает view, на которой упало событие; nil — ошибка до записи во view
+func failedView(err error) *int64 {
+	var viewErr *viewEventError
+	if errors.As(err, &viewErr) {
+		id := viewErr.viewID
+		return &id
+	}
+	return nil
+}
+
+// isTransientError определяет ошибки, которые могут пройти сами: обрыв соединения,
+// таймаут, конфликт транзакций, перегрузка или перезапуск базы
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{
+		"connection refused",
+		"connection reset",
+		"broken pipe",
+		"timeout",
+		"deadlock detected",
+		"could not serialize access",
+		"too many connections",
+		"the database system is starting up",
+		"the database system is shutting down",
+		"terminating connection",
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// deadLetterBackoff — пауза перед повтором после attempts неудачных попыток
+func deadLetterBackoff(opts DeadLetterOptions, attempts int) time.Duration {
+	backoff := opts.Backoff
+	for i := 1; i < attempts && backoff < opts.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > opts.MaxBackoff {
+		backoff = opts.MaxBackoff
+	}
+	return backoff
+}
+
+// markAttemptFailed записывает в событие итог неудачной попытки: временную ошибку
+// повторяют, пока не исчерпаны попытки, остальные ждут решения оператора
+func (a *AnalyticsDataCenterService) markAttemptFailed(event *models.DeadLetterEvent, cause error, now time.Time) {
+	opts := a.deadLetterOptions()
+	event.Attempts++
+	event.Error = cause.Error()
+	if view := failedView(cause); view != nil {
+		event.ViewID = view
+	}
+	if isTransientError(cause) && event.Attempts < opts.MaxAttempts {
+		next := now.Add(deadLetterBackoff(opts, event.Attempts))
+		event.Status = models.DeadLetterRetrying
+		event.NextRetryAt = &next
+		return
+	}
+	event.Status = models.DeadLetterFailed
+	event.NextRetryAt = nil
+}
+
+// deadLetter сохраняет событие, которое не удалось записать во view. После сохранения
+// offset события можно коммитить: событие больше не потеряется.
+func (a *AnalyticsDataCenterService) deadLetter(ctx context.Context, evt models.CDCEvent, cause error) error {
+	const op = "analytics.deadLetter"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("table", evt.Data.Source.Table),
+	)
+
+	payload := evt.Payload
+	if len(payload) == 0 {
+		data, err := json.Marshal(evt.Data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		payload = data
+	}
+	event := models.DeadLetterEvent{
+		DatabaseName: evt.Data.Source.DB,
+		SchemaName:   evt.Data.Source.Schema,
+		TableName:    evt.Data.Source.Table,
+		RowKey:       string(evt.Key),
+		Op:           evt.Data.Op,
+		Payload:      payload,
+	}
+	a.markAttemptFailed(&event, cause, time.Now())
+
+	id, err := a.DeadLetterStorage.SaveDeadLetter(ctx, event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Warn("событие сохранено в dead letter",
+		slog.Int64("id", id),
+		slog.String("status", event.Status),
+		slog.String("error", event.Error),
+	)
+	return nil
+}
+
+// holdBehindDeadLetter сохраняет событие в dead letter без попытки записи, если там уже
+// есть событие той же строки: применённое раньше него, более новое изменение затёрлось
+// бы при повторе. Событие ждёт своей очереди и повторяется после более раннего.
+// События без ключа строки не задерживаются.
+func (a *AnalyticsDataCenterService) holdBehindDeadLetter(ctx context.Context, evt models.CDCEvent) (bool, error) {
+	const op = "analytics.holdBehindDeadLetter"
+	if len(evt.Key) == 0 {
+		return false, nil
+	}
+	src := evt.Data.Source
+	held, err := a.DeadLetterStorage.HasDeadLetters(ctx, src.DB, src.Schema, src.Table, string(evt.Key), 0)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if !held {
+		return false, nil
+	}
+	if err := a.holdDeadLetter(ctx, evt); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+// holdDeadLetter сохраняет событие в dead letter за более ранним событием той же строки.
+// Попыток записи у него ещё не было: оно повторяется, как только более раннее будет
+// записано или отброшено.
+func (a *AnalyticsDataCenterService) holdDeadLetter(ctx context.Context, evt models.CDCEvent) error {
+	const op = "analytics.holdDeadLetter"
+	payload := evt.Payload
+	if len(payload) == 0 {
+		data, err := json.Marshal(evt.Data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		payload = data
+	}
+	now := time.Now()
+	src := evt.Data.Source
+	id, err := a.DeadLetterStorage.SaveDeadLetter(ctx, models.DeadLetterEvent{
+		DatabaseName: src.DB,
+		SchemaName:   src.Schema,
+		TableName:    src.Table,
+		RowKey:       string(evt.Key),
+		Op:           evt.Data.Op,
+		Payload:      payload,
+		Error:        ErrDeadLetterBlocked.Error(),
+		Status:       models.DeadLetterRetrying,
+		NextRetryAt:  &now,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.log.Warn("событие строки ждёт в dead letter за более ранним",
+		slog.String("op", op),
+		slog.String("table", src.Table),
+		slog.Int64("id", id),
+	)
+	return nil
+}
+
+// StartDeadLetterRetries запускает фоновый повтор событий dead letter с временными ошибками.
+func (a *AnalyticsDataCenterService) StartDeadLetterRetries() {
+	go func() {
+		ticker := time.NewTicker(a.deadLetterOptions().Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.retryDeadLetters(context.Background(), time.Now())
+		}
+	}()
+}
+
+// retryDeadLetters повторяет события, время повтора которых наступило, и возвращает
+// число записанных во view
+func (a *AnalyticsDataCenterService) retryDeadLetters(ctx context.Context, now time.Time) int {
+	const op = "analytics.retryDeadLetters"
+	log := a.log.With(slog.String("op", op))
+
+	events, err := a.DeadLetterStorage.ListDueDeadLetters(ctx, now, deadLetterRetryBatch)
+	if err != nil {
+		log.Error("не удалось получить события для повтора", slog.String("error", err.Error()))
+		return 0
+	}
+	replayed := 0
+	for _, event := range events {
+		if err := a.replayDeadLetter(ctx, event, now); err == nil {
+			replayed++
+		}
+	}
+	if len(events) > 0 {
+		log.Info("повтор событий dead letter",
+			slog.Int("events", len(events)),
+			slog.Int("replayed", replayed),
+		)
+	}
+	return replayed
+}
+
+// replayDeadLetter снова записывает событие во view. Записанное событие удаляется,
+// иначе сохраняется итог попытки. Событие повторяется целиком: запись по ключам
+// обновления не создаёт дублей в view, где оно уже было записано. Пока в dead letter
+// есть более раннее событие той же строки, повтор не выполняется: более новые события
+// строки не применяются, пока ждут в dead letter, поэтому повтор их не затрёт.
+func (a *AnalyticsDataCenterService) replayDeadLetter(ctx context.Context, event models.DeadLetterEvent, now time.Time) error {
+	const op = "analytics.replayDeadLetter"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("id", event.ID),
+	)
+
+	if event.RowKey != "" {
+		blocked, err := a.DeadLetterStorage.HasDeadLetters(ctx, event.DatabaseName, event.SchemaName, event.TableName, event.RowKey, event.ID)
+		if err != nil {
+			log.Error("не удалось проверить более ранние события строки", slog.String("error", err.Error()))
+			return err
+		}
+		if blocked {
+			log.Info("перед событием есть более раннее событие строки, повтор отложен")
+			return ErrDeadLetterBlocked
+		}
+	}
+
+	var data models.CDCEventData
+	cause := json.Unmarshal(event.Payload, &data)
+	if cause == nil {
+		cause = a.handlerCDCFunc(ctx, models.CDCEvent{Data: data, Payload: event.Payload})
+	}
+	if cause == nil {
+		if _, err := a.DeadLetterStorage.DeleteDeadLetters(ctx, []int64{event.ID}); err != nil {
+			log.Error("событие повторено, но не удалено из dead letter", slog.String("error", err.Error()))
+			return err
+		}
+		log.Info("событие dead letter записано во view")
+		return nil
+	}
+
+	a.markAttemptFailed(&event, cause, now)
+	if err := a.DeadLetterStorage.UpdateDeadLetter(ctx, event); err != nil {
+		log.Error("не удалось сохранить итог повтора", slog.String("error", err.Error()))
+	}
+	log.Warn("повтор события dead letter не удался",
+		slog.Int("attempts", event.Attempts),
+		slog.String("status", event.Status),
+		slog.String("error", cause.Error()),
+	)
+	return fmt.Errorf("%w: %v", ErrDeadLetterReplayFailed, cause)
+}
+
+func (a *AnalyticsDataCenterService) ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetterEvent, error) {
+	events, err := a.DeadLetterStorage.ListDeadLetters(ctx, filter)
+	if err != nil {
+		a.log.Error("ошибка получения событий dead letter", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return events, nil
+}
+
+func (a *AnalyticsDataCenterService) GetDeadLetter(ctx context.Context, id int64) (models.DeadLetterEvent, error) {
+	event, err := a.DeadLetterStorage.GetDeadLetter(ctx, id)
+	if err != nil {
+		if !errors.Is(err, storage.ErrDeadLetterNotFound) {
+			a.log.Error("ошибка получения события dead letter", slog.String("error", err.Error()))
+		}
+		return models.DeadLetterEvent{}, err
+	}
+	return event, nil
+}
+
+// ReplayDeadLetter повторяет событие по запросу оператора, не дожидаясь его времени повтора
+func (a *AnalyticsDataCenterService) ReplayDeadLetter(ctx context.Context, id int64) error {
+	event, err := a.GetDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	return a.replayDeadLetter(ctx, event, time.Now())
+}
+
+// DiscardDeadLetter удаляет событие без записи во view
+func (a *AnalyticsDataCenterService) DiscardDeadLetter(ctx context.Context, id int64) error {
+	deleted, err := a.DeadLetterStorage.DeleteDeadLetters(ctx, []int64{id})
+	if err != nil {
+		a.log.Error("ошибка удаления события dead letter", slog.String("error", err.Error()))
+		return err
+	}
+	if deleted == 0 {
+		return storage.ErrDeadLetterNotFound
+	}
+	a.log.Info("событие dead letter отброшено", slog.Int64("id", id))
+	return nil
+}
+
+// ReplayDeadLetters повторяет выбранные события по порядку их появления
+func (a *AnalyticsDataCenterService) ReplayDeadLetters(ctx context.Context, selection models.DeadLetterSelection) ([]models.DeadLetterReplayResult, error) {
+	events, err := a.selectDeadLetters(ctx, selection)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	results := make([]models.DeadLetterReplayResult, 0, len(events))
+	// список отсортирован от новых к старым, а повторять нужно в порядке изменений источника
+	for i := len(events) - 1; i >= 0; i-- {
+		result := models.DeadLetterReplayResult{ID: events[i].ID, Replayed: true}
+		if err := a.replayDeadLetter(ctx, events[i], now); err != nil {
+			result.Replayed = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// DiscardDeadLetters удаляет выбранные события и возвращает число удалённых
+func (a *AnalyticsDataCenterService) DiscardDeadLetters(ctx context.Context, selection models.DeadLetterSelection) (int64, error) {
+	events, err := a.selectDeadLetters(ctx, selection)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	deleted, err := a.DeadLetterStorage.DeleteDeadLetters(ctx, ids)
+	if err != nil {
+		a.log.Error("ошибка удаления событий dead letter", slog.String("error", err.Error()))
+		return 0, err
+	}
+	a.log.Info("события dead letter отброшены", slog.Int64("events", deleted))
+	return deleted, nil
+}
+
+func (a *AnalyticsDataCenterService) selectDeadLetters(ctx context.Context, selection models.DeadLetterSelection) ([]models.DeadLetterEvent, error) {
+	if selection.Empty() {
+		return nil, ErrDeadLetterSelectionEmpty
+	}
+	return a.ListDeadLetters(ctx, selection.Filter())
+}
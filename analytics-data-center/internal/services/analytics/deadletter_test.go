@@ -0,0 +1,176 @@
+package serviceanalytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetter_TransientErrorRetriedWithBackoff(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}, upsertErr: errors.New("dial tcp 10.0.0.5:5432: connect: connection refused")}
+	svc, _ := newHandoffTestService(dwh, &mockTaskService{})
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+
+	evt := usersEvent(1, 90, 12)
+	evt.Payload = []byte(`{"op":"u","after":{"id":1,"name":"user"},"source":{"db":"db1","schema":"public","table":"users"}}`)
+	svc.applyCDCEvent(context.Background(), evt)
+
+	require.Len(t, schemas.deadLetters, 1)
+	saved := schemas.deadLetters[0]
+	require.Equal(t, models.DeadLetterRetrying, saved.Status)
+	require.Equal(t, 1, saved.Attempts)
+	require.Equal(t, int64(1), *saved.ViewID)
+	require.Equal(t, "users", saved.TableName)
+	require.JSONEq(t, string(evt.Payload), string(saved.Payload))
+	require.WithinDuration(t, time.Now().Add(defaultDeadLetterBackoff), *saved.NextRetryAt, 5*time.Second)
+
+	// до времени повтора событие не трогается
+	now := time.Now()
+	require.Zero(t, svc.retryDeadLetters(context.Background(), now))
+	require.Len(t, dwh.upsertCalls, 1)
+
+	// неудачный повтор удваивает паузу
+	now = saved.NextRetryAt.Add(time.Second)
+	require.Zero(t, svc.retryDeadLetters(context.Background(), now))
+	require.Equal(t, 2, schemas.deadLetters[0].Attempts)
+	require.Equal(t, now.Add(2*defaultDeadLetterBackoff), *schemas.deadLetters[0].NextRetryAt)
+
+	// после восстановления DWH событие записывается и удаляется
+	dwh.upsertErr = nil
+	require.Equal(t, 1, svc.retryDeadLetters(context.Background(), now.Add(time.Hour)))
+	require.Empty(t, schemas.deadLetters)
+	require.Equal(t, []interface{}{1, float64(1), float64(1)}, upsertedIDs(dwh))
+}
+
+func TestDeadLetter_PermanentOrExhaustedErrorWaitsForOperator(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}, upsertErr: errors.New(`column "name" is of type integer`)}
+	svc, _ := newHandoffTestService(dwh, &mockTaskService{})
+	svc.SetDeadLetterOptions(DeadLetterOptions{MaxAttempts: 2})
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+
+	svc.applyCDCEvent(context.Background(), usersEvent(1, 90, 12))
+	require.Equal(t, models.DeadLetterFailed, schemas.deadLetters[0].Status)
+	require.Nil(t, schemas.deadLetters[0].NextRetryAt)
+
+	// временная ошибка тоже ждёт оператора, когда попытки исчерпаны
+	dwh.upsertErr = errors.New("deadlock detected")
+	svc.applyCDCEvent(context.Background(), usersEvent(2, 91, 13))
+	require.Equal(t, models.DeadLetterRetrying, schemas.deadLetters[1].Status)
+	svc.retryDeadLetters(context.Background(), time.Now().Add(time.Hour))
+	require.Equal(t, models.DeadLetterFailed, schemas.deadLetters[1].Status)
+	require.Equal(t, 2, schemas.deadLetters[1].Attempts)
+
+	err := svc.ReplayDeadLetter(context.Background(), 1)
+	require.ErrorIs(t, err, ErrDeadLetterReplayFailed)
+	require.Equal(t, 2, schemas.deadLetters[0].Attempts)
+
+	dwh.upsertErr = nil
+	require.NoError(t, svc.ReplayDeadLetter(context.Background(), 1))
+	require.ErrorIs(t, svc.ReplayDeadLetter(context.Background(), 1), storage.ErrDeadLetterNotFound)
+	require.NoError(t, svc.DiscardDeadLetter(context.Background(), 2))
+	require.ErrorIs(t, svc.DiscardDeadLetter(context.Background(), 2), storage.ErrDeadLetterNotFound)
+	require.Empty(t, schemas.deadLetters)
+}
+
+func TestDeadLetter_LaterEventsOfRowWaitBehindIt(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}, upsertErr: errors.New("connection reset by peer")}
+	svc, _ := newHandoffTestService(dwh, &mockTaskService{})
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+	rowEvent := func(id int, name string, lsn int64) models.CDCEvent {
+		evt := usersEvent(id, lsn, 12)
+		evt.Key = []byte(fmt.Sprintf(`{"id":%d}`, id))
+		evt.Data.After["name"] = name
+		return evt
+	}
+
+	svc.applyCDCEvent(context.Background(), rowEvent(1, "old", 90))
+	require.Len(t, schemas.deadLetters, 1)
+	require.Equal(t, `{"id":1}`, schemas.deadLetters[0].RowKey)
+
+	// DWH восстановился, но более новое изменение строки не применяется раньше старого
+	dwh.upsertErr = nil
+	dwh.upsertCalls = nil
+	svc.applyCDCEvent(context.Background(), rowEvent(1, "new", 95))
+	svc.applyCDCEvent(context.Background(), rowEvent(2, "other", 96))
+	require.Len(t, schemas.deadLetters, 2)
+	held := schemas.deadLetters[1]
+	require.Equal(t, models.DeadLetterRetrying, held.Status)
+	require.Zero(t, held.Attempts)
+	require.Equal(t, []interface{}{2}, upsertedIDs(dwh))
+
+	// оператор не может повторить событие раньше предыдущего
+	require.ErrorIs(t, svc.ReplayDeadLetter(context.Background(), held.ID), ErrDeadLetterBlocked)
+	require.Zero(t, schemas.deadLetters[1].Attempts)
+
+	// повторы идут в порядке изменений строки: сначала старое, затем новое
+	later := time.Now().Add(time.Hour)
+	require.Equal(t, 1, svc.retryDeadLetters(context.Background(), later))
+	require.Equal(t, 1, svc.retryDeadLetters(context.Background(), later))
+	require.Empty(t, schemas.deadLetters)
+	var names []interface{}
+	for _, call := range dwh.upsertCalls[1:] {
+		names = append(names, call.row["name"])
+	}
+	require.Equal(t, []interface{}{"old", "new"}, names)
+
+	// после разбора dead letter события строки применяются сразу
+	svc.applyCDCEvent(context.Background(), rowEvent(1, "newest", 100))
+	require.Empty(t, schemas.deadLetters)
+	require.Equal(t, "newest", dwh.upsertCalls[len(dwh.upsertCalls)-1].row["name"])
+}
+
+func TestDeadLetter_BulkOperations(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}, upsertErr: errors.New("syntax error")}
+	svc, _ := newHandoffTestService(dwh, &mockTaskService{})
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+	for id := 1; id <= 3; id++ {
+		svc.applyCDCEvent(context.Background(), usersEvent(id, int64(90+id), 12))
+	}
+	require.Len(t, schemas.deadLetters, 3)
+
+	_, err := svc.ReplayDeadLetters(context.Background(), models.DeadLetterSelection{})
+	require.ErrorIs(t, err, ErrDeadLetterSelectionEmpty)
+	_, err = svc.DiscardDeadLetters(context.Background(), models.DeadLetterSelection{})
+	require.ErrorIs(t, err, ErrDeadLetterSelectionEmpty)
+
+	// события повторяются в порядке появления
+	dwh.upsertCalls = nil
+	dwh.upsertErr = nil
+	status := models.DeadLetterFailed
+	results, err := svc.ReplayDeadLetters(context.Background(), models.DeadLetterSelection{Status: &status})
+	require.NoError(t, err)
+	require.Equal(t, []models.DeadLetterReplayResult{
+		{ID: 1, Replayed: true}, {ID: 2, Replayed: true}, {ID: 3, Replayed: true},
+	}, results)
+	require.Equal(t, []interface{}{float64(1), float64(2), float64(3)}, upsertedIDs(dwh))
+	require.Empty(t, schemas.deadLetters)
+
+	dwh.upsertErr = errors.New("syntax error")
+	svc.applyCDCEvent(context.Background(), usersEvent(4, 95, 12))
+	viewID := int64(1)
+	discarded, err := svc.DiscardDeadLetters(context.Background(), models.DeadLetterSelection{ViewID: &viewID})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), discarded)
+	require.Empty(t, schemas.deadLetters)
+}
+
+func TestIsTransientError(t *testing.T) {
+	require.True(t, isTransientError(context.DeadlineExceeded))
+	require.True(t, isTransientError(errors.New("read tcp: connection reset by peer")))
+	require.True(t, isTransientError(errors.New("pq: could not serialize access due to concurrent update")))
+	require.True(t, isTransientError(viewEventFailed(eventView{id: 1}, errors.New("pq: sorry, too many connections"))))
+	require.False(t, isTransientError(errors.New(`pq: column "x" does not exist`)))
+	require.False(t, isTransientError(nil))
+
+	opts := DeadLetterOptions{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	require.Equal(t, time.Second, deadLetterBackoff(opts, 1))
+	require.Equal(t, 4*time.Second, deadLetterBackoff(opts, 3))
+	require.Equal(t, 5*time.Second, deadLetterBackoff(opts, 10))
+}
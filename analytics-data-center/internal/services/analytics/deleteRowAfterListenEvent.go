@@ -0,0 +1,156 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+func (a *AnalyticsDataCenterService) deleteRowAfterListenEventInDWH(ctx context.Context, evtData models.CDCEventData) error {
+	const op = "deleteRowAfterListenEventInDWH"
+	log := a.log.With(slog.String("op", op))
+
+	before := evtData.Before
+	databaseEvt := evtData.Source.DB
+	schemaEvt := evtData.Source.Schema
+	tableEvt := evtData.Source.Table
+
+	log.Info("Начинаю удаление строки", slog.String("table", tableEvt))
+
+	if len(before) == 0 {
+		log.Warn("событие удаления без before-образа, удаление пропущено",
+			slog.String("table", tableEvt))
+		return nil
+	}
+
+	schemaIds, err := a.SchemaProvider.GetSchems(ctx, databaseEvt, schemaEvt, tableEvt)
+	if err != nil {
+		log.Error("ошибка получения схемы", slog.Any("ошибка", err))
+		return err
+	}
+
+	schems, err := a.loadEventViews(ctx, schemaIds)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// view на загрузке получат событие после публикации новой таблицы
+	schems = a.routeEventViews(ctx, schems, evtData)
+	// удаление не должно обогнать вставки строки, накопленные в пачке воркера
+	a.flushCDCBatch(ctx)
+
+	for _, schema := range schems {
+		viewName := schema.view.Name
+
+		// удаление не должно обогнать вставки, отложенные до решения по переименованию
+		deferred, err := a.deferViewEvent(ctx, schema, evtData)
+		if err != nil {
+			return viewEventFailed(schema, err)
+		}
+		if deferred {
+			continue
+		}
+
+		// удаление строки присоединённой таблицы меняет состав строк view,
+		// поэтому такие строки пересобираются по графу джоинов
+		if isJoinedChild(schema.view, evtData) {
+			if err := a.refreshJoinedViewRow(ctx, schema, evtData); err != nil {
+				return viewEventFailed(schema, err)
+			}
+			continue
+		}
+
+		keys := make(map[string]interface{})
+		for _, table := range eventTables(schema.view, databaseEvt, schemaEvt, tableEvt) {
+			for k, v := range a.updateKeyValues(table, before, log.Logger) {
+				keys[k] = v
+			}
+		}
+
+		if len(keys) == 0 {
+			log.Warn("для view не заданы ключи обновления или их нет в before-образе, удаление пропущено",
+				slog.String("view", viewName))
+			continue
+		}
+
+		if err := a.removeViewRow(ctx, schema.view, keys, log.Logger); err != nil {
+			return viewEventFailed(schema, err)
+		}
+	}
+
+	return nil
+}
+
+// removeViewRow удаляет строку view по ключам с учётом DeletePolicy
+func (a *AnalyticsDataCenterService) removeViewRow(ctx context.Context, view models.View, keys map[string]interface{}, log *slog.Logger) error {
+	var err error
+	if view.IsSoftDelete() {
+		err = a.DWHProvider.SoftDeleteRow(ctx, view.Name, keys)
+	} else {
+		err = a.DWHProvider.DeleteRow(ctx, view.Name, keys)
+	}
+	if err != nil {
+		if isRelationDoesNotExist(err) {
+			log.Warn("таблица отсутствует в DWH, пропускаю удаление",
+				slog.String("view", view.Name))
+			return nil
+		}
+		log.Error("ошибка удаления строки",
+			slog.String("error", err.Error()),
+			slog.String("view", view.Name))
+		return err
+	}
+	log.Info("строка удалена из view",
+		slog.String("view", view.Name),
+		slog.Bool("soft", view.IsSoftDelete()))
+	return nil
+}
+
+// eventTables возвращает описания таблицы события во view (обычно одно)
+func eventTables(view models.View, databaseEvt, schemaEvt, tableEvt string) []models.Table {
+	var tables []models.Table
+	for _, source := range view.Sources {
+		if source.Name != databaseEvt {
+			continue
+		}
+		for _, sch := range source.Schemas {
+			if sch.Name != schemaEvt {
+				continue
+			}
+			for _, table := range sch.Tables {
+				if table.Name == tableEvt {
+					tables = append(tables, table)
+				}
+			}
+		}
+	}
+	return tables
+}
+
+// updateKeyValues собирает значения ключей обновления из образа строки
+// в именах колонок view (алиас и ViewKey учитываются так же, как при вставке)
+func (a *AnalyticsDataCenterService) updateKeyValues(table models.Table, image map[string]interface{}, log *slog.Logger) map[string]interface{} {
+	keys := make(map[string]interface{})
+	for _, column := range table.Columns {
+		if !column.IsUpdateKey {
+			continue
+		}
+		val, ok := image[column.Name]
+		if !ok {
+			continue
+		}
+		if a.DWHDbName == DbPostgres && isTimeColumn(column) {
+			val = convertDebeziumTemporal(column, val, log)
+		}
+
+		targetColumnName := column.Name
+		if column.Alias != "" {
+			targetColumnName = column.Alias
+		}
+		keys[targetColumnName] = val
+		if column.ViewKey != "" && column.ViewKey != targetColumnName {
+			keys[column.ViewKey] = val
+		}
+	}
+	return keys
+}
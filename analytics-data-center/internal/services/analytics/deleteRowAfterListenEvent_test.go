@@ -0,0 +1,78 @@
+package serviceanalytics
+
+import (
+	"context"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func deleteTestView(policy string) models.View {
+	return models.View{
+		Name:         "users_view",
+		DeletePolicy: policy,
+		Sources: []models.Source{{
+			Name: "db1",
+			Schemas: []models.Schema{{
+				Name: "public",
+				Tables: []models.Table{{
+					Name: "users",
+					Columns: []models.Column{
+						{Name: "id", Alias: "user_id", IsUpdateKey: true},
+						{Name: "name"},
+					},
+				}},
+			}},
+		}},
+	}
+}
+
+func deleteTestEvent() models.CDCEventData {
+	return models.CDCEventData{
+		Op:     "d",
+		Before: map[string]interface{}{"id": float64(7), "name": "bob"},
+		Source: models.CDCSource{DB: "db1", Schema: "public", Table: "users"},
+	}
+}
+
+func TestDeleteRowAfterListenEvent_HardDelete(t *testing.T) {
+	schemaProvider := &mockSchemaProvider{views: map[int]models.View{1: deleteTestView("")}, schems: []int{1}}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{log: getTestLogger(), SchemaProvider: schemaProvider, RenameSuggestionStorage: schemaProvider, PendingEventStorage: schemaProvider, DWHProvider: dwh, DWHDbName: DbPostgres}
+
+	err := svc.deleteRowAfterListenEventInDWH(context.Background(), deleteTestEvent())
+
+	require.NoError(t, err)
+	require.Len(t, dwh.rowDeleteCalls, 1)
+	require.Empty(t, dwh.softDeleteCalls)
+	require.Equal(t, "users_view", dwh.rowDeleteCalls[0].table)
+	require.Equal(t, map[string]interface{}{"user_id": float64(7)}, dwh.rowDeleteCalls[0].row)
+}
+
+func TestDeleteRowAfterListenEvent_SoftDelete(t *testing.T) {
+	schemaProvider := &mockSchemaProvider{views: map[int]models.View{1: deleteTestView(models.DeletePolicySoft)}, schems: []int{1}}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{log: getTestLogger(), SchemaProvider: schemaProvider, RenameSuggestionStorage: schemaProvider, PendingEventStorage: schemaProvider, DWHProvider: dwh, DWHDbName: DbPostgres}
+
+	err := svc.deleteRowAfterListenEventInDWH(context.Background(), deleteTestEvent())
+
+	require.NoError(t, err)
+	require.Empty(t, dwh.rowDeleteCalls)
+	require.Len(t, dwh.softDeleteCalls, 1)
+	require.Equal(t, map[string]interface{}{"user_id": float64(7)}, dwh.softDeleteCalls[0].row)
+}
+
+func TestDeleteRowAfterListenEvent_SkipsViewWithoutKeys(t *testing.T) {
+	view := deleteTestView("")
+	view.Sources[0].Schemas[0].Tables[0].Columns[0].IsUpdateKey = false
+	schemaProvider := &mockSchemaProvider{views: map[int]models.View{1: view}, schems: []int{1}}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{log: getTestLogger(), SchemaProvider: schemaProvider, RenameSuggestionStorage: schemaProvider, PendingEventStorage: schemaProvider, DWHProvider: dwh, DWHDbName: DbPostgres}
+
+	err := svc.deleteRowAfterListenEventInDWH(context.Background(), deleteTestEvent())
+
+	require.NoError(t, err)
+	require.Empty(t, dwh.rowDeleteCalls)
+}
@@ -0,0 +1,89 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"hash/fnv"
+)
+
+// Значения пула CDC-воркеров по умолчанию
+const (
+	defaultEventWorkers   = 4
+	defaultEventQueueSize = 100
+	defaultHandoffBuffer  = 100_000
+)
+
+// EventWorkerOptions — параметры пула воркеров, применяющих CDC-события к view. Нулевые
+// поля заменяются значениями по умолчанию.
+type EventWorkerOptions struct {
+	// Workers — сколько событий применяется одновременно
+	Workers int
+	// QueueSize — сколько событий может ждать в очереди каждого воркера
+	QueueSize int
+	// HandoffBuffer — сколько событий копит передача view, пока view загружается или
+	// ждёт продолжения загрузки
+	HandoffBuffer int
+}
+
+// SetEventWorkerOptions задаёт параметры пула CDC-воркеров; вызывается до StartEventWorkers.
+func (a *AnalyticsDataCenterService) SetEventWorkerOptions(opts EventWorkerOptions) {
+	a.eventWorkerOpts = opts
+}
+
+func (a *AnalyticsDataCenterService) eventWorkerOptions() EventWorkerOptions {
+	opts := a.eventWorkerOpts
+	if opts.Workers <= 0 {
+		opts.Workers = defaultEventWorkers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultEventQueueSize
+	}
+	if opts.HandoffBuffer <= 0 {
+		opts.HandoffBuffer = defaultHandoffBuffer
+	}
+	return opts
+}
+
+// StartEventWorkers запускает пул CDC-воркеров. У каждого воркера своя очередь: события
+// одной строки всегда попадают к одному воркеру и применяются по порядку, события разных
+// строк и таблиц применяются параллельно.
+func (a *AnalyticsDataCenterService) StartEventWorkers() {
+	opts := a.eventWorkerOptions()
+	queues := make([]chan models.CDCEvent, opts.Workers)
+	for i := range queues {
+		queues[i] = make(chan models.CDCEvent, opts.QueueSize)
+		go a.eventWorker(queues[i])
+	}
+	a.eventQueues = queues
+}
+
+// EventQueueLoad — заполненность самой загруженной очереди CDC-воркеров или буфера
+// передачи загружаемой view от 0 до 1. По ней слушатель Kafka приостанавливает чтение,
+// пока воркеры не разберут очередь, а загрузка не закончится.
+func (a *AnalyticsDataCenterService) EventQueueLoad() float64 {
+	load := a.handoffLoad()
+	for _, queue := range a.eventQueues {
+		if c := cap(queue); c > 0 {
+			if l := float64(len(queue)) / float64(c); l > load {
+				load = l
+			}
+		}
+	}
+	return load
+}
+
+// eventWorkerIndex выбирает воркер события по таблице источника и ключу строки —
+// ключу сообщения Kafka, в котором Debezium передаёт первичный ключ. События без
+// ключа идут к воркеру таблицы. Строку view с джоинами меняют события разных таблиц,
+// попадающие к разным воркерам; их пересборку упорядочивает refreshJoinedView.
+func eventWorkerIndex(evt models.CDCEvent, workers int) int {
+	h := fnv.New32a()
+	src := evt.Data.Source
+	h.Write([]byte(src.DB))
+	h.Write([]byte{0})
+	h.Write([]byte(src.Schema))
+	h.Write([]byte{0})
+	h.Write([]byte(src.Table))
+	h.Write([]byte{0})
+	h.Write(evt.Key)
+	return int(h.Sum32() % uint32(workers))
+}
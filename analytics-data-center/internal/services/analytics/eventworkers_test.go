@@ -0,0 +1,91 @@
+package serviceanalytics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func keyedEvent(table string, key string) models.CDCEvent {
+	evt := models.CDCEvent{Data: models.CDCEventData{Source: models.CDCSource{DB: "db1", Schema: "public", Table: table}}}
+	if key != "" {
+		evt.Key = []byte(key)
+	}
+	return evt
+}
+
+func TestEventWorkerIndex_RoutesByTableAndKey(t *testing.T) {
+	const workers = 4
+
+	// события одной строки всегда у одного воркера
+	require.Equal(t, eventWorkerIndex(keyedEvent("users", `{"id":1}`), workers), eventWorkerIndex(keyedEvent("users", `{"id":1}`), workers))
+	// события без ключа идут к воркеру таблицы
+	require.Equal(t, eventWorkerIndex(keyedEvent("users", ""), workers), eventWorkerIndex(keyedEvent("users", ""), workers))
+
+	used := make(map[int]struct{})
+	for i := 0; i < 100; i++ {
+		idx := eventWorkerIndex(keyedEvent("users", fmt.Sprintf(`{"id":%d}`, i)), workers)
+		require.GreaterOrEqual(t, idx, 0)
+		require.Less(t, idx, workers)
+		used[idx] = struct{}{}
+	}
+	require.Len(t, used, workers)
+}
+
+func TestEventQueueLoad(t *testing.T) {
+	svc := &AnalyticsDataCenterService{log: getTestLogger()}
+	require.Zero(t, svc.EventQueueLoad())
+
+	svc.eventQueues = []chan models.CDCEvent{make(chan models.CDCEvent, 4), make(chan models.CDCEvent, 4)}
+	svc.eventQueues[1] <- models.CDCEvent{}
+	svc.eventQueues[1] <- models.CDCEvent{}
+	svc.eventQueues[0] <- models.CDCEvent{}
+
+	// важна самая загруженная очередь: её воркер задерживает чтение
+	require.Equal(t, 0.5, svc.EventQueueLoad())
+}
+
+func TestEventQueueLoad_CountsHandoffBuffer(t *testing.T) {
+	svc := &AnalyticsDataCenterService{log: getTestLogger()}
+	svc.SetEventWorkerOptions(EventWorkerOptions{HandoffBuffer: 4})
+	svc.handoffs = map[string]*viewHandoff{
+		"load":      {events: make([]models.CDCEventData, 3)},
+		"drain":     {events: make([]models.CDCEventData, 4), applied: 4},
+		"suspended": {events: make([]models.CDCEventData, 4), suspended: true},
+	}
+
+	// отложенные события загружаемой view держат подтверждения Kafka и задерживают чтение
+	require.Equal(t, 0.75, svc.EventQueueLoad())
+
+	svc.handoffs["load"].events = append(svc.handoffs["load"].events, models.CDCEventData{})
+	require.Equal(t, 1.0, svc.EventQueueLoad())
+}
+
+func TestEventPreprocessing_AppliesInWorkers(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}}
+	svc, _ := newHandoffTestService(dwh, &mockTaskService{})
+	svc.SetEventWorkerOptions(EventWorkerOptions{Workers: 1})
+	svc.StartEventWorkers()
+
+	acked := make(chan int, 3)
+	for id := 1; id <= 3; id++ {
+		evt := usersEvent(id, int64(90+id), int64(12+id))
+		evt.Key = []byte(fmt.Sprintf(`{"id":%d}`, id))
+		evt.Ack = func() { acked <- id }
+		svc.EventPreprocessing(evt)
+	}
+
+	for want := 1; want <= 3; want++ {
+		select {
+		case got := <-acked:
+			require.Equal(t, want, got)
+		case <-time.After(time.Second):
+			t.Fatal("событие не применено воркером")
+		}
+	}
+	require.Equal(t, []interface{}{1, 2, 3}, upsertedIDs(dwh))
+}
@@ -0,0 +1,284 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// handoffOutcome — чем закончилась загрузка view
+type handoffOutcome int
+
+const (
+	handoffPublished handoffOutcome = iota
+	handoffFailed
+	handoffCancelled
+)
+
+// viewHandoff — передача view от первичной загрузки к CDC. Пока задача загружает view,
+// CDC-события view не применяются к рабочей таблице, а копятся. После публикации новой
+// таблицы накопленные события применяются к ней по порядку, кроме уже попавших в
+// снимки загрузки.
+type viewHandoff struct {
+	taskID string
+	viewID int64
+	// positions — снимки источников, из которых загружена view; при продолжении
+	// задачи остаются снимки первого запуска
+	positions map[string]models.SnapshotPosition
+	// events — события view с начала загрузки; первые applied из них уже применены
+	// к текущей таблице view
+	events  []models.CDCEventData
+	applied int
+	// acks — подтверждения отложенных событий, по индексу в events; offset события
+	// коммитится только после его применения к таблице view
+	acks []func()
+	// suspended — загрузка упала и ждёт продолжения: таблица view прежняя, события
+	// применяются к ней сразу и сохраняются для будущей таблицы
+	suspended   bool
+	suspendedAt time.Time
+}
+
+// replayViewKey помечает контекст повторного применения отложенного события: оно
+// применяется только к указанной view
+type replayViewKey struct{}
+
+// beginHandoff начинает копить CDC-события view задачи. При продолжении задачи
+// возвращается передача упавшего запуска; continued=false — такой передачи нет, и
+// события между падением и продолжением не сохранились.
+func (a *AnalyticsDataCenterService) beginHandoff(taskID string, viewID int64, resume bool) (handoff *viewHandoff, continued bool) {
+	a.handoffMu.Lock()
+	defer a.handoffMu.Unlock()
+
+	a.expireHandoffs(time.Now())
+	if h, ok := a.handoffs[taskID]; ok && resume && h.suspended {
+		h.suspended = false
+		// новая таблица view собирается заново: ей нужны все сохранённые события
+		h.applied = 0
+		return h, true
+	}
+	if a.handoffs == nil {
+		a.handoffs = make(map[string]*viewHandoff)
+	}
+	h := &viewHandoff{
+		taskID:    taskID,
+		viewID:    viewID,
+		positions: make(map[string]models.SnapshotPosition),
+	}
+	a.handoffs[taskID] = h
+	return h, false
+}
+
+// expireHandoffs удаляет передачи упавших задач, которые уже нельзя продолжить;
+// вызывается под handoffMu
+func (a *AnalyticsDataCenterService) expireHandoffs(now time.Time) {
+	retention := a.tempTableJanitorOptions().ResumeRetention
+	for taskID, h := range a.handoffs {
+		if h.suspended && now.Sub(h.suspendedAt) > retention {
+			delete(a.handoffs, taskID)
+		}
+	}
+}
+
+// recordSnapshot запоминает снимок источника, из которого загружается view. Возвращает
+// false, если снимок источника уже записан прошлым запуском задачи.
+func (a *AnalyticsDataCenterService) recordSnapshot(h *viewHandoff, source string, position models.SnapshotPosition) bool {
+	if h == nil {
+		return true
+	}
+	a.handoffMu.Lock()
+	defer a.handoffMu.Unlock()
+	if _, ok := h.positions[source]; ok {
+		return false
+	}
+	h.positions[source] = position
+	return true
+}
+
+// routeEventViews оставляет view, к которым CDC-событие применяется сейчас
+func (a *AnalyticsDataCenterService) routeEventViews(ctx context.Context, views []eventView, evtData models.CDCEventData) []eventView {
+	routed := make([]eventView, 0, len(views))
+	for _, ev := range views {
+		if a.eventApplies(ctx, int64(ev.id), evtData) {
+			routed = append(routed, ev)
+		}
+	}
+	return routed
+}
+
+// eventApplies решает, применять ли CDC-событие к таблице view сейчас. Пока view
+// загружается, событие откладывается до публикации. Событие, уже попавшее в снимок,
+// из которого загружена текущая таблица view, пропускается. Коннектор Debezium не
+// привязан к снимку загрузки (snapshot.mode=never) и читает слот со своей позиции,
+// поэтому изменения на границе снимка приходят и до неё, и после, в том числе
+// повторно; отделяет их друг от друга только эта проверка.
+func (a *AnalyticsDataCenterService) eventApplies(ctx context.Context, viewID int64, evtData models.CDCEventData) bool {
+	const op = "analytics.eventApplies"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("view", viewID),
+		slog.Int64("lsn", evtData.Source.LSN),
+	)
+
+	a.handoffMu.Lock()
+	target, replay := ctx.Value(replayViewKey{}).(int64)
+	deferred := false
+	if !replay {
+		for taskID, h := range a.handoffs {
+			if h.viewID != viewID {
+				continue
+			}
+			h.events = append(h.events, evtData)
+			if !h.suspended {
+				h.acks = append(h.acks, holdEventAck(ctx))
+				deferred = true
+				continue
+			}
+			// событие применяется к прежней таблице сразу и подтверждается вместе с ней
+			h.acks = append(h.acks, nil)
+			h.applied = len(h.events)
+			// при переполнении передача сбрасывается, и продолжение задачи загружает таблицы заново
+			if len(h.events) > a.eventWorkerOptions().HandoffBuffer {
+				log.Warn("передача упавшей загрузки переполнена: продолжение загрузит таблицы заново",
+					slog.String("task", taskID))
+				delete(a.handoffs, taskID)
+			}
+		}
+	}
+	position := a.loadedPositions[viewID][evtData.Source.DB]
+	a.handoffMu.Unlock()
+
+	switch {
+	case replay && target != viewID:
+		return false
+	case deferred:
+		log.Info("view загружается, событие отложено до публикации")
+		return false
+	case position.Contains(evtData.Source):
+		log.Info("событие уже попало в снимок загрузки view, пропускаю")
+		return false
+	}
+	return true
+}
+
+// handoffLoad — заполненность буфера самой нагруженной передачи загружаемой view от 0
+// до 1. Отложенные события держат подтверждения Kafka, поэтому при заполнении буфера
+// чтение приостанавливается; события, уже прочитанные к этому моменту, ещё попадают
+// в буфер сверх лимита. Передачи упавших загрузок подтверждений не держат и не учитываются.
+func (a *AnalyticsDataCenterService) handoffLoad() float64 {
+	limit := float64(a.eventWorkerOptions().HandoffBuffer)
+	a.handoffMu.Lock()
+	defer a.handoffMu.Unlock()
+
+	load := 0.0
+	for _, h := range a.handoffs {
+		if h.suspended {
+			continue
+		}
+		if l := float64(len(h.events)-h.applied) / limit; l > load {
+			load = l
+		}
+	}
+	return load
+}
+
+// LoadViewSnapshots восстанавливает снимки, из которых загружены опубликованные view;
+// вызывается при запуске до чтения CDC-событий
+func (a *AnalyticsDataCenterService) LoadViewSnapshots(ctx context.Context) error {
+	const op = "analytics.LoadViewSnapshots"
+	log := a.log.With(slog.String("op", op))
+
+	snapshots, err := a.ViewSnapshotStorage.ListViewSnapshots(ctx)
+	if err != nil {
+		log.Error("не удалось загрузить снимки view", slog.String("error", err.Error()))
+		return err
+	}
+
+	a.handoffMu.Lock()
+	defer a.handoffMu.Unlock()
+	if a.loadedPositions == nil {
+		a.loadedPositions = make(map[int64]map[string]models.SnapshotPosition)
+	}
+	for _, s := range snapshots {
+		if a.loadedPositions[s.ViewID] == nil {
+			a.loadedPositions[s.ViewID] = make(map[string]models.SnapshotPosition)
+		}
+		a.loadedPositions[s.ViewID][s.Source] = s.Position
+	}
+	log.Info("снимки view загружены", slog.Int("snapshots", len(snapshots)))
+	return nil
+}
+
+// finishHandoff применяет накопленные события к таблице view. После публикации это
+// новая таблица, и события, попавшие в снимки загрузки, пропускаются. После ошибки или
+// отмены таблица прежняя и получает все отложенные события; передача упавшей задачи
+// продолжает сохранять события до её продолжения.
+func (a *AnalyticsDataCenterService) finishHandoff(ctx context.Context, h *viewHandoff, outcome handoffOutcome) {
+	const op = "analytics.finishHandoff"
+	if h == nil {
+		return
+	}
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("task", h.taskID),
+		slog.Int64("view", h.viewID),
+	)
+
+	if outcome == handoffPublished {
+		a.handoffMu.Lock()
+		if a.loadedPositions == nil {
+			a.loadedPositions = make(map[int64]map[string]models.SnapshotPosition)
+		}
+		a.loadedPositions[h.viewID] = h.positions
+		a.handoffMu.Unlock()
+
+		// граница нужна и после перезапуска: Kafka заново доставит события, offset которых
+		// ещё не закоммичен
+		if err := a.ViewSnapshotStorage.SaveViewSnapshots(ctx, h.viewID, h.positions); err != nil {
+			log.Error("не удалось сохранить снимки загрузки view: после перезапуска события из снимка применятся повторно",
+				slog.String("error", err.Error()))
+		}
+	}
+
+	replayed := 0
+	for {
+		// новые события view копятся, пока очередь не опустеет: порядок применения не нарушается
+		a.handoffMu.Lock()
+		if h.applied >= len(h.events) {
+			if outcome == handoffFailed {
+				h.suspended = true
+				h.suspendedAt = time.Now()
+			} else if a.handoffs[h.taskID] == h {
+				delete(a.handoffs, h.taskID)
+			}
+			a.handoffMu.Unlock()
+			break
+		}
+		evt := h.events[h.applied]
+		ack := h.acks[h.applied]
+		h.acks[h.applied] = nil
+		h.applied++
+		a.handoffMu.Unlock()
+
+		if err := a.replayEvent(ctx, h.viewID, evt); err == nil && ack != nil {
+			ack()
+		}
+		replayed++
+	}
+	log.Info("отложенные события применены", slog.Int("events", replayed))
+}
+
+// replayEvent применяет отложенное событие только к view viewID. Offset события,
+// которое не удалось применить, не коммитится.
+func (a *AnalyticsDataCenterService) replayEvent(ctx context.Context, viewID int64, evtData models.CDCEventData) error {
+	ctx = context.WithValue(ctx, replayViewKey{}, viewID)
+	if _, err := a.eventDispathFunction(ctx, a.eventIdentifier(evtData.Op), evtData); err != nil {
+		a.log.Error("не удалось применить отложенное событие",
+			slog.Int64("view", viewID),
+			slog.String("table", evtData.Source.Table),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+	return nil
+}
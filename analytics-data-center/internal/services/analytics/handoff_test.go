@@ -0,0 +1,206 @@
+package serviceanalytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotPositionContains(t *testing.T) {
+	position := models.SnapshotPosition{LSN: 100, Xmin: 10, Xmax: 20, Xip: []int64{15}}
+
+	cases := []struct {
+		name   string
+		source models.CDCSource
+		want   bool
+	}{
+		{"зафиксирована до снимка", models.CDCSource{LSN: 90, TxID: 12}, true},
+		{"старше xmin", models.CDCSource{LSN: 50, TxID: 5}, true},
+		{"активна во время снимка", models.CDCSource{LSN: 95, TxID: 15}, false},
+		{"началась после снимка", models.CDCSource{LSN: 99, TxID: 21}, false},
+		{"после позиции WAL", models.CDCSource{LSN: 120, TxID: 12}, false},
+		{"без транзакции", models.CDCSource{LSN: 90}, true},
+		{"без позиции", models.CDCSource{TxID: 12}, false},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.want, position.Contains(tc.source), tc.name)
+	}
+
+	// xid после переполнения счётчика относится к эпохе xmax
+	wrapped := models.SnapshotPosition{LSN: 100, Xmin: 1<<32 + 5, Xmax: 1<<32 + 10}
+	require.True(t, wrapped.Contains(models.CDCSource{LSN: 90, TxID: 3}))
+	require.False(t, wrapped.Contains(models.CDCSource{LSN: 90, TxID: 11}))
+	require.False(t, models.SnapshotPosition{}.Contains(models.CDCSource{LSN: 90}))
+}
+
+func usersEvent(id int, lsn int64, txID int64) models.CDCEvent {
+	return models.CDCEvent{Data: models.CDCEventData{
+		Op:     "u",
+		After:  map[string]interface{}{"id": id, "name": "user"},
+		Source: models.CDCSource{DB: "db1", Schema: "public", Table: "users", LSN: lsn, TxID: txID},
+	}}
+}
+
+// newHandoffTestService — ETL-сервис, view которого получает CDC-события таблицы users
+func newHandoffTestService(dwh *mockDWH, tasks *mockTaskService) (*AnalyticsDataCenterService, *mockOLTP) {
+	dwh.columns["v"] = []string{"id", "name"}
+	svc := newETLTestService(dwh, tasks)
+	svc.SchemaProvider.(*mockSchemaProvider).schems = []int{1}
+	svc.RenameSuggestionStorage = svc.SchemaProvider
+	svc.DeadLetterStorage = svc.SchemaProvider
+	svc.PendingEventStorage = svc.SchemaProvider
+	oltp := svc.OLTPFactory.(*mockFactory).store["db1"].(*mockOLTP)
+	oltp.columns = []models.Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "text"}}
+	oltp.snapshot = models.SnapshotPosition{LSN: 100, Xmin: 10, Xmax: 20, Xip: []int64{15}}
+	return svc, oltp
+}
+
+func upsertedIDs(dwh *mockDWH) []interface{} {
+	ids := make([]interface{}, 0, len(dwh.upsertCalls))
+	for _, call := range dwh.upsertCalls {
+		ids = append(ids, call.row["id"])
+	}
+	return ids
+}
+
+func TestProcessETLJob_ReplaysOnlyEventsAfterSnapshot(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	tasks := &mockTaskService{}
+	svc, oltp := newHandoffTestService(dwh, tasks)
+
+	// события приходят, пока view загружается
+	oltp.countHook = func(ctx context.Context) {
+		require.NoError(t, svc.handlerCDCFunc(ctx, usersEvent(1, 90, 12)))
+		require.NoError(t, svc.handlerCDCFunc(ctx, usersEvent(2, 95, 15)))
+		require.NoError(t, svc.handlerCDCFunc(ctx, usersEvent(3, 120, 25)))
+		require.Empty(t, dwh.upsertCalls)
+	}
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	// подсчёт и выгрузка читали один снимок, и он закрыт
+	require.Equal(t, []string{"db1@snapshot-1"}, svc.OLTPFactory.(*mockFactory).snapshotReads)
+	require.Equal(t, 1, oltp.released)
+	require.Equal(t, []models.TaskSnapshot{{Source: "db1", Position: oltp.snapshot, TakenAt: tasks.snapshots[0].TakenAt}}, tasks.snapshots)
+	// событие транзакции, видимой в снимке, не применяется повторно
+	require.Equal(t, []interface{}{2, 3}, upsertedIDs(dwh))
+
+	// после передачи события применяются сразу, запоздавшие из снимка пропускаются
+	require.NoError(t, svc.handlerCDCFunc(context.Background(), usersEvent(4, 80, 11)))
+	require.NoError(t, svc.handlerCDCFunc(context.Background(), usersEvent(5, 130, 26)))
+	require.Equal(t, []interface{}{2, 3, 5}, upsertedIDs(dwh))
+	require.Empty(t, svc.handoffs)
+}
+
+func TestProcessETLJob_SnapshotBoundarySurvivesRestart(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	svc, oltp := newHandoffTestService(dwh, &mockTaskService{})
+	schemaProvider := svc.SchemaProvider.(*mockSchemaProvider)
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1}, getTestLogger())
+	require.Equal(t, map[int64]map[string]models.SnapshotPosition{1: {"db1": oltp.snapshot}}, schemaProvider.viewSnapshots)
+
+	// после перезапуска Kafka заново доставляет события, offset которых не закоммичен
+	restarted, _ := newHandoffTestService(&mockDWH{columns: map[string][]string{}}, &mockTaskService{})
+	restarted.SchemaProvider = schemaProvider
+	restarted.ViewSnapshotStorage = schemaProvider
+	require.NoError(t, restarted.LoadViewSnapshots(context.Background()))
+
+	restartedDWH := restarted.DWHProvider.(*mockDWH)
+	require.NoError(t, restarted.handlerCDCFunc(context.Background(), usersEvent(1, 90, 12)))
+	require.NoError(t, restarted.handlerCDCFunc(context.Background(), usersEvent(2, 95, 15)))
+	require.Equal(t, []interface{}{2}, upsertedIDs(restartedDWH))
+}
+
+func TestLoadViewSnapshots_Error(t *testing.T) {
+	schemaProvider := &mockSchemaProvider{viewSnapshotErr: errors.New("sys db down")}
+	svc := &AnalyticsDataCenterService{log: getTestLogger(), ViewSnapshotStorage: schemaProvider}
+
+	require.Error(t, svc.LoadViewSnapshots(context.Background()))
+	require.Empty(t, svc.loadedPositions)
+}
+
+// Коннектор стартует без снимка (snapshot.mode=never) и не привязан к позиции снимка
+// загрузки: изменения на границе снимка приходят вперемешку и повторно, и отделяет их
+// только фильтр по LSN и границам транзакций.
+func TestProcessETLJob_SnapshotBoundaryEvents(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	tasks := &mockTaskService{}
+	svc, oltp := newHandoffTestService(dwh, tasks)
+
+	// снимок: LSN 100, xmin 10, xmax 20, транзакция 15 активна
+	oltp.countHook = func(ctx context.Context) {
+		// ровно на позиции снимка: видимая в снимке транзакция пропускается
+		require.NoError(t, svc.handlerCDCFunc(ctx, usersEvent(1, 100, 12)))
+		// на той же позиции, но транзакция была активна во время снимка
+		require.NoError(t, svc.handlerCDCFunc(ctx, usersEvent(2, 100, 15)))
+		// на той же позиции, транзакция началась после снимка
+		require.NoError(t, svc.handlerCDCFunc(ctx, usersEvent(3, 100, 20)))
+		// сразу после позиции снимка
+		require.NoError(t, svc.handlerCDCFunc(ctx, usersEvent(4, 101, 12)))
+		// повторная доставка события из снимка
+		require.NoError(t, svc.handlerCDCFunc(ctx, usersEvent(1, 100, 12)))
+	}
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	require.Equal(t, []interface{}{2, 3, 4}, upsertedIDs(dwh))
+
+	// после передачи повторы с границы снимка по-прежнему пропускаются
+	require.NoError(t, svc.handlerCDCFunc(context.Background(), usersEvent(1, 100, 12)))
+	require.NoError(t, svc.handlerCDCFunc(context.Background(), usersEvent(2, 100, 15)))
+	require.Equal(t, []interface{}{2, 3, 4, 2}, upsertedIDs(dwh))
+}
+
+func TestProcessETLJob_FailedLoadKeepsEventsForResume(t *testing.T) {
+	dwh := &mockDWH{
+		columns:  map[string][]string{"temp_task1_db1_public_users": {"id", "name"}},
+		mergeErr: errors.New("merge failed"),
+	}
+	tasks := &mockTaskService{}
+	svc, oltp := newHandoffTestService(dwh, tasks)
+	oltp.countHook = func(ctx context.Context) {
+		require.NoError(t, svc.handlerCDCFunc(ctx, usersEvent(1, 90, 12)))
+		require.NoError(t, svc.handlerCDCFunc(ctx, usersEvent(2, 95, 15)))
+	}
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1}, getTestLogger())
+
+	require.Equal(t, []string{Error}, tasks.statuses)
+	// рабочая таблица осталась прежней и получает все отложенные события
+	require.Equal(t, []interface{}{1, 2}, upsertedIDs(dwh))
+	require.NoError(t, svc.handlerCDCFunc(context.Background(), usersEvent(3, 140, 30)))
+	require.Equal(t, []interface{}{1, 2, 3}, upsertedIDs(dwh))
+
+	// продолжение читает новый снимок, но CDC отсчитывается от снимка первого запуска
+	oltp.countHook = nil
+	oltp.snapshot = models.SnapshotPosition{LSN: 135, Xmin: 28, Xmax: 29}
+	dwh.mergeErr = nil
+	tasks.chunks = []models.TaskChunk{usersCheckpoint(0, nil, nil, true)}
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1, Resume: true}, getTestLogger())
+
+	require.Equal(t, []string{Error, Completed}, tasks.statuses)
+	require.Empty(t, dwh.chunkDeletes)
+	require.Equal(t, int64(100), tasks.snapshots[0].Position.LSN)
+	require.Equal(t, []interface{}{1, 2, 3, 2, 3}, upsertedIDs(dwh))
+}
+
+func TestProcessETLJob_ResumeWithoutHandoffReloadsTables(t *testing.T) {
+	dwh, tasks := resumableUsersTask(usersCheckpoint(0, nil, nil, true))
+	tasks.snapshots = []models.TaskSnapshot{{Source: "db1", Position: models.SnapshotPosition{LSN: 50}}}
+	svc, oltp := newHandoffTestService(dwh, tasks)
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1, Resume: true}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	// чанки старого снимка не согласовать с CDC: таблица очищается и загружается заново
+	require.Equal(t, []mockChunkDelete{{table: "temp_task1_db1_public_users"}}, dwh.chunkDeletes)
+	require.Len(t, oltp.streamCalls, 1)
+	require.Equal(t, int64(100), tasks.snapshots[0].Position.LSN)
+}
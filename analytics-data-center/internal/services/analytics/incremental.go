@@ -0,0 +1,346 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"analyticDataCenter/analytics-data-center/internal/lib/rowfilter"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrIncrementalNotSupported — view нельзя загружать инкрементально
+	ErrIncrementalNotSupported = errors.New("инкрементальная загрузка view невозможна")
+)
+
+// watermarkTable — таблица view с колонкой отметки
+type watermarkTable struct {
+	source string
+	schema string
+	table  models.Table
+}
+
+func (w watermarkTable) key() string {
+	return checkpointKey(w.source, w.schema, w.table.Name)
+}
+
+func (w watermarkTable) watermark(value string) models.ViewWatermark {
+	return models.ViewWatermark{
+		Source:    w.source,
+		Schema:    w.schema,
+		Table:     w.table.Name,
+		Column:    w.table.Watermark,
+		Value:     value,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// watermarkTables возвращает таблицы view, для которых задана колонка отметки
+func watermarkTables(view models.View) []watermarkTable {
+	var tables []watermarkTable
+	for _, source := range view.Sources {
+		for _, sch := range source.Schemas {
+			for _, table := range sch.Tables {
+				if table.Watermark != "" {
+					tables = append(tables, watermarkTable{source: source.Name, schema: sch.Name, table: table})
+				}
+			}
+		}
+	}
+	return tables
+}
+
+// incrementalTables проверяет, что view можно загружать инкрементально, и возвращает
+// её таблицы с колонкой отметки. Строки view без джоинов записываются по ключам
+// обновления таблицы, поэтому у таких таблиц ключи должны быть.
+func incrementalTables(view models.View) ([]watermarkTable, error) {
+	tables := watermarkTables(view)
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("%w: ни у одной таблицы не задана колонка отметки", ErrIncrementalNotSupported)
+	}
+	if len(view.Joins) > 0 {
+		return tables, nil
+	}
+	for _, wt := range tables {
+		hasKey := false
+		for _, column := range wt.table.Columns {
+			hasKey = hasKey || column.IsUpdateKey
+		}
+		if !hasKey {
+			return nil, fmt.Errorf("%w: у таблицы %s нет ключей обновления", ErrIncrementalNotSupported, wt.key())
+		}
+	}
+	return tables, nil
+}
+
+// StartIncrementalETL ставит в очередь инкрементальную загрузку view: в рабочую таблицу
+// view дозагружаются строки, изменившиеся после сохранённых отметок таблиц. Подходит для
+// источников без CDC, где иначе остаётся только полная пересборка.
+func (a *AnalyticsDataCenterService) StartIncrementalETL(ctx context.Context, idView int64) (taskID string, err error) {
+	const op = "analytics.StartIncrementalETL"
+
+	view, err := a.SchemaProvider.GetView(ctx, idView)
+	if err != nil {
+		if errors.Is(err, storage.ErrSchemaNotFound) {
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidSchemID)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := incrementalTables(view); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return a.enqueueETL(ctx, idView, models.ETLModeIncremental)
+}
+
+// runIncrementalETL дозагружает во view строки таблиц с колонкой отметки, изменившиеся
+// после сохранённых отметок. Строки записываются в рабочую таблицу так же, как изменения
+// из CDC: по ключам обновления, а во view с джоинами — пересборкой связанных строк.
+// Отметка таблицы сдвигается, только когда записаны все её строки: после ошибки
+// следующий запуск повторит их, и запись по ключам обновления не создаст дублей.
+func (a *AnalyticsDataCenterService) runIncrementalETL(ctx context.Context, idView int64, tracker *taskTracker) error {
+	const op = "analytics.runIncrementalETL"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("idSchema", idView),
+	)
+	log.Info("инкрементальная загрузка view")
+
+	tracker.enter(ctx, models.StageExtract)
+	viewSchema, err := a.SchemaProvider.GetView(ctx, idView)
+	if err != nil {
+		if errors.Is(err, storage.ErrSchemaNotFound) {
+			return fmt.Errorf("%s:%s", op, ErrInvalidSchemID)
+		}
+		return fmt.Errorf("%s:%s", op, err)
+	}
+	tables, err := incrementalTables(viewSchema)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	stored, err := a.WatermarkStorage.ListViewWatermarks(ctx, idView)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	marks := make(map[string]models.ViewWatermark, len(stored))
+	for _, mark := range stored {
+		marks[checkpointKey(mark.Source, mark.Schema, mark.Table)] = mark
+	}
+
+	ev := eventView{id: int(idView), view: viewSchema}
+	for _, wt := range tables {
+		mark, ok := marks[wt.key()]
+		if ok && mark.Column != wt.table.Watermark {
+			// колонку отметки поменяли: прежнее значение к ней не относится
+			log.Warn("колонка отметки таблицы изменилась, таблица загружается целиком",
+				slog.String("table", wt.key()), slog.String("column", mark.Column))
+			ok = false
+		}
+
+		next, rows, err := a.refreshWatermarkTable(ctx, ev, wt, mark.Value, ok)
+		if err != nil {
+			log.Error("ошибка инкрементальной загрузки таблицы", slog.String("table", wt.key()), slog.String("error", err.Error()))
+			return fmt.Errorf("%s: %s: %w", op, ErrorSelectInsertData, err)
+		}
+		tracker.rowsLoaded(ctx, models.CountInsertData{DataBaseName: wt.source, SchemaName: wt.schema, TableName: wt.table.Name}, rows)
+		if rows == 0 || next == "" {
+			log.Info("новых строк нет", slog.String("table", wt.key()))
+			continue
+		}
+		if err := a.WatermarkStorage.SaveViewWatermark(ctx, idView, wt.watermark(next)); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		log.Info("отметка таблицы сдвинута",
+			slog.String("table", wt.key()),
+			slog.String("watermark", next),
+			slog.Int64("rows", rows),
+		)
+	}
+	return nil
+}
+
+// refreshWatermarkTable записывает во view строки таблицы после отметки mark в порядке
+// колонки отметки, порциями по batchSize строк. Возвращает отметку последней
+// прочитанной строки и число строк.
+func (a *AnalyticsDataCenterService) refreshWatermarkTable(
+	ctx context.Context,
+	ev eventView,
+	wt watermarkTable,
+	mark string,
+	hasMark bool,
+) (next string, rows int64, err error) {
+	query, err := sqlgenerator.GenerateWatermarkRowsQuery(wt.schema, wt.table, hasMark)
+	if err != nil {
+		return "", 0, err
+	}
+	var args []interface{}
+	if hasMark {
+		args = append(args, mark)
+	}
+
+	oltpStorage, err := a.OLTPFactory.GetOLTPStorage(ctx, wt.source)
+	if err != nil {
+		return "", 0, err
+	}
+	stream, err := oltpStorage.StreamRows(ctx, query, args...)
+	if err != nil {
+		return "", 0, err
+	}
+	defer stream.Close()
+
+	batchSize := a.batchSize()
+	portion := make([]map[string]interface{}, 0, batchSize)
+	source := a.throttleRows(ctx, wt.source, stream)
+	for source.Next() {
+		if ctx.Err() != nil {
+			return "", rows, ctx.Err()
+		}
+		row := source.Row()
+		normalizeOLTPRow(wt.table, row)
+		portion = append(portion, row)
+		rows++
+		if value := watermarkValue(row[wt.table.Watermark]); value != "" {
+			next = value
+		}
+		if len(portion) >= batchSize {
+			if err := a.applyWatermarkRows(ctx, ev, wt, portion); err != nil {
+				return "", rows, err
+			}
+			portion = portion[:0]
+		}
+	}
+	if err := source.Err(); err != nil {
+		return "", rows, err
+	}
+	if err := a.applyWatermarkRows(ctx, ev, wt, portion); err != nil {
+		return "", rows, err
+	}
+	return next, rows, nil
+}
+
+// applyWatermarkRows записывает порцию строк таблицы в рабочую таблицу view так же, как
+// изменения этих строк, пришедшие из CDC: строки, прошедшие фильтр, — одной пачкой по
+// ключам обновления, вышедшие из фильтра убираются из view. Во view с джоинами
+// связанные строки пересобираются за один проход.
+func (a *AnalyticsDataCenterService) applyWatermarkRows(ctx context.Context, ev eventView, wt watermarkTable, rows []map[string]interface{}) error {
+	const op = "analytics.applyWatermarkRows"
+	log := a.log.With(slog.String("op", op), slog.String("view", ev.view.Name))
+	if len(rows) == 0 {
+		return nil
+	}
+
+	eventSource := models.CDCSource{DB: wt.source, Schema: wt.schema, Table: wt.table.Name}
+	if len(ev.view.Joins) > 0 {
+		events := make([]models.CDCEventData, 0, len(rows))
+		for _, row := range rows {
+			events = append(events, models.CDCEventData{Op: "r", After: row, Source: eventSource})
+		}
+		return a.refreshJoinedView(ctx, ev, events...)
+	}
+
+	var (
+		upserts  []map[string]interface{}
+		conflict []string
+		// byKey — строки пачки по ключам: в пачке ключ встречается один раз, и более
+		// поздняя по отметке строка заменяет раннюю
+		byKey = make(map[string]int)
+	)
+	for _, row := range rows {
+		if !rowfilter.Match(wt.table.Filter, row) {
+			evtData := models.CDCEventData{Op: "u", After: row, Source: eventSource}
+			if err := a.removeFilteredRow(ctx, ev.view, []models.Table{wt.table}, evtData, wt.table, log.Logger); err != nil {
+				return err
+			}
+			continue
+		}
+
+		finalRow := make(map[string]interface{})
+		conflictKeys := make(map[string]struct{})
+		if !a.fillViewRow(ev.view.Name, wt.table, row, finalRow, conflictKeys, log.Logger) {
+			continue
+		}
+		resetSoftDelete(ev.view, finalRow)
+		if conflict == nil {
+			for column := range conflictKeys {
+				conflict = append(conflict, column)
+			}
+			sort.Strings(conflict)
+		}
+		key := rowConflictKey(finalRow, conflict)
+		if idx, ok := byKey[key]; ok {
+			upserts[idx] = finalRow
+			continue
+		}
+		byKey[key] = len(upserts)
+		upserts = append(upserts, finalRow)
+	}
+
+	if err := a.DWHProvider.UpsertBatch(ctx, ev.view.Name, upserts, conflict); err != nil {
+		if isRelationDoesNotExist(err) {
+			return fmt.Errorf("таблица view %s ещё не создана, нужна полная загрузка: %w", ev.view.Name, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// captureWatermarks читает отметки таблиц из снимков, из которых полная пересборка
+// загружает источники: строки до этих отметок попадут во view вместе с новой таблицей
+func (a *AnalyticsDataCenterService) captureWatermarks(ctx context.Context, tracker *taskTracker, view models.View) ([]models.ViewWatermark, error) {
+	var marks []models.ViewWatermark
+	for _, wt := range watermarkTables(view) {
+		query, err := sqlgenerator.GenerateWatermarkMaxQuery(wt.schema, wt.table)
+		if err != nil {
+			return nil, err
+		}
+		oltpStorage, err := a.sourceStorage(ctx, tracker, wt.source)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := oltpStorage.SelectRows(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("отметка таблицы %s: %w", wt.key(), err)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		// у пустой таблицы отметки нет: следующая инкрементальная загрузка прочитает её целиком
+		if value := watermarkValue(rows[0][sqlgenerator.WatermarkMaxColumn]); value != "" {
+			marks = append(marks, wt.watermark(value))
+		}
+	}
+	return marks, nil
+}
+
+// saveWatermarks сохраняет отметки опубликованной пересборки. Ошибка не валит задачу:
+// прежние отметки меньше, и следующая инкрементальная загрузка лишь повторит строки.
+func (a *AnalyticsDataCenterService) saveWatermarks(ctx context.Context, idView int64, marks []models.ViewWatermark) {
+	for _, mark := range marks {
+		if err := a.WatermarkStorage.SaveViewWatermark(ctx, idView, mark); err != nil {
+			a.log.Warn("не удалось сохранить отметку таблицы",
+				slog.Int64("view", idView),
+				slog.String("table", checkpointKey(mark.Source, mark.Schema, mark.Table)),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// watermarkValue приводит значение колонки отметки к тексту, который Postgres разберёт
+// обратно в тип колонки
+func watermarkValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
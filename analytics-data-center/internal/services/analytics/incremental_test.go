@@ -0,0 +1,144 @@
+package serviceanalytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newIncrementalTestService — сервис с view над users, у которой задана колонка отметки updated_at
+func newIncrementalTestService(dwh *mockDWH, tasks *mockTaskService) (*AnalyticsDataCenterService, *mockSchemaProvider, *mockOLTP) {
+	svc := newETLTestService(dwh, tasks)
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+	svc.WatermarkStorage = schemas
+
+	view := schemas.views[1]
+	table := &view.Sources[0].Schemas[0].Tables[0]
+	table.Columns[0].IsUpdateKey = true
+	table.Columns = append(table.Columns, models.Column{Name: "updated_at", Type: "timestamp"})
+	table.Watermark = "updated_at"
+	schemas.views[1] = view
+
+	oltp := svc.OLTPFactory.(*mockFactory).store["db1"].(*mockOLTP)
+	return svc, schemas, oltp
+}
+
+func TestStartIncrementalETL_RequiresWatermark(t *testing.T) {
+	svc := newETLTestService(&mockDWH{}, &mockTaskService{})
+	jobs := &mockJobStorage{}
+	svc.JobStorage = jobs
+	svc.SetJobQueueOptions(JobQueueOptions{Limit: 10})
+
+	_, err := svc.StartIncrementalETL(context.Background(), 1)
+	require.ErrorIs(t, err, ErrIncrementalNotSupported)
+	require.Empty(t, jobs.jobs)
+
+	_, err = svc.StartIncrementalETL(context.Background(), 2)
+	require.ErrorIs(t, err, ErrInvalidSchemID)
+
+	svc, _, _ = newIncrementalTestService(&mockDWH{}, &mockTaskService{})
+	svc.JobStorage = jobs
+	svc.SetJobQueueOptions(JobQueueOptions{Limit: 10})
+	taskID, err := svc.StartIncrementalETL(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, []models.ETLJob{{TaskID: taskID, ViewID: 1, Status: models.ETLJobQueued, Mode: models.ETLModeIncremental}}, jobs.jobs)
+}
+
+func TestProcessETLJob_IncrementalUpsertsRowsAfterWatermark(t *testing.T) {
+	dwh := &mockDWH{}
+	tasks := &mockTaskService{}
+	svc, schemas, oltp := newIncrementalTestService(dwh, tasks)
+	updated := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	schemas.watermarks = map[int64][]models.ViewWatermark{1: {{
+		Source: "db1", Schema: "public", Table: "users", Column: "updated_at", Value: "2025-02-01T00:00:00Z",
+	}}}
+	oltp.selectResult = []map[string]interface{}{
+		{"id": 1, "name": "A", "updated_at": updated.Add(-time.Hour)},
+		{"id": 2, "name": "B", "updated_at": updated},
+	}
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1, Mode: models.ETLModeIncremental}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	// строки читаются после отметки и записываются в рабочую таблицу по ключам обновления
+	require.Len(t, oltp.streamCalls, 1)
+	require.Equal(t, []interface{}{"2025-02-01T00:00:00Z"}, oltp.streamCalls[0].Args)
+	// строки записаны одной пачкой
+	require.Equal(t, []int{2}, dwh.upsertBatches)
+	require.Len(t, dwh.upsertCalls, 2)
+	for _, call := range dwh.upsertCalls {
+		require.Equal(t, "v", call.table)
+		require.Equal(t, []string{"id"}, call.keys)
+	}
+	require.Empty(t, dwh.createCalls)
+	require.Empty(t, dwh.swapCalls)
+	require.Equal(t, "2025-03-01T10:00:00Z", schemas.watermarks[1][0].Value)
+	require.Equal(t, []models.TaskTableProgress{{Source: "db1", Schema: "public", Table: "users", Loaded: 2}}, tasks.loaded)
+}
+
+func TestProcessETLJob_IncrementalRemovesRowsLeavingFilter(t *testing.T) {
+	dwh := &mockDWH{}
+	tasks := &mockTaskService{}
+	svc, schemas, oltp := newIncrementalTestService(dwh, tasks)
+	view := schemas.views[1]
+	view.Sources[0].Schemas[0].Tables[0].Filter = &models.RowFilter{Column: "name", Operator: models.FilterNotEq, Value: "archived"}
+	schemas.views[1] = view
+	schemas.watermarks = map[int64][]models.ViewWatermark{1: {{
+		Source: "db1", Schema: "public", Table: "users", Column: "updated_at", Value: "2025-02-01T00:00:00Z",
+	}}}
+	updated := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	oltp.selectResult = []map[string]interface{}{
+		{"id": 1, "name": "A", "updated_at": updated},
+		{"id": 2, "name": "archived", "updated_at": updated},
+	}
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1, Mode: models.ETLModeIncremental}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	// после отметки фильтр не сужает выборку: строка, вышедшая из него, удаляется из view
+	require.NotContains(t, oltp.streamCalls[0].Query, "archived")
+	require.Contains(t, oltp.streamCalls[0].Query, `"updated_at" >= $1`)
+	require.Len(t, dwh.upsertCalls, 1)
+	require.Equal(t, 1, dwh.upsertCalls[0].row["id"])
+	require.Equal(t, []mockRowCall{{table: "v", row: map[string]interface{}{"id": 2}}}, dwh.rowDeleteCalls)
+}
+
+func TestProcessETLJob_IncrementalKeepsWatermarkOnError(t *testing.T) {
+	dwh := &mockDWH{upsertErr: errors.New("dwh down")}
+	tasks := &mockTaskService{}
+	svc, schemas, oltp := newIncrementalTestService(dwh, tasks)
+	oltp.selectResult = []map[string]interface{}{{"id": 1, "name": "A", "updated_at": time.Now()}}
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1, Mode: models.ETLModeIncremental}, getTestLogger())
+
+	require.Equal(t, []string{Error}, tasks.statuses)
+	// без отметки таблица читается целиком, а после ошибки отметка не появляется
+	require.Empty(t, oltp.streamCalls[0].Args)
+	require.Empty(t, schemas.watermarks[1])
+}
+
+func TestRunETL_RecordsWatermarksFromSnapshot(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name", "updated_at"}}}
+	tasks := &mockTaskService{}
+	svc, schemas, oltp := newIncrementalTestService(dwh, tasks)
+	oltp.selectRows = func(query string, _ []interface{}) ([]map[string]interface{}, error) {
+		require.Contains(t, query, `max("updated_at")`)
+		return []map[string]interface{}{{sqlgenerator.WatermarkMaxColumn: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}}, nil
+	}
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	// отметка читается из того же снимка, что и данные пересборки
+	require.Equal(t, []string{"db1@snapshot-1"}, svc.OLTPFactory.(*mockFactory).snapshotReads)
+	require.Len(t, oltp.selectRowsCalls, 1)
+	require.Len(t, schemas.watermarks[1], 1)
+	require.Equal(t, "updated_at", schemas.watermarks[1][0].Column)
+	require.Equal(t, "2025-03-01T00:00:00Z", schemas.watermarks[1][0].Value)
+}
@@ -0,0 +1,157 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tempTablePrefix — префикс имён временных и теневых таблиц ETL, см. sqlgenerator.TempTableName
+const tempTablePrefix = sqlgenerator.TempTablePrefix
+
+// Значения сборщика временных таблиц по умолчанию
+const (
+	defaultJanitorInterval        = 10 * time.Minute
+	defaultJanitorGracePeriod     = time.Hour
+	defaultJanitorResumeRetention = 72 * time.Hour
+)
+
+// TempTableJanitorOptions — параметры сборщика брошенных временных таблиц. Нулевые поля
+// заменяются значениями по умолчанию.
+type TempTableJanitorOptions struct {
+	// Interval — как часто DWH проверяется на брошенные временные таблицы
+	Interval time.Duration
+	// GracePeriod — сколько таблица должна оставаться ничьей, прежде чем её удалят
+	GracePeriod time.Duration
+	// ResumeRetention — сколько временные таблицы упавшей задачи хранятся для её продолжения
+	ResumeRetention time.Duration
+}
+
+// SetTempTableJanitorOptions задаёт параметры сборщика; вызывается до StartTempTableJanitor.
+func (a *AnalyticsDataCenterService) SetTempTableJanitorOptions(opts TempTableJanitorOptions) {
+	a.janitorOptions = opts
+}
+
+func (a *AnalyticsDataCenterService) tempTableJanitorOptions() TempTableJanitorOptions {
+	opts := a.janitorOptions
+	if opts.Interval <= 0 {
+		opts.Interval = defaultJanitorInterval
+	}
+	if opts.GracePeriod <= 0 {
+		opts.GracePeriod = defaultJanitorGracePeriod
+	}
+	if opts.ResumeRetention <= 0 {
+		opts.ResumeRetention = defaultJanitorResumeRetention
+	}
+	return opts
+}
+
+// StartTempTableJanitor запускает фоновую очистку DWH от временных таблиц, которые не
+// принадлежат ни выполняющимся задачам, ни упавшим задачам, ещё доступным для продолжения.
+func (a *AnalyticsDataCenterService) StartTempTableJanitor() {
+	go func() {
+		ticker := time.NewTicker(a.tempTableJanitorOptions().Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			a.sweepTempTables(context.Background(), time.Now())
+		}
+	}()
+}
+
+// sweepTempTables удаляет временные таблицы, остававшиеся ничьими дольше GracePeriod,
+// и возвращает их имена. Таблица считается ничьей с момента, когда сборщик впервые не
+// нашёл её владельца; таблица, снова закреплённая за задачей, отсчёт сбрасывает.
+func (a *AnalyticsDataCenterService) sweepTempTables(ctx context.Context, now time.Time) []string {
+	const op = "analytics.sweepTempTables"
+	log := a.log.With(slog.String("op", op))
+	opts := a.tempTableJanitorOptions()
+
+	// таблицы DWH читаются раньше владельцев: задача закрепляет таблицы до их создания,
+	// поэтому каждая найденная таблица активной задачи уже есть в списке владельцев
+	tables, err := a.DWHProvider.ListTempTables(ctx, tempTablePrefix)
+	if err != nil {
+		log.Error("не удалось получить временные таблицы DWH", slog.String("error", err.Error()))
+		return nil
+	}
+	owned, err := a.TaskService.ListTaskTempTables(ctx, Progress, Error, now.Add(-opts.ResumeRetention))
+	if err != nil {
+		log.Error("не удалось получить временные таблицы задач", slog.String("error", err.Error()))
+		return nil
+	}
+	views, err := a.SchemaProvider.ListViews(ctx)
+	if err != nil {
+		log.Error("не удалось получить список view", slog.String("error", err.Error()))
+		return nil
+	}
+
+	keep := make(map[string]struct{}, len(owned)+len(views))
+	for _, table := range owned {
+		keep[strings.ToLower(table)] = struct{}{}
+	}
+	// view с именем, похожим на временную таблицу, сборщик не трогает
+	for _, view := range views {
+		keep[strings.ToLower(view.Name)] = struct{}{}
+	}
+
+	a.janitorMu.Lock()
+	defer a.janitorMu.Unlock()
+	if a.orphanSince == nil {
+		a.orphanSince = make(map[string]time.Time)
+	}
+
+	present := make(map[string]struct{}, len(tables))
+	var removed []string
+	for _, table := range tables {
+		present[table] = struct{}{}
+		if _, ok := keep[strings.ToLower(table)]; ok {
+			delete(a.orphanSince, table)
+			continue
+		}
+		since, seen := a.orphanSince[table]
+		if !seen {
+			a.orphanSince[table] = now
+			log.Info("найдена ничья временная таблица", slog.String("table", table))
+			continue
+		}
+		if now.Sub(since) < opts.GracePeriod {
+			continue
+		}
+		if err := a.DWHProvider.DeleteTempTable(ctx, table); err != nil {
+			log.Error("не удалось удалить брошенную временную таблицу", slog.String("table", table), slog.String("error", err.Error()))
+			continue
+		}
+		log.Warn("брошенная временная таблица удалена", slog.String("table", table), slog.Duration("orphaned", now.Sub(since)))
+		delete(a.orphanSince, table)
+		removed = append(removed, table)
+	}
+	// таблицы, удалённые не сборщиком, перестают отслеживаться
+	for table := range a.orphanSince {
+		if _, ok := present[table]; !ok {
+			delete(a.orphanSince, table)
+		}
+	}
+
+	if len(removed) > 0 {
+		sort.Strings(removed)
+		log.Info("очистка временных таблиц завершена", slog.Int("removed", len(removed)))
+		a.notifyTempTablesRemoved(removed, now)
+	}
+	return removed
+}
+
+func (a *AnalyticsDataCenterService) notifyTempTablesRemoved(tables []string, now time.Time) {
+	if a.notifier == nil {
+		return
+	}
+	a.notifier.Publish(models.Notification{
+		Type:      "temp_tables_cleanup",
+		Title:     "Очистка временных таблиц",
+		Message:   "Удалены брошенные временные таблицы: " + strings.Join(tables, ", "),
+		Tables:    tables,
+		CreatedAt: now,
+	})
+}
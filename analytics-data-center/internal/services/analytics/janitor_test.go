@@ -0,0 +1,87 @@
+package serviceanalytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	notifications []models.Notification
+}
+
+func (n *recordingNotifier) Publish(notification models.Notification) {
+	n.notifications = append(n.notifications, notification)
+}
+
+func TestSweepTempTables_DropsOrphansAfterGracePeriod(t *testing.T) {
+	dwh := &mockDWH{tempTables: []string{"temp_db1_public_users", "temp_db1_public_orders", "temp_report"}}
+	tasks := &mockTaskService{owned: []string{"temp_db1_public_users"}}
+	notifier := &recordingNotifier{}
+	svc := &AnalyticsDataCenterService{
+		log:            getTestLogger(),
+		SchemaProvider: &mockSchemaProvider{views: map[int]models.View{1: {Name: "temp_report"}}},
+		TaskService:    tasks,
+		DWHProvider:    dwh,
+		notifier:       notifier,
+	}
+	svc.SetTempTableJanitorOptions(TempTableJanitorOptions{GracePeriod: time.Hour})
+	start := time.Now()
+
+	// впервые найденная ничья таблица только берётся на учёт
+	require.Empty(t, svc.sweepTempTables(context.Background(), start))
+	require.Empty(t, svc.sweepTempTables(context.Background(), start.Add(30*time.Minute)))
+	require.Empty(t, dwh.deleteCalls)
+
+	removed := svc.sweepTempTables(context.Background(), start.Add(time.Hour))
+	require.Equal(t, []string{"temp_db1_public_orders"}, removed)
+	require.Equal(t, []string{"temp_db1_public_orders"}, dwh.deleteCalls)
+	require.Len(t, notifier.notifications, 1)
+	require.Equal(t, []string{"temp_db1_public_orders"}, notifier.notifications[0].Tables)
+}
+
+func TestSweepTempTables_ClaimedTableResetsGracePeriod(t *testing.T) {
+	dwh := &mockDWH{tempTables: []string{"temp_db1_public_users"}}
+	tasks := &mockTaskService{}
+	svc := &AnalyticsDataCenterService{
+		log:            getTestLogger(),
+		SchemaProvider: &mockSchemaProvider{},
+		TaskService:    tasks,
+		DWHProvider:    dwh,
+	}
+	svc.SetTempTableJanitorOptions(TempTableJanitorOptions{GracePeriod: time.Hour})
+	start := time.Now()
+
+	svc.sweepTempTables(context.Background(), start)
+	// таблицу закрепила новая задача
+	tasks.owned = []string{"temp_db1_public_users"}
+	svc.sweepTempTables(context.Background(), start.Add(50*time.Minute))
+	tasks.owned = nil
+	require.Empty(t, svc.sweepTempTables(context.Background(), start.Add(2*time.Hour)))
+	require.Empty(t, dwh.deleteCalls)
+}
+
+func TestRunETL_RegistersTempTablesBeforeCreating(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	tasks := &mockTaskService{}
+	svc := newETLTestService(dwh, tasks)
+
+	require.NoError(t, svc.runETL(context.Background(), 1, svc.newTaskTracker("task-1")))
+	require.Equal(t, []string{"temp_task1_db1_public_users", "temp_task1_v__shadow"}, tasks.register)
+}
+
+func TestRunETL_ClaimedTempTableIsNotCreated(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	tasks := &mockTaskService{registerErr: storage.ErrTempTableClaimed}
+	svc := newETLTestService(dwh, tasks)
+
+	err := svc.runETL(context.Background(), 1, svc.newTaskTracker("task-1"))
+	require.ErrorIs(t, err, storage.ErrTempTableClaimed)
+	require.Empty(t, dwh.createCalls)
+	require.Empty(t, dwh.deleteCalls)
+}
@@ -0,0 +1,190 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	loggerpkg "analyticDataCenter/analytics-data-center/internal/logger"
+
+	"github.com/google/uuid"
+)
+
+// Значения очереди ETL по умолчанию
+const (
+	defaultETLWorkers      = 1
+	defaultETLQueueLimit   = 100
+	defaultETLJobLease     = time.Minute
+	defaultETLPollInterval = 2 * time.Second
+	defaultETLMaxAttempts  = 3
+)
+
+// ErrorJobAttemptsExhausted — комментарий задачи, чьё задание не пережило перезапусков
+const ErrorJobAttemptsExhausted = "Задача прервана перезапуском сервиса и исчерпала попытки выполнения"
+
+// JobQueueOptions — параметры персистентной очереди ETL. Нулевые поля заменяются
+// значениями по умолчанию.
+type JobQueueOptions struct {
+	// Workers — сколько заданий выполняется одновременно
+	Workers int
+	// Limit — сколько заданий может ожидать в очереди; сверх лимита запуск отклоняется
+	Limit int
+	// Lease — на сколько воркер арендует задание; аренда продлевается, пока задание выполняется
+	Lease time.Duration
+	// PollInterval — как часто свободный воркер проверяет очередь
+	PollInterval time.Duration
+	// MaxAttempts — сколько раз задание берётся в работу, прежде чем задача считается упавшей
+	MaxAttempts int
+}
+
+// SetJobQueueOptions задаёт параметры очереди ETL; вызывается до StartETLWorkers.
+func (a *AnalyticsDataCenterService) SetJobQueueOptions(opts JobQueueOptions) {
+	a.jobQueueOptions = opts
+}
+
+func (a *AnalyticsDataCenterService) queueOptions() JobQueueOptions {
+	opts := a.jobQueueOptions
+	if opts.Workers <= 0 {
+		opts.Workers = defaultETLWorkers
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultETLQueueLimit
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = defaultETLJobLease
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultETLPollInterval
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultETLMaxAttempts
+	}
+	return opts
+}
+
+// StartETLWorkers восстанавливает очередь после перезапуска и запускает воркеры.
+// Задания с истёкшей арендой возвращаются в очередь или, исчерпав попытки, завершают
+// задачу ошибкой; задачи «в работе» без задания в очереди также помечаются упавшими.
+func (a *AnalyticsDataCenterService) StartETLWorkers() {
+	opts := a.queueOptions()
+	host, _ := os.Hostname()
+	instance := fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+
+	a.recoverETLJobs(context.Background(), true)
+	go a.recoveryLoop(opts.Lease)
+	for i := 0; i < opts.Workers; i++ {
+		go a.etlWorker(fmt.Sprintf("%s-%d", instance, i))
+	}
+}
+
+func (a *AnalyticsDataCenterService) recoveryLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		a.recoverETLJobs(context.Background(), false)
+	}
+}
+
+func (a *AnalyticsDataCenterService) recoverETLJobs(ctx context.Context, startup bool) {
+	const op = "analytics.recoverETLJobs"
+	log := a.log.With(slog.String("op", op))
+
+	requeued, failed, err := a.JobStorage.RecoverETLJobs(ctx, a.queueOptions().MaxAttempts)
+	if err != nil {
+		log.Error("не удалось восстановить очередь ETL", slog.String("error", err.Error()))
+		return
+	}
+	for _, taskID := range requeued {
+		log.Warn("задание с истёкшей арендой возвращено в очередь", slog.String("task", taskID))
+	}
+	if startup {
+		// задачи, созданные до появления очереди или потерявшие задание
+		orphans, err := a.JobStorage.ListOrphanTasks(ctx, Progress)
+		if err != nil {
+			log.Error("не удалось получить зависшие задачи", slog.String("error", err.Error()))
+		}
+		failed = append(failed, orphans...)
+	}
+	for _, taskID := range failed {
+		log.Warn("задача завершена ошибкой при восстановлении очереди", slog.String("task", taskID))
+		a.TaskService.ChangeStatusTask(ctx, taskID, Error, ErrorJobAttemptsExhausted)
+		a.newTaskTracker(taskID).notify(Error, ErrorJobAttemptsExhausted)
+	}
+	if len(requeued) > 0 {
+		select {
+		case a.jobSignal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (a *AnalyticsDataCenterService) etlWorker(workerID string) {
+	opts := a.queueOptions()
+	log := a.log.With(
+		slog.String("component", "ETLWorker"),
+		slog.String("worker", workerID),
+	)
+
+	for {
+		job, err := a.JobStorage.ClaimETLJob(context.Background(), workerID, opts.Lease)
+		if err != nil {
+			log.Error("не удалось взять задание из очереди", slog.String("error", err.Error()))
+		}
+		if job == nil {
+			select {
+			case <-a.jobSignal:
+			case <-time.After(opts.PollInterval):
+			}
+			continue
+		}
+		a.runJob(*job, workerID, opts.Lease, log.With(slog.String("task", job.TaskID)))
+	}
+}
+
+// runJob выполняет взятое задание, продлевая его аренду, и убирает его из очереди.
+// Если аренда утрачена (задание отменили или передали другому воркеру), выполнение
+// прерывается так же, как при отмене.
+func (a *AnalyticsDataCenterService) runJob(job models.ETLJob, workerID string, lease time.Duration, log *loggerpkg.Logger) {
+	log.InfoMsg(loggerpkg.MsgETLWorkerStart, slog.Int("attempt", job.Attempts))
+
+	ctx := a.startRun(job.TaskID)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		a.heartbeat(ctx, job.TaskID, workerID, lease, log)
+	}()
+
+	a.processETLJob(ctx, job, log)
+	a.finishRun(job.TaskID)
+	<-heartbeatDone
+
+	if err := a.JobStorage.FinishETLJob(context.Background(), job.TaskID, workerID); err != nil {
+		log.Error("не удалось убрать задание из очереди", slog.String("error", err.Error()))
+	}
+}
+
+func (a *AnalyticsDataCenterService) heartbeat(ctx context.Context, taskID, workerID string, lease time.Duration, log *loggerpkg.Logger) {
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := a.JobStorage.ExtendETLJobLease(ctx, taskID, workerID, lease)
+			if errors.Is(err, storage.ErrJobLost) {
+				log.Warn("аренда задания утрачена, выполнение прерывается")
+				a.cancelRun(taskID)
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Error("не удалось продлить аренду задания", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
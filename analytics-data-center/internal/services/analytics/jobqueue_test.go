@@ -0,0 +1,64 @@
+package serviceanalytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartETLProcess_RejectsWhenQueueFull(t *testing.T) {
+	jobs := &mockJobStorage{}
+	svc := newCancelTestService(&mockTaskService{}, jobs)
+	svc.jobSignal = make(chan struct{}, 1)
+	svc.SetJobQueueOptions(JobQueueOptions{Limit: 2})
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.StartETLProcess(context.Background(), 1)
+		require.NoError(t, err)
+	}
+	_, err := svc.StartETLProcess(context.Background(), 1)
+
+	require.ErrorIs(t, err, storage.ErrQueueFull)
+	require.Len(t, jobs.jobs, 2)
+	require.Equal(t, 2, jobs.limit)
+}
+
+func TestRecoverETLJobs_FailsExhaustedAndOrphanTasks(t *testing.T) {
+	tasks := &mockTaskService{}
+	jobs := &mockJobStorage{requeued: []string{"t-requeued"}, failed: []string{"t-failed"}, orphans: []string{"t-orphan"}}
+	svc := newCancelTestService(tasks, jobs)
+	svc.jobSignal = make(chan struct{}, 1)
+
+	svc.recoverETLJobs(context.Background(), true)
+
+	require.Equal(t, []string{Error, Error}, tasks.statuses)
+	require.Equal(t, []string{ErrorJobAttemptsExhausted, ErrorJobAttemptsExhausted}, tasks.comments)
+	// о возвращённых заданиях сообщается воркерам
+	require.Len(t, svc.jobSignal, 1)
+}
+
+func TestRunJob_LostLeaseCancelsTask(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	tasks := &mockTaskService{}
+	svc := newETLTestService(dwh, tasks)
+	jobs := &mockJobStorage{leaseLost: true, jobs: []models.ETLJob{{TaskID: "task-1", ViewID: 1, Status: models.ETLJobQueued}}}
+	svc.JobStorage = jobs
+	svc.running = make(map[string]context.CancelFunc)
+	// выгрузка длится, пока воркер не обнаружит потерю аренды
+	svc.OLTPFactory.(*mockFactory).store["db1"].(*mockOLTP).countHook = func(ctx context.Context) {
+		<-ctx.Done()
+	}
+
+	job, err := jobs.ClaimETLJob(context.Background(), "w1", 30*time.Millisecond)
+	require.NoError(t, err)
+	svc.runJob(*job, "w1", 30*time.Millisecond, getTestLogger())
+
+	require.Equal(t, []string{Cancelled}, tasks.statuses)
+	require.Equal(t, []string{"task-1"}, jobs.finished)
+	require.Empty(t, svc.running)
+}
@@ -0,0 +1,630 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"analyticDataCenter/analytics-data-center/internal/lib/joingraph"
+	"analyticDataCenter/analytics-data-center/internal/lib/rowfilter"
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// joinedRow — одна комбинация строк таблиц view, соединённых по джоинам
+type joinedRow map[joingraph.TableRef]map[string]interface{}
+
+// refreshJoinedView пересобирает строки view с джоинами, затронутые изменениями строк
+// её таблиц. По графу джоинов находятся строки корневой таблицы, связанные со старым и
+// новым образом каждой строки, после чего для каждой из них — один раз, сколько бы
+// изменений её ни затронуло, — полная строка view заново собирается из OLTP-источников
+// так же, как это сделал бы полный runETL.
+func (a *AnalyticsDataCenterService) refreshJoinedView(ctx context.Context, ev eventView, events ...models.CDCEventData) error {
+	const op = "refreshJoinedView"
+	log := a.log.With(slog.String("op", op), slog.String("view", ev.view.Name))
+
+	graph, err := joingraph.Build(ev.view)
+	if err != nil {
+		log.Error("не удалось построить граф джоинов", slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, ok := joingraph.FindTable(ev.view, graph.Root); !ok {
+		return fmt.Errorf("%s: корневая таблица %s не найдена в источниках view", op, graph.Root)
+	}
+
+	var changes []joinChange
+	for _, evtData := range events {
+		eventRef := joingraph.TableRef{Source: evtData.Source.DB, Schema: evtData.Source.Schema, Table: evtData.Source.Table}
+		if !graph.Contains(eventRef) {
+			log.Warn("таблица события не участвует в джоинах view, обновление пропущено",
+				slog.String("table", eventRef.String()))
+			continue
+		}
+
+		var images []map[string]interface{}
+		if len(evtData.After) > 0 {
+			images = append(images, evtData.After)
+		}
+		if len(evtData.Before) > 0 {
+			images = append(images, evtData.Before)
+		}
+		if len(images) == 0 {
+			log.Warn("в событии нет образов строки, обновление пропущено")
+			continue
+		}
+		changes = append(changes, joinChange{ref: eventRef, images: images})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// 1. Опорные строки и их блокировка. События родительской и дочерней таблиц одной
+	// строки view попадают к разным воркерам, поэтому пересборка держит блокировку
+	// опорных ключей от чтения OLTP до записи в DWH — иначе более раннее чтение может
+	// записаться позже и затереть новое. Опорные строки ищутся заново уже под
+	// блокировкой; если связи успели измениться, блокировка расширяется.
+	var (
+		anchors []joinAnchor
+		locked  = make(map[string]struct{})
+		unlock  = func() {}
+	)
+	for {
+		anchors = anchors[:0]
+		for _, change := range changes {
+			found, err := a.joinAnchors(ctx, ev.view, graph, change.ref, change.images)
+			if err != nil {
+				unlock()
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			anchors = append(anchors, found...)
+		}
+		var keys []string
+		anchors, keys = uniqueJoinAnchors(anchors, a.joinLockKeys(ev.view, anchors, log.Logger))
+		if hasKeys(locked, keys) {
+			break
+		}
+		unlock()
+		for _, key := range keys {
+			locked[key] = struct{}{}
+		}
+		unlock = a.joinLocks.lock(setKeys(locked))
+	}
+	defer unlock()
+
+	// 2. Устаревшие строки view убираются по ключам опорных строк
+	deleted := make(map[string]struct{})
+	var (
+		removeKeys []map[string]interface{}
+		rebuild    []joinAnchor
+	)
+	for _, anchor := range anchors {
+		table, ok := joingraph.FindTable(ev.view, anchor.ref)
+		if !ok {
+			continue
+		}
+
+		keys := a.updateKeyValues(table, anchor.row, log.Logger)
+		if len(keys) == 0 {
+			log.Warn("у опорной таблицы нет ключей обновления, устаревшие строки view не удаляются",
+				slog.String("table", anchor.ref.String()))
+		} else if fp := fingerprint(keys); !hasKey(deleted, fp) {
+			deleted[fp] = struct{}{}
+			removeKeys = append(removeKeys, keys)
+		}
+
+		if !anchor.refetch {
+			rebuild = append(rebuild, anchor)
+			continue
+		}
+		// образ из события перечитываем из OLTP: строки могло уже не стать
+		lookupColumns := rowLookupColumns(table)
+		rows, err := a.selectRowsByColumns(ctx, ev.view, anchor.ref, lookupColumns, collectTuples([]map[string]interface{}{anchor.row}, lookupColumns))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, row := range rows {
+			rebuild = append(rebuild, joinAnchor{ref: anchor.ref, row: row})
+		}
+	}
+
+	// 3. Полные строки view собираются заново
+	seenAnchors := make(map[string]struct{})
+	var rows []models.RowUpsert
+	for _, anchor := range rebuild {
+		fp := anchor.ref.String() + "|" + fingerprint(anchor.row)
+		if hasKey(seenAnchors, fp) {
+			continue
+		}
+		seenAnchors[fp] = struct{}{}
+
+		combos, err := a.buildJoinedRows(ctx, ev.view, graph, anchor.ref, anchor.row)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, combo := range combos {
+			finalRow := a.joinedViewRow(ev.view, combo, log.Logger)
+			conflictColumns := conflictColumnsOf(ev.view, combo)
+			if len(conflictColumns) == 0 {
+				log.Warn("во view нет ключей обновления, строка не может быть записана")
+				continue
+			}
+
+			rows = append(rows, models.RowUpsert{Row: finalRow, ConflictColumns: conflictColumns})
+		}
+	}
+
+	// 4. Удаление и вставка выполняются одной операцией DWH: сбой между ними не оставит
+	// view без строк. Удаление учитывает DeletePolicy, как removeViewRow.
+	if err := a.DWHProvider.ReplaceRows(ctx, ev.view.Name, removeKeys, ev.view.IsSoftDelete(), rows); err != nil {
+		if isRelationDoesNotExist(err) {
+			log.Warn("таблица отсутствует в DWH, пропускаю обновление")
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("строки view пересобраны",
+		slog.Int("anchors", len(seenAnchors)),
+		slog.Int("events", len(changes)))
+	return nil
+}
+
+// joinChange — изменённая строка таблицы view: её старый и новый образы
+type joinChange struct {
+	ref    joingraph.TableRef
+	images []map[string]interface{}
+}
+
+// uniqueJoinAnchors оставляет по одной опорной строке на ключ блокировки: строка view,
+// затронутая несколькими изменениями, пересобирается один раз
+func uniqueJoinAnchors(anchors []joinAnchor, keys []string) ([]joinAnchor, []string) {
+	seen := make(map[string]struct{}, len(keys))
+	uniqueAnchors := make([]joinAnchor, 0, len(anchors))
+	uniqueKeys := make([]string, 0, len(keys))
+	for i, key := range keys {
+		if hasKey(seen, key) {
+			continue
+		}
+		seen[key] = struct{}{}
+		uniqueAnchors = append(uniqueAnchors, anchors[i])
+		uniqueKeys = append(uniqueKeys, key)
+	}
+	return uniqueAnchors, uniqueKeys
+}
+
+// joinAnchor — строка, от которой по графу джоинов разворачиваются строки view.
+// refetch — строка взята из события и перед сборкой перечитывается из OLTP.
+type joinAnchor struct {
+	ref     joingraph.TableRef
+	row     map[string]interface{}
+	refetch bool
+}
+
+// joinAnchors находит опорные строки: строки корня, связанные со старым и новым образом
+// строки. Если цепочка до корня обрывается на внешнем джоине, опорной становится
+// последняя найденная строка — во view она есть с NULL на стороне корня.
+func (a *AnalyticsDataCenterService) joinAnchors(
+	ctx context.Context,
+	view models.View,
+	graph joingraph.Graph,
+	eventRef joingraph.TableRef,
+	images []map[string]interface{},
+) ([]joinAnchor, error) {
+	var anchors []joinAnchor
+	if eventRef == graph.Root {
+		for _, image := range images {
+			anchors = append(anchors, joinAnchor{ref: graph.Root, row: image, refetch: true})
+		}
+		return anchors, nil
+	}
+
+	path, ok := graph.PathTo(eventRef, graph.Root)
+	if !ok {
+		return nil, fmt.Errorf("нет пути от %s до корневой таблицы %s", eventRef, graph.Root)
+	}
+	for _, image := range images {
+		found, err := a.resolveJoinAnchors(ctx, view, eventRef, path, image)
+		if err != nil {
+			return nil, err
+		}
+		anchors = append(anchors, found...)
+	}
+	return anchors, nil
+}
+
+// joinLockKeys — ключи блокировки строк view, собираемых от опорных строк. Строка без
+// ключей обновления блокируется по всему образу.
+func (a *AnalyticsDataCenterService) joinLockKeys(view models.View, anchors []joinAnchor, log *slog.Logger) []string {
+	keys := make([]string, 0, len(anchors))
+	for _, anchor := range anchors {
+		values := anchor.row
+		if table, ok := joingraph.FindTable(view, anchor.ref); ok {
+			if updateKeys := a.updateKeyValues(table, anchor.row, log); len(updateKeys) > 0 {
+				values = updateKeys
+			}
+		}
+		keys = append(keys, view.Name+"|"+anchor.ref.String()+"|"+fingerprint(values))
+	}
+	return keys
+}
+
+// resolveJoinAnchors поднимается от образа строки события к корню графа джоинов
+func (a *AnalyticsDataCenterService) resolveJoinAnchors(
+	ctx context.Context,
+	view models.View,
+	eventRef joingraph.TableRef,
+	path []joingraph.Step,
+	image map[string]interface{},
+) ([]joinAnchor, error) {
+	current := []map[string]interface{}{image}
+	at := eventRef
+
+	for _, step := range path {
+		next, err := a.selectRowsByColumns(ctx, view, step.To, step.ToColumns, collectTuples(current, step.FromColumns))
+		if err != nil {
+			return nil, err
+		}
+		if len(next) == 0 {
+			if !step.Optional() {
+				return nil, nil
+			}
+			anchors := make([]joinAnchor, 0, len(current))
+			for _, row := range current {
+				anchors = append(anchors, joinAnchor{ref: at, row: row, refetch: at == eventRef})
+			}
+			return anchors, nil
+		}
+		current = next
+		at = step.To
+	}
+
+	anchors := make([]joinAnchor, 0, len(current))
+	for _, row := range current {
+		anchors = append(anchors, joinAnchor{ref: at, row: row})
+	}
+	return anchors, nil
+}
+
+// buildJoinedRows разворачивает опорную строку во все строки view, проходя по джоинам.
+// Комбинации без пары во внутреннем джоине отбрасываются, во внешнем — необязательная
+// сторона остаётся пустой (nil) и заполняется NULL.
+func (a *AnalyticsDataCenterService) buildJoinedRows(
+	ctx context.Context,
+	view models.View,
+	graph joingraph.Graph,
+	anchorRef joingraph.TableRef,
+	anchorRow map[string]interface{},
+) ([]joinedRow, error) {
+	combos := []joinedRow{{anchorRef: anchorRow}}
+	cache := make(map[string][]map[string]interface{})
+
+	for _, step := range graph.Walk(anchorRef) {
+		var next []joinedRow
+		for _, combo := range combos {
+			from := combo[step.From]
+
+			// обе таблицы уже в комбинации — ребро работает как дополнительное условие
+			if existing, known := combo[step.To]; known {
+				if from == nil || existing == nil {
+					if step.Optional() {
+						next = append(next, combo)
+					}
+					continue
+				}
+				if columnsMatch(existing, step.ToColumns, from, step.FromColumns) {
+					next = append(next, combo)
+				}
+				continue
+			}
+
+			var matches []map[string]interface{}
+			if tuples := collectTuples([]map[string]interface{}{from}, step.FromColumns); len(tuples) > 0 {
+				cacheKey := fmt.Sprintf("%s|%v|%v", step.To, step.ToColumns, tuples[0])
+				cached, found := cache[cacheKey]
+				if !found {
+					var err error
+					cached, err = a.selectRowsByColumns(ctx, view, step.To, step.ToColumns, tuples)
+					if err != nil {
+						return nil, err
+					}
+					cache[cacheKey] = cached
+				}
+				matches = cached
+			}
+
+			if len(matches) == 0 {
+				// сторона From уже пустая или джоин внешний — продолжаем с NULL
+				if from == nil || step.Optional() {
+					next = append(next, combo.with(step.To, nil))
+				}
+				continue
+			}
+
+			for _, match := range matches {
+				next = append(next, combo.with(step.To, match))
+			}
+		}
+		combos = next
+	}
+
+	return combos, nil
+}
+
+// columnsMatch сравнивает значения пар колонок двух строк; NULL ни с чем не совпадает
+func columnsMatch(left map[string]interface{}, leftColumns []string, right map[string]interface{}, rightColumns []string) bool {
+	if len(leftColumns) != len(rightColumns) {
+		return false
+	}
+	for i := range leftColumns {
+		l, r := left[leftColumns[i]], right[rightColumns[i]]
+		if l == nil || r == nil || fmt.Sprintf("%v", l) != fmt.Sprintf("%v", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r joinedRow) with(ref joingraph.TableRef, row map[string]interface{}) joinedRow {
+	extended := make(joinedRow, len(r)+1)
+	for k, v := range r {
+		extended[k] = v
+	}
+	extended[ref] = row
+	return extended
+}
+
+// joinedViewRow собирает строку view из комбинации строк таблиц. Колонки таблиц
+// без пары во внешнем джоине заполняются NULL, не перетирая уже заданные значения.
+func (a *AnalyticsDataCenterService) joinedViewRow(view models.View, combo joinedRow, log *slog.Logger) map[string]interface{} {
+	finalRow := make(map[string]interface{})
+	conflictKeys := make(map[string]struct{})
+
+	for ref, raw := range combo {
+		if raw == nil {
+			continue
+		}
+		if table, ok := joingraph.FindTable(view, ref); ok {
+			a.fillViewRow(view.Name, table, raw, finalRow, conflictKeys, log)
+		}
+	}
+	for ref, raw := range combo {
+		if raw != nil {
+			continue
+		}
+		if table, ok := joingraph.FindTable(view, ref); ok {
+			fillNullViewRow(table, finalRow)
+		}
+	}
+
+	resetSoftDelete(view, finalRow)
+	return finalRow
+}
+
+// conflictColumnsOf — ключи обновления строки view: ключевые колонки непустых сторон джоина
+func conflictColumnsOf(view models.View, combo joinedRow) []string {
+	seen := make(map[string]struct{})
+	for ref, raw := range combo {
+		if raw == nil {
+			continue
+		}
+		table, ok := joingraph.FindTable(view, ref)
+		if !ok {
+			continue
+		}
+		for _, column := range table.Columns {
+			if !column.IsUpdateKey {
+				continue
+			}
+			if _, ok := raw[column.Name]; !ok {
+				continue
+			}
+			target := column.Name
+			if column.Alias != "" {
+				target = column.Alias
+			}
+			seen[target] = struct{}{}
+			if column.ViewKey != "" {
+				seen[column.ViewKey] = struct{}{}
+			}
+		}
+	}
+
+	columns := make([]string, 0, len(seen))
+	for k := range seen {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// fillNullViewRow заполняет NULL колонки view, относящиеся к таблице без пары во внешнем джоине
+func fillNullViewRow(table models.Table, finalRow map[string]interface{}) {
+	setNull := func(column string) {
+		if _, exists := finalRow[column]; !exists {
+			finalRow[column] = nil
+		}
+	}
+
+	for _, column := range table.Columns {
+		target := column.Name
+		if column.Alias != "" {
+			target = column.Alias
+		}
+		setNull(target)
+
+		if column.Transform == nil || column.Transform.Mapping == nil {
+			continue
+		}
+		switch column.Transform.Type {
+		case "FieldTransform":
+			outputColumn := column.Transform.Mapping.AliasNewColumnTransform
+			if outputColumn == "" {
+				outputColumn = column.Name + "_transformed"
+			}
+			setNull(outputColumn)
+		case "JSON":
+			for _, mappingJSON := range column.Transform.Mapping.MappingJSON {
+				for _, outputColumn := range mappingJSON.Mapping {
+					setNull(outputColumn)
+				}
+			}
+		}
+	}
+}
+
+// selectRowsByColumns читает строки таблицы OLTP, у которых набор колонок совпадает с одним из кортежей
+func (a *AnalyticsDataCenterService) selectRowsByColumns(
+	ctx context.Context,
+	view models.View,
+	ref joingraph.TableRef,
+	columns []string,
+	tuples [][]interface{},
+) ([]map[string]interface{}, error) {
+	if len(tuples) == 0 || len(columns) == 0 {
+		return nil, nil
+	}
+
+	query, err := sqlgenerator.GenerateSelectRowsByColumnsQuery(ref.Schema, ref.Table, columns, len(tuples))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, 0, len(tuples)*len(columns))
+	for _, tuple := range tuples {
+		args = append(args, tuple...)
+	}
+
+	oltp, err := a.OLTPFactory.GetOLTPStorage(ctx, ref.Source)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := oltp.SelectRows(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// строки, не прошедшие фильтр таблицы, во view не попадают — как и при полной загрузке
+	table, _ := joingraph.FindTable(view, ref)
+	matched := rows[:0]
+	for _, row := range rows {
+		normalizeOLTPRow(table, row)
+		if rowfilter.Match(table.Filter, row) {
+			matched = append(matched, row)
+		}
+	}
+	return matched, nil
+}
+
+// rowLookupColumns — колонки, по которым строка таблицы однозначно находится в OLTP
+func rowLookupColumns(table models.Table) []string {
+	var columns []string
+	for _, column := range table.Columns {
+		if column.IsUpdateKey {
+			columns = append(columns, column.Name)
+		}
+	}
+	if len(columns) > 0 {
+		return columns
+	}
+	for _, column := range table.Columns {
+		if column.IsPrimaryKey {
+			columns = append(columns, column.Name)
+		}
+	}
+	return columns
+}
+
+// collectTuples собирает уникальные непустые кортежи значений колонок из строк
+func collectTuples(rows []map[string]interface{}, columns []string) [][]interface{} {
+	var tuples [][]interface{}
+	seen := make(map[string]struct{})
+
+rowsLoop:
+	for _, row := range rows {
+		tuple := make([]interface{}, 0, len(columns))
+		for _, col := range columns {
+			val, ok := row[col]
+			if !ok || val == nil {
+				continue rowsLoop
+			}
+			tuple = append(tuple, val)
+		}
+		key := fmt.Sprintf("%v", tuple)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		tuples = append(tuples, tuple)
+	}
+	return tuples
+}
+
+// normalizeOLTPRow приводит значения, которые драйвер вернул байтами (uuid, numeric, json),
+// к строкам, как их присылает Debezium. bytea-колонки остаются как есть.
+func normalizeOLTPRow(table models.Table, row map[string]interface{}) {
+	byteaColumns := make(map[string]struct{})
+	for _, column := range table.Columns {
+		t := strings.ToLower(column.DataType + " " + column.UdtName + " " + column.Type)
+		if strings.Contains(t, "bytea") {
+			byteaColumns[column.Name] = struct{}{}
+		}
+	}
+	for col, val := range row {
+		b, ok := val.([]byte)
+		if !ok {
+			continue
+		}
+		if _, keep := byteaColumns[col]; keep {
+			continue
+		}
+		row[col] = string(b)
+	}
+}
+
+func hasKey(set map[string]struct{}, key string) bool {
+	_, ok := set[key]
+	return ok
+}
+
+// hasKeys сообщает, что в множестве есть все ключи
+func hasKeys(set map[string]struct{}, keys []string) bool {
+	for _, key := range keys {
+		if !hasKey(set, key) {
+			return false
+		}
+	}
+	return true
+}
+
+func setKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func fingerprint(values map[string]interface{}) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%v;", k, values[k])
+	}
+	return b.String()
+}
+
+// isJoinedChild сообщает, что таблица события участвует в джоинах view, но не является корнем
+func isJoinedChild(view models.View, evtData models.CDCEventData) bool {
+	graph, err := joingraph.Build(view)
+	if err != nil {
+		return false
+	}
+	ref := joingraph.TableRef{Source: evtData.Source.DB, Schema: evtData.Source.Schema, Table: evtData.Source.Table}
+	return graph.Contains(ref) && ref != graph.Root
+}
@@ -0,0 +1,381 @@
+package serviceanalytics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+func joinedTestView() models.View {
+	return models.View{
+		Name: "user_basic_info",
+		Sources: []models.Source{{
+			Name: "db1",
+			Schemas: []models.Schema{{
+				Name: "public",
+				Tables: []models.Table{
+					{
+						Name: "users",
+						Columns: []models.Column{
+							{Name: "id", IsUpdateKey: true, IsPrimaryKey: true},
+							{Name: "email"},
+						},
+					},
+					{
+						Name: "profiles",
+						Columns: []models.Column{
+							{Name: "profile_id", IsUpdateKey: true},
+							{Name: "user_id"},
+							{Name: "age"},
+						},
+					},
+				},
+			}},
+		}},
+		Joins: []*models.Join{{Inner: &models.JoinCondition{
+			Left:  models.JoinEndpoint{Source: "db1", Schema: "public", Table: "users", Column: "id"},
+			Right: models.JoinEndpoint{Source: "db1", Schema: "public", Table: "profiles", Column: "user_id"},
+		}}},
+	}
+}
+
+// tableRowsSelector эмулирует OLTP: фильтрует строки таблицы из запроса по колонкам условия WHERE
+func tableRowsSelector(data map[string][]map[string]interface{}) func(string, []interface{}) ([]map[string]interface{}, error) {
+	return func(query string, args []interface{}) ([]map[string]interface{}, error) {
+		for table, rows := range data {
+			if !strings.Contains(query, fmt.Sprintf(`"public".%q`, table)) {
+				continue
+			}
+			where := query[strings.Index(query, "WHERE ")+len("WHERE ") : strings.Index(query, " IN ")]
+			var columns []string
+			for _, col := range strings.Split(strings.Trim(where, "()"), ", ") {
+				columns = append(columns, strings.Trim(col, `"`))
+			}
+			var result []map[string]interface{}
+			for _, row := range rows {
+				for i := 0; i+len(columns) <= len(args); i += len(columns) {
+					matched := true
+					for j, column := range columns {
+						if fmt.Sprintf("%v", row[column]) != fmt.Sprintf("%v", args[i+j]) {
+							matched = false
+							break
+						}
+					}
+					if matched {
+						copyRow := make(map[string]interface{}, len(row))
+						for k, v := range row {
+							copyRow[k] = v
+						}
+						result = append(result, copyRow)
+						break
+					}
+				}
+			}
+			return result, nil
+		}
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+}
+
+func TestRefreshJoinedView_ChildInsertGetsParentColumns(t *testing.T) {
+	oltp := &mockOLTP{selectRows: tableRowsSelector(map[string][]map[string]interface{}{
+		"users": {{"id": int64(1), "email": "a@example.com"}},
+		"profiles": {
+			{"profile_id": int64(10), "user_id": int64(1), "age": int64(30)},
+			{"profile_id": int64(11), "user_id": int64(1), "age": int64(31)},
+		},
+	})}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		DWHProvider: dwh,
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHDbName:   DbPostgres,
+	}
+
+	evt := models.CDCEventData{
+		Op:     "c",
+		After:  map[string]interface{}{"profile_id": float64(11), "user_id": float64(1), "age": float64(31)},
+		Source: models.CDCSource{DB: "db1", Schema: "public", Table: "profiles"},
+	}
+
+	err := svc.refreshJoinedView(context.Background(), eventView{id: 1, view: joinedTestView()}, evt)
+	require.NoError(t, err)
+
+	require.Len(t, dwh.rowDeleteCalls, 1)
+	require.Equal(t, map[string]interface{}{"id": int64(1)}, dwh.rowDeleteCalls[0].row)
+
+	require.Len(t, dwh.upsertCalls, 2)
+	for _, call := range dwh.upsertCalls {
+		require.Equal(t, "a@example.com", call.row["email"])
+		require.ElementsMatch(t, []string{"id", "profile_id"}, call.keys)
+	}
+}
+
+func TestRefreshJoinedView_SerializesParentAndChildOfOneRow(t *testing.T) {
+	selector := tableRowsSelector(map[string][]map[string]interface{}{
+		"users":    {{"id": int64(1), "email": "a@example.com"}},
+		"profiles": {{"profile_id": int64(10), "user_id": int64(1), "age": int64(30)}},
+	})
+	parentRead := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	oltp := &mockOLTP{selectRows: func(query string, args []interface{}) ([]map[string]interface{}, error) {
+		// событие родителя перечитывает строку users под блокировкой и задерживается
+		if strings.Contains(query, `"users" WHERE "id"`) {
+			blocked := false
+			once.Do(func() { blocked = true })
+			if blocked {
+				close(parentRead)
+				<-release
+			}
+		}
+		return selector(query, args)
+	}}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		DWHProvider: dwh,
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHDbName:   DbPostgres,
+	}
+	ev := eventView{id: 1, view: joinedTestView()}
+
+	parentDone := make(chan error, 1)
+	go func() {
+		parentDone <- svc.refreshJoinedView(context.Background(), ev, models.CDCEventData{
+			Op:     "u",
+			After:  map[string]interface{}{"id": float64(1), "email": "a@example.com"},
+			Source: models.CDCSource{DB: "db1", Schema: "public", Table: "users"},
+		})
+	}()
+	<-parentRead
+
+	childDone := make(chan error, 1)
+	go func() {
+		childDone <- svc.refreshJoinedView(context.Background(), ev, models.CDCEventData{
+			Op:     "u",
+			After:  map[string]interface{}{"profile_id": float64(10), "user_id": float64(1), "age": float64(30)},
+			Source: models.CDCSource{DB: "db1", Schema: "public", Table: "profiles"},
+		})
+	}()
+
+	// событие дочерней таблицы той же строки view ждёт, пока родитель не запишет её
+	require.Eventually(t, func() bool {
+		svc.joinLocks.mu.Lock()
+		defer svc.joinLocks.mu.Unlock()
+		for _, l := range svc.joinLocks.locks {
+			if l.refs == 2 {
+				return true
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+	require.Zero(t, dwh.replaceCalls)
+
+	close(release)
+	require.NoError(t, <-parentDone)
+	require.NoError(t, <-childDone)
+	require.Equal(t, 2, dwh.replaceCalls)
+	require.Empty(t, svc.joinLocks.locks)
+}
+
+func TestRefreshJoinedView_ChildDeleteRemovesInnerJoinedRow(t *testing.T) {
+	oltp := &mockOLTP{selectRows: tableRowsSelector(map[string][]map[string]interface{}{
+		"users":    {{"id": int64(1), "email": "a@example.com"}},
+		"profiles": {},
+	})}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		DWHProvider: dwh,
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHDbName:   DbPostgres,
+	}
+
+	evt := models.CDCEventData{
+		Op:     "d",
+		Before: map[string]interface{}{"profile_id": float64(10), "user_id": float64(1)},
+		Source: models.CDCSource{DB: "db1", Schema: "public", Table: "profiles"},
+	}
+
+	err := svc.refreshJoinedView(context.Background(), eventView{id: 1, view: joinedTestView()}, evt)
+	require.NoError(t, err)
+	require.Len(t, dwh.rowDeleteCalls, 1)
+	require.Empty(t, dwh.upsertCalls)
+}
+
+func TestRefreshJoinedView_LeftJoinFillsOptionalSideWithNull(t *testing.T) {
+	view := joinedTestView()
+	view.Joins[0].LeftOuter, view.Joins[0].Inner = view.Joins[0].Inner, nil
+
+	oltp := &mockOLTP{selectRows: tableRowsSelector(map[string][]map[string]interface{}{
+		"users":    {{"id": int64(2), "email": "b@example.com"}},
+		"profiles": {},
+	})}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		DWHProvider: dwh,
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHDbName:   DbPostgres,
+	}
+
+	evt := models.CDCEventData{
+		Op:     "c",
+		After:  map[string]interface{}{"id": float64(2), "email": "b@example.com"},
+		Source: models.CDCSource{DB: "db1", Schema: "public", Table: "users"},
+	}
+
+	err := svc.refreshJoinedView(context.Background(), eventView{id: 1, view: view}, evt)
+	require.NoError(t, err)
+
+	require.Len(t, dwh.upsertCalls, 1)
+	row := dwh.upsertCalls[0].row
+	require.Equal(t, "b@example.com", row["email"])
+	require.Contains(t, row, "age")
+	require.Nil(t, row["age"])
+	require.Nil(t, row["profile_id"])
+	require.Equal(t, []string{"id"}, dwh.upsertCalls[0].keys)
+}
+
+func TestRefreshJoinedView_RightJoinKeepsOrphanChild(t *testing.T) {
+	view := joinedTestView()
+	view.Joins[0].RightOuter, view.Joins[0].Inner = view.Joins[0].Inner, nil
+
+	oltp := &mockOLTP{selectRows: tableRowsSelector(map[string][]map[string]interface{}{
+		"users":    {},
+		"profiles": {{"profile_id": int64(12), "user_id": int64(99), "age": int64(40)}},
+	})}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		DWHProvider: dwh,
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHDbName:   DbPostgres,
+	}
+
+	evt := models.CDCEventData{
+		Op:     "c",
+		After:  map[string]interface{}{"profile_id": float64(12), "user_id": float64(99), "age": float64(40)},
+		Source: models.CDCSource{DB: "db1", Schema: "public", Table: "profiles"},
+	}
+
+	err := svc.refreshJoinedView(context.Background(), eventView{id: 1, view: view}, evt)
+	require.NoError(t, err)
+
+	require.Len(t, dwh.rowDeleteCalls, 1)
+	require.Equal(t, map[string]interface{}{"profile_id": float64(12)}, dwh.rowDeleteCalls[0].row)
+	require.Len(t, dwh.upsertCalls, 1)
+	row := dwh.upsertCalls[0].row
+	require.Equal(t, int64(40), row["age"])
+	require.Nil(t, row["email"])
+	require.Nil(t, row["id"])
+}
+
+func TestRefreshJoinedView_CompositeJoinKeys(t *testing.T) {
+	view := joinedTestView()
+	view.Sources[0].Schemas[0].Tables[0].Columns = append(view.Sources[0].Schemas[0].Tables[0].Columns, models.Column{Name: "tenant_id"})
+	view.Sources[0].Schemas[0].Tables[1].Columns = append(view.Sources[0].Schemas[0].Tables[1].Columns, models.Column{Name: "tenant_id"})
+	cond := view.Joins[0].Inner
+	cond.Left.Column, cond.Right.Column = "", ""
+	cond.Columns = []models.JoinColumnPair{
+		{Left: "tenant_id", Right: "tenant_id"},
+		{Left: "id", Right: "user_id"},
+	}
+
+	oltp := &mockOLTP{selectRows: tableRowsSelector(map[string][]map[string]interface{}{
+		"users": {
+			{"id": int64(1), "tenant_id": int64(7), "email": "t7@example.com"},
+			{"id": int64(1), "tenant_id": int64(8), "email": "t8@example.com"},
+		},
+		"profiles": {
+			{"profile_id": int64(10), "user_id": int64(1), "tenant_id": int64(8), "age": int64(30)},
+		},
+	})}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		DWHProvider: dwh,
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHDbName:   DbPostgres,
+	}
+
+	evt := models.CDCEventData{
+		Op:     "u",
+		After:  map[string]interface{}{"profile_id": float64(10), "user_id": float64(1), "tenant_id": float64(8), "age": float64(30)},
+		Source: models.CDCSource{DB: "db1", Schema: "public", Table: "profiles"},
+	}
+
+	err := svc.refreshJoinedView(context.Background(), eventView{id: 1, view: view}, evt)
+	require.NoError(t, err)
+
+	// опорная строка ищется повторно под блокировкой её ключей
+	require.Len(t, oltp.selectRowsCalls, 3)
+	require.Contains(t, oltp.selectRowsCalls[0], `("tenant_id", "id") IN`)
+	require.Equal(t, oltp.selectRowsCalls[0], oltp.selectRowsCalls[1])
+
+	require.Len(t, dwh.upsertCalls, 1)
+	require.Equal(t, "t8@example.com", dwh.upsertCalls[0].row["email"])
+}
+
+func TestRefreshJoinedView_SoftDeleteViewMarksStaleRows(t *testing.T) {
+	oltp := &mockOLTP{selectRows: tableRowsSelector(map[string][]map[string]interface{}{
+		"users":    {{"id": int64(1), "email": "a@example.com"}},
+		"profiles": {{"profile_id": int64(10), "user_id": int64(1), "age": int64(30)}},
+	})}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		DWHProvider: dwh,
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHDbName:   DbPostgres,
+	}
+	view := joinedTestView()
+	view.DeletePolicy = models.DeletePolicySoft
+
+	evt := models.CDCEventData{
+		Op:     "u",
+		After:  map[string]interface{}{"profile_id": float64(10), "user_id": float64(1), "age": float64(30)},
+		Source: models.CDCSource{DB: "db1", Schema: "public", Table: "profiles"},
+	}
+
+	require.NoError(t, svc.refreshJoinedView(context.Background(), eventView{id: 1, view: view}, evt))
+	require.Empty(t, dwh.rowDeleteCalls)
+	require.Len(t, dwh.softDeleteCalls, 1)
+	require.Equal(t, map[string]interface{}{"id": int64(1)}, dwh.softDeleteCalls[0].row)
+	require.Len(t, dwh.upsertCalls, 1)
+}
+
+func TestRefreshJoinedView_FailedInsertKeepsStaleRows(t *testing.T) {
+	oltp := &mockOLTP{selectRows: tableRowsSelector(map[string][]map[string]interface{}{
+		"users":    {{"id": int64(1), "email": "a@example.com"}},
+		"profiles": {{"profile_id": int64(10), "user_id": int64(1), "age": int64(30)}},
+	})}
+	dwh := &mockDWH{upsertErr: fmt.Errorf("dwh down")}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		DWHProvider: dwh,
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHDbName:   DbPostgres,
+	}
+
+	evt := models.CDCEventData{
+		Op:     "u",
+		After:  map[string]interface{}{"profile_id": float64(10), "user_id": float64(1), "age": float64(30)},
+		Source: models.CDCSource{DB: "db1", Schema: "public", Table: "profiles"},
+	}
+
+	require.Error(t, svc.refreshJoinedView(context.Background(), eventView{id: 1, view: joinedTestView()}, evt))
+	// удаление и вставка — одна операция DWH: без вставки строки не удаляются
+	require.Equal(t, 1, dwh.replaceCalls)
+	require.Empty(t, dwh.rowDeleteCalls)
+}
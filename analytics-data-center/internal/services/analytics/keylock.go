@@ -0,0 +1,58 @@
+package serviceanalytics
+
+import (
+	"sort"
+	"sync"
+)
+
+// keyLocks — мьютексы по строковым ключам. Мьютекс ключа существует, пока его кто-то
+// держит или ждёт. Нулевое значение готово к работе.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock захватывает мьютексы всех ключей в порядке сортировки, чтобы два вызова с
+// пересекающимися ключами не ждали друг друга бесконечно. Возвращает функцию освобождения.
+func (l *keyLocks) lock(keys []string) (unlock func()) {
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+
+	held := make([]string, 0, len(keys))
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+		l.mu.Lock()
+		if l.locks == nil {
+			l.locks = make(map[string]*keyLock)
+		}
+		kl, ok := l.locks[key]
+		if !ok {
+			kl = &keyLock{}
+			l.locks[key] = kl
+		}
+		kl.refs++
+		l.mu.Unlock()
+
+		kl.Lock()
+		held = append(held, key)
+	}
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, key := range held {
+			kl := l.locks[key]
+			kl.Unlock()
+			if kl.refs--; kl.refs == 0 {
+				delete(l.locks, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// pendingReplayBatch — сколько отложенных событий читается за один проход
+const pendingReplayBatch = 100
+
+// pendingReplayKey помечает контекст применения события из буфера предложений
+// переименования: событие не откладывается повторно
+type pendingReplayKey struct{}
+
+// deferViewEvent откладывает CDC-событие таблицы для view, пока по таблице есть
+// нерешённое предложение переименования. Следующие события таблицы откладываются за
+// ним, пока буфер не будет применён, чтобы не нарушить порядок изменений.
+func (a *AnalyticsDataCenterService) deferViewEvent(ctx context.Context, ev eventView, evtData models.CDCEventData) (bool, error) {
+	const op = "analytics.deferViewEvent"
+	if replay, _ := ctx.Value(pendingReplayKey{}).(bool); replay {
+		return false, nil
+	}
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("view", ev.view.Name),
+		slog.String("table", evtData.Source.Table),
+	)
+
+	viewID := int64(ev.id)
+	db, schema, table := evtData.Source.DB, evtData.Source.Schema, evtData.Source.Table
+
+	a.pendingMu.Lock()
+	defer a.pendingMu.Unlock()
+
+	hasSuggestion, err := a.RenameSuggestionStorage.HasSuggestion(ctx, viewID, db, schema, table)
+	if err != nil {
+		log.Error("ошибка проверки предложений переименования", slog.String("error", err.Error()))
+		return false, err
+	}
+	if !hasSuggestion {
+		pending, err := a.PendingEventStorage.HasPendingEvents(ctx, viewID, db, schema, table)
+		if err != nil {
+			log.Error("ошибка проверки отложенных событий", slog.String("error", err.Error()))
+			return false, err
+		}
+		if !pending {
+			return false, nil
+		}
+	}
+
+	payload, err := json.Marshal(evtData)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := a.PendingEventStorage.SavePendingEvent(ctx, models.PendingCDCEvent{
+		ViewID:       viewID,
+		DatabaseName: db,
+		SchemaName:   schema,
+		TableName:    table,
+		Payload:      payload,
+	}); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Warn("событие отложено до решения по предложению переименования")
+	return true, nil
+}
+
+// replayPendingEvents применяет отложенные события таблицы к view, если по ней не
+// осталось нерешённых предложений. renames — принятые переименования (старое имя →
+// новое): колонки событий, пришедших до переименования в источнике, переносятся на
+// новые имена. Событие, которое не удалось применить, уходит в dead letter.
+func (a *AnalyticsDataCenterService) replayPendingEvents(ctx context.Context, viewID int64, database, schema, table string, renames map[string]string) error {
+	const op = "analytics.replayPendingEvents"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("view", viewID),
+		slog.String("table", table),
+	)
+
+	hasSuggestion, err := a.RenameSuggestionStorage.HasSuggestion(ctx, viewID, database, schema, table)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if hasSuggestion {
+		log.Info("по таблице остались предложения переименования, отложенные события ждут")
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, pendingReplayKey{}, true)
+	replayed := 0
+	for {
+		// новые события таблицы копятся в буфере, пока он не опустеет: порядок не нарушается
+		a.pendingMu.Lock()
+		events, err := a.PendingEventStorage.ListPendingEvents(ctx, viewID, database, schema, table, pendingReplayBatch)
+		a.pendingMu.Unlock()
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		ids := make([]int64, 0, len(events))
+		var replayErr error
+		for _, pending := range events {
+			var evtData models.CDCEventData
+			if err := json.Unmarshal(pending.Payload, &evtData); err != nil {
+				log.Error("не удалось разобрать отложенное событие",
+					slog.Int64("id", pending.ID),
+					slog.String("error", err.Error()))
+				ids = append(ids, pending.ID)
+				continue
+			}
+			renameEventColumns(&evtData, renames)
+
+			if err := a.replayEvent(ctx, viewID, evtData); err != nil {
+				if dlErr := a.deadLetter(ctx, models.CDCEvent{Data: evtData}, err); dlErr != nil {
+					// событие и следующие за ним остаются в буфере до следующего применения
+					replayErr = dlErr
+					break
+				}
+			}
+			ids = append(ids, pending.ID)
+			replayed++
+		}
+		if err := a.PendingEventStorage.DeletePendingEvents(ctx, ids); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if replayErr != nil {
+			return fmt.Errorf("%s: %w", op, replayErr)
+		}
+	}
+
+	if replayed > 0 {
+		log.Info("отложенные события применены", slog.Int("events", replayed))
+	}
+	return nil
+}
+
+// releasePendingEvents применяет буфер после решения по предложению переименования.
+// Решение уже сохранено, поэтому ошибка применения только логируется: события
+// остаются в буфере до следующего решения по таблице.
+func (a *AnalyticsDataCenterService) releasePendingEvents(ctx context.Context, viewID int64, database, schema, table string, renames map[string]string) {
+	if err := a.replayPendingEvents(ctx, viewID, database, schema, table, renames); err != nil {
+		a.log.Error("не удалось применить отложенные события",
+			slog.Int64("view", viewID),
+			slog.String("table", table),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// renameEventColumns переносит значения колонок события со старых имён на новые,
+// если событие пришло до переименования колонки в источнике
+func renameEventColumns(evtData *models.CDCEventData, renames map[string]string) {
+	for oldName, newName := range renames {
+		renameRowColumn(evtData.Before, oldName, newName)
+		renameRowColumn(evtData.After, oldName, newName)
+	}
+}
+
+func renameRowColumn(row map[string]interface{}, oldName, newName string) {
+	if row == nil || strings.EqualFold(oldName, newName) {
+		return
+	}
+	for k := range row {
+		if strings.EqualFold(k, newName) {
+			return
+		}
+	}
+	for k, v := range row {
+		if strings.EqualFold(k, oldName) {
+			delete(row, k)
+			row[newName] = v
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package serviceanalytics
+
+import (
+	"context"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+// nameSuggestion — предложение переименовать колонку name таблицы users view 1
+func nameSuggestion(id int64) models.ColumnRenameSuggestion {
+	return models.ColumnRenameSuggestion{
+		ID:            id,
+		SchemaID:      1,
+		DatabaseName:  "db1",
+		SchemaName:    "public",
+		TableName:     "users",
+		OldColumnName: "name",
+		NewColumnName: "full_name",
+	}
+}
+
+// renameSourceColumn переименовывает name в full_name в источнике и DWH, как это
+// происходит к моменту решения по предложению
+func renameSourceColumn(dwh *mockDWH, oltp *mockOLTP) {
+	dwh.columns["v"] = []string{"id", "full_name"}
+	oltp.columns = []models.Column{{Name: "id", Type: "integer"}, {Name: "full_name", Type: "text"}}
+}
+
+func deleteUsersEvent(id int) models.CDCEvent {
+	return models.CDCEvent{Data: models.CDCEventData{
+		Op:     "d",
+		Before: map[string]interface{}{"id": id, "name": "user"},
+		Source: models.CDCSource{DB: "db1", Schema: "public", Table: "users"},
+	}}
+}
+
+func TestPendingEvents_ReplayedWithRenamedColumnAfterAccept(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}}
+	svc, oltp := newHandoffTestService(dwh, &mockTaskService{})
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+	schemas.suggestions = []models.ColumnRenameSuggestion{nameSuggestion(1)}
+
+	acks := 0
+	svc.applyCDCEvent(context.Background(), ackedEvent(1, 90, 12, &acks))
+	// удаление не обгоняет отложенную вставку
+	svc.applyCDCEvent(context.Background(), deleteUsersEvent(1))
+
+	require.Empty(t, dwh.upsertCalls)
+	require.Empty(t, dwh.rowDeleteCalls)
+	require.Len(t, schemas.pendingEvents, 2)
+	// событие сохранено в буфере, его offset можно коммитить
+	require.Equal(t, 1, acks)
+
+	renameSourceColumn(dwh, oltp)
+	require.NoError(t, svc.AcceptColumnRenameSuggestion(context.Background(), 1))
+
+	require.Len(t, dwh.upsertCalls, 1)
+	require.Equal(t, map[string]interface{}{"id": float64(1), "full_name": "user"}, dwh.upsertCalls[0].row)
+	require.Empty(t, schemas.pendingEvents)
+
+	// после решения события применяются сразу
+	evt := usersEvent(2, 100, 13)
+	evt.Data.After = map[string]interface{}{"id": 2, "full_name": "user"}
+	svc.applyCDCEvent(context.Background(), evt)
+	require.Len(t, dwh.upsertCalls, 2)
+}
+
+func TestPendingEvents_WaitForLastSuggestionOnReject(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}}
+	svc, _ := newHandoffTestService(dwh, &mockTaskService{})
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+	second := nameSuggestion(2)
+	second.OldColumnName = "email"
+	schemas.suggestions = []models.ColumnRenameSuggestion{nameSuggestion(1), second}
+
+	svc.applyCDCEvent(context.Background(), usersEvent(1, 90, 12))
+
+	require.NoError(t, svc.RejectColumnRenameSuggestion(context.Background(), 1))
+	require.Empty(t, dwh.upsertCalls)
+	require.Len(t, schemas.pendingEvents, 1)
+
+	// отклонённое переименование не меняет колонки события
+	require.NoError(t, svc.RejectColumnRenameSuggestion(context.Background(), 2))
+	require.Equal(t, []interface{}{float64(1)}, upsertedIDs(dwh))
+	require.Equal(t, "user", dwh.upsertCalls[0].row["name"])
+	require.Empty(t, schemas.pendingEvents)
+}
+
+func TestPendingEvents_ReplayedAfterMismatchResolution(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{}}
+	svc, oltp := newHandoffTestService(dwh, &mockTaskService{})
+	svc.ColumnMismatchStorage = svc.SchemaProvider
+	schemas := svc.SchemaProvider.(*mockSchemaProvider)
+	schemas.suggestions = []models.ColumnRenameSuggestion{nameSuggestion(1)}
+
+	svc.applyCDCEvent(context.Background(), usersEvent(1, 90, 12))
+	require.Len(t, schemas.pendingEvents, 1)
+
+	// предложение закрыто вместе с группой рассинхронов
+	schemas.suggestions = nil
+	renameSourceColumn(dwh, oltp)
+	schemas.mismatchGroups = map[int64]models.ColumnMismatchGroupWithItems{
+		1: {Group: models.ColumnMismatchGroup{ID: 1, SchemaID: 1, DatabaseName: "db1", SchemaName: "public", TableName: "users", Status: models.ColumnMismatchStatusOpen}},
+	}
+	require.NoError(t, svc.ApplyColumnMismatchResolution(context.Background(), 1, models.ColumnMismatchResolution{
+		Renames: []models.RenameDecision{{OldName: "name", NewName: "full_name"}},
+	}))
+
+	require.Len(t, dwh.upsertCalls, 1)
+	require.Equal(t, "user", dwh.upsertCalls[0].row["full_name"])
+	require.Empty(t, schemas.pendingEvents)
+}
+
+func TestRenameEventColumns(t *testing.T) {
+	evt := models.CDCEventData{
+		Before: map[string]interface{}{"id": 1, "Name": "old"},
+		After:  map[string]interface{}{"id": 1, "name": "stale", "full_name": "new"},
+	}
+
+	renameEventColumns(&evt, map[string]string{"name": "full_name"})
+
+	require.Equal(t, map[string]interface{}{"id": 1, "full_name": "old"}, evt.Before)
+	// колонка уже пришла под новым именем: значение не перезаписывается
+	require.Equal(t, map[string]interface{}{"id": 1, "name": "stale", "full_name": "new"}, evt.After)
+}
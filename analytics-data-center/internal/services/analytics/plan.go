@@ -0,0 +1,207 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"analyticDataCenter/analytics-data-center/internal/lib/joingraph"
+	"analyticDataCenter/analytics-data-center/internal/lib/rowfilter"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// PlanETL возвращает SQL, который ETL сгенерирует для сохранённого view, ничего не выполняя
+func (a *AnalyticsDataCenterService) PlanETL(ctx context.Context, idView int64) (models.ETLPlan, error) {
+	const op = "analytics.PlanETL"
+
+	view, err := a.SchemaProvider.GetView(ctx, idView)
+	if err != nil {
+		if errors.Is(err, storage.ErrSchemaNotFound) {
+			return models.ETLPlan{}, fmt.Errorf("%s: %w", op, ErrInvalidSchemID)
+		}
+		return models.ETLPlan{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return a.planView(ctx, view)
+}
+
+// PlanView возвращает SQL для ещё не сохранённого описания view. Описание проверяется
+// так же, как при загрузке схемы.
+func (a *AnalyticsDataCenterService) PlanView(ctx context.Context, view models.View) (models.ETLPlan, error) {
+	if err := joingraph.Validate(view); err != nil {
+		a.log.Warn("некорректное описание джоинов", slog.String("error", err.Error()))
+		return models.ETLPlan{}, err
+	}
+	if err := rowfilter.ValidateView(view); err != nil {
+		a.log.Warn("некорректный фильтр строк", slog.String("error", err.Error()))
+		return models.ETLPlan{}, err
+	}
+	view.Name = strings.ToLower(view.Name)
+	return a.planView(ctx, view)
+}
+
+// planView собирает план по фазам runETL. Запросы генерируются теми же функциями,
+// что и при загрузке; из OLTP читаются только описания индексов. Границы чанков
+// заранее неизвестны, поэтому в запросах выборки они подставлены символически.
+func (a *AnalyticsDataCenterService) planView(ctx context.Context, view models.View) (models.ETLPlan, error) {
+	const op = "analytics.planView"
+	log := a.log.With(slog.String("op", op), slog.String("view", view.Name))
+
+	plan := models.ETLPlan{View: view.Name, DWH: a.DWHDbName, OLTP: a.OLTPDbName}
+	warn := func(format string, args ...interface{}) {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	// план строится вне задачи, поэтому имена таблиц в нём без метки задачи
+	tempQueries, duplicates, err := sqlgenerator.GenerateQueryCreateTempTable(&view, "", log.Logger, a.DWHDbName)
+	if err != nil {
+		return models.ETLPlan{}, fmt.Errorf("%s: %s: %w", op, ErrorCreateTemplateTable, err)
+	}
+	if len(duplicates) > 0 {
+		warn("дублирующиеся колонки временных таблиц: %s", strings.Join(duplicates, ", "))
+	}
+	countQueries, err := sqlgenerator.GenerateCountQueries(view, log.Logger)
+	if err != nil {
+		return models.ETLPlan{}, fmt.Errorf("%s: %s: %w", op, ErrorCountInsertData, err)
+	}
+
+	var (
+		tempPhase   = models.PlanPhase{Name: models.PlanPhaseTempTables}
+		countPhase  = models.PlanPhase{Name: models.PlanPhaseCount}
+		selectPhase = models.PlanPhase{Name: models.PlanPhaseSelect}
+		insertPhase = models.PlanPhase{Name: models.PlanPhaseInsert}
+		tempMeta    []models.TempTable
+	)
+	for idx, tempQuery := range tempQueries.Queries {
+		source, schema, tableName := tempQuery.SourceName, tempQuery.SchemaName, tempQuery.BaseTableName
+		planQuery := func(description, query string, args []interface{}) models.PlanQuery {
+			return models.PlanQuery{
+				Source:      source,
+				Schema:      schema,
+				Table:       tableName,
+				Target:      tempQuery.TableName,
+				Description: description,
+				Query:       query,
+				Args:        args,
+			}
+		}
+
+		tempPhase.Queries = append(tempPhase.Queries, planQuery("", tempQuery.Query, nil))
+		if idx < len(countQueries.Queries) {
+			countPhase.Queries = append(countPhase.Queries, planQuery("", countQueries.Queries[idx].Query, nil))
+		}
+
+		table, ok := findCountTable(view, models.CountInsertData{TableName: tableName, DataBaseName: source, SchemaName: schema})
+		if !ok {
+			warn("таблица %s.%s.%s не найдена в представлении", source, schema, tableName)
+			continue
+		}
+		selects, err := a.planSelectQueries(view, table, a.chunkSize(view, source))
+		if err != nil {
+			warn("не удалось сформировать выборку таблицы %s: %s", tableName, err)
+		}
+		for _, q := range selects {
+			selectPhase.Queries = append(selectPhase.Queries, planQuery(q.Description, q.Query, q.Args))
+		}
+
+		// колонки вставки сортируются так же, как в InsertColumns
+		columns := sqlgenerator.TempTableColumnNames(table)
+		insertColumns := append([]string(nil), columns...)
+		sort.Strings(insertColumns)
+		insert, err := sqlgenerator.GenerateInsertTemplate(tempQuery.TableName, insertColumns, a.DWHDbName)
+		if err != nil {
+			return models.ETLPlan{}, fmt.Errorf("%s: %w", op, err)
+		}
+		insertPhase.Queries = append(insertPhase.Queries, planQuery("", insert, nil))
+
+		tempColumns := make([]models.TempColumn, 0, len(columns))
+		for _, column := range columns {
+			tempColumns = append(tempColumns, models.TempColumn{ColumnName: column})
+		}
+		tempMeta = append(tempMeta, models.TempTable{
+			TempTableName: tempQuery.TableName,
+			Source:        source,
+			Schema:        schema,
+			Table:         tableName,
+			TempColumns:   tempColumns,
+		})
+	}
+
+	shadowTable := sqlgenerator.ShadowTableName(view.Name, "")
+	mergePhase := models.PlanPhase{Name: models.PlanPhaseMerge}
+	shadowView := view
+	shadowView.Name = shadowTable
+	merge, err := sqlgenerator.CreateViewQuery(shadowView, models.ViewJoinTable{TempTables: tempMeta}, log.Logger, a.DWHDbName)
+	if err != nil {
+		warn("не удалось сформировать запрос сборки view: %s", err)
+	} else {
+		mergePhase.Queries = append(mergePhase.Queries, models.PlanQuery{Target: shadowTable, Query: merge.Query, Args: merge.Args})
+	}
+
+	indexPhase := models.PlanPhase{Name: models.PlanPhaseIndexes}
+	if a.DWHDbName == DbClickhouse {
+		warn("индексы источников в ClickHouse не переносятся")
+	} else if indexes, err := a.indexQueries(ctx, &view, shadowTable); err != nil {
+		warn("не удалось получить индексы источников: %s", err)
+	} else {
+		for _, query := range indexes {
+			indexPhase.Queries = append(indexPhase.Queries, models.PlanQuery{Target: shadowTable, Query: query})
+		}
+	}
+
+	plan.Phases = []models.PlanPhase{tempPhase, countPhase, selectPhase, insertPhase, mergePhase, indexPhase}
+	log.Info("план ETL сформирован", slog.Int("tables", len(tempQueries.Queries)), slog.Int("warnings", len(plan.Warnings)))
+	return plan, nil
+}
+
+// planSelectQueries возвращает выборку таблицы целиком и, если таблица делится на чанки,
+// запрос границ чанков и выборку одного чанка с символическими границами.
+func (a *AnalyticsDataCenterService) planSelectQueries(view models.View, table models.Table, chunkSize int64) ([]models.PlanQuery, error) {
+	whole, err := sqlgenerator.GenerateSelectInsertDataQuery(view, models.Chunk{}, table.Name, a.log.Logger, a.OLTPDbName)
+	if err != nil {
+		return nil, err
+	}
+	queries := []models.PlanQuery{{
+		Description: fmt.Sprintf("выборка таблицы одним чанком (не больше %d строк)", chunkSize),
+		Query:       whole.Query,
+		Args:        whole.Args,
+	}}
+
+	strategy, keys := a.chunkStrategyFor(table)
+	var boundaries string
+	switch strategy {
+	case chunkByIntegerKey:
+		boundaries, err = sqlgenerator.GenerateKeyRangeQuery(table.Name, keys[0], table.Filter)
+	case chunkBySampledKey:
+		boundaries, err = sqlgenerator.GenerateKeyBoundariesQuery(table.Name, keys, table.Filter, chunkSize)
+	case chunkByCtid:
+		boundaries = sqlgenerator.GenerateRelationPagesQuery(table.Name)
+	default:
+		return queries, nil
+	}
+	if err != nil {
+		return queries, err
+	}
+	queries = append(queries, models.PlanQuery{
+		Description: fmt.Sprintf("границы чанков по %s (больше %d строк)", strings.Join(keys, ", "), chunkSize),
+		Query:       boundaries,
+	})
+
+	chunk := models.Chunk{Index: 1, Columns: keys}
+	for _, key := range keys {
+		chunk.Lower = append(chunk.Lower, fmt.Sprintf("<lower %s>", key))
+		chunk.Upper = append(chunk.Upper, fmt.Sprintf("<upper %s>", key))
+	}
+	selectChunk, err := sqlgenerator.GenerateSelectInsertDataQuery(view, chunk, table.Name, a.log.Logger, a.OLTPDbName)
+	if err != nil {
+		return queries, err
+	}
+	return append(queries, models.PlanQuery{
+		Description: "выборка чанка",
+		Query:       selectChunk.Query,
+		Args:        selectChunk.Args,
+	}), nil
+}
@@ -0,0 +1,98 @@
+package serviceanalytics
+
+import (
+	"context"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func planPhase(t *testing.T, plan models.ETLPlan, name string) models.PlanPhase {
+	t.Helper()
+	for _, phase := range plan.Phases {
+		if phase.Name == name {
+			return phase
+		}
+	}
+	t.Fatalf("фаза %s не найдена", name)
+	return models.PlanPhase{}
+}
+
+func TestPlanETL_RendersPhasesWithoutExecuting(t *testing.T) {
+	dwh := &mockDWH{}
+	svc := newETLTestService(dwh, &mockTaskService{})
+	oltp := svc.OLTPFactory.(*mockFactory).store["db1"].(*mockOLTP)
+	oltp.indexResult = models.Indexes{Indexes: []models.Index{{IndexName: "idx", IndexDef: "CREATE INDEX idx ON public.users(name)"}}}
+
+	plan, err := svc.PlanETL(context.Background(), 1)
+	require.NoError(t, err)
+
+	var names []string
+	for _, phase := range plan.Phases {
+		names = append(names, phase.Name)
+	}
+	require.Equal(t, []string{
+		models.PlanPhaseTempTables,
+		models.PlanPhaseCount,
+		models.PlanPhaseSelect,
+		models.PlanPhaseInsert,
+		models.PlanPhaseMerge,
+		models.PlanPhaseIndexes,
+	}, names)
+	require.Empty(t, plan.Warnings)
+
+	temp := planPhase(t, plan, models.PlanPhaseTempTables)
+	require.Len(t, temp.Queries, 1)
+	require.Equal(t, "temp_db1_public_users", temp.Queries[0].Target)
+	require.Contains(t, temp.Queries[0].Query, "CREATE TABLE")
+
+	require.Contains(t, planPhase(t, plan, models.PlanPhaseCount).Queries[0].Query, "COUNT(*)")
+
+	// выборка целиком, запрос границ по целочисленному ключу и выборка чанка
+	selects := planPhase(t, plan, models.PlanPhaseSelect).Queries
+	require.Len(t, selects, 3)
+	require.Equal(t, []interface{}{"<lower id>", "<upper id>"}, selects[2].Args)
+
+	insert := planPhase(t, plan, models.PlanPhaseInsert).Queries
+	require.Len(t, insert, 1)
+	require.Contains(t, insert[0].Query, "COPY")
+
+	merge := planPhase(t, plan, models.PlanPhaseMerge).Queries
+	require.Len(t, merge, 1)
+	require.Equal(t, "temp_v__shadow", merge[0].Target)
+
+	indexes := planPhase(t, plan, models.PlanPhaseIndexes).Queries
+	require.Len(t, indexes, 1)
+	require.Contains(t, indexes[0].Query, "temp_v__shadow")
+
+	// план ничего не выполняет ни в DWH, ни в OLTP
+	require.Empty(t, dwh.createCalls)
+	require.Zero(t, dwh.mergeCalls)
+	require.Empty(t, dwh.indexCalls)
+	require.Empty(t, oltp.streamCalls)
+	require.Empty(t, oltp.selectRowsCalls)
+}
+
+func TestPlanETL_UnknownView(t *testing.T) {
+	svc := newETLTestService(&mockDWH{}, &mockTaskService{})
+
+	_, err := svc.PlanETL(context.Background(), 42)
+	require.ErrorIs(t, err, ErrInvalidSchemID)
+}
+
+func TestPlanView_ClickhouseSkipsIndexes(t *testing.T) {
+	svc := newETLTestService(&mockDWH{}, &mockTaskService{})
+	svc.DWHDbName = DbClickhouse
+	view, err := svc.SchemaProvider.GetView(context.Background(), 1)
+	require.NoError(t, err)
+	view.Name = "V"
+
+	plan, err := svc.PlanView(context.Background(), view)
+	require.NoError(t, err)
+	require.Equal(t, "v", plan.View)
+	require.Empty(t, planPhase(t, plan, models.PlanPhaseIndexes).Queries)
+	require.Len(t, plan.Warnings, 1)
+	require.Contains(t, planPhase(t, plan, models.PlanPhaseInsert).Queries[0].Query, "INSERT INTO temp_db1_public_users (`id`, `name`)")
+}
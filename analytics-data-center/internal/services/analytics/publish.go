@@ -0,0 +1,112 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// publishView завершает пересборку view: переносит индексы на собранную теневую таблицу,
+// строит на ней уникальные индексы по ключам обновления и подменяет ею рабочую таблицу.
+// Пока подмена не прошла, аналитики видят прежнюю версию;
+// при ошибке теневая таблица удаляется, а прежняя версия остаётся нетронутой.
+func (a *AnalyticsDataCenterService) publishView(ctx context.Context, tracker *taskTracker, viewSchema *models.View) error {
+	const op = "analytics.publishView"
+	shadowTable := tracker.shadowTable(viewSchema.Name)
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("view", viewSchema.Name),
+		slog.String("shadow", shadowTable),
+	)
+
+	tracker.enter(ctx, models.StageIndexes)
+	if err := a.transferIndixesAndConstraint(ctx, viewSchema, shadowTable, a.DWHDbName); err != nil {
+		log.Error("не удалось перенести индексы на теневую таблицу", slog.String("error", err.Error()))
+		a.dropShadowTable(ctx, shadowTable)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	a.createUpdateKeyIndexes(ctx, viewSchema, shadowTable)
+
+	tracker.enter(ctx, models.StagePublish)
+	if err := a.DWHProvider.SwapTables(ctx, shadowTable, viewSchema.Name); err != nil {
+		log.Error("не удалось подменить таблицу view", slog.String("error", err.Error()))
+		a.dropShadowTable(ctx, shadowTable)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("новая версия view опубликована")
+	return nil
+}
+
+func (a *AnalyticsDataCenterService) dropShadowTable(ctx context.Context, shadowTable string) {
+	if err := a.DWHProvider.DeleteTempTable(context.WithoutCancel(ctx), shadowTable); err != nil {
+		a.log.Warn("не удалось удалить теневую таблицу", slog.String("table", shadowTable), slog.String("error", err.Error()))
+	}
+}
+
+// createUpdateKeyIndexes строит на теневой таблице уникальные индексы по ключам
+// обновления view, чтобы CDC писал пачки через INSERT ... ON CONFLICT. Если ключи в
+// данных неуникальны, индекс не создаётся и пачки пишутся без ON CONFLICT, поэтому
+// ошибка не мешает публикации.
+func (a *AnalyticsDataCenterService) createUpdateKeyIndexes(ctx context.Context, viewSchema *models.View, shadowTable string) {
+	const op = "analytics.createUpdateKeyIndexes"
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("view", viewSchema.Name),
+	)
+	if a.DWHDbName != DbPostgres || len(viewSchema.Joins) > 0 {
+		return
+	}
+	for i, keys := range viewUpdateKeySets(*viewSchema) {
+		query := sqlgenerator.UniqueKeyIndexQuery("public", shadowTable, keys, i+1)
+		if err := a.DWHProvider.CreateIndex(ctx, query); err != nil {
+			log.Warn("уникальный индекс по ключам обновления не создан, CDC будет писать пачки без ON CONFLICT",
+				slog.Any("keys", keys), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// viewUpdateKeySets — наборы ключей обновления view в именах её колонок, по одному на
+// таблицу с ключами; совпадают с ключами, по которым CDC обновляет строки таблицы
+func viewUpdateKeySets(view models.View) [][]string {
+	var sets [][]string
+	seen := make(map[string]struct{})
+	for _, source := range view.Sources {
+		for _, sch := range source.Schemas {
+			for _, table := range sch.Tables {
+				keys := make(map[string]struct{})
+				for _, column := range table.Columns {
+					if !column.IsUpdateKey {
+						continue
+					}
+					target := column.Name
+					if column.Alias != "" {
+						target = column.Alias
+					}
+					keys[target] = struct{}{}
+					if column.ViewKey != "" {
+						keys[column.ViewKey] = struct{}{}
+					}
+				}
+				if len(keys) == 0 {
+					continue
+				}
+				columns := make([]string, 0, len(keys))
+				for k := range keys {
+					columns = append(columns, k)
+				}
+				sort.Strings(columns)
+				id := strings.Join(columns, ",")
+				if _, ok := seen[id]; ok {
+					continue
+				}
+				seen[id] = struct{}{}
+				sets = append(sets, columns)
+			}
+		}
+	}
+	return sets
+}
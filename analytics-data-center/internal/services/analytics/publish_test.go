@@ -0,0 +1,92 @@
+package serviceanalytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishView_SwapsShadowTable(t *testing.T) {
+	oltp := &mockOLTP{indexResult: models.Indexes{Indexes: []models.Index{{IndexName: "idx", IndexDef: "CREATE INDEX idx ON public.t1(id)"}}}}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHProvider: dwh,
+		DWHDbName:   DbPostgres,
+	}
+	view := &models.View{Name: "Sales", Sources: []models.Source{{Name: "db1", Schemas: []models.Schema{{Name: "public", Tables: []models.Table{{Name: "t1"}}}}}}}
+
+	err := svc.publishView(context.Background(), nil, view)
+
+	require.NoError(t, err)
+	require.Len(t, dwh.indexCalls, 1)
+	require.Contains(t, dwh.indexCalls[0], "ON public.temp_sales__shadow")
+	require.Equal(t, [][2]string{{"temp_sales__shadow", "Sales"}}, dwh.swapCalls)
+	require.Empty(t, dwh.deleteCalls)
+}
+
+func TestPublishView_CreatesUpdateKeyIndexes(t *testing.T) {
+	dwh := &mockDWH{indexErr: errors.New("could not create unique index")}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": &mockOLTP{}}},
+		DWHProvider: dwh,
+		DWHDbName:   DbPostgres,
+	}
+	view := &models.View{Name: "sales", Sources: []models.Source{{Name: "db1", Schemas: []models.Schema{{Name: "public", Tables: []models.Table{
+		{Name: "orders", Columns: []models.Column{{Name: "id", Alias: "order_id", IsUpdateKey: true}, {Name: "tenant_id", IsUpdateKey: true}}},
+		{Name: "items", Columns: []models.Column{{Name: "id", IsUpdateKey: true, ViewKey: "item_id"}, {Name: "name"}}},
+		{Name: "notes", Columns: []models.Column{{Name: "text"}}},
+	}}}}}}
+
+	err := svc.publishView(context.Background(), nil, view)
+
+	// по индексу на набор ключей каждой таблицы; неуникальные ключи не мешают публикации
+	require.NoError(t, err)
+	require.Len(t, dwh.indexCalls, 2)
+	require.Contains(t, dwh.indexCalls[0], "ON public.temp_sales__shadow (order_id, tenant_id)")
+	require.Contains(t, dwh.indexCalls[1], "ON public.temp_sales__shadow (id, item_id)")
+	require.Equal(t, [][2]string{{"temp_sales__shadow", "sales"}}, dwh.swapCalls)
+}
+
+func TestPublishView_SwapFailureKeepsPreviousVersion(t *testing.T) {
+	dwh := &mockDWH{swapErr: errors.New("lock timeout")}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{}},
+		DWHProvider: dwh,
+		DWHDbName:   DbClickhouse,
+	}
+	view := &models.View{Name: "sales"}
+
+	err := svc.publishView(context.Background(), nil, view)
+
+	require.Error(t, err)
+	require.Equal(t, [][2]string{{"temp_sales__shadow", "sales"}}, dwh.swapCalls)
+	// удаляется только теневая таблица, рабочая не трогается
+	require.Equal(t, []string{"temp_sales__shadow"}, dwh.deleteCalls)
+}
+
+func TestPublishView_IndexFailureSkipsSwap(t *testing.T) {
+	oltp := &mockOLTP{indexErr: errors.New("boom")}
+	dwh := &mockDWH{}
+	svc := &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHProvider: dwh,
+		DWHDbName:   DbPostgres,
+	}
+	view := &models.View{Name: "sales", Sources: []models.Source{{Name: "db1", Schemas: []models.Schema{{Name: "public", Tables: []models.Table{{Name: "t1"}}}}}}}
+
+	err := svc.publishView(context.Background(), nil, view)
+
+	require.Error(t, err)
+	require.Empty(t, dwh.swapCalls)
+	require.Equal(t, []string{"temp_sales__shadow"}, dwh.deleteCalls)
+}
@@ -0,0 +1,200 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrTaskNotResumable — задачу нельзя продолжить: она не упала или не оставила чекпоинтов
+	ErrTaskNotResumable = errors.New("задачу нельзя продолжить")
+)
+
+// ResumedTask — сообщение уведомления о продолжении задачи
+const ResumedTask = "Задача продолжена с сохранённых чекпоинтов"
+
+// ResumeTask повторно ставит в очередь задачу, упавшую во время загрузки. Воркер
+// переиспользует сохранённые временные таблицы и загружает только чанки, не
+// отмеченные выполненными, после чего задача продолжается со сборки view. Если таблиц
+// задачи уже нет, view загружается заново (см. ownsResumeTables).
+func (a *AnalyticsDataCenterService) ResumeTask(ctx context.Context, taskID string) error {
+	const op = "analytics.ResumeTask"
+	log := a.log.With(slog.String("op", op), slog.String("task", taskID))
+
+	task, err := a.TaskService.GetTask(ctx, taskID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if task.Status != Error {
+		return fmt.Errorf("%s: %w: статус %s", op, ErrTaskNotResumable, task.Status)
+	}
+	if task.ViewID == nil {
+		return fmt.Errorf("%s: %w: у задачи не сохранён view", op, ErrTaskNotResumable)
+	}
+	if time.Since(task.CreateDate) > a.tempTableJanitorOptions().ResumeRetention {
+		// временные таблицы такой задачи уже считаются брошенными
+		return fmt.Errorf("%s: %w: истёк срок хранения временных таблиц", op, ErrTaskNotResumable)
+	}
+	chunks, err := a.TaskService.ListTaskChunks(ctx, taskID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(chunks) == 0 {
+		return fmt.Errorf("%s: %w: нет чекпоинтов загрузки", op, ErrTaskNotResumable)
+	}
+
+	if err := a.JobStorage.RequeueETLJob(ctx, taskID, *task.ViewID, Progress, a.queueOptions().Limit); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("задача поставлена в очередь на продолжение", slog.Int("chunks", len(chunks)))
+
+	select {
+	case a.jobSignal <- struct{}{}:
+	default:
+	}
+	a.newTaskTracker(taskID).notify(Progress, ResumedTask)
+	return nil
+}
+
+// ownsResumeTables проверяет, что продолжаемой задаче остались её временные таблицы:
+// каждая закреплена за задачей и есть в DWH, а чекпоинты ссылаются только на них.
+// Иначе загруженные чанки продолжать не из чего — задача загружается заново.
+func (a *AnalyticsDataCenterService) ownsResumeTables(ctx context.Context, tracker *taskTracker, tables []string) (bool, error) {
+	const op = "analytics.ownsResumeTables"
+	log := a.log.With(slog.String("op", op), slog.String("task", tracker.id()))
+
+	registered, err := a.TaskService.GetTaskTempTables(ctx, tracker.id())
+	if err != nil {
+		return false, err
+	}
+	present, err := a.DWHProvider.ListTempTables(ctx, sqlgenerator.TaskTablePrefix(tracker.id()))
+	if err != nil {
+		return false, err
+	}
+	owned := tableSet(registered)
+	existing := tableSet(present)
+	expected := tableSet(tables)
+	for _, table := range tables {
+		if _, ok := owned[strings.ToLower(table)]; !ok {
+			log.Warn("временная таблица не закреплена за задачей, view загружается заново", slog.String("table", table))
+			return false, nil
+		}
+		if _, ok := existing[strings.ToLower(table)]; !ok {
+			log.Warn("временной таблицы задачи нет в DWH, view загружается заново", slog.String("table", table))
+			return false, nil
+		}
+	}
+	for _, checkpoints := range tracker.stored {
+		for _, checkpoint := range checkpoints {
+			if _, ok := expected[strings.ToLower(checkpoint.TempTable)]; !ok {
+				log.Warn("чекпоинт ссылается на чужую временную таблицу, view загружается заново",
+					slog.String("table", checkpoint.TempTable))
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func tableSet(tables []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(tables))
+	for _, table := range tables {
+		set[strings.ToLower(table)] = struct{}{}
+	}
+	return set
+}
+
+// loadPlan возвращает чанки таблицы, которые нужно загрузить. При обычном запуске
+// таблица делится на чанки заново, а план сохраняется чекпоинтами задачи. При
+// продолжении задачи из сохранённого плана берутся только незагруженные чанки, а их
+// частично вставленные строки удаляются из временной таблицы.
+func (a *AnalyticsDataCenterService) loadPlan(
+	ctx context.Context,
+	tracker *taskTracker,
+	oltpStorage storage.OLTPDB,
+	view models.View,
+	item models.CountInsertData,
+	chunkSize int64,
+) ([]models.Chunk, error) {
+	if tracker.resuming() {
+		if stored := tracker.checkpoints(item); len(stored) > 0 {
+			return a.resumePlan(ctx, tracker, view, item, stored)
+		}
+		// план таблицы не успел сохраниться — таблица загружается заново
+		if err := a.DWHProvider.DeleteChunkRows(ctx, item.TempTableName, ""); err != nil {
+			return nil, err
+		}
+		tracker.expectRows(ctx, item)
+	}
+
+	chunks, err := a.planChunks(ctx, oltpStorage, view, item, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	tracker.saveChunks(ctx, item, chunks)
+	return chunks, nil
+}
+
+func (a *AnalyticsDataCenterService) resumePlan(
+	ctx context.Context,
+	tracker *taskTracker,
+	view models.View,
+	item models.CountInsertData,
+	stored []models.TaskChunk,
+) ([]models.Chunk, error) {
+	const op = "analytics.resumePlan"
+	log := a.log.With(slog.String("op", op), slog.String("table", item.TableName))
+
+	var (
+		all     []models.Chunk
+		pending []models.Chunk
+		byCtid  bool
+	)
+	for _, checkpoint := range stored {
+		all = append(all, checkpoint.Chunk)
+		if !checkpoint.Done {
+			pending = append(pending, checkpoint.Chunk)
+			byCtid = byCtid || checkpoint.Chunk.ByCtid()
+		}
+	}
+	log.Info("продолжение загрузки таблицы",
+		slog.Int("chunks", len(all)),
+		slog.Int("pending", len(pending)),
+	)
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	if byCtid {
+		// строки чанков по ctid во временной таблице не различить — таблица загружается заново
+		log.Warn("чанки построены по ctid, таблица загружается заново")
+		if err := a.DWHProvider.DeleteChunkRows(ctx, item.TempTableName, ""); err != nil {
+			return nil, err
+		}
+		tracker.expectRows(ctx, item)
+		tracker.saveChunks(ctx, item, all)
+		return all, nil
+	}
+
+	table, ok := findCountTable(view, item)
+	if !ok {
+		return nil, fmt.Errorf("%s: таблица %s не найдена в представлении", op, item.TableName)
+	}
+	for _, chunk := range pending {
+		condition, args, err := sqlgenerator.GenerateChunkRowsCondition(table, chunk, a.DWHDbName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := a.DWHProvider.DeleteChunkRows(ctx, item.TempTableName, condition, args...); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,134 @@
+package serviceanalytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func usersCheckpoint(index int, lower, upper []interface{}, done bool) models.TaskChunk {
+	return models.TaskChunk{
+		Source:    "db1",
+		Schema:    "public",
+		Table:     "users",
+		TempTable: "temp_task1_db1_public_users",
+		Chunk:     models.Chunk{Index: index, Columns: []string{"id"}, Lower: lower, Upper: upper},
+		Done:      done,
+	}
+}
+
+// resumableUsersTask возвращает DWH и задачу, оставившие временную таблицу users
+// прошлого запуска задачи task-1 с чекпоинтами checkpoints
+func resumableUsersTask(checkpoints ...models.TaskChunk) (*mockDWH, *mockTaskService) {
+	dwh := &mockDWH{
+		columns:    map[string][]string{"temp_task1_db1_public_users": {"id", "name"}},
+		tempTables: []string{"temp_task1_db1_public_users"},
+	}
+	tasks := &mockTaskService{
+		chunks:   checkpoints,
+		register: []string{"temp_task1_db1_public_users", "temp_task1_v__shadow"},
+	}
+	return dwh, tasks
+}
+
+func TestProcessETLJob_ResumeLoadsOnlyPendingChunks(t *testing.T) {
+	dwh, tasks := resumableUsersTask(
+		usersCheckpoint(0, nil, []interface{}{int64(100)}, true),
+		usersCheckpoint(1, []interface{}{int64(100)}, nil, false),
+	)
+	svc := newETLTestService(dwh, tasks)
+	oltp := svc.OLTPFactory.(*mockFactory).store["db1"].(*mockOLTP)
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1, Resume: true}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	// временные таблицы прошлого запуска не пересоздаются
+	require.Empty(t, dwh.createCalls)
+	require.Equal(t, []mockChunkDelete{{
+		table:     "temp_task1_db1_public_users",
+		condition: `"id" >= $1`,
+		args:      []interface{}{int64(100)},
+	}}, dwh.chunkDeletes)
+	require.Len(t, oltp.streamCalls, 1)
+	require.Equal(t, []interface{}{int64(100)}, oltp.streamCalls[0].Args)
+	require.Len(t, tasks.done, 1)
+	require.Equal(t, 1, tasks.done[0].Chunk.Index)
+	// прогресс таблицы не обнуляется
+	require.Empty(t, tasks.expected)
+	require.Equal(t, 1, dwh.mergeCalls)
+	require.Contains(t, dwh.deleteCalls, "temp_task1_db1_public_users")
+}
+
+func TestProcessETLJob_ResumeWithoutTempTableReloadsView(t *testing.T) {
+	dwh, tasks := resumableUsersTask(
+		usersCheckpoint(0, nil, []interface{}{int64(100)}, true),
+		usersCheckpoint(1, []interface{}{int64(100)}, nil, false),
+	)
+	// таблицу удалили, пока задача ждала продолжения
+	dwh.tempTables = nil
+	svc := newETLTestService(dwh, tasks)
+	oltp := svc.OLTPFactory.(*mockFactory).store["db1"].(*mockOLTP)
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1, Resume: true}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	require.Equal(t, []string{"temp_task1_db1_public_users"}, dwh.createCalls)
+	require.Empty(t, dwh.chunkDeletes)
+	require.Len(t, oltp.streamCalls, 1)
+	require.Nil(t, oltp.streamCalls[0].Args)
+	require.Len(t, tasks.plans, 1)
+	require.NotEmpty(t, tasks.expected)
+}
+
+func TestProcessETLJob_ResumeWithForeignCheckpointsReloadsView(t *testing.T) {
+	// чекпоинты записаны с общим для всех задач именем таблицы
+	legacy := usersCheckpoint(0, nil, []interface{}{int64(100)}, true)
+	legacy.TempTable = "temp_db1_public_users"
+	dwh, tasks := resumableUsersTask(legacy)
+	svc := newETLTestService(dwh, tasks)
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1, Resume: true}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	require.Equal(t, []string{"temp_task1_db1_public_users"}, dwh.createCalls)
+	require.Len(t, tasks.plans, 1)
+	require.Equal(t, "temp_task1_db1_public_users", tasks.plans[0].TempTable)
+}
+
+func TestProcessETLJob_SavesChunkPlan(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	tasks := &mockTaskService{}
+	svc := newETLTestService(dwh, tasks)
+
+	svc.processETLJob(context.Background(), models.ETLJob{TaskID: "task-1", ViewID: 1}, getTestLogger())
+
+	require.Equal(t, []string{Completed}, tasks.statuses)
+	require.Len(t, tasks.plans, 1)
+	require.Equal(t, "temp_task1_db1_public_users", tasks.plans[0].TempTable)
+	require.False(t, tasks.plans[0].Done)
+	require.Len(t, tasks.done, 1)
+	require.Equal(t, int64(2), tasks.done[0].Rows)
+}
+
+func TestResumeTask(t *testing.T) {
+	viewID := int64(1)
+	checkpoints := []models.TaskChunk{usersCheckpoint(0, nil, nil, false)}
+
+	running := newCancelTestService(&mockTaskService{task: models.Task{Status: Progress, ViewID: &viewID}, chunks: checkpoints}, &mockJobStorage{})
+	require.ErrorIs(t, running.ResumeTask(context.Background(), "task-1"), ErrTaskNotResumable)
+
+	noCheckpoints := newCancelTestService(&mockTaskService{task: models.Task{Status: Error, ViewID: &viewID, CreateDate: time.Now()}}, &mockJobStorage{})
+	require.ErrorIs(t, noCheckpoints.ResumeTask(context.Background(), "task-1"), ErrTaskNotResumable)
+
+	jobs := &mockJobStorage{}
+	failed := newCancelTestService(&mockTaskService{task: models.Task{Status: Error, ViewID: &viewID, CreateDate: time.Now()}, chunks: checkpoints}, jobs)
+	require.NoError(t, failed.ResumeTask(context.Background(), "task-1"))
+	require.Equal(t, []models.ETLJob{{TaskID: "task-1", ViewID: 1, Status: models.ETLJobQueued, Resume: true}}, jobs.jobs)
+
+	expired := newCancelTestService(&mockTaskService{task: models.Task{Status: Error, ViewID: &viewID, CreateDate: time.Now().Add(-100 * time.Hour)}, chunks: checkpoints}, &mockJobStorage{})
+	require.ErrorIs(t, expired.ResumeTask(context.Background(), "task-1"), ErrTaskNotResumable)
+}
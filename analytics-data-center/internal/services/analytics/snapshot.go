@@ -0,0 +1,128 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// exportSnapshots экспортирует по снимку на каждый источник view. Подсчёт строк, план
+// чанков и сами чанки читаются из этих снимков, поэтому загрузка видит каждый источник
+// на один момент времени. Позиции снимков сохраняются в задаче и в передаче view в CDC.
+func (a *AnalyticsDataCenterService) exportSnapshots(ctx context.Context, tracker *taskTracker, view models.View) error {
+	const op = "analytics.exportSnapshots"
+	log := a.log.With(slog.String("op", op))
+	if tracker == nil {
+		return nil
+	}
+
+	for _, source := range view.Sources {
+		if tracker.snapshotStorage(source.Name) != nil {
+			continue
+		}
+		oltpStorage, err := a.OLTPFactory.GetOLTPStorage(ctx, source.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		snapshot, release, err := oltpStorage.ExportSnapshot(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: снимок источника %s: %w", op, source.Name, err)
+		}
+		reader, err := a.OLTPFactory.GetOLTPSnapshotStorage(ctx, source.Name, snapshot.ID)
+		if err != nil {
+			_ = release()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		tracker.useSnapshot(source.Name, reader, release)
+
+		if a.recordSnapshot(tracker.handoff, source.Name, snapshot.Position) {
+			// без сохранённого снимка продолжение задачи не отличит чанки старого снимка
+			err := a.TaskService.SaveTaskSnapshot(ctx, tracker.taskID, models.TaskSnapshot{
+				Source:   source.Name,
+				Position: snapshot.Position,
+				TakenAt:  time.Now(),
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		log.Info("загрузка читает снимок источника",
+			slog.String("source", source.Name),
+			slog.String("snapshot", snapshot.ID),
+			slog.Int64("lsn", snapshot.Position.LSN),
+		)
+	}
+	return nil
+}
+
+// sourceStorage возвращает хранилище источника для загрузки: если задача экспортировала
+// снимок источника — читающее этот снимок
+func (a *AnalyticsDataCenterService) sourceStorage(ctx context.Context, tracker *taskTracker, source string) (storage.OLTPDB, error) {
+	if reader := tracker.snapshotStorage(source); reader != nil {
+		return reader, nil
+	}
+	return a.OLTPFactory.GetOLTPStorage(ctx, source)
+}
+
+// useSnapshot запоминает хранилище, читающее снимок источника, и функцию освобождения снимка
+func (t *taskTracker) useSnapshot(source string, reader storage.OLTPDB, release func() error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.snapshots == nil {
+		t.snapshots = make(map[string]storage.OLTPDB)
+	}
+	t.snapshots[source] = reader
+	t.releases = append(t.releases, release)
+}
+
+func (t *taskTracker) snapshotStorage(source string) storage.OLTPDB {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshots[source]
+}
+
+// releaseSnapshots закрывает транзакции снимков; повторный вызов ничего не делает
+func (t *taskTracker) releaseSnapshots() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	releases := t.releases
+	t.releases = nil
+	t.snapshots = nil
+	t.mu.Unlock()
+
+	for _, release := range releases {
+		if err := release(); err != nil {
+			t.a.log.Warn("не удалось закрыть транзакцию снимка", slog.String("task", t.taskID), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// dropStaleCheckpoints отбрасывает чекпоинты продолжаемой задачи, если её чанки читались
+// из снимка, а передача view в CDC не сохранилась (сервис перезапускался, истёк срок
+// продолжения или переполнился буфер событий). События между падением и продолжением
+// потеряны, поэтому таблицы загружаются заново из нового снимка.
+func (t *taskTracker) dropStaleCheckpoints(ctx context.Context) error {
+	if !t.resuming() || t.handoffContinued {
+		return nil
+	}
+	snapshots, err := t.a.TaskService.ListTaskSnapshots(ctx, t.taskID)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		// прошлый запуск читал источники без снимка
+		return nil
+	}
+	t.a.log.Warn("передача view в CDC не сохранилась с прошлого запуска: таблицы загружаются заново",
+		slog.String("task", t.taskID))
+	t.stored = nil
+	return nil
+}
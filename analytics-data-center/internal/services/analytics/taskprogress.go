@@ -0,0 +1,289 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	loggerpkg "analyticDataCenter/analytics-data-center/internal/logger"
+)
+
+// taskTracker ведёт таймлайн этапов ETL-задачи: сохраняет каждый этап в sys DB и
+// рассылает его в websocket-уведомлениях. Ошибки записи прогресса только логируются —
+// загрузка из-за них не прерывается. Методы безопасны для nil-получателя, поэтому
+// вспомогательные шаги ETL можно вызывать и вне задачи.
+type taskTracker struct {
+	a      *AnalyticsDataCenterService
+	taskID string
+
+	mu     sync.Mutex
+	stages []models.TaskStage
+
+	// resume — задача продолжает прерванную загрузку; stored — её чекпоинты по таблицам
+	resume bool
+	stored map[string][]models.TaskChunk
+
+	// handoff — передача view задачи в CDC; handoffContinued — она продолжает передачу
+	// упавшего запуска
+	handoff          *viewHandoff
+	handoffContinued bool
+	// snapshots — хранилища, читающие снимки источников загрузки; releases закрывают снимки
+	snapshots map[string]storage.OLTPDB
+	releases  []func() error
+}
+
+func (a *AnalyticsDataCenterService) newTaskTracker(taskID string) *taskTracker {
+	return &taskTracker{a: a, taskID: taskID}
+}
+
+// resuming сообщает, что задача продолжает прерванную загрузку
+func (t *taskTracker) resuming() bool {
+	return t != nil && t.resume
+}
+
+// loadCheckpoints читает чекпоинты чанков, сохранённые прошлым запуском задачи
+func (t *taskTracker) loadCheckpoints(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	chunks, err := t.a.TaskService.ListTaskChunks(ctx, t.taskID)
+	if err != nil {
+		return err
+	}
+	t.stored = make(map[string][]models.TaskChunk)
+	for _, chunk := range chunks {
+		key := checkpointKey(chunk.Source, chunk.Schema, chunk.Table)
+		t.stored[key] = append(t.stored[key], chunk)
+	}
+	return nil
+}
+
+// checkpoints возвращает сохранённый план чанков таблицы
+func (t *taskTracker) checkpoints(item models.CountInsertData) []models.TaskChunk {
+	if t == nil {
+		return nil
+	}
+	return t.stored[checkpointKey(item.DataBaseName, item.SchemaName, item.TableName)]
+}
+
+// saveChunks сохраняет план чанков таблицы; все чанки считаются незагруженными
+func (t *taskTracker) saveChunks(ctx context.Context, item models.CountInsertData, chunks []models.Chunk) {
+	if t == nil || len(chunks) == 0 {
+		return
+	}
+	plan := make([]models.TaskChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		plan = append(plan, taskChunk(item, chunk, 0))
+	}
+	if err := t.a.TaskService.SaveTaskChunks(ctx, t.taskID, plan); err != nil {
+		t.a.log.WarnMsg(loggerpkg.MsgSaveChunkFailed, slog.String("task", t.taskID), slog.String("error", err.Error()))
+	}
+}
+
+// restartLoad переводит продолжаемую задачу на полную загрузку: чекпоинты прошлого
+// запуска отбрасываются, временные таблицы создаются заново
+func (t *taskTracker) restartLoad() {
+	if t == nil {
+		return
+	}
+	t.resume = false
+	t.stored = nil
+}
+
+// id возвращает идентификатор задачи; у загрузки без задачи он пустой
+func (t *taskTracker) id() string {
+	if t == nil {
+		return ""
+	}
+	return t.taskID
+}
+
+// shadowTable возвращает имя теневой таблицы, в которую задача собирает view
+func (t *taskTracker) shadowTable(viewName string) string {
+	return sqlgenerator.ShadowTableName(viewName, t.id())
+}
+
+// registerTempTables закрепляет временные таблицы за задачей. В отличие от прогресса,
+// ошибка возвращается: таблицу без владельца удалит сборщик временных таблиц.
+func (t *taskTracker) registerTempTables(ctx context.Context, tables []string) error {
+	if t == nil {
+		return nil
+	}
+	return t.a.TaskService.RegisterTaskTempTables(ctx, t.taskID, tables)
+}
+
+// chunkDone фиксирует загрузку чанка и добавляет его строки к прогрессу таблицы
+func (t *taskTracker) chunkDone(ctx context.Context, item models.CountInsertData, chunk models.Chunk, rows int64) {
+	if t == nil {
+		return
+	}
+	if err := t.a.TaskService.CompleteTaskChunk(ctx, t.taskID, taskChunk(item, chunk, rows)); err != nil {
+		t.a.log.WarnMsg(loggerpkg.MsgSaveChunkFailed, slog.String("task", t.taskID), slog.String("error", err.Error()))
+	}
+	t.rowsLoaded(ctx, item, rows)
+}
+
+// enter завершает текущий этап успешно и начинает этап stage
+func (t *taskTracker) enter(ctx context.Context, stage string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.finishCurrent(ctx, models.StageCompleted, nil)
+	t.stages = append(t.stages, models.TaskStage{Name: stage, Status: models.StageRunning, StartedAt: time.Now()})
+	t.save(ctx, t.stages[len(t.stages)-1])
+	t.mu.Unlock()
+
+	t.a.log.InfoMsg(loggerpkg.MsgETLStageStart, slog.String("task", t.taskID), slog.String("stage", stage))
+	t.notify(Progress, "Этап: "+stage)
+}
+
+// complete успешно завершает последний этап задачи
+func (t *taskTracker) complete(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finishCurrent(ctx, models.StageCompleted, nil)
+}
+
+// fail помечает текущий этап упавшим с текстом ошибки
+func (t *taskTracker) fail(ctx context.Context, err error) {
+	if t == nil {
+		return
+	}
+	message := err.Error()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if stage := t.current(); stage != nil {
+		t.a.log.ErrorMsg(loggerpkg.MsgETLStageFailed,
+			slog.String("task", t.taskID),
+			slog.String("stage", stage.Name),
+			slog.String("error", message),
+		)
+	}
+	t.finishCurrent(ctx, models.StageFailed, &message)
+}
+
+// cancel помечает текущий этап отменённым
+func (t *taskTracker) cancel(ctx context.Context) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finishCurrent(ctx, models.StageCancelled, nil)
+}
+
+// expectRows фиксирует, сколько строк таблицы источника должно быть загружено
+func (t *taskTracker) expectRows(ctx context.Context, item models.CountInsertData) {
+	if t == nil {
+		return
+	}
+	progress := models.TaskTableProgress{
+		Source:   item.DataBaseName,
+		Schema:   item.SchemaName,
+		Table:    item.TableName,
+		Expected: item.Count,
+	}
+	if err := t.a.TaskService.SaveTaskTableProgress(ctx, t.taskID, progress); err != nil {
+		t.a.log.WarnMsg(loggerpkg.MsgSaveProgressFailed, slog.String("task", t.taskID), slog.String("error", err.Error()))
+	}
+}
+
+// rowsLoaded добавляет к прогрессу таблицы строки, записанные очередным чанком
+func (t *taskTracker) rowsLoaded(ctx context.Context, item models.CountInsertData, rows int64) {
+	if t == nil || rows <= 0 {
+		return
+	}
+	progress := models.TaskTableProgress{
+		Source: item.DataBaseName,
+		Schema: item.SchemaName,
+		Table:  item.TableName,
+		Loaded: rows,
+	}
+	if err := t.a.TaskService.AddTaskTableRows(ctx, t.taskID, progress); err != nil {
+		t.a.log.WarnMsg(loggerpkg.MsgSaveProgressFailed, slog.String("task", t.taskID), slog.String("error", err.Error()))
+	}
+}
+
+// timeline возвращает копию таймлайна этапов
+func (t *taskTracker) timeline() []models.TaskStage {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]models.TaskStage(nil), t.stages...)
+}
+
+// notify публикует статус задачи вместе с текущим этапом и таймлайном
+func (t *taskTracker) notify(status, message string) {
+	if t == nil || t.a.notifier == nil {
+		return
+	}
+	stages := t.timeline()
+	notification := models.Notification{
+		Type:      "task_status",
+		Title:     "ETL задача",
+		Message:   message,
+		TaskID:    t.taskID,
+		Status:    status,
+		Stages:    stages,
+		CreatedAt: time.Now(),
+	}
+	if len(stages) > 0 {
+		notification.Stage = stages[len(stages)-1].Name
+	}
+	t.a.notifier.Publish(notification)
+}
+
+func checkpointKey(source, schema, table string) string {
+	return source + "." + schema + "." + table
+}
+
+func taskChunk(item models.CountInsertData, chunk models.Chunk, rows int64) models.TaskChunk {
+	return models.TaskChunk{
+		Source:    item.DataBaseName,
+		Schema:    item.SchemaName,
+		Table:     item.TableName,
+		TempTable: item.TempTableName,
+		Chunk:     chunk,
+		Rows:      rows,
+	}
+}
+
+func (t *taskTracker) current() *models.TaskStage {
+	if len(t.stages) == 0 || t.stages[len(t.stages)-1].Status != models.StageRunning {
+		return nil
+	}
+	return &t.stages[len(t.stages)-1]
+}
+
+// finishCurrent закрывает выполняющийся этап; вызывается под t.mu
+func (t *taskTracker) finishCurrent(ctx context.Context, status string, message *string) {
+	stage := t.current()
+	if stage == nil {
+		return
+	}
+	finished := time.Now()
+	stage.Status = status
+	stage.FinishedAt = &finished
+	stage.Error = message
+	t.save(ctx, *stage)
+}
+
+func (t *taskTracker) save(ctx context.Context, stage models.TaskStage) {
+	if err := t.a.TaskService.SaveTaskStage(ctx, t.taskID, stage); err != nil {
+		t.a.log.WarnMsg(loggerpkg.MsgSaveStageFailed,
+			slog.String("task", t.taskID),
+			slog.String("stage", stage.Name),
+			slog.String("error", err.Error()),
+		)
+	}
+}
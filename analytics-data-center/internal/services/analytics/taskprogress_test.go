@@ -0,0 +1,114 @@
+package serviceanalytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newETLTestService(dwh *mockDWH, tasks *mockTaskService) *AnalyticsDataCenterService {
+	oltp := &mockOLTP{countResult: 2, selectResult: []map[string]interface{}{{"id": 1, "name": "A"}, {"id": 2, "name": "B"}}}
+	view := models.View{
+		Name: "v",
+		Sources: []models.Source{{
+			Name: "db1",
+			Schemas: []models.Schema{{
+				Name: "public",
+				Tables: []models.Table{{
+					Name: "users",
+					Columns: []models.Column{
+						{Name: "id", Type: "integer", IsPrimaryKey: true},
+						{Name: "name", Type: "text"},
+					},
+				}},
+			}},
+		}},
+	}
+	schemaProvider := &mockSchemaProvider{views: map[int]models.View{1: view}}
+	return &AnalyticsDataCenterService{
+		log:                 getTestLogger(),
+		SchemaProvider:      schemaProvider,
+		ViewSnapshotStorage: schemaProvider,
+		TaskService:         tasks,
+		OLTPFactory:         &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHProvider:         dwh,
+		DWHDbName:           DbPostgres,
+		OLTPDbName:          DbPostgres,
+	}
+}
+
+func stageNames(stages []models.TaskStage) []string {
+	names := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		names = append(names, stage.Name)
+	}
+	return names
+}
+
+func TestRunETL_RecordsStagesInOrder(t *testing.T) {
+	dwh := &mockDWH{columns: map[string][]string{"temp_task1_db1_public_users": {"id", "name"}}}
+	tasks := &mockTaskService{}
+	svc := newETLTestService(dwh, tasks)
+	tracker := svc.newTaskTracker("task-1")
+
+	err := svc.runETL(context.Background(), 1, tracker)
+	require.NoError(t, err)
+	tracker.complete(context.Background())
+
+	stages := tasks.finalStages()
+	require.Equal(t, []string{
+		models.StageTempTables,
+		models.StageExtract,
+		models.StageMerge,
+		models.StageIndexes,
+		models.StagePublish,
+		models.StageReplication,
+	}, stageNames(stages))
+	for _, stage := range stages {
+		require.Equal(t, models.StageCompleted, stage.Status, stage.Name)
+		require.NotNil(t, stage.FinishedAt, stage.Name)
+	}
+
+	require.Equal(t, []models.TaskTableProgress{{Source: "db1", Schema: "public", Table: "users", Expected: 2}}, tasks.expected)
+	require.Equal(t, []models.TaskTableProgress{{Source: "db1", Schema: "public", Table: "users", Loaded: 2}}, tasks.loaded)
+	// итоговый статус выставляет воркер, runETL его не трогает
+	require.Empty(t, tasks.statuses)
+}
+
+func TestRunETL_FailedStageIsRecorded(t *testing.T) {
+	dwh := &mockDWH{
+		columns:  map[string][]string{"temp_task1_db1_public_users": {"id", "name"}},
+		mergeErr: errors.New("disk full"),
+	}
+	tasks := &mockTaskService{}
+	svc := newETLTestService(dwh, tasks)
+	tracker := svc.newTaskTracker("task-1")
+
+	err := svc.runETL(context.Background(), 1, tracker)
+	require.Error(t, err)
+	tracker.fail(context.Background(), err)
+
+	stages := tasks.finalStages()
+	require.Equal(t, []string{models.StageTempTables, models.StageExtract, models.StageMerge}, stageNames(stages))
+	merge := stages[len(stages)-1]
+	require.Equal(t, models.StageFailed, merge.Status)
+	require.NotNil(t, merge.Error)
+	require.Contains(t, *merge.Error, "disk full")
+	require.Empty(t, dwh.swapCalls)
+}
+
+func TestTaskTracker_NilIsNoop(t *testing.T) {
+	var tracker *taskTracker
+	ctx := context.Background()
+
+	tracker.enter(ctx, models.StageExtract)
+	tracker.rowsLoaded(ctx, models.CountInsertData{TableName: "users"}, 10)
+	tracker.fail(ctx, errors.New("boom"))
+	tracker.complete(ctx)
+	require.Nil(t, tracker.timeline())
+}
@@ -0,0 +1,279 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Значения загрузки по умолчанию
+const (
+	defaultChunkSize    int64 = 500_000
+	defaultWorkerBudget       = 16
+)
+
+// ETLTuningOptions — глобальные параметры загрузки и ограничения источников OLTP.
+// Нулевые поля заменяются значениями по умолчанию.
+type ETLTuningOptions struct {
+	// ChunkSize — сколько строк таблицы источника попадает в один чанк
+	ChunkSize int64
+	// Concurrency — сколько чанков одной задачи загружается одновременно;
+	// 0 — по объёму данных задачи (calculateWorkerCount)
+	Concurrency int
+	// WorkerBudget — сколько чанков загружается одновременно во всех задачах
+	WorkerBudget int
+	// Sources — ограничения загрузки по именам источников OLTP
+	Sources map[string]SourceTuning
+}
+
+// SourceTuning — ограничения загрузки из одного источника OLTP. Действуют на все
+// задачи, читающие источник, и переопределяются настройками view.
+type SourceTuning struct {
+	// Concurrency — сколько чанков источника читается одновременно во всех задачах
+	Concurrency int
+	// ChunkSize — размер чанка для таблиц источника
+	ChunkSize int64
+	// RowsPerSecond — сколько строк в секунду читается из источника во всех задачах; 0 — без ограничения
+	RowsPerSecond int
+	// Throttle — часы, в которые действует RowsPerSecond; пустое окно — круглосуточно
+	Throttle ThrottleWindow
+}
+
+// ThrottleWindow — ежедневный интервал локального времени [From, To). Если To
+// меньше From, интервал переходит через полночь.
+type ThrottleWindow struct {
+	From time.Duration
+	To   time.Duration
+}
+
+// ParseThrottleWindow разбирает окно вида "09:00-19:00"; пустая строка — круглосуточно
+func ParseThrottleWindow(value string) (ThrottleWindow, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ThrottleWindow{}, nil
+	}
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return ThrottleWindow{}, fmt.Errorf("окно ограничения %q должно иметь вид ЧЧ:ММ-ЧЧ:ММ", value)
+	}
+	var window ThrottleWindow
+	for _, bound := range []struct {
+		text   string
+		target *time.Duration
+	}{{from, &window.From}, {to, &window.To}} {
+		t, err := time.Parse("15:04", strings.TrimSpace(bound.text))
+		if err != nil {
+			return ThrottleWindow{}, fmt.Errorf("окно ограничения %q: %w", value, err)
+		}
+		*bound.target = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	return window, nil
+}
+
+func (w ThrottleWindow) always() bool {
+	return w.From == w.To
+}
+
+func (w ThrottleWindow) contains(t time.Time) bool {
+	if w.always() {
+		return true
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.From < w.To {
+		return offset >= w.From && offset < w.To
+	}
+	return offset >= w.From || offset < w.To
+}
+
+// loadLimits — общие для всех задач ограничения загрузки
+type loadLimits struct {
+	opts     ETLTuningOptions
+	budget   chan struct{}
+	sources  map[string]chan struct{}
+	limiters map[string]*rowLimiter
+}
+
+// SetETLTuning задаёт параметры загрузки; вызывается до StartETLWorkers.
+func (a *AnalyticsDataCenterService) SetETLTuning(opts ETLTuningOptions) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultChunkSize
+	}
+	if opts.WorkerBudget <= 0 {
+		opts.WorkerBudget = defaultWorkerBudget
+	}
+	limits := &loadLimits{
+		opts:     opts,
+		budget:   make(chan struct{}, opts.WorkerBudget),
+		sources:  make(map[string]chan struct{}),
+		limiters: make(map[string]*rowLimiter),
+	}
+	for name, source := range opts.Sources {
+		if source.Concurrency > 0 {
+			limits.sources[name] = make(chan struct{}, source.Concurrency)
+		}
+		if source.RowsPerSecond > 0 {
+			limits.limiters[name] = &rowLimiter{rate: source.RowsPerSecond, window: source.Throttle}
+		}
+	}
+	a.limits = limits
+}
+
+func (a *AnalyticsDataCenterService) tuningOptions() ETLTuningOptions {
+	if a.limits == nil {
+		return ETLTuningOptions{ChunkSize: defaultChunkSize, WorkerBudget: defaultWorkerBudget}
+	}
+	return a.limits.opts
+}
+
+// chunkSize возвращает размер чанка для таблицы источника: view → источник → глобальный
+func (a *AnalyticsDataCenterService) chunkSize(view models.View, source string) int64 {
+	if view.Tuning != nil && view.Tuning.ChunkSize > 0 {
+		return view.Tuning.ChunkSize
+	}
+	opts := a.tuningOptions()
+	if size := opts.Sources[source].ChunkSize; size > 0 {
+		return size
+	}
+	return opts.ChunkSize
+}
+
+// taskConcurrency возвращает, сколько чанков задачи загружается одновременно
+func (a *AnalyticsDataCenterService) taskConcurrency(view models.View, totalRows int64) int {
+	if view.Tuning != nil && view.Tuning.Concurrency > 0 {
+		return view.Tuning.Concurrency
+	}
+	if concurrency := a.tuningOptions().Concurrency; concurrency > 0 {
+		return concurrency
+	}
+	return int(a.calculateWorkerCount(totalRows))
+}
+
+// acquireLoadSlot занимает слот источника и слот общего бюджета загрузки. Слоты
+// занимаются всегда в одном порядке, поэтому задачи не блокируют друг друга.
+func (a *AnalyticsDataCenterService) acquireLoadSlot(ctx context.Context, source string) (func(), error) {
+	if a.limits == nil {
+		return func() {}, nil
+	}
+	var held []chan struct{}
+	release := func() {
+		for _, slot := range held {
+			<-slot
+		}
+	}
+	for _, slot := range []chan struct{}{a.limits.sources[source], a.limits.budget} {
+		if slot == nil {
+			continue
+		}
+		select {
+		case slot <- struct{}{}:
+			held = append(held, slot)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// throttleRows ограничивает скорость чтения строк из источника, если для него задан RowsPerSecond
+func (a *AnalyticsDataCenterService) throttleRows(ctx context.Context, source string, rows models.RowStream) models.RowStream {
+	if a.limits == nil {
+		return rows
+	}
+	limiter, ok := a.limits.limiters[source]
+	if !ok {
+		return rows
+	}
+	return &throttledRows{RowStream: rows, ctx: ctx, limiter: limiter, step: limiter.step()}
+}
+
+// rowLimiter распределяет чтение строк источника во времени: каждая порция строк
+// резервирует свой интервал, и читающий ждёт начала интервала. Лимит общий для
+// всех чанков источника.
+type rowLimiter struct {
+	rate   int
+	window ThrottleWindow
+	now    func() time.Time
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// step — сколько строк резервируется за раз: примерно десятая доля секунды чтения
+func (l *rowLimiter) step() int {
+	if step := l.rate / 10; step > 1 {
+		return step
+	}
+	return 1
+}
+
+func (l *rowLimiter) clock() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+// reserve резервирует интервал для rows строк и возвращает, сколько нужно подождать
+func (l *rowLimiter) reserve(rows int) time.Duration {
+	now := l.clock()
+	if !l.window.contains(now) {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(rows) * time.Second / time.Duration(l.rate))
+	return delay
+}
+
+func (l *rowLimiter) wait(ctx context.Context, rows int) error {
+	delay := l.reserve(rows)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type throttledRows struct {
+	models.RowStream
+	ctx     context.Context
+	limiter *rowLimiter
+	step    int
+	read    int
+	err     error
+}
+
+func (t *throttledRows) Next() bool {
+	if t.read%t.step == 0 {
+		if err := t.limiter.wait(t.ctx, t.step); err != nil {
+			t.err = err
+			return false
+		}
+	}
+	if !t.RowStream.Next() {
+		return false
+	}
+	t.read++
+	return true
+}
+
+func (t *throttledRows) Err() error {
+	if t.err != nil {
+		return t.err
+	}
+	return t.RowStream.Err()
+}
@@ -0,0 +1,119 @@
+package serviceanalytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThrottleWindow(t *testing.T) {
+	window, err := ParseThrottleWindow("09:00-19:30")
+	require.NoError(t, err)
+	require.Equal(t, ThrottleWindow{From: 9 * time.Hour, To: 19*time.Hour + 30*time.Minute}, window)
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	require.True(t, window.contains(day.Add(9*time.Hour)))
+	require.False(t, window.contains(day.Add(19*time.Hour+30*time.Minute)))
+	require.False(t, window.contains(day.Add(3*time.Hour)))
+
+	overnight, err := ParseThrottleWindow("22:00-06:00")
+	require.NoError(t, err)
+	require.True(t, overnight.contains(day.Add(23*time.Hour)))
+	require.True(t, overnight.contains(day.Add(5*time.Hour)))
+	require.False(t, overnight.contains(day.Add(12*time.Hour)))
+
+	always, err := ParseThrottleWindow("")
+	require.NoError(t, err)
+	require.True(t, always.contains(day))
+
+	_, err = ParseThrottleWindow("9-19")
+	require.Error(t, err)
+}
+
+func TestETLTuningResolution(t *testing.T) {
+	svc := &AnalyticsDataCenterService{log: getTestLogger()}
+	svc.SetETLTuning(ETLTuningOptions{
+		ChunkSize: 1000,
+		Sources:   map[string]SourceTuning{"db1": {ChunkSize: 200}},
+	})
+
+	view := models.View{}
+	require.Equal(t, int64(200), svc.chunkSize(view, "db1"))
+	require.Equal(t, int64(1000), svc.chunkSize(view, "db2"))
+	// без настроек число потоков подбирается по объёму данных
+	require.Equal(t, 1, svc.taskConcurrency(view, 100))
+	require.Equal(t, 8, svc.taskConcurrency(view, 1_000_000))
+
+	view.Tuning = &models.ETLTuning{ChunkSize: 50, Concurrency: 2}
+	require.Equal(t, int64(50), svc.chunkSize(view, "db1"))
+	require.Equal(t, 2, svc.taskConcurrency(view, 1_000_000))
+
+	svc.SetETLTuning(ETLTuningOptions{Concurrency: 3})
+	require.Equal(t, defaultChunkSize, svc.chunkSize(models.View{}, "db1"))
+	require.Equal(t, 3, svc.taskConcurrency(models.View{}, 1_000_000))
+}
+
+func TestAcquireLoadSlot_SharedAcrossTasks(t *testing.T) {
+	svc := &AnalyticsDataCenterService{log: getTestLogger()}
+	svc.SetETLTuning(ETLTuningOptions{
+		WorkerBudget: 2,
+		Sources:      map[string]SourceTuning{"db1": {Concurrency: 1}},
+	})
+
+	release, err := svc.acquireLoadSlot(context.Background(), "db1")
+	require.NoError(t, err)
+
+	// второй чанк источника ждёт, пока первый не освободит слот
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = svc.acquireLoadSlot(ctx, "db1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// другой источник ограничен только общим бюджетом
+	releaseOther, err := svc.acquireLoadSlot(context.Background(), "db2")
+	require.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = svc.acquireLoadSlot(ctx, "db3")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	releaseOther()
+	release, err = svc.acquireLoadSlot(context.Background(), "db1")
+	require.NoError(t, err)
+	release()
+}
+
+func TestRowLimiter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+	window, err := ParseThrottleWindow("09:00-19:00")
+	require.NoError(t, err)
+	limiter := &rowLimiter{rate: 10, window: window, now: func() time.Time { return now }}
+
+	require.Equal(t, time.Duration(0), limiter.reserve(5))
+	require.Equal(t, 500*time.Millisecond, limiter.reserve(5))
+	require.Equal(t, time.Second, limiter.reserve(10))
+
+	// вне окна ограничение не действует
+	now = now.Add(10 * time.Hour)
+	require.Equal(t, time.Duration(0), limiter.reserve(100))
+}
+
+func TestThrottleRows_StopsOnCancel(t *testing.T) {
+	svc := &AnalyticsDataCenterService{log: getTestLogger()}
+	svc.SetETLTuning(ETLTuningOptions{Sources: map[string]SourceTuning{"db1": {RowsPerSecond: 1}}})
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := svc.throttleRows(ctx, "db1", &sliceRowStream{rows: []map[string]interface{}{{"id": 1}, {"id": 2}}})
+
+	require.True(t, rows.Next())
+	cancel()
+	require.False(t, rows.Next())
+	require.ErrorIs(t, rows.Err(), context.Canceled)
+
+	unlimited := svc.throttleRows(context.Background(), "db2", &sliceRowStream{})
+	require.IsType(t, &sliceRowStream{}, unlimited)
+}
@@ -0,0 +1,419 @@
+package serviceanalytics
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"analyticDataCenter/analytics-data-center/internal/lib/duplicate"
+	"analyticDataCenter/analytics-data-center/internal/lib/joingraph"
+	"analyticDataCenter/analytics-data-center/internal/lib/rowfilter"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ErrInvalidView — описание view не прошло предварительную проверку
+var ErrInvalidView = errors.New("описание view не прошло проверку")
+
+// ViewValidationError возвращается при загрузке view, в описании которого найдены ошибки
+type ViewValidationError struct {
+	Result models.ViewValidation
+}
+
+func (e *ViewValidationError) Error() string {
+	messages := make([]string, 0, len(e.Result.Errors))
+	for _, issue := range e.Result.Errors {
+		messages = append(messages, issue.Field+": "+issue.Message)
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidView, strings.Join(messages, "; "))
+}
+
+func (e *ViewValidationError) Unwrap() error {
+	return ErrInvalidView
+}
+
+// ValidateSchema проверяет сохранённый view по текущему каталогу источников
+func (a *AnalyticsDataCenterService) ValidateSchema(ctx context.Context, idView int64) (models.ViewValidation, error) {
+	const op = "analytics.ValidateSchema"
+
+	view, err := a.SchemaProvider.GetView(ctx, idView)
+	if err != nil {
+		if errors.Is(err, storage.ErrSchemaNotFound) {
+			return models.ViewValidation{}, fmt.Errorf("%s: %w", op, ErrInvalidSchemID)
+		}
+		return models.ViewValidation{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return a.ValidateView(ctx, view), nil
+}
+
+// ValidateView проверяет описание view до запуска ETL: таблицы и колонки сверяются
+// с каталогом OLTP, джоины — с таблицами view и типами колонок, имена колонок
+// итоговой таблицы — на уникальность. Недоступный каталог источника даёт
+// предупреждение: такие таблицы проверяются только по самому описанию.
+func (a *AnalyticsDataCenterService) ValidateView(ctx context.Context, view models.View) models.ViewValidation {
+	const op = "analytics.ValidateView"
+	log := a.log.With(slog.String("op", op), slog.String("view", view.Name))
+
+	v := &viewValidator{
+		ctx:      ctx,
+		svc:      a,
+		view:     view,
+		catalogs: make(map[joingraph.TableRef]map[string]models.Column),
+		storages: make(map[string]storage.OLTPDB),
+	}
+	v.checkTuning()
+	v.checkSources()
+	v.checkOutputNames()
+	v.checkJoins()
+
+	v.result.Valid = len(v.result.Errors) == 0
+	log.Info("проверка view завершена",
+		slog.Int("errors", len(v.result.Errors)),
+		slog.Int("warnings", len(v.result.Warnings)),
+	)
+	return v.result
+}
+
+type viewValidator struct {
+	ctx    context.Context
+	svc    *AnalyticsDataCenterService
+	view   models.View
+	result models.ViewValidation
+	// catalogs — колонки таблиц из каталога OLTP; таблицы с недоступным каталогом отсутствуют
+	catalogs map[joingraph.TableRef]map[string]models.Column
+	storages map[string]storage.OLTPDB
+}
+
+func (v *viewValidator) fail(field, code, format string, args ...interface{}) {
+	v.result.Errors = append(v.result.Errors, models.ViewIssue{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *viewValidator) warn(field, code, format string, args ...interface{}) {
+	v.result.Warnings = append(v.result.Warnings, models.ViewIssue{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *viewValidator) checkTuning() {
+	if v.view.Tuning == nil {
+		return
+	}
+	if v.view.Tuning.Concurrency < 0 {
+		v.fail("tuning.concurrency", models.ViewIssueInvalidTuning, "число одновременно загружаемых чанков не может быть отрицательным")
+	}
+	if v.view.Tuning.ChunkSize < 0 {
+		v.fail("tuning.chunk_size", models.ViewIssueInvalidTuning, "размер чанка не может быть отрицательным")
+	}
+}
+
+// checkSources сверяет источники, таблицы, колонки и фильтры view с каталогом OLTP
+func (v *viewValidator) checkSources() {
+	configured := v.svc.OLTPFactory.GetOLTPStrings(v.ctx)
+	for i, source := range v.view.Sources {
+		sourcePath := fmt.Sprintf("sources[%d]", i)
+		if _, ok := configured[source.Name]; configured != nil && !ok {
+			v.fail(sourcePath+".name", models.ViewIssueUnknownSource, "источник %s не настроен", source.Name)
+			continue
+		}
+		oltpStorage, err := v.svc.OLTPFactory.GetOLTPStorage(v.ctx, source.Name)
+		if err != nil {
+			v.warn(sourcePath, models.ViewIssueCatalogUnavailable, "каталог источника %s недоступен: %s", source.Name, err)
+		} else {
+			v.storages[source.Name] = oltpStorage
+		}
+
+		for j, sch := range source.Schemas {
+			for k, table := range sch.Tables {
+				tablePath := fmt.Sprintf("%s.schemas[%d].tables[%d]", sourcePath, j, k)
+				if err := rowfilter.Validate(table.Filter); err != nil {
+					v.fail(tablePath+".filter", models.ViewIssueInvalidFilter, "%s", err)
+				}
+				if oltpStorage == nil {
+					continue
+				}
+				v.checkTable(oltpStorage, joingraph.TableRef{Source: source.Name, Schema: sch.Name, Table: table.Name}, table, tablePath)
+			}
+		}
+	}
+}
+
+func (v *viewValidator) checkTable(oltpStorage storage.OLTPDB, ref joingraph.TableRef, table models.Table, tablePath string) {
+	columns, err := oltpStorage.GetColumns(v.ctx, ref.Schema, ref.Table)
+	if err != nil {
+		v.warn(tablePath, models.ViewIssueCatalogUnavailable, "не удалось получить колонки таблицы %s: %s", ref, err)
+		return
+	}
+	if len(columns) == 0 {
+		v.fail(tablePath+".name", models.ViewIssueUnknownTable, "таблица %s не найдена в источнике", ref)
+		return
+	}
+	catalog := make(map[string]models.Column, len(columns))
+	for _, column := range columns {
+		catalog[column.Name] = column
+	}
+	v.catalogs[ref] = catalog
+
+	for idx, column := range table.Columns {
+		columnPath := fmt.Sprintf("%s.columns[%d]", tablePath, idx)
+		actual, ok := catalog[column.Name]
+		if !ok {
+			v.fail(columnPath+".name", models.ViewIssueUnknownColumn, "колонка %s не найдена в таблице %s%s", column.Name, ref, similarColumnHint(catalog, column.Name))
+			continue
+		}
+		declared := columnTypeName(column)
+		if declared == "" {
+			continue
+		}
+		if family, known := typeFamily(declared); known {
+			if actualFamily, _ := typeFamily(columnTypeName(actual)); actualFamily != family {
+				v.warn(columnPath+".type", models.ViewIssueTypeMismatch, "тип колонки %s в описании (%s) не совпадает с типом в источнике (%s)", column.Name, declared, columnTypeName(actual))
+			}
+		}
+	}
+	for _, column := range rowfilter.Columns(table.Filter) {
+		if _, ok := catalog[column]; !ok {
+			v.fail(tablePath+".filter", models.ViewIssueUnknownColumn, "колонка фильтра %s не найдена в таблице %s%s", column, ref, similarColumnHint(catalog, column))
+		}
+	}
+	v.checkWatermark(ref, table, catalog, tablePath)
+}
+
+// checkWatermark проверяет колонку отметки таблицы: по ней инкрементальная загрузка
+// сравнивает строки с сохранённой отметкой
+func (v *viewValidator) checkWatermark(ref joingraph.TableRef, table models.Table, catalog map[string]models.Column, tablePath string) {
+	if table.Watermark == "" {
+		return
+	}
+	actual, ok := catalog[table.Watermark]
+	if !ok {
+		v.fail(tablePath+".watermark", models.ViewIssueUnknownColumn, "колонка отметки %s не найдена в таблице %s%s", table.Watermark, ref, similarColumnHint(catalog, table.Watermark))
+		return
+	}
+	if family, _ := typeFamily(columnTypeName(actual)); family != "number" && family != "timestamp" {
+		v.warn(tablePath+".watermark", models.ViewIssueInvalidWatermark, "колонка отметки %s имеет тип %s: отметкой может быть число или время", table.Watermark, columnTypeName(actual))
+	}
+	if len(v.view.Joins) == 0 {
+		for _, column := range table.Columns {
+			if column.IsUpdateKey {
+				return
+			}
+		}
+		v.warn(tablePath+".watermark", models.ViewIssueInvalidWatermark, "у таблицы %s нет ключей обновления: инкрементальная загрузка невозможна", ref)
+	}
+}
+
+// checkOutputNames проверяет, что колонки временных таблиц не совпадают по именам:
+// запрос сборки выбирает все колонки всех таблиц в одну итоговую таблицу
+func (v *viewValidator) checkOutputNames() {
+	owners := make(map[string]string)
+	if v.view.IsSoftDelete() {
+		owners[models.SoftDeleteFlagColumn] = "служебная колонка soft delete"
+		owners[models.SoftDeleteTimeColumn] = "служебная колонка soft delete"
+	}
+	for i, source := range v.view.Sources {
+		for j, sch := range source.Schemas {
+			for k, table := range sch.Tables {
+				tablePath := fmt.Sprintf("sources[%d].schemas[%d].tables[%d]", i, j, k)
+				ref := joingraph.TableRef{Source: source.Name, Schema: sch.Name, Table: table.Name}
+
+				if _, duplicates := duplicate.RemoveDuplicateColumns(table.Columns); len(duplicates) > 0 {
+					v.warn(tablePath+".columns", models.ViewIssueDuplicateColumn, "колонки %s описаны несколько раз, во временную таблицу попадёт первая", strings.Join(duplicates, ", "))
+				}
+				for _, name := range sqlgenerator.TempTableColumnNames(table) {
+					owner, taken := owners[name]
+					if !taken {
+						owners[name] = ref.String()
+						continue
+					}
+					// ClickHouse переименует повторную колонку сам, Postgres отклонит запрос сборки
+					if v.svc.DWHDbName == DbPostgres {
+						v.fail(tablePath+".columns", models.ViewIssueDuplicateOutput, "колонка %s уже есть во view (%s), задайте alias", name, owner)
+					} else {
+						v.warn(tablePath+".columns", models.ViewIssueDuplicateOutput, "колонка %s уже есть во view (%s), задайте alias", name, owner)
+					}
+				}
+			}
+		}
+	}
+}
+
+// checkJoins проверяет таблицы и колонки условий джоинов и связность графа
+func (v *viewValidator) checkJoins() {
+	failed := len(v.result.Errors)
+	for idx, join := range v.view.Joins {
+		cond, joinType := join.Condition()
+		if cond == nil {
+			continue
+		}
+		joinPath := fmt.Sprintf("joins[%d].%s", idx, joinConditionKey(joinType))
+
+		leftTable, leftOK := v.joinTable(cond.Left, joinPath+".left")
+		rightTable, rightOK := v.joinTable(cond.Right, joinPath+".right")
+		if !leftOK || !rightOK {
+			continue
+		}
+		left, right := joingraph.RefFromEndpoint(cond.Left), joingraph.RefFromEndpoint(cond.Right)
+		for pairIdx, pair := range cond.ColumnPairs() {
+			leftPath, rightPath := joinPath+".left.column", joinPath+".right.column"
+			if len(cond.Columns) > 0 {
+				leftPath = fmt.Sprintf("%s.columns[%d].left", joinPath, pairIdx)
+				rightPath = fmt.Sprintf("%s.columns[%d].right", joinPath, pairIdx)
+			}
+			leftColumn, leftOK := v.joinColumn(left, leftTable, pair.Left, leftPath)
+			rightColumn, rightOK := v.joinColumn(right, rightTable, pair.Right, rightPath)
+			if leftOK && rightOK {
+				v.checkJoinTypes(left, leftColumn, right, rightColumn, rightPath)
+			}
+		}
+	}
+
+	// структурные ошибки графа имеют смысл, только если сами условия корректны
+	if len(v.result.Errors) > failed {
+		return
+	}
+	if err := joingraph.Validate(v.view); err != nil {
+		v.fail("joins", models.ViewIssueInvalidJoin, "%s", err)
+		return
+	}
+	graph, err := joingraph.Build(v.view)
+	if err != nil {
+		return
+	}
+	for i, source := range v.view.Sources {
+		for j, sch := range source.Schemas {
+			for k, table := range sch.Tables {
+				ref := joingraph.TableRef{Source: source.Name, Schema: sch.Name, Table: table.Name}
+				if !graph.Contains(ref) {
+					v.warn(fmt.Sprintf("sources[%d].schemas[%d].tables[%d]", i, j, k), models.ViewIssueTableNotJoined, "таблица %s не участвует в джоинах и не попадёт во view", ref)
+				}
+			}
+		}
+	}
+}
+
+func (v *viewValidator) joinTable(endpoint models.JoinEndpoint, path string) (models.Table, bool) {
+	table, ok := joingraph.FindTable(v.view, joingraph.RefFromEndpoint(endpoint))
+	if !ok {
+		v.fail(path+".table", models.ViewIssueUnknownTable, "таблица %s не описана в источниках view", joingraph.RefFromEndpoint(endpoint))
+	}
+	return table, ok
+}
+
+// joinColumn находит колонку джоина среди колонок временной таблицы и возвращает
+// имя колонки в источнике
+func (v *viewValidator) joinColumn(ref joingraph.TableRef, table models.Table, name, path string) (string, bool) {
+	for _, column := range table.Columns {
+		if column.Alias != "" && column.Alias == name {
+			return column.Name, true
+		}
+		if column.Alias == "" && column.Name == name {
+			return column.Name, true
+		}
+	}
+	for _, column := range table.Columns {
+		if column.Name == name {
+			v.fail(path, models.ViewIssueJoinColumn, "колонка %s таблицы %s переименована в %s, в условии джоина нужно указать alias", name, ref, column.Alias)
+			return "", false
+		}
+	}
+	if catalog, ok := v.catalogs[ref]; ok {
+		if _, exists := catalog[name]; !exists {
+			v.fail(path, models.ViewIssueUnknownColumn, "колонка %s не найдена в таблице %s%s", name, ref, similarColumnHint(catalog, name))
+			return "", false
+		}
+	}
+	v.fail(path, models.ViewIssueJoinColumn, "колонка %s не выбрана в таблице %s, во временной таблице её не будет", name, ref)
+	return "", false
+}
+
+func (v *viewValidator) checkJoinTypes(left joingraph.TableRef, leftColumn string, right joingraph.TableRef, rightColumn string, path string) {
+	leftType, rightType := v.columnType(left, leftColumn), v.columnType(right, rightColumn)
+	if leftType == "" || rightType == "" || strings.EqualFold(leftType, rightType) {
+		return
+	}
+	leftFamily, leftKnown := typeFamily(leftType)
+	rightFamily, rightKnown := typeFamily(rightType)
+	switch {
+	case leftKnown && rightKnown && leftFamily != rightFamily:
+		v.fail(path, models.ViewIssueJoinTypeMismatch, "несовместимые типы колонок джоина: %s.%s (%s) и %s.%s (%s)", left, leftColumn, leftType, right, rightColumn, rightType)
+	case !leftKnown || !rightKnown:
+		v.warn(path, models.ViewIssueJoinTypeMismatch, "не удалось сравнить типы колонок джоина: %s.%s (%s) и %s.%s (%s)", left, leftColumn, leftType, right, rightColumn, rightType)
+	}
+}
+
+// columnType возвращает тип колонки источника: GetColumnInfo различает
+// пользовательские типы и типы элементов массивов, каталог таблицы — запасной вариант
+func (v *viewValidator) columnType(ref joingraph.TableRef, column string) string {
+	if oltpStorage, ok := v.storages[ref.Source]; ok {
+		if info, err := oltpStorage.GetColumnInfo(v.ctx, ref.Table, column); err == nil && info.Type != "" {
+			return info.Type
+		}
+	}
+	if catalog, ok := v.catalogs[ref]; ok {
+		return columnTypeName(catalog[column])
+	}
+	return ""
+}
+
+func joinConditionKey(joinType models.JoinType) string {
+	switch joinType {
+	case models.JoinLeft:
+		return "left"
+	case models.JoinRight:
+		return "right"
+	case models.JoinFull:
+		return "full"
+	default:
+		return "inner"
+	}
+}
+
+func similarColumnHint(catalog map[string]models.Column, name string) string {
+	for existing := range catalog {
+		if strings.EqualFold(existing, name) {
+			return fmt.Sprintf(" (есть колонка %s)", existing)
+		}
+	}
+	return ""
+}
+
+func columnTypeName(column models.Column) string {
+	if column.Type != "" {
+		return column.Type
+	}
+	if column.UdtName != "" {
+		return column.UdtName
+	}
+	return column.DataType
+}
+
+// typeFamily сводит тип Postgres к группе типов, значения которых сравнимы между собой
+func typeFamily(typ string) (string, bool) {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if strings.HasPrefix(typ, "_") || strings.HasSuffix(typ, "[]") || typ == "array" {
+		return "array", true
+	}
+	if idx := strings.IndexByte(typ, '('); idx >= 0 {
+		typ = strings.TrimSpace(typ[:idx])
+	}
+	switch typ {
+	case "smallint", "integer", "bigint", "int", "int2", "int4", "int8", "smallserial", "serial", "bigserial",
+		"numeric", "decimal", "real", "double precision", "float4", "float8":
+		return "number", true
+	case "text", "character varying", "varchar", "character", "char", "bpchar", "citext", "public.citext", "name":
+		return "text", true
+	case "uuid":
+		return "uuid", true
+	case "boolean", "bool":
+		return "boolean", true
+	case "date", "timestamp", "timestamptz", "timestamp without time zone", "timestamp with time zone":
+		return "timestamp", true
+	case "time", "timetz", "time without time zone", "time with time zone":
+		return "time", true
+	case "json", "jsonb":
+		return "json", true
+	case "bytea":
+		return "bytes", true
+	}
+	return typ, false
+}
@@ -0,0 +1,176 @@
+package serviceanalytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+func usersOrdersCatalog() *mockOLTP {
+	return &mockOLTP{
+		tableColumns: map[string][]models.Column{
+			"users": {
+				{Name: "id", Type: "integer"},
+				{Name: "name", Type: "text"},
+			},
+			"orders": {
+				{Name: "id", Type: "integer"},
+				{Name: "user_id", Type: "integer"},
+				{Name: "amount", Type: "numeric"},
+			},
+		},
+	}
+}
+
+func usersOrdersView() models.View {
+	return models.View{
+		Name: "v",
+		Sources: []models.Source{{
+			Name: "db1",
+			Schemas: []models.Schema{{
+				Name: "public",
+				Tables: []models.Table{
+					{Name: "users", Columns: []models.Column{{Name: "id", Type: "integer"}, {Name: "name", Type: "text"}}},
+					{Name: "orders", Columns: []models.Column{
+						{Name: "id", Alias: "order_id", Type: "integer"},
+						{Name: "user_id", Type: "integer"},
+						{Name: "amount", Type: "numeric"},
+					}},
+				},
+			}},
+		}},
+		Joins: []*models.Join{{Inner: &models.JoinCondition{
+			Left:  models.JoinEndpoint{Source: "db1", Schema: "public", Table: "users", Column: "id"},
+			Right: models.JoinEndpoint{Source: "db1", Schema: "public", Table: "orders", Column: "user_id"},
+		}}},
+	}
+}
+
+func newValidationTestService(oltp *mockOLTP) *AnalyticsDataCenterService {
+	return &AnalyticsDataCenterService{
+		log:         getTestLogger(),
+		OLTPFactory: &mockFactory{store: map[string]storage.OLTPDB{"db1": oltp}},
+		DWHDbName:   DbPostgres,
+		OLTPDbName:  DbPostgres,
+	}
+}
+
+func issueCodes(issues []models.ViewIssue) map[string]string {
+	codes := make(map[string]string, len(issues))
+	for _, issue := range issues {
+		codes[issue.Field] = issue.Code
+	}
+	return codes
+}
+
+func TestValidateView_Valid(t *testing.T) {
+	svc := newValidationTestService(usersOrdersCatalog())
+
+	result := svc.ValidateView(context.Background(), usersOrdersView())
+
+	require.True(t, result.Valid)
+	require.Empty(t, result.Errors)
+	require.Empty(t, result.Warnings)
+}
+
+func TestValidateView_ReportsFieldErrors(t *testing.T) {
+	oltp := usersOrdersCatalog()
+	oltp.columnInfo = map[string]models.ColumnInfo{
+		"users.id":       {Type: "uuid"},
+		"orders.user_id": {Type: "integer"},
+	}
+	svc := newValidationTestService(oltp)
+	view := usersOrdersView()
+	tables := view.Sources[0].Schemas[0].Tables
+	tables[0].Columns = append(tables[0].Columns, models.Column{Name: "emial", Type: "text"})
+	tables[1].Columns[0].Alias = ""
+	tables[1].Filter = &models.RowFilter{Column: "Amount", Operator: models.FilterGt, Value: float64(1)}
+
+	result := svc.ValidateView(context.Background(), view)
+
+	require.False(t, result.Valid)
+	require.Equal(t, map[string]string{
+		"sources[0].schemas[0].tables[0].columns[2].name": models.ViewIssueUnknownColumn,
+		"sources[0].schemas[0].tables[1].filter":          models.ViewIssueUnknownColumn,
+		"sources[0].schemas[0].tables[1].columns":         models.ViewIssueDuplicateOutput,
+		"joins[0].inner.right.column":                     models.ViewIssueJoinTypeMismatch,
+	}, issueCodes(result.Errors))
+}
+
+func TestValidateView_Watermark(t *testing.T) {
+	svc := newValidationTestService(usersOrdersCatalog())
+	view := usersOrdersView()
+	tables := view.Sources[0].Schemas[0].Tables
+	tables[0].Watermark = "updated"
+	tables[1].Watermark = "amount"
+
+	result := svc.ValidateView(context.Background(), view)
+	require.Equal(t, map[string]string{
+		"sources[0].schemas[0].tables[0].watermark": models.ViewIssueUnknownColumn,
+	}, issueCodes(result.Errors))
+	require.Empty(t, result.Warnings)
+
+	// без джоинов строки пишутся по ключам обновления, отметка-текст не упорядочивает изменения
+	view.Joins = nil
+	view.Sources[0].Schemas[0].Tables = tables[:1]
+	tables[0].Watermark = "name"
+	result = svc.ValidateView(context.Background(), view)
+	require.True(t, result.Valid)
+	require.Len(t, result.Warnings, 2)
+	require.Equal(t, map[string]string{
+		"sources[0].schemas[0].tables[0].watermark": models.ViewIssueInvalidWatermark,
+	}, issueCodes(result.Warnings))
+}
+
+func TestValidateView_JoinEndpoints(t *testing.T) {
+	svc := newValidationTestService(usersOrdersCatalog())
+	view := usersOrdersView()
+	view.Joins = append(view.Joins, &models.Join{LeftOuter: &models.JoinCondition{
+		Left:    models.JoinEndpoint{Source: "db1", Schema: "public", Table: "orders"},
+		Right:   models.JoinEndpoint{Source: "db1", Schema: "public", Table: "payments"},
+		Columns: []models.JoinColumnPair{{Left: "id", Right: "order_id"}},
+	}})
+	view.Joins[0].Inner.Right.Column = "id"
+
+	result := svc.ValidateView(context.Background(), view)
+
+	require.False(t, result.Valid)
+	require.Equal(t, map[string]string{
+		"joins[0].inner.right.column": models.ViewIssueJoinColumn,
+		"joins[1].left.right.table":   models.ViewIssueUnknownTable,
+	}, issueCodes(result.Errors))
+}
+
+func TestValidateView_UnavailableCatalog(t *testing.T) {
+	oltp := usersOrdersCatalog()
+	oltp.columnsErr = errors.New("connection refused")
+	svc := newValidationTestService(oltp)
+	view := usersOrdersView()
+	view.Sources = append(view.Sources, models.Source{Name: "db2"})
+
+	result := svc.ValidateView(context.Background(), view)
+
+	require.False(t, result.Valid)
+	require.Equal(t, map[string]string{"sources[1].name": models.ViewIssueUnknownSource}, issueCodes(result.Errors))
+	require.Len(t, result.Warnings, 2)
+	require.Equal(t, models.ViewIssueCatalogUnavailable, result.Warnings[0].Code)
+}
+
+func TestUploadSchema_RejectsInvalidView(t *testing.T) {
+	svc := newValidationTestService(usersOrdersCatalog())
+	svc.SchemaProvider = &mockSchemaProvider{views: map[int]models.View{}}
+	view := usersOrdersView()
+	view.Sources[0].Schemas[0].Tables[0].Columns[1].Name = "full_name"
+
+	_, err := svc.UploadSchema(context.Background(), view)
+
+	require.ErrorIs(t, err, ErrInvalidView)
+	var validationErr *ViewValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, models.ViewIssueUnknownColumn, validationErr.Result.Errors[0].Code)
+}
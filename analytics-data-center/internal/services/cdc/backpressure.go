@@ -0,0 +1,28 @@
+package cdc
+
+// Пороги заполненности очереди CDC-воркеров: при pauseLoad чтение Kafka
+// приостанавливается, при resumeLoad возобновляется
+const (
+	pauseLoad  = 0.8
+	resumeLoad = 0.5
+)
+
+// backpressure решает, приостановить ли чтение Kafka, по заполненности очереди
+// обработчика. Разные пороги остановки и возобновления не дают слушателю дёргать
+// Pause/Resume на каждом сообщении.
+type backpressure struct {
+	paused bool
+}
+
+// update учитывает текущую заполненность и сообщает, изменилось ли состояние
+func (b *backpressure) update(load float64) (changed bool) {
+	switch {
+	case !b.paused && load >= pauseLoad:
+		b.paused = true
+		return true
+	case b.paused && load <= resumeLoad:
+		b.paused = false
+		return true
+	}
+	return false
+}
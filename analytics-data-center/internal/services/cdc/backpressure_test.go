@@ -0,0 +1,24 @@
+package cdc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackpressure_Hysteresis(t *testing.T) {
+	var b backpressure
+
+	require.False(t, b.update(0.5))
+	require.True(t, b.update(0.8))
+	require.True(t, b.paused)
+
+	// между порогами состояние не меняется
+	require.False(t, b.update(0.9))
+	require.False(t, b.update(0.6))
+	require.True(t, b.paused)
+
+	require.True(t, b.update(0.5))
+	require.False(t, b.paused)
+	require.False(t, b.update(0.7))
+}
@@ -12,17 +12,26 @@ type HandlerCDC interface {
 	EventPreprocessing(models.CDCEvent)
 }
 
-func Dispatch(eventBytes []byte, log *logger.Logger, analyticsHandler HandlerCDC) {
+// Dispatch разбирает сообщение и передаёт событие обработчику вместе с ключом и
+// подтверждением ack. Сообщение, которое не разбирается, подтверждается сразу:
+// повторное чтение его не исправит.
+func Dispatch(key []byte, eventBytes []byte, ack func(), log *logger.Logger, analyticsHandler HandlerCDC) {
 	var eventData models.CDCEventData
 	if err := json.Unmarshal(eventBytes, &eventData); err != nil {
 		log.ErrorMsg(logger.Message{RU: "Ошибка парсинга JSON", EN: "JSON parse error", CN: "JSON解析错误"}, slog.String("error", err.Error()))
+		if ack != nil {
+			ack()
+		}
 		return
 	}
 
 	evt := models.CDCEvent{
-		Event: "",
-		ID:    "",
-		Data:  eventData, // всё остальное — в Data
+		Event:   "",
+		ID:      "",
+		Data:    eventData, // всё остальное — в Data
+		Ack:     ack,
+		Payload: eventBytes,
+		Key:     key,
 	}
 
 	analyticsHandler.EventPreprocessing(evt)
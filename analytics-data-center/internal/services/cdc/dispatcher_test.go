@@ -21,20 +21,26 @@ func TestDispatch_ValidJSON(t *testing.T) {
 	handler := &mockHandler{}
 	data := []byte(`{"before":null,"after":{"id":1},"source":{"db":"test","schema":"public","table":"users"},"op":"c","transaction":null,"ts_ms":123}`)
 
-	Dispatch(data, loggerpkg.New("test", "ru"), handler)
+	acked := false
+	Dispatch([]byte(`{"id":1}`), data, func() { acked = true }, loggerpkg.New("test", "ru"), handler)
 
 	require.True(t, handler.called, "handler should be called")
+	require.False(t, acked, "event is acked by the handler after it is applied")
+	require.NotNil(t, handler.event.Ack)
 	require.Equal(t, "test", handler.event.Data.Source.DB)
 	require.Equal(t, "users", handler.event.Data.Source.Table)
 	require.Equal(t, "c", handler.event.Data.Op)
 	require.Equal(t, float64(1), handler.event.Data.After["id"])
+	require.Equal(t, []byte(`{"id":1}`), handler.event.Key)
 }
 
 func TestDispatch_InvalidJSON(t *testing.T) {
 	handler := &mockHandler{}
 	data := []byte("{invalid json}")
 
-	Dispatch(data, loggerpkg.New("test", "ru"), handler)
+	acked := false
+	Dispatch(nil, data, func() { acked = true }, loggerpkg.New("test", "ru"), handler)
 
 	require.False(t, handler.called, "handler should not be called on invalid JSON")
+	require.True(t, acked, "unparsable message should not block the partition")
 }
@@ -11,32 +11,95 @@ import (
 type Listener struct {
 	Consumer *kafka.Consumer
 	Log      *loggerpkg.Logger
-	Handler  func(event []byte)
+	// Handler получает ключ и значение сообщения и функцию подтверждения: offset
+	// сообщения коммитится только после вызова ack
+	Handler func(key []byte, event []byte, ack func())
+	Offsets *OffsetTracker
+	// Load — заполненность очереди обработчика от 0 до 1; пока очередь почти полна,
+	// чтение назначенных разделов приостановлено. nil — без приостановки.
+	Load     func() float64
+	pressure backpressure
 }
 
-func NewListener(consumer *kafka.Consumer, log *loggerpkg.Logger, handler func([]byte)) *Listener {
-	return &Listener{Consumer: consumer, Log: log, Handler: handler}
+func NewListener(consumer *kafka.Consumer, log *loggerpkg.Logger, handler func([]byte, []byte, func()), load func() float64) *Listener {
+	return &Listener{Consumer: consumer, Log: log, Handler: handler, Offsets: NewOffsetTracker(), Load: load}
 }
 
 func (l *Listener) Start() {
 	go func() {
 		for {
 			ev := l.Consumer.Poll(1000)
-			if ev == nil {
-				continue
-			}
 			switch e := ev.(type) {
 			case *kafka.Message:
 				l.Log.InfoMsg(loggerpkg.MsgCDCMessageReceived, slog.String("topic", *e.TopicPartition.Topic))
-				l.Handler(e.Value) // отправляем на обработку
-				// ручной коммит
-				_, err := l.Consumer.CommitMessage(e)
-				if err != nil {
-					l.Log.ErrorMsg(loggerpkg.MsgKafkaCommitError, slog.String("error", err.Error()))
-				}
+				l.Handler(e.Key, e.Value, l.Offsets.Track(e.TopicPartition)) // отправляем на обработку
 			case kafka.Error:
 				l.Log.ErrorMsg(loggerpkg.MsgKafkaError, slog.String("error", e.Error()))
 			}
+			l.throttle()
+			// ручной коммит обработанных сообщений
+			l.commit(l.Consumer, l.Offsets.Committable())
 		}
 	}()
 }
+
+// throttle приостанавливает чтение назначенных разделов, пока очередь обработчика
+// почти полна, и возобновляет, когда воркеры её разберут. Poll продолжает вызываться:
+// коммиты и ребалансировки обслуживаются и во время паузы.
+func (l *Listener) throttle() {
+	if l.Load == nil {
+		return
+	}
+	load := l.Load()
+	changed := l.pressure.update(load)
+	if !changed && !l.pressure.paused {
+		return
+	}
+	assignment, err := l.Consumer.Assignment()
+	if err != nil {
+		l.Log.ErrorMsg(loggerpkg.MsgKafkaError, slog.String("error", err.Error()))
+		return
+	}
+	if l.pressure.paused {
+		// разделы, назначенные во время паузы, тоже приостанавливаются
+		if err := l.Consumer.Pause(assignment); err != nil {
+			l.Log.ErrorMsg(loggerpkg.MsgKafkaError, slog.String("error", err.Error()))
+			return
+		}
+		if changed {
+			l.Log.Warn("очередь CDC-воркеров заполнена, чтение Kafka приостановлено", slog.Float64("load", load))
+		}
+		return
+	}
+	if err := l.Consumer.Resume(assignment); err != nil {
+		l.Log.ErrorMsg(loggerpkg.MsgKafkaError, slog.String("error", err.Error()))
+		return
+	}
+	l.Log.Info("очередь CDC-воркеров разобрана, чтение Kafka возобновлено", slog.Float64("load", load))
+}
+
+// RevokePartitions коммитит обработанные сообщения отзываемых разделов; вызывается
+// из обработчика ребалансировки до снятия назначения
+func (l *Listener) RevokePartitions(c *kafka.Consumer, partitions []kafka.TopicPartition) {
+	l.commit(c, l.Offsets.Revoke(partitions))
+}
+
+func (l *Listener) commit(c *kafka.Consumer, offsets []kafka.TopicPartition) {
+	if len(offsets) == 0 {
+		return
+	}
+	committed, err := c.CommitOffsets(offsets)
+	if err != nil {
+		l.Log.ErrorMsg(loggerpkg.MsgKafkaCommitError, slog.String("error", err.Error()))
+		return
+	}
+	for _, tp := range committed {
+		if tp.Error != nil {
+			l.Log.ErrorMsg(loggerpkg.MsgKafkaCommitError,
+				slog.String("topic", topicName(tp)),
+				slog.Any("partition", tp.Partition),
+				slog.String("error", tp.Error.Error()))
+		}
+	}
+	l.Offsets.Committed(committed)
+}
@@ -0,0 +1,136 @@
+package cdc
+
+import (
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// partitionKey — раздел топика
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// partitionOffsets — offset'ы раздела, полученные после последнего коммита
+type partitionOffsets struct {
+	// pending — полученные и ещё не закоммиченные offset'ы в порядке получения
+	pending []kafka.Offset
+	done    map[kafka.Offset]struct{}
+	// ready — offset, который можно закоммитить: все сообщения до него обработаны
+	ready     kafka.Offset
+	committed kafka.Offset
+}
+
+// OffsetTracker считает offset'ы сообщений по разделам. Коммитится только offset, до
+// которого обработаны все сообщения раздела: сообщение, обработка которого не
+// подтверждена, после перезапуска или ребалансировки будет прочитано снова.
+type OffsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+func NewOffsetTracker() *OffsetTracker {
+	return &OffsetTracker{partitions: make(map[partitionKey]*partitionOffsets)}
+}
+
+// Track регистрирует полученное сообщение и возвращает функцию подтверждения его
+// обработки. Подтверждение сообщения отозванного раздела игнорируется.
+func (t *OffsetTracker) Track(tp kafka.TopicPartition) (ack func()) {
+	key := partitionKey{topic: topicName(tp), partition: tp.Partition}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{
+			done:      make(map[kafka.Offset]struct{}),
+			ready:     kafka.OffsetInvalid,
+			committed: kafka.OffsetInvalid,
+		}
+		t.partitions[key] = p
+	}
+	p.pending = append(p.pending, tp.Offset)
+	offset := tp.Offset
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.partitions[key] != p {
+				return
+			}
+			p.done[offset] = struct{}{}
+			for len(p.pending) > 0 {
+				head := p.pending[0]
+				if _, ok := p.done[head]; !ok {
+					break
+				}
+				delete(p.done, head)
+				p.pending = p.pending[1:]
+				p.ready = head + 1
+			}
+		})
+	}
+}
+
+// Committable возвращает offset'ы разделов, продвинувшиеся с последнего коммита
+func (t *OffsetTracker) Committable() []kafka.TopicPartition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var offsets []kafka.TopicPartition
+	for key, p := range t.partitions {
+		if p.ready != kafka.OffsetInvalid && p.ready != p.committed {
+			offsets = append(offsets, topicPartition(key, p.ready))
+		}
+	}
+	return offsets
+}
+
+// Committed отмечает offset'ы, которые Kafka приняла без ошибки
+func (t *OffsetTracker) Committed(offsets []kafka.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tp := range offsets {
+		if tp.Error != nil {
+			continue
+		}
+		if p, ok := t.partitions[partitionKey{topic: topicName(tp), partition: tp.Partition}]; ok && p.ready == tp.Offset {
+			p.committed = tp.Offset
+		}
+	}
+}
+
+// Revoke забывает отозванные разделы и возвращает их offset'ы, которые нужно
+// закоммитить до снятия назначения. Необработанные сообщения этих разделов получит
+// новый владелец раздела.
+func (t *OffsetTracker) Revoke(partitions []kafka.TopicPartition) []kafka.TopicPartition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var offsets []kafka.TopicPartition
+	for _, tp := range partitions {
+		key := partitionKey{topic: topicName(tp), partition: tp.Partition}
+		p, ok := t.partitions[key]
+		if !ok {
+			continue
+		}
+		if p.ready != kafka.OffsetInvalid && p.ready != p.committed {
+			offsets = append(offsets, topicPartition(key, p.ready))
+		}
+		delete(t.partitions, key)
+	}
+	return offsets
+}
+
+func topicName(tp kafka.TopicPartition) string {
+	if tp.Topic == nil {
+		return ""
+	}
+	return *tp.Topic
+}
+
+func topicPartition(key partitionKey, offset kafka.Offset) kafka.TopicPartition {
+	topic := key.topic
+	return kafka.TopicPartition{Topic: &topic, Partition: key.partition, Offset: offset}
+}
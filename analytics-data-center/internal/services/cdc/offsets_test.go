@@ -0,0 +1,70 @@
+package cdc
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+func message(topic string, partition int32, offset kafka.Offset) kafka.TopicPartition {
+	return kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: offset}
+}
+
+func TestOffsetTracker_CommitsContiguousAcks(t *testing.T) {
+	tracker := NewOffsetTracker()
+	ack10 := tracker.Track(message("db.public.users", 0, 10))
+	ack11 := tracker.Track(message("db.public.users", 0, 11))
+	ack12 := tracker.Track(message("db.public.users", 0, 12))
+	ackOther := tracker.Track(message("db.public.users", 1, 5))
+
+	// 11 обработано раньше 10: коммитить нечего
+	ack11()
+	require.Empty(t, tracker.Committable())
+
+	ack10()
+	ackOther()
+	offsets := tracker.Committable()
+	require.ElementsMatch(t, []kafka.TopicPartition{
+		message("db.public.users", 0, 12),
+		message("db.public.users", 1, 6),
+	}, offsets)
+
+	// пока коммит не принят, offset'ы предлагаются снова
+	require.Len(t, tracker.Committable(), 2)
+	tracker.Committed(offsets)
+	require.Empty(t, tracker.Committable())
+
+	// повторное подтверждение не сдвигает offset
+	ack11()
+	ack12()
+	require.Equal(t, []kafka.TopicPartition{message("db.public.users", 0, 13)}, tracker.Committable())
+}
+
+func TestOffsetTracker_CommittedSkipsFailedPartitions(t *testing.T) {
+	tracker := NewOffsetTracker()
+	tracker.Track(message("t", 0, 1))()
+	tracker.Track(message("t", 1, 1))()
+
+	failed := message("t", 1, 2)
+	failed.Error = kafka.NewError(kafka.ErrRequestTimedOut, "timeout", false)
+	tracker.Committed([]kafka.TopicPartition{message("t", 0, 2), failed})
+
+	require.Equal(t, []kafka.TopicPartition{message("t", 1, 2)}, tracker.Committable())
+}
+
+func TestOffsetTracker_RevokeDropsPartition(t *testing.T) {
+	tracker := NewOffsetTracker()
+	tracker.Track(message("t", 0, 1))()
+	late := tracker.Track(message("t", 0, 2))
+	tracker.Track(message("t", 1, 7))()
+
+	// отзыв отдаёт обработанный offset и забывает необработанные сообщения раздела
+	require.Equal(t, []kafka.TopicPartition{message("t", 0, 2)}, tracker.Revoke([]kafka.TopicPartition{message("t", 0, kafka.OffsetInvalid)}))
+	late()
+	require.Equal(t, []kafka.TopicPartition{message("t", 1, 8)}, tracker.Committable())
+
+	// раздел, назначенный снова, отсчитывается заново
+	tracker.Track(message("t", 0, 2))()
+	require.ElementsMatch(t, []kafka.TopicPartition{message("t", 0, 3), message("t", 1, 8)}, tracker.Committable())
+}
@@ -31,6 +31,9 @@ func RegisterPostgresConnector(connectURL string, name string, pgConn string) er
 		return fmt.Errorf("не верно указан порт: %w", err)
 	}
 
+	// snapshot.mode=never: начальные данные загружает ETL из своего снимка. Коннектор с
+	// этим снимком не согласован, и изменения, уже попавшие в снимок, отсеивает только
+	// фильтр по LSN в serviceanalytics.eventApplies.
 	connector := DebeziumConnectorConfig{
 		Name: fmt.Sprintf("conn_%s", name),
 		Config: map[string]interface{}{
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"log/slog"
 	"slices"
+	"time"
 
 	loggerpkg "analyticDataCenter/analytics-data-center/internal/logger"
 )
@@ -111,3 +112,201 @@ func (s *TasksService) GetTasks(ctx context.Context, filters models.TaskFilter)
 	}
 	return tasks, nil
 }
+
+func (s *TasksService) SaveTaskStage(ctx context.Context, taskID string, stage models.TaskStage) error {
+	const op = "tasks.SaveTaskStage"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+		slog.String("stage", stage.Name),
+	)
+	if taskID == "" {
+		return errors.New("идентификатор задачи не может быть пустым")
+	}
+	if stage.Name == "" || stage.Status == "" {
+		return errors.New("этап и его статус не могут быть пустыми")
+	}
+
+	err := s.TaskProvider.SaveTaskStage(ctx, taskID, stage)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgSaveStageFailed, slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *TasksService) SaveTaskTableProgress(ctx context.Context, taskID string, progress models.TaskTableProgress) error {
+	const op = "tasks.SaveTaskTableProgress"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+		slog.String("table", progress.Table),
+	)
+	if taskID == "" {
+		return errors.New("идентификатор задачи не может быть пустым")
+	}
+
+	err := s.TaskProvider.SaveTaskTableProgress(ctx, taskID, progress)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgSaveProgressFailed, slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *TasksService) AddTaskTableRows(ctx context.Context, taskID string, progress models.TaskTableProgress) error {
+	const op = "tasks.AddTaskTableRows"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+		slog.String("table", progress.Table),
+	)
+	if taskID == "" {
+		return errors.New("идентификатор задачи не может быть пустым")
+	}
+
+	err := s.TaskProvider.AddTaskTableRows(ctx, taskID, progress)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgSaveProgressFailed, slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *TasksService) SaveTaskChunks(ctx context.Context, taskID string, chunks []models.TaskChunk) error {
+	const op = "tasks.SaveTaskChunks"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+	if taskID == "" {
+		return errors.New("идентификатор задачи не может быть пустым")
+	}
+
+	err := s.TaskProvider.SaveTaskChunks(ctx, taskID, chunks)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgSaveChunkFailed, slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *TasksService) CompleteTaskChunk(ctx context.Context, taskID string, chunk models.TaskChunk) error {
+	const op = "tasks.CompleteTaskChunk"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+		slog.String("table", chunk.Table),
+		slog.Int("chunk", chunk.Chunk.Index),
+	)
+	if taskID == "" {
+		return errors.New("идентификатор задачи не может быть пустым")
+	}
+
+	err := s.TaskProvider.CompleteTaskChunk(ctx, taskID, chunk)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgSaveChunkFailed, slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *TasksService) ListTaskChunks(ctx context.Context, taskID string) ([]models.TaskChunk, error) {
+	const op = "tasks.ListTaskChunks"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+	if taskID == "" {
+		return nil, errors.New("идентификатор задачи не может быть пустым")
+	}
+
+	chunks, err := s.TaskProvider.ListTaskChunks(ctx, taskID)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgGetTaskFailed, slog.String("error", err.Error()))
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (s *TasksService) RegisterTaskTempTables(ctx context.Context, taskID string, tables []string) error {
+	const op = "tasks.RegisterTaskTempTables"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+	if taskID == "" {
+		return errors.New("идентификатор задачи не может быть пустым")
+	}
+
+	err := s.TaskProvider.RegisterTaskTempTables(ctx, taskID, tables)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgRegisterTempTablesFailed, slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *TasksService) GetTaskTempTables(ctx context.Context, taskID string) ([]string, error) {
+	const op = "tasks.GetTaskTempTables"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+
+	tables, err := s.TaskProvider.GetTaskTempTables(ctx, taskID)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgGetTaskFailed, slog.String("error", err.Error()))
+		return nil, err
+	}
+	return tables, nil
+}
+
+func (s *TasksService) ListTaskTempTables(ctx context.Context, activeStatus string, failedStatus string, failedSince time.Time) ([]string, error) {
+	const op = "tasks.ListTaskTempTables"
+	log := s.log.With(slog.String("op", op))
+
+	tables, err := s.TaskProvider.ListTaskTempTables(ctx, activeStatus, failedStatus, failedSince)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgGetTaskFailed, slog.String("error", err.Error()))
+		return nil, err
+	}
+	return tables, nil
+}
+
+func (s *TasksService) SaveTaskSnapshot(ctx context.Context, taskID string, snapshot models.TaskSnapshot) error {
+	const op = "tasks.SaveTaskSnapshot"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+		slog.String("source", snapshot.Source),
+	)
+	if taskID == "" {
+		return errors.New("идентификатор задачи не может быть пустым")
+	}
+
+	err := s.TaskProvider.SaveTaskSnapshot(ctx, taskID, snapshot)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgSaveSnapshotFailed, slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *TasksService) ListTaskSnapshots(ctx context.Context, taskID string) ([]models.TaskSnapshot, error) {
+	const op = "tasks.ListTaskSnapshots"
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+	if taskID == "" {
+		return nil, errors.New("идентификатор задачи не может быть пустым")
+	}
+
+	snapshots, err := s.TaskProvider.ListTaskSnapshots(ctx, taskID)
+	if err != nil {
+		log.ErrorMsg(loggerpkg.MsgGetTaskFailed, slog.String("error", err.Error()))
+		return nil, err
+	}
+	return snapshots, nil
+}
@@ -13,6 +13,9 @@ type OLTPFactory interface {
 	GetOLTPStorage(ctx context.Context, sourceName string) (OLTPDB, error)
 	CloseAll() error // чтобы можно было корректно закрыть соединения при завершении программы
 	GetOLTPStrings(ctx context.Context) map[string]string
+	// GetOLTPSnapshotStorage возвращает хранилище источника, выборки данных которого
+	// читают снимок snapshotID, экспортированный ExportSnapshot
+	GetOLTPSnapshotStorage(ctx context.Context, sourceName string, snapshotID string) (OLTPDB, error)
 }
 
 type InstanceOLTPFactory struct {
@@ -67,6 +70,19 @@ func (f *InstanceOLTPFactory) GetOLTPStorage(ctx context.Context, sourceName str
 	return storage, nil
 }
 
+func (f *InstanceOLTPFactory) GetOLTPSnapshotStorage(ctx context.Context, sourceName string, snapshotID string) (OLTPDB, error) {
+	storage, err := f.GetOLTPStorage(ctx, sourceName)
+	if err != nil {
+		return nil, err
+	}
+	// снимок импортируется соединениями того же пула, что и экспортировал его
+	postgres, ok := storage.(*postgresoltp.PostgresOLTP)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", sourceName, ErrSnapshotNotSupported)
+	}
+	return postgres.WithSnapshot(snapshotID), nil
+}
+
 // Закрыть все соединения при завершении работы
 func (f *InstanceOLTPFactory) CloseAll() error {
 	f.mu.Lock()
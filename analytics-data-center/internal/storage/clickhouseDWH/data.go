@@ -1,25 +1,162 @@
 package clickhousedwh
 
 import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
 	"context"
+	"database/sql"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 )
 
-func (c *ClickHouseDB) InsertDataToDWH(ctx context.Context, query string) error {
-	const op = "Storage.ClickHouseDB.InsertDataToDWH"
+// InsertStream загружает строки потока в таблицу пакетными вставками clickhouse-go:
+// значения передаются в колонки типизированными (массивы, Decimal, DateTime64, Nullable),
+// пакет отправляется на сервер каждые batchSize строк.
+func (c *ClickHouseDB) InsertStream(ctx context.Context, view models.View, tableName string, rows models.RowStream, batchSize int) (int64, error) {
+	const op = "Storage.ClickHouseDB.InsertStream"
 	log := c.Log.With(
 		slog.String("op", op),
+		slog.String("table", tableName),
 	)
-	_, err := c.Db.ExecContext(ctx, query)
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("%s: некорректный размер порции вставки: %d", op, batchSize)
+	}
+
+	columnTypes, err := c.columnTypes(ctx, tableName)
 	if err != nil {
-		log.Error("ошибка подготовки запроса", slog.String("error", err.Error()))
+		log.Error("не удалось получить типы колонок", slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	var (
+		inserted int64
+		columns  []string
+		batch    *insertBatch
+	)
+	fail := func(msg string, err error) (int64, error) {
+		if batch != nil {
+			batch.abort()
+		}
+		log.Error(msg, slog.Int64("inserted", inserted), slog.String("error", err.Error()))
+		return inserted, err
+	}
+
+	for rows.Next() {
+		row := rows.Row()
+		if columns == nil {
+			if columns, err = sqlgenerator.InsertColumns(view, row); err != nil {
+				return fail("не найдены колонки для вставки", err)
+			}
+		}
+		values, err := sqlgenerator.ClickhouseBatchValues(row, columns, columnTypes)
+		if err != nil {
+			return fail("ошибка подготовки значений строки", err)
+		}
+
+		if batch == nil {
+			if batch, err = c.beginBatch(ctx, tableName, columns); err != nil {
+				return fail("ошибка подготовки пакетной вставки", err)
+			}
+		}
+		if err := batch.add(ctx, values); err != nil {
+			return fail("ошибка добавления строки в пакет", err)
+		}
+		if batch.size < batchSize {
+			continue
+		}
+		if err := batch.send(); err != nil {
+			batch = nil
+			return fail("ошибка отправки пакета", err)
+		}
+		inserted += int64(batch.size)
+		batch = nil
+	}
+	if err := rows.Err(); err != nil {
+		return fail("ошибка чтения строк источника", err)
+	}
+	if batch != nil {
+		if err := batch.send(); err != nil {
+			batch = nil
+			return fail("ошибка отправки пакета", err)
+		}
+		inserted += int64(batch.size)
+	}
+	return inserted, nil
+}
+
+// columnTypes возвращает типы колонок таблицы текущей базы в нотации ClickHouse
+func (c *ClickHouseDB) columnTypes(ctx context.Context, tableName string) (map[string]string, error) {
+	rows, err := c.Db.QueryContext(ctx, "SELECT name, type FROM system.columns WHERE database = currentDatabase() AND table = ?", tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make(map[string]string)
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		types[name] = typ
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("таблица %s не найдена", tableName)
+	}
+	return types, nil
+}
+
+// insertBatch — пакет clickhouse-go: строки копятся в драйвере и уходят на сервер при Commit
+type insertBatch struct {
+	tx   *sql.Tx
+	stmt *sql.Stmt
+	size int
+}
+
+func (c *ClickHouseDB) beginBatch(ctx context.Context, tableName string, columns []string) (*insertBatch, error) {
+	query, err := sqlgenerator.GenerateInsertTemplate(tableName, columns, sqlgenerator.DbClickhouse)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := c.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return &insertBatch{tx: tx, stmt: stmt}, nil
+}
+
+func (b *insertBatch) add(ctx context.Context, values []interface{}) error {
+	if _, err := b.stmt.ExecContext(ctx, values...); err != nil {
 		return err
 	}
+	b.size++
 	return nil
 }
 
+func (b *insertBatch) send() error {
+	if err := b.tx.Commit(); err != nil {
+		_ = b.stmt.Close()
+		return err
+	}
+	return b.stmt.Close()
+}
+
+func (b *insertBatch) abort() {
+	_ = b.stmt.Close()
+	_ = b.tx.Rollback()
+}
+
 func (c *ClickHouseDB) MergeTempTables(ctx context.Context, query string) error {
 	const op = "Storage.ClickHouseDB.MergeTempTables"
 	log := c.Log.With(
@@ -72,3 +209,86 @@ func (c *ClickHouseDB) InsertOrUpdateTransactional(
 	}
 	return nil
 }
+
+func (c *ClickHouseDB) DeleteRow(ctx context.Context, tableName string, keys map[string]interface{}) error {
+	const op = "Storage.ClickHouseDB.DeleteRow"
+	log := c.Log.With(
+		slog.String("op", op),
+		slog.String("table", tableName),
+	)
+	if len(keys) == 0 {
+		return fmt.Errorf("не заданы ключи для удаления строки из %s", tableName)
+	}
+
+	where, values := buildKeyCondition(keys)
+	query := fmt.Sprintf("ALTER TABLE %s DELETE WHERE %s", tableName, where)
+	if _, err := c.Db.ExecContext(ctx, query, values...); err != nil {
+		log.Error("ошибка удаления строки", slog.String("query", query), slog.Any("err", err))
+		return fmt.Errorf("ошибка удаления в ClickHouse: %w", err)
+	}
+	return nil
+}
+
+func (c *ClickHouseDB) SoftDeleteRow(ctx context.Context, tableName string, keys map[string]interface{}) error {
+	const op = "Storage.ClickHouseDB.SoftDeleteRow"
+	log := c.Log.With(
+		slog.String("op", op),
+		slog.String("table", tableName),
+	)
+	if len(keys) == 0 {
+		return fmt.Errorf("не заданы ключи для пометки удаления строки в %s", tableName)
+	}
+
+	where, values := buildKeyCondition(keys)
+	query := fmt.Sprintf("ALTER TABLE %s UPDATE %s = 1, %s = now() WHERE %s",
+		tableName, models.SoftDeleteFlagColumn, models.SoftDeleteTimeColumn, where)
+	if _, err := c.Db.ExecContext(ctx, query, values...); err != nil {
+		log.Error("ошибка пометки строки удалённой", slog.String("query", query), slog.Any("err", err))
+		return fmt.Errorf("ошибка пометки удаления в ClickHouse: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRows убирает строки по ключам removeKeys и записывает rows. Транзакций в
+// ClickHouse нет, поэтому шаги выполняются по очереди; удаление — мутация, которая не
+// затрагивает строки, вставленные после неё.
+func (c *ClickHouseDB) ReplaceRows(
+	ctx context.Context,
+	tableName string,
+	removeKeys []map[string]interface{},
+	softDelete bool,
+	rows []models.RowUpsert,
+) error {
+	for _, keys := range removeKeys {
+		remove := c.DeleteRow
+		if softDelete {
+			remove = c.SoftDeleteRow
+		}
+		if err := remove(ctx, tableName, keys); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		if err := c.InsertOrUpdateTransactional(ctx, tableName, row.Row, row.ConflictColumns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildKeyCondition собирает условие "k1 = ? AND k2 = ?" в стабильном порядке ключей
+func buildKeyCondition(keys map[string]interface{}) (string, []interface{}) {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	where := make([]string, 0, len(names))
+	values := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		where = append(where, fmt.Sprintf("%s = ?", name))
+		values = append(values, keys[name])
+	}
+	return strings.Join(where, " AND "), values
+}
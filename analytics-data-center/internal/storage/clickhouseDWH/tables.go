@@ -117,3 +117,92 @@ func (c *ClickHouseDB) DropColumn(ctx context.Context, query string) error {
 
 	return nil
 }
+
+// SwapTables подменяет targetTable таблицей shadowTable через EXCHANGE TABLES (атомарно
+// в базе Atomic), после чего удаляет прежнюю версию, оказавшуюся под именем shadowTable.
+// Если рабочей таблицы ещё нет, теневая просто переименовывается.
+func (c *ClickHouseDB) SwapTables(ctx context.Context, shadowTable string, targetTable string) error {
+	const op = "Storage.ClickHouseDB.SwapTables"
+	log := c.Log.With(
+		slog.String("op", op),
+		slog.String("shadow", shadowTable),
+		slog.String("target", targetTable),
+	)
+
+	var exists uint64
+	err := c.Db.QueryRowContext(ctx,
+		"SELECT count() FROM system.tables WHERE database = currentDatabase() AND name = ?", targetTable,
+	).Scan(&exists)
+	if err != nil {
+		log.Error("не удалось проверить наличие таблицы", slog.String("error", err.Error()))
+		return err
+	}
+
+	if exists == 0 {
+		if _, err := c.Db.ExecContext(ctx, fmt.Sprintf("RENAME TABLE %s TO %s", shadowTable, targetTable)); err != nil {
+			log.Error("ошибка переименования теневой таблицы", slog.String("error", err.Error()))
+			return err
+		}
+		log.Info("таблица view создана из теневой")
+		return nil
+	}
+
+	if _, err := c.Db.ExecContext(ctx, fmt.Sprintf("EXCHANGE TABLES %s AND %s", shadowTable, targetTable)); err != nil {
+		log.Error("ошибка подмены таблицы", slog.String("error", err.Error()))
+		return err
+	}
+	if _, err := c.Db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", shadowTable)); err != nil {
+		// подмена уже состоялась, осталась лишь прежняя версия под теневым именем
+		log.Warn("не удалось удалить прежнюю версию таблицы", slog.String("error", err.Error()))
+	}
+	log.Info("таблица view подменена")
+	return nil
+}
+
+// DeleteChunkRows удаляет из временной таблицы строки недогруженного чанка. Без условия
+// таблица очищается через TRUNCATE, иначе используется lightweight DELETE.
+func (c *ClickHouseDB) DeleteChunkRows(ctx context.Context, tableName string, condition string, args ...interface{}) error {
+	const op = "Storage.ClickHouseDB.DeleteChunkRows"
+	log := c.Log.With(
+		slog.String("op", op),
+		slog.String("tableName", tableName),
+		slog.String("condition", condition),
+	)
+
+	query := fmt.Sprintf("TRUNCATE TABLE IF EXISTS %s", tableName)
+	if condition != "" {
+		query = fmt.Sprintf("DELETE FROM %s WHERE %s", tableName, condition)
+	}
+	if _, err := c.Db.ExecContext(ctx, query, args...); err != nil {
+		log.Error("не удалось удалить строки чанка", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (c *ClickHouseDB) ListTempTables(ctx context.Context, prefix string) ([]string, error) {
+	const op = "Storage.ClickHouseDB.ListTempTables"
+	log := c.Log.With(
+		slog.String("op", op),
+		slog.String("prefix", prefix),
+	)
+
+	query := "SELECT name FROM system.tables WHERE database = currentDatabase() AND startsWith(name, ?)"
+	rows, err := c.Db.QueryContext(ctx, query, prefix)
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			log.Error("Ошибка чтения строки", slog.String("error", err.Error()))
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
@@ -0,0 +1,78 @@
+package clickhousedwh
+
+import (
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// UpsertBatch вставляет пачку строк одним блоком на группу строк с одинаковым набором
+// колонок. ClickHouse не обновляет строки на месте: как и при вставке по одной строке,
+// каждая версия строки добавляется с updated_at, поэтому ключи конфликта не нужны.
+func (c *ClickHouseDB) UpsertBatch(ctx context.Context, tableName string, rows []map[string]interface{}, _ []string) error {
+	const op = "Storage.ClickHouseDB.UpsertBatch"
+	log := c.Log.With(
+		slog.String("op", op),
+		slog.String("table", tableName),
+		slog.Int("rows", len(rows)),
+	)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	columnTypes, err := c.columnTypes(ctx, tableName)
+	if err != nil {
+		log.Error("не удалось получить типы колонок", slog.String("error", err.Error()))
+		return err
+	}
+	_, hasUpdatedAt := columnTypes["updated_at"]
+	now := time.Now()
+
+	for _, group := range sqlgenerator.GroupRowsByColumns(rows) {
+		columns := group.Columns
+		stamp := hasUpdatedAt && !containsColumn(columns, "updated_at")
+		if stamp {
+			columns = append(columns, "updated_at")
+		}
+
+		batch, err := c.beginBatch(ctx, tableName, columns)
+		if err != nil {
+			log.Error("ошибка подготовки пакетной вставки", slog.String("error", err.Error()))
+			return err
+		}
+		for _, row := range group.Rows {
+			if stamp {
+				stamped := make(map[string]interface{}, len(row)+1)
+				for k, v := range row {
+					stamped[k] = v
+				}
+				stamped["updated_at"] = now
+				row = stamped
+			}
+			values, err := sqlgenerator.ClickhouseBatchValues(row, columns, columnTypes)
+			if err == nil {
+				err = batch.add(ctx, values)
+			}
+			if err != nil {
+				batch.abort()
+				log.Error("ошибка добавления строки в пакет", slog.String("error", err.Error()))
+				return err
+			}
+		}
+		if err := batch.send(); err != nil {
+			log.Error("ошибка отправки пакета", slog.String("error", err.Error()))
+			return err
+		}
+	}
+	return nil
+}
+
+func containsColumn(columns []string, name string) bool {
+	for _, col := range columns {
+		if col == name {
+			return true
+		}
+	}
+	return false
+}
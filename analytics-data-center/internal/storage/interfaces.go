@@ -5,6 +5,7 @@ package storage
 import (
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
 	"context"
+	"time"
 )
 
 //	type DB interface {
@@ -16,6 +17,11 @@ type SysDB interface {
 	SchemaProvider
 	ColumnRenameSuggestionStorage
 	ColumnMismatchStorage
+	ETLJobStorage
+	WatermarkStorage
+	ViewSnapshotStorage
+	DeadLetterStorage
+	PendingEventStorage
 }
 type DWHDB interface {
 	TableProvider
@@ -25,6 +31,7 @@ type DWHDB interface {
 type OLTPDB interface {
 	DataProviderOLTP
 	DataBaseProviderOLTP
+	SnapshotProviderOLTP
 }
 type SchemaProvider interface {
 	GetView(ctx context.Context, idView int64) (models.View, error)
@@ -41,6 +48,103 @@ type TaskProvider interface {
 	GetTask(ctx context.Context, taskID string) (models.Task, error)
 	ChangeStatusTask(ctx context.Context, taskID string, newStatus string, comment string) error
 	GetTasks(ctx context.Context, filters models.TaskFilter) ([]models.Task, error)
+	// SaveTaskStage создаёт или обновляет запись этапа задачи
+	SaveTaskStage(ctx context.Context, taskID string, stage models.TaskStage) error
+	// SaveTaskTableProgress фиксирует ожидаемое число строк таблицы и обнуляет счётчик загруженных
+	SaveTaskTableProgress(ctx context.Context, taskID string, progress models.TaskTableProgress) error
+	// AddTaskTableRows увеличивает число загруженных строк таблицы
+	AddTaskTableRows(ctx context.Context, taskID string, progress models.TaskTableProgress) error
+	// SaveTaskChunks заменяет план чанков таблицы; все чанки должны относиться к одной таблице
+	SaveTaskChunks(ctx context.Context, taskID string, chunks []models.TaskChunk) error
+	// CompleteTaskChunk отмечает чанк загруженным и сохраняет число его строк
+	CompleteTaskChunk(ctx context.Context, taskID string, chunk models.TaskChunk) error
+	// ListTaskChunks возвращает сохранённые чекпоинты задачи
+	ListTaskChunks(ctx context.Context, taskID string) ([]models.TaskChunk, error)
+	// RegisterTaskTempTables закрепляет временные таблицы DWH за задачей. Таблица,
+	// закреплённая за другой задачей, не перезакрепляется: возвращается ErrTempTableClaimed
+	RegisterTaskTempTables(ctx context.Context, taskID string, tables []string) error
+	// GetTaskTempTables возвращает временные таблицы, закреплённые за задачей
+	GetTaskTempTables(ctx context.Context, taskID string) ([]string, error)
+	// ListTaskTempTables возвращает временные таблицы задач в статусе activeStatus, а также
+	// задач в статусе failedStatus, созданных после failedSince и оставивших чекпоинты
+	ListTaskTempTables(ctx context.Context, activeStatus string, failedStatus string, failedSince time.Time) ([]string, error)
+	// SaveTaskSnapshot сохраняет снимок, из которого задача загружает источник; снимок
+	// прошлого запуска того же источника заменяется
+	SaveTaskSnapshot(ctx context.Context, taskID string, snapshot models.TaskSnapshot) error
+	// ListTaskSnapshots возвращает снимки источников, сохранённые задачей
+	ListTaskSnapshots(ctx context.Context, taskID string) ([]models.TaskSnapshot, error)
+}
+
+// ETLJobStorage — персистентная очередь заданий ETL
+type ETLJobStorage interface {
+	// EnqueueETLJob создаёт задачу со статусом status и её задание в режиме mode в очереди.
+	// Если в очереди уже maxBacklog ожидающих заданий, возвращает ErrQueueFull и ничего не создаёт.
+	EnqueueETLJob(ctx context.Context, taskID string, viewID int64, mode string, status string, maxBacklog int) error
+	// RequeueETLJob повторно ставит существующую задачу в очередь как продолжение загрузки
+	// и возвращает ей статус status; лимит очереди проверяется так же, как в EnqueueETLJob
+	RequeueETLJob(ctx context.Context, taskID string, viewID int64, status string, maxBacklog int) error
+	// ClaimETLJob забирает самое старое ожидающее задание в аренду воркеру workerID.
+	// Если заданий нет, возвращает nil.
+	ClaimETLJob(ctx context.Context, workerID string, lease time.Duration) (*models.ETLJob, error)
+	// ExtendETLJobLease продлевает аренду; ErrJobLost — задание удалено или передано другому воркеру
+	ExtendETLJobLease(ctx context.Context, taskID string, workerID string, lease time.Duration) error
+	// FinishETLJob убирает выполненное воркером задание из очереди
+	FinishETLJob(ctx context.Context, taskID string, workerID string) error
+	// DeleteETLJob убирает задание из очереди в любом статусе и возвращает его
+	DeleteETLJob(ctx context.Context, taskID string) (models.ETLJob, error)
+	// RecoverETLJobs возвращает в очередь задания с истёкшей арендой; задания, исчерпавшие
+	// maxAttempts попыток, удаляются и возвращаются в failed
+	RecoverETLJobs(ctx context.Context, maxAttempts int) (requeued []string, failed []string, err error)
+	// ListOrphanTasks возвращает задачи в статусе status, для которых нет задания в очереди
+	ListOrphanTasks(ctx context.Context, status string) ([]string, error)
+}
+
+// WatermarkStorage — верхние отметки таблиц view для инкрементальной загрузки
+type WatermarkStorage interface {
+	// ListViewWatermarks возвращает сохранённые отметки таблиц view
+	ListViewWatermarks(ctx context.Context, viewID int64) ([]models.ViewWatermark, error)
+	// SaveViewWatermark сохраняет отметку таблицы view, заменяя прежнюю
+	SaveViewWatermark(ctx context.Context, viewID int64, watermark models.ViewWatermark) error
+}
+
+// ViewSnapshotStorage — снимки источников, из которых загружены опубликованные view
+type ViewSnapshotStorage interface {
+	// SaveViewSnapshots заменяет снимки view снимками, из которых загружена её новая таблица
+	SaveViewSnapshots(ctx context.Context, viewID int64, positions map[string]models.SnapshotPosition) error
+	// ListViewSnapshots возвращает снимки всех опубликованных view
+	ListViewSnapshots(ctx context.Context) ([]models.ViewSnapshot, error)
+}
+
+// DeadLetterStorage — CDC-события, которые не удалось записать во view
+type DeadLetterStorage interface {
+	// SaveDeadLetter сохраняет событие и возвращает его идентификатор
+	SaveDeadLetter(ctx context.Context, event models.DeadLetterEvent) (int64, error)
+	// ListDeadLetters возвращает события по фильтру, новые первыми
+	ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetterEvent, error)
+	// GetDeadLetter возвращает событие; ErrDeadLetterNotFound — события нет
+	GetDeadLetter(ctx context.Context, id int64) (models.DeadLetterEvent, error)
+	// ListDueDeadLetters возвращает до limit событий в статусе повтора, время повтора которых наступило к now.
+	// События, перед которыми в dead letter есть более раннее событие той же строки, не возвращаются
+	ListDueDeadLetters(ctx context.Context, now time.Time, limit int) ([]models.DeadLetterEvent, error)
+	// HasDeadLetters сообщает, есть ли события строки rowKey таблицы; при beforeID > 0 —
+	// только сохранённые раньше события beforeID
+	HasDeadLetters(ctx context.Context, database, schema, table, rowKey string, beforeID int64) (bool, error)
+	// UpdateDeadLetter сохраняет итог очередной попытки: view, ошибку, число попыток, статус и время повтора
+	UpdateDeadLetter(ctx context.Context, event models.DeadLetterEvent) error
+	// DeleteDeadLetters удаляет события и возвращает число удалённых
+	DeleteDeadLetters(ctx context.Context, ids []int64) (int64, error)
+}
+
+// PendingEventStorage — CDC-события, отложенные для view до решения по предложению переименования
+type PendingEventStorage interface {
+	// SavePendingEvent откладывает событие таблицы для view
+	SavePendingEvent(ctx context.Context, event models.PendingCDCEvent) error
+	// HasPendingEvents сообщает, есть ли отложенные события таблицы для view
+	HasPendingEvents(ctx context.Context, viewID int64, database, schema, table string) (bool, error)
+	// ListPendingEvents возвращает до limit отложенных событий таблицы для view в порядке поступления
+	ListPendingEvents(ctx context.Context, viewID int64, database, schema, table string, limit int) ([]models.PendingCDCEvent, error)
+	// DeletePendingEvents удаляет применённые события
+	DeletePendingEvents(ctx context.Context, ids []int64) error
 }
 
 type TableProvider interface {
@@ -50,24 +154,53 @@ type TableProvider interface {
 	CreateConstraint(ctx context.Context, query string) error
 	RenameColumn(ctx context.Context, query string) error
 	DropColumn(ctx context.Context, query string) error
+	// SwapTables атомарно подменяет таблицу targetTable собранной таблицей shadowTable;
+	// прежняя версия удаляется только после успешной подмены
+	SwapTables(ctx context.Context, shadowTable string, targetTable string) error
+	// DeleteChunkRows удаляет из таблицы строки, попадающие под условие condition;
+	// пустое условие очищает таблицу целиком
+	DeleteChunkRows(ctx context.Context, tableName string, condition string, args ...interface{}) error
+	// ListTempTables возвращает таблицы текущей схемы (базы) DWH, имя которых начинается с prefix
+	ListTempTables(ctx context.Context, prefix string) ([]string, error)
 }
 
 type DataProviderDWH interface {
-	InsertDataToDWH(ctx context.Context, query string) error
+	// InsertStream вычитывает строки из потока порциями по batchSize и вставляет
+	// их в таблицу tableName; возвращает число вставленных строк
+	InsertStream(ctx context.Context, view models.View, tableName string, rows models.RowStream, batchSize int) (int64, error)
 	GetColumnsTables(ctx context.Context, schemaName string, tempTableName string) ([]string, error)
 	MergeTempTables(ctx context.Context, query string) error
 	// Insert(ctx context.Context, schemaName string, row map[string]interface{}) error
 	ReplicaIdentityFull(ctx context.Context, tableDWHName string) error
 	InsertOrUpdateTransactional(ctx context.Context, schemaName string, row map[string]interface{}, conflictColumns []string) error
+	// UpsertBatch вставляет или обновляет пачку строк таблицы одним запросом на группу
+	// строк с одинаковым набором колонок; ключи conflictColumns строк пачки уникальны
+	UpsertBatch(ctx context.Context, tableName string, rows []map[string]interface{}, conflictColumns []string) error
+	// DeleteRow физически удаляет строки таблицы, совпадающие по ключам
+	DeleteRow(ctx context.Context, tableName string, keys map[string]interface{}) error
+	// SoftDeleteRow помечает строки таблицы удалёнными (is_deleted/deleted_at)
+	SoftDeleteRow(ctx context.Context, tableName string, keys map[string]interface{}) error
+	// ReplaceRows одной операцией убирает строки таблицы по ключам removeKeys (при
+	// softDelete — помечает удалёнными) и вставляет или обновляет строки rows
+	ReplaceRows(ctx context.Context, tableName string, removeKeys []map[string]interface{}, softDelete bool, rows []models.RowUpsert) error
 }
 
 type DataProviderOLTP interface {
-	GetCountInsertData(ctx context.Context, query string) (int64, error)                    // count of insert datas
-	SelectDataToInsert(ctx context.Context, query string) ([]map[string]interface{}, error) // select data to insert
+	GetCountInsertData(ctx context.Context, query string) (int64, error) // count of insert datas
+	// StreamRows выполняет выборку данных для вставки и отдаёт строки по одной
+	StreamRows(ctx context.Context, query string, args ...interface{}) (models.RowStream, error)
+	SelectRows(ctx context.Context, query string, args ...interface{}) ([]map[string]interface{}, error)
 	GetIndexes(ctx context.Context, tableName string, schemaName string) (models.Indexes, error)
 	GetConstraint(ctx context.Context, tableName string, schemaName string) (models.Constraints, error)
 }
 
+// SnapshotProviderOLTP — чтение источника из одного согласованного снимка
+type SnapshotProviderOLTP interface {
+	// ExportSnapshot открывает транзакцию и экспортирует её снимок вместе с позицией WAL.
+	// Снимок доступен другим соединениям, пока не вызван release.
+	ExportSnapshot(ctx context.Context) (snapshot models.OLTPSnapshot, release func() error, err error)
+}
+
 type DataBaseProviderOLTP interface {
 	GetSchemas(ctx context.Context, source string) ([]models.Schema, error)
 	GetTables(ctx context.Context, schema string) ([]models.Table, error)
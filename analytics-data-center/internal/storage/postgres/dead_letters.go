@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const deadLetterColumns = `id, view_id, database_name, schema_name, table_name, COALESCE(row_key, ''), op, payload, error, attempts, status, next_retry_at, created_at, updated_at`
+
+func (p *PostgresSys) SaveDeadLetter(ctx context.Context, event models.DeadLetterEvent) (int64, error) {
+	const op = "Storage.PostgreSQL.SaveDeadLetter"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("table", event.TableName),
+	)
+
+	query := `INSERT INTO cdc_dead_letters (view_id, database_name, schema_name, table_name, row_key, op, payload, error, attempts, status, next_retry_at)
+				VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11) RETURNING id`
+	var id int64
+	err := p.Db.QueryRowContext(ctx, query, event.ViewID, event.DatabaseName, event.SchemaName, event.TableName, event.RowKey,
+		event.Op, []byte(event.Payload), event.Error, event.Attempts, event.Status, event.NextRetryAt).Scan(&id)
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
+
+func (p *PostgresSys) ListDeadLetters(ctx context.Context, filter models.DeadLetterFilter) ([]models.DeadLetterEvent, error) {
+	const op = "Storage.PostgreSQL.ListDeadLetters"
+	log := p.Log.With(slog.String("op", op))
+
+	var args []interface{}
+	var conditions []string
+
+	if len(filter.IDs) > 0 {
+		args = append(args, pq.Array(filter.IDs))
+		conditions = append(conditions, fmt.Sprintf("id = ANY($%d)", len(args)))
+	}
+	if filter.ViewID != nil {
+		args = append(args, *filter.ViewID)
+		conditions = append(conditions, fmt.Sprintf("view_id = $%d", len(args)))
+	}
+	if filter.DatabaseName != nil {
+		args = append(args, *filter.DatabaseName)
+		conditions = append(conditions, fmt.Sprintf("database_name = $%d", len(args)))
+	}
+	if filter.SchemaName != nil {
+		args = append(args, *filter.SchemaName)
+		conditions = append(conditions, fmt.Sprintf("schema_name = $%d", len(args)))
+	}
+	if filter.TableName != nil {
+		args = append(args, *filter.TableName)
+		conditions = append(conditions, fmt.Sprintf("table_name = $%d", len(args)))
+	}
+	if filter.Status != nil {
+		args = append(args, *filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	query := `SELECT ` + deadLetterColumns + ` FROM cdc_dead_letters`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	events, err := p.queryDeadLetters(ctx, query, args...)
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return events, nil
+}
+
+func (p *PostgresSys) GetDeadLetter(ctx context.Context, id int64) (models.DeadLetterEvent, error) {
+	const op = "Storage.PostgreSQL.GetDeadLetter"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.Int64("id", id),
+	)
+
+	event, err := scanDeadLetter(p.Db.QueryRowContext(ctx, `SELECT `+deadLetterColumns+` FROM cdc_dead_letters WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DeadLetterEvent{}, storage.ErrDeadLetterNotFound
+		}
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return models.DeadLetterEvent{}, err
+	}
+	return event, nil
+}
+
+func (p *PostgresSys) ListDueDeadLetters(ctx context.Context, now time.Time, limit int) ([]models.DeadLetterEvent, error) {
+	const op = "Storage.PostgreSQL.ListDueDeadLetters"
+	log := p.Log.With(slog.String("op", op))
+
+	query := `SELECT ` + deadLetterColumns + ` FROM cdc_dead_letters d
+				WHERE status = $1 AND next_retry_at <= $2
+					AND NOT EXISTS (SELECT 1 FROM cdc_dead_letters e
+						WHERE e.database_name = d.database_name AND e.schema_name = d.schema_name
+							AND e.table_name = d.table_name AND e.row_key = d.row_key AND e.id < d.id)
+				ORDER BY next_retry_at, id LIMIT $3`
+	events, err := p.queryDeadLetters(ctx, query, models.DeadLetterRetrying, now, limit)
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return events, nil
+}
+
+func (p *PostgresSys) HasDeadLetters(ctx context.Context, database, schema, table, rowKey string, beforeID int64) (bool, error) {
+	const op = "Storage.PostgreSQL.HasDeadLetters"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("table", table),
+	)
+
+	query := `SELECT EXISTS (SELECT 1 FROM cdc_dead_letters
+				WHERE database_name = $1 AND schema_name = $2 AND table_name = $3 AND row_key = $4
+					AND ($5 = 0 OR id < $5))`
+	var exists bool
+	if err := p.Db.QueryRowContext(ctx, query, database, schema, table, rowKey, beforeID).Scan(&exists); err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+func (p *PostgresSys) UpdateDeadLetter(ctx context.Context, event models.DeadLetterEvent) error {
+	const op = "Storage.PostgreSQL.UpdateDeadLetter"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.Int64("id", event.ID),
+	)
+
+	query := `UPDATE cdc_dead_letters
+				SET view_id = $1, error = $2, attempts = $3, status = $4, next_retry_at = $5, updated_at = now()
+				WHERE id = $6`
+	res, err := p.Db.ExecContext(ctx, query, event.ViewID, event.Error, event.Attempts, event.Status, event.NextRetryAt, event.ID)
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return storage.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+func (p *PostgresSys) DeleteDeadLetters(ctx context.Context, ids []int64) (int64, error) {
+	const op = "Storage.PostgreSQL.DeleteDeadLetters"
+	log := p.Log.With(slog.String("op", op))
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	res, err := p.Db.ExecContext(ctx, `DELETE FROM cdc_dead_letters WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (p *PostgresSys) queryDeadLetters(ctx context.Context, query string, args ...interface{}) ([]models.DeadLetterEvent, error) {
+	rows, err := p.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.DeadLetterEvent
+	for rows.Next() {
+		event, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func scanDeadLetter(row interface{ Scan(dest ...any) error }) (models.DeadLetterEvent, error) {
+	var event models.DeadLetterEvent
+	var payload []byte
+	err := row.Scan(
+		&event.ID,
+		&event.ViewID,
+		&event.DatabaseName,
+		&event.SchemaName,
+		&event.TableName,
+		&event.RowKey,
+		&event.Op,
+		&payload,
+		&event.Error,
+		&event.Attempts,
+		&event.Status,
+		&event.NextRetryAt,
+		&event.CreatedAt,
+		&event.UpdatedAt,
+	)
+	event.Payload = payload
+	return event, err
+}
@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// etlQueueLockKey — ключ advisory-блокировки, сериализующей проверку размера очереди
+const etlQueueLockKey = "etl_jobs"
+
+func (p *PostgresSys) EnqueueETLJob(ctx context.Context, taskID string, viewID int64, mode string, status string, maxBacklog int) error {
+	const op = "Storage.PostgreSQL.EnqueueETLJob"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+
+	return p.enqueueETLJob(ctx, log, maxBacklog, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tasks (id, create_at, status, view_id) VALUES ($1, $2, $3, $4)`,
+			taskID, time.Now(), status, viewID); err != nil {
+			log.Error("failed to insert task", slog.String("error", err.Error()))
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO etl_jobs (task_id, view_id, status, mode) VALUES ($1, $2, $3, $4)`,
+			taskID, viewID, models.ETLJobQueued, mode); err != nil {
+			log.Error("failed to insert job", slog.String("error", err.Error()))
+			return err
+		}
+		return nil
+	})
+}
+
+// RequeueETLJob ставит упавшую задачу в очередь повторно: задание помечается как
+// продолжение загрузки, а задача снова переходит в статус status без комментария.
+func (p *PostgresSys) RequeueETLJob(ctx context.Context, taskID string, viewID int64, status string, maxBacklog int) error {
+	const op = "Storage.PostgreSQL.RequeueETLJob"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+
+	return p.enqueueETLJob(ctx, log, maxBacklog, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE tasks SET status = $1, comment = NULL WHERE id = $2`, status, taskID); err != nil {
+			log.Error("failed to update task", slog.String("error", err.Error()))
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO etl_jobs (task_id, view_id, status, resume) VALUES ($1, $2, $3, true)`,
+			taskID, viewID, models.ETLJobQueued); err != nil {
+			log.Error("failed to insert job", slog.String("error", err.Error()))
+			return err
+		}
+		return nil
+	})
+}
+
+// enqueueETLJob выполняет insert в транзакции, предварительно проверив под
+// advisory-блокировкой, что очередь не переполнена
+func (p *PostgresSys) enqueueETLJob(ctx context.Context, log *slog.Logger, maxBacklog int, insert func(tx *sql.Tx) error) (err error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin tx", slog.String("error", err.Error()))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, etlQueueLockKey); err != nil {
+		log.Error("failed to lock queue", slog.String("error", err.Error()))
+		return err
+	}
+
+	var queued int
+	if err = tx.QueryRowContext(ctx, `SELECT count(*) FROM etl_jobs WHERE status = $1`, models.ETLJobQueued).Scan(&queued); err != nil {
+		log.Error("failed to count queue", slog.String("error", err.Error()))
+		return err
+	}
+	if maxBacklog > 0 && queued >= maxBacklog {
+		log.Warn("очередь ETL переполнена", slog.Int("queued", queued))
+		err = storage.ErrQueueFull
+		return err
+	}
+
+	if err = insert(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("failed to commit job", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresSys) ClaimETLJob(ctx context.Context, workerID string, lease time.Duration) (*models.ETLJob, error) {
+	const op = "Storage.PostgreSQL.ClaimETLJob"
+	log := p.Log.With(slog.String("op", op), slog.String("worker", workerID))
+
+	query := `UPDATE etl_jobs SET status = $1, worker_id = $2, attempts = attempts + 1,
+				lease_until = now() + make_interval(secs => $3)
+				WHERE task_id = (
+					SELECT task_id FROM etl_jobs WHERE status = $4
+					ORDER BY created_at
+					FOR UPDATE SKIP LOCKED
+					LIMIT 1
+				)
+				RETURNING task_id, view_id, status, attempts, worker_id, lease_until, created_at, resume, mode`
+
+	var job models.ETLJob
+	err := p.Db.QueryRowContext(ctx, query, models.ETLJobRunning, workerID, lease.Seconds(), models.ETLJobQueued).
+		Scan(&job.TaskID, &job.ViewID, &job.Status, &job.Attempts, &job.WorkerID, &job.LeaseUntil, &job.CreatedAt, &job.Resume, &job.Mode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Error("failed to claim job", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (p *PostgresSys) ExtendETLJobLease(ctx context.Context, taskID string, workerID string, lease time.Duration) error {
+	const op = "Storage.PostgreSQL.ExtendETLJobLease"
+	log := p.Log.With(slog.String("op", op), slog.String("taskID", taskID))
+
+	query := `UPDATE etl_jobs SET lease_until = now() + make_interval(secs => $1)
+				WHERE task_id = $2 AND worker_id = $3 AND status = $4`
+	res, err := p.Db.ExecContext(ctx, query, lease.Seconds(), taskID, workerID, models.ETLJobRunning)
+	if err != nil {
+		log.Error("failed to extend lease", slog.String("error", err.Error()))
+		return err
+	}
+	if count, err := res.RowsAffected(); err == nil && count == 0 {
+		return storage.ErrJobLost
+	}
+	return nil
+}
+
+func (p *PostgresSys) FinishETLJob(ctx context.Context, taskID string, workerID string) error {
+	const op = "Storage.PostgreSQL.FinishETLJob"
+	log := p.Log.With(slog.String("op", op), slog.String("taskID", taskID))
+
+	if _, err := p.Db.ExecContext(ctx, `DELETE FROM etl_jobs WHERE task_id = $1 AND worker_id = $2`, taskID, workerID); err != nil {
+		log.Error("failed to finish job", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresSys) DeleteETLJob(ctx context.Context, taskID string) (models.ETLJob, error) {
+	const op = "Storage.PostgreSQL.DeleteETLJob"
+	log := p.Log.With(slog.String("op", op), slog.String("taskID", taskID))
+
+	var job models.ETLJob
+	err := p.Db.QueryRowContext(ctx, `DELETE FROM etl_jobs WHERE task_id = $1
+				RETURNING task_id, view_id, status, attempts, worker_id, lease_until, created_at, resume, mode`, taskID).
+		Scan(&job.TaskID, &job.ViewID, &job.Status, &job.Attempts, &job.WorkerID, &job.LeaseUntil, &job.CreatedAt, &job.Resume, &job.Mode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ETLJob{}, storage.ErrJobNotFound
+	}
+	if err != nil {
+		log.Error("failed to delete job", slog.String("error", err.Error()))
+		return models.ETLJob{}, err
+	}
+	return job, nil
+}
+
+func (p *PostgresSys) RecoverETLJobs(ctx context.Context, maxAttempts int) (requeued []string, failed []string, err error) {
+	const op = "Storage.PostgreSQL.RecoverETLJobs"
+	log := p.Log.With(slog.String("op", op))
+
+	failed, err = p.collectTaskIDs(ctx, `DELETE FROM etl_jobs
+				WHERE status = $1 AND lease_until < now() AND attempts >= $2
+				RETURNING task_id`, models.ETLJobRunning, maxAttempts)
+	if err != nil {
+		log.Error("failed to drop exhausted jobs", slog.String("error", err.Error()))
+		return nil, nil, err
+	}
+
+	requeued, err = p.collectTaskIDs(ctx, `UPDATE etl_jobs SET status = $1, worker_id = NULL, lease_until = NULL
+				WHERE status = $2 AND lease_until < now()
+				RETURNING task_id`, models.ETLJobQueued, models.ETLJobRunning)
+	if err != nil {
+		log.Error("failed to requeue jobs", slog.String("error", err.Error()))
+		return nil, nil, err
+	}
+	return requeued, failed, nil
+}
+
+func (p *PostgresSys) ListOrphanTasks(ctx context.Context, status string) ([]string, error) {
+	const op = "Storage.PostgreSQL.ListOrphanTasks"
+	log := p.Log.With(slog.String("op", op))
+
+	ids, err := p.collectTaskIDs(ctx, `SELECT t.id FROM tasks t
+				WHERE t.status = $1 AND NOT EXISTS (SELECT 1 FROM etl_jobs j WHERE j.task_id = t.id)`, status)
+	if err != nil {
+		log.Error("failed to list orphan tasks", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (p *PostgresSys) collectTaskIDs(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := p.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
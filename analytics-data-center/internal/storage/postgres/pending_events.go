@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"context"
+	"log/slog"
+
+	"github.com/lib/pq"
+)
+
+func (p *PostgresSys) SavePendingEvent(ctx context.Context, event models.PendingCDCEvent) error {
+	const op = "Storage.PostgreSQL.SavePendingEvent"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.Int64("viewID", event.ViewID),
+		slog.String("table", event.TableName),
+	)
+
+	query := `INSERT INTO cdc_pending_events (view_id, database_name, schema_name, table_name, payload)
+				VALUES ($1, $2, $3, $4, $5)`
+	if _, err := p.Db.ExecContext(ctx, query, event.ViewID, event.DatabaseName, event.SchemaName, event.TableName, event.Payload); err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresSys) HasPendingEvents(ctx context.Context, viewID int64, database, schema, table string) (bool, error) {
+	const op = "Storage.PostgreSQL.HasPendingEvents"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.Int64("viewID", viewID),
+		slog.String("table", table),
+	)
+
+	query := `SELECT EXISTS (SELECT 1 FROM cdc_pending_events
+				WHERE view_id = $1 AND database_name = $2 AND schema_name = $3 AND table_name = $4)`
+	var exists bool
+	if err := p.Db.QueryRowContext(ctx, query, viewID, database, schema, table).Scan(&exists); err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+func (p *PostgresSys) ListPendingEvents(ctx context.Context, viewID int64, database, schema, table string, limit int) ([]models.PendingCDCEvent, error) {
+	const op = "Storage.PostgreSQL.ListPendingEvents"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.Int64("viewID", viewID),
+		slog.String("table", table),
+	)
+
+	rows, err := p.Db.QueryContext(ctx, `SELECT id, view_id, database_name, schema_name, table_name, payload, created_at
+				FROM cdc_pending_events
+				WHERE view_id = $1 AND database_name = $2 AND schema_name = $3 AND table_name = $4
+				ORDER BY id LIMIT $5`, viewID, database, schema, table, limit)
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.PendingCDCEvent
+	for rows.Next() {
+		var e models.PendingCDCEvent
+		if err := rows.Scan(&e.ID, &e.ViewID, &e.DatabaseName, &e.SchemaName, &e.TableName, &e.Payload, &e.CreatedAt); err != nil {
+			log.Error("Ошибка сканирования строки", slog.String("error", err.Error()))
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (p *PostgresSys) DeletePendingEvents(ctx context.Context, ids []int64) error {
+	const op = "Storage.PostgreSQL.DeletePendingEvents"
+	log := p.Log.With(slog.String("op", op))
+
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := p.Db.ExecContext(ctx, `DELETE FROM cdc_pending_events WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
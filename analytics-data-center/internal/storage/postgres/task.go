@@ -2,9 +2,16 @@ package postgres
 
 import (
 	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"analyticDataCenter/analytics-data-center/internal/storage"
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 func (p *PostgresSys) CreateTask(ctx context.Context, taskID string, status string) error {
@@ -36,13 +43,26 @@ func (p *PostgresSys) GetTask(ctx context.Context, taskID string) (task models.T
 	)
 	log.Info("получение задачи")
 
-	query := "SELECT id, create_at, status FROM tasks WHERE id = ($1)"
+	query := "SELECT id, create_at, status, comment, view_id FROM tasks WHERE id = ($1)"
+
+	err = p.Db.QueryRowContext(ctx, query, taskID).Scan(&task.ID, &task.CreateDate, &task.Status, &task.Comment, &task.ViewID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("задача не найдена")
+			return models.Task{}, storage.ErrTaskNotFound
+		}
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return models.Task{}, err
+	}
 
-	err = p.Db.QueryRowContext(ctx, query, taskID).Scan(&task.ID, &task.CreateDate, &task.Status)
+	details, err := p.taskDetails(ctx, []string{task.ID})
 	if err != nil {
 		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
 		return models.Task{}, err
 	}
+	task.Stages = details[task.ID].Stages
+	task.Tables = details[task.ID].Tables
+	task.Snapshots = details[task.ID].Snapshots
 
 	return task, nil
 }
@@ -79,7 +99,7 @@ func (p *PostgresSys) GetTasks(ctx context.Context, filters models.TaskFilter) (
 		filters.PageSize = 10
 	}
 	log.Info("получение списка задач")
-	query := `SELECT id, create_at, status, comment FROM tasks
+	query := `SELECT id, create_at, status, comment, view_id FROM tasks
 				WHERE ($1::timestamp IS NULL OR create_at >= $1::timestamp)
 	  			AND ($2::timestamp IS NULL OR create_at <= $2::timestamp)
 				ORDER BY create_at DESC
@@ -92,7 +112,7 @@ func (p *PostgresSys) GetTasks(ctx context.Context, filters models.TaskFilter) (
 	defer rows.Close()
 	for rows.Next() {
 		var task models.Task
-		err = rows.Scan(&task.ID, &task.CreateDate, &task.Status, &task.Comment)
+		err = rows.Scan(&task.ID, &task.CreateDate, &task.Status, &task.Comment, &task.ViewID)
 		if err != nil {
 			log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
 			return []models.Task{}, err
@@ -100,5 +120,418 @@ func (p *PostgresSys) GetTasks(ctx context.Context, filters models.TaskFilter) (
 		tasks = append(tasks, task)
 
 	}
+	if err = rows.Err(); err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return []models.Task{}, err
+	}
+
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	details, err := p.taskDetails(ctx, ids)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return []models.Task{}, err
+	}
+	for i := range tasks {
+		tasks[i].Stages = details[tasks[i].ID].Stages
+		tasks[i].Tables = details[tasks[i].ID].Tables
+		tasks[i].Snapshots = details[tasks[i].ID].Snapshots
+	}
 	return tasks, nil
 }
+
+func (p *PostgresSys) SaveTaskStage(ctx context.Context, taskID string, stage models.TaskStage) error {
+	const op = "Storage.PostgreSQL.SaveTaskStage"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+		slog.String("stage", stage.Name),
+	)
+
+	query := `INSERT INTO task_stages (task_id, stage, status, started_at, finished_at, error)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (task_id, stage) DO UPDATE
+				SET status = EXCLUDED.status, started_at = EXCLUDED.started_at,
+					finished_at = EXCLUDED.finished_at, error = EXCLUDED.error`
+	_, err := p.Db.ExecContext(ctx, query, taskID, stage.Name, stage.Status, stage.StartedAt, stage.FinishedAt, stage.Error)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresSys) SaveTaskTableProgress(ctx context.Context, taskID string, progress models.TaskTableProgress) error {
+	const op = "Storage.PostgreSQL.SaveTaskTableProgress"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+		slog.String("table", progress.Table),
+	)
+
+	query := `INSERT INTO task_table_progress (task_id, source_name, schema_name, table_name, expected_rows, loaded_rows)
+				VALUES ($1, $2, $3, $4, $5, 0)
+				ON CONFLICT (task_id, source_name, schema_name, table_name) DO UPDATE
+				SET expected_rows = EXCLUDED.expected_rows, loaded_rows = 0`
+	_, err := p.Db.ExecContext(ctx, query, taskID, progress.Source, progress.Schema, progress.Table, progress.Expected)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresSys) AddTaskTableRows(ctx context.Context, taskID string, progress models.TaskTableProgress) error {
+	const op = "Storage.PostgreSQL.AddTaskTableRows"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+		slog.String("table", progress.Table),
+	)
+
+	query := `UPDATE task_table_progress SET loaded_rows = loaded_rows + $1
+				WHERE task_id = $2 AND source_name = $3 AND schema_name = $4 AND table_name = $5`
+	_, err := p.Db.ExecContext(ctx, query, progress.Loaded, taskID, progress.Source, progress.Schema, progress.Table)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// taskDetails загружает таймлайн этапов и прогресс таблиц для набора задач
+func (p *PostgresSys) taskDetails(ctx context.Context, taskIDs []string) (map[string]models.Task, error) {
+	details := make(map[string]models.Task, len(taskIDs))
+	if len(taskIDs) == 0 {
+		return details, nil
+	}
+
+	stageRows, err := p.Db.QueryContext(ctx, `SELECT task_id, stage, status, started_at, finished_at, error
+				FROM task_stages WHERE task_id = ANY($1::uuid[]) ORDER BY started_at`, pq.Array(taskIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer stageRows.Close()
+	for stageRows.Next() {
+		var (
+			taskID string
+			stage  models.TaskStage
+		)
+		if err := stageRows.Scan(&taskID, &stage.Name, &stage.Status, &stage.StartedAt, &stage.FinishedAt, &stage.Error); err != nil {
+			return nil, err
+		}
+		task := details[taskID]
+		task.Stages = append(task.Stages, stage)
+		details[taskID] = task
+	}
+	if err := stageRows.Err(); err != nil {
+		return nil, err
+	}
+
+	tableRows, err := p.Db.QueryContext(ctx, `SELECT task_id, source_name, schema_name, table_name, expected_rows, loaded_rows
+				FROM task_table_progress WHERE task_id = ANY($1::uuid[]) ORDER BY source_name, schema_name, table_name`, pq.Array(taskIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer tableRows.Close()
+	for tableRows.Next() {
+		var (
+			taskID   string
+			progress models.TaskTableProgress
+		)
+		if err := tableRows.Scan(&taskID, &progress.Source, &progress.Schema, &progress.Table, &progress.Expected, &progress.Loaded); err != nil {
+			return nil, err
+		}
+		task := details[taskID]
+		task.Tables = append(task.Tables, progress)
+		details[taskID] = task
+	}
+	if err := tableRows.Err(); err != nil {
+		return nil, err
+	}
+
+	snapshotRows, err := p.Db.QueryContext(ctx, `SELECT task_id, source_name, lsn, xmin, xmax, xip, taken_at
+				FROM task_snapshots WHERE task_id = ANY($1::uuid[]) ORDER BY source_name`, pq.Array(taskIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer snapshotRows.Close()
+	for snapshotRows.Next() {
+		var taskID string
+		snapshot, err := scanTaskSnapshot(snapshotRows, &taskID)
+		if err != nil {
+			return nil, err
+		}
+		task := details[taskID]
+		task.Snapshots = append(task.Snapshots, snapshot)
+		details[taskID] = task
+	}
+	return details, snapshotRows.Err()
+}
+
+func (p *PostgresSys) SaveTaskChunks(ctx context.Context, taskID string, chunks []models.TaskChunk) (err error) {
+	const op = "Storage.PostgreSQL.SaveTaskChunks"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// план таблицы заменяется целиком
+	first := chunks[0]
+	if _, err = tx.ExecContext(ctx, `DELETE FROM task_chunks
+				WHERE task_id = $1 AND source_name = $2 AND schema_name = $3 AND table_name = $4`,
+		taskID, first.Source, first.Schema, first.Table); err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+
+	query := `INSERT INTO task_chunks (task_id, source_name, schema_name, table_name, chunk_index, temp_table, bounds, done, loaded_rows)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	for _, chunk := range chunks {
+		var bounds []byte
+		if bounds, err = json.Marshal(chunk.Chunk); err != nil {
+			log.Error("не удалось сериализовать границы чанка", slog.String("error", err.Error()))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, taskID, chunk.Source, chunk.Schema, chunk.Table,
+			chunk.Chunk.Index, chunk.TempTable, bounds, chunk.Done, chunk.Rows); err != nil {
+			log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresSys) CompleteTaskChunk(ctx context.Context, taskID string, chunk models.TaskChunk) error {
+	const op = "Storage.PostgreSQL.CompleteTaskChunk"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+		slog.String("table", chunk.Table),
+		slog.Int("chunk", chunk.Chunk.Index),
+	)
+
+	query := `UPDATE task_chunks SET done = true, loaded_rows = $1, finished_at = now()
+				WHERE task_id = $2 AND source_name = $3 AND schema_name = $4 AND table_name = $5 AND chunk_index = $6`
+	_, err := p.Db.ExecContext(ctx, query, chunk.Rows, taskID, chunk.Source, chunk.Schema, chunk.Table, chunk.Chunk.Index)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresSys) ListTaskChunks(ctx context.Context, taskID string) ([]models.TaskChunk, error) {
+	const op = "Storage.PostgreSQL.ListTaskChunks"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+
+	rows, err := p.Db.QueryContext(ctx, `SELECT source_name, schema_name, table_name, temp_table, bounds, done, loaded_rows
+				FROM task_chunks WHERE task_id = $1
+				ORDER BY source_name, schema_name, table_name, chunk_index`, taskID)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chunks []models.TaskChunk
+	for rows.Next() {
+		var (
+			chunk  models.TaskChunk
+			bounds []byte
+		)
+		if err := rows.Scan(&chunk.Source, &chunk.Schema, &chunk.Table, &chunk.TempTable, &bounds, &chunk.Done, &chunk.Rows); err != nil {
+			log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+			return nil, err
+		}
+		// числа границ читаются как json.Number, чтобы большие ключи не теряли точность
+		decoder := json.NewDecoder(bytes.NewReader(bounds))
+		decoder.UseNumber()
+		if err := decoder.Decode(&chunk.Chunk); err != nil {
+			log.Error("не удалось разобрать границы чанка", slog.String("error", err.Error()))
+			return nil, err
+		}
+		chunk.Chunk.Lower = chunkBounds(chunk.Chunk.Lower)
+		chunk.Chunk.Upper = chunkBounds(chunk.Chunk.Upper)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// chunkBounds возвращает целочисленным границам тип int64, остальные числа остаются строками
+func chunkBounds(values []interface{}) []interface{} {
+	for i, v := range values {
+		number, ok := v.(json.Number)
+		if !ok {
+			continue
+		}
+		if n, err := number.Int64(); err == nil {
+			values[i] = n
+		} else {
+			values[i] = number.String()
+		}
+	}
+	return values
+}
+
+func (p *PostgresSys) RegisterTaskTempTables(ctx context.Context, taskID string, tables []string) error {
+	const op = "Storage.PostgreSQL.RegisterTaskTempTables"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+	if len(tables) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO task_temp_tables (task_id, temp_table)
+				SELECT $1, unnest($2::text[])
+				ON CONFLICT (task_id, temp_table) DO NOTHING`
+	if _, err := p.Db.ExecContext(ctx, query, taskID, pq.Array(tables)); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			// уникальный индекс по temp_table: таблица уже закреплена за другой задачей
+			log.Warn("временная таблица закреплена за другой задачей", slog.String("error", err.Error()))
+			return storage.ErrTempTableClaimed
+		}
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresSys) GetTaskTempTables(ctx context.Context, taskID string) ([]string, error) {
+	const op = "Storage.PostgreSQL.GetTaskTempTables"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+
+	rows, err := p.Db.QueryContext(ctx, `SELECT temp_table FROM task_temp_tables WHERE task_id = $1 ORDER BY temp_table`, taskID)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+func (p *PostgresSys) ListTaskTempTables(ctx context.Context, activeStatus string, failedStatus string, failedSince time.Time) ([]string, error) {
+	const op = "Storage.PostgreSQL.ListTaskTempTables"
+	log := p.Log.With(slog.String("op", op))
+
+	query := `SELECT tt.temp_table FROM task_temp_tables tt
+				JOIN tasks t ON t.id = tt.task_id
+				WHERE t.status = $1
+				OR (t.status = $2 AND t.create_at >= $3
+					AND EXISTS (SELECT 1 FROM task_chunks c WHERE c.task_id = t.id))`
+	rows, err := p.Db.QueryContext(ctx, query, activeStatus, failedStatus, failedSince)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+func (p *PostgresSys) SaveTaskSnapshot(ctx context.Context, taskID string, snapshot models.TaskSnapshot) error {
+	const op = "Storage.PostgreSQL.SaveTaskSnapshot"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+		slog.String("source", snapshot.Source),
+	)
+
+	position := snapshot.Position
+	query := `INSERT INTO task_snapshots (task_id, source_name, lsn, xmin, xmax, xip, taken_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (task_id, source_name) DO UPDATE
+				SET lsn = EXCLUDED.lsn, xmin = EXCLUDED.xmin, xmax = EXCLUDED.xmax,
+					xip = EXCLUDED.xip, taken_at = EXCLUDED.taken_at`
+	_, err := p.Db.ExecContext(ctx, query, taskID, snapshot.Source, position.LSN, position.Xmin, position.Xmax,
+		pq.Array(position.Xip), snapshot.TakenAt)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresSys) ListTaskSnapshots(ctx context.Context, taskID string) ([]models.TaskSnapshot, error) {
+	const op = "Storage.PostgreSQL.ListTaskSnapshots"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("taskID", taskID),
+	)
+
+	rows, err := p.Db.QueryContext(ctx, `SELECT task_id, source_name, lsn, xmin, xmax, xip, taken_at
+				FROM task_snapshots WHERE task_id = $1 ORDER BY source_name`, taskID)
+	if err != nil {
+		log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []models.TaskSnapshot
+	for rows.Next() {
+		var owner string
+		snapshot, err := scanTaskSnapshot(rows, &owner)
+		if err != nil {
+			log.Error("Запросвыполнен с ошибкой", slog.String("error", err.Error()))
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+func scanTaskSnapshot(rows *sql.Rows, taskID *string) (models.TaskSnapshot, error) {
+	var snapshot models.TaskSnapshot
+	err := rows.Scan(taskID, &snapshot.Source, &snapshot.Position.LSN, &snapshot.Position.Xmin,
+		&snapshot.Position.Xmax, pq.Array(&snapshot.Position.Xip), &snapshot.TakenAt)
+	return snapshot, err
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"context"
+	"log/slog"
+
+	"github.com/lib/pq"
+)
+
+func (p *PostgresSys) SaveViewSnapshots(ctx context.Context, viewID int64, positions map[string]models.SnapshotPosition) error {
+	const op = "Storage.PostgreSQL.SaveViewSnapshots"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.Int64("viewID", viewID),
+	)
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("failed to begin tx", slog.String("error", err.Error()))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM view_snapshots WHERE view_id = $1`, viewID); err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	for source, position := range positions {
+		_, err = tx.ExecContext(ctx, `INSERT INTO view_snapshots (view_id, source_name, lsn, xmin, xmax, xip)
+				VALUES ($1, $2, $3, $4, $5, $6)`,
+			viewID, source, position.LSN, position.Xmin, position.Xmax, pq.Array(position.Xip))
+		if err != nil {
+			log.Error("Запрос выполнен с ошибкой", slog.String("source", source), slog.String("error", err.Error()))
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		log.Error("failed to commit tx", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresSys) ListViewSnapshots(ctx context.Context) ([]models.ViewSnapshot, error) {
+	const op = "Storage.PostgreSQL.ListViewSnapshots"
+	log := p.Log.With(slog.String("op", op))
+
+	rows, err := p.Db.QueryContext(ctx, `SELECT view_id, source_name, lsn, xmin, xmax, xip
+				FROM view_snapshots ORDER BY view_id, source_name`)
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []models.ViewSnapshot
+	for rows.Next() {
+		var s models.ViewSnapshot
+		err := rows.Scan(&s.ViewID, &s.Source, &s.Position.LSN, &s.Position.Xmin, &s.Position.Xmax,
+			pq.Array(&s.Position.Xip))
+		if err != nil {
+			log.Error("Ошибка сканирования строки", slog.String("error", err.Error()))
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
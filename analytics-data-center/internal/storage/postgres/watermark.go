@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"context"
+	"log/slog"
+)
+
+func (p *PostgresSys) ListViewWatermarks(ctx context.Context, viewID int64) ([]models.ViewWatermark, error) {
+	const op = "Storage.PostgreSQL.ListViewWatermarks"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.Int64("viewID", viewID),
+	)
+
+	rows, err := p.Db.QueryContext(ctx, `SELECT source_name, schema_name, table_name, column_name, value, updated_at
+				FROM view_watermarks WHERE view_id = $1
+				ORDER BY source_name, schema_name, table_name`, viewID)
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watermarks []models.ViewWatermark
+	for rows.Next() {
+		var w models.ViewWatermark
+		if err := rows.Scan(&w.Source, &w.Schema, &w.Table, &w.Column, &w.Value, &w.UpdatedAt); err != nil {
+			log.Error("Ошибка сканирования строки", slog.String("error", err.Error()))
+			return nil, err
+		}
+		watermarks = append(watermarks, w)
+	}
+	return watermarks, rows.Err()
+}
+
+func (p *PostgresSys) SaveViewWatermark(ctx context.Context, viewID int64, watermark models.ViewWatermark) error {
+	const op = "Storage.PostgreSQL.SaveViewWatermark"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.Int64("viewID", viewID),
+		slog.String("table", watermark.Table),
+	)
+
+	query := `INSERT INTO view_watermarks (view_id, source_name, schema_name, table_name, column_name, value, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (view_id, source_name, schema_name, table_name) DO UPDATE
+				SET column_name = EXCLUDED.column_name, value = EXCLUDED.value, updated_at = EXCLUDED.updated_at`
+	_, err := p.Db.ExecContext(ctx, query, viewID, watermark.Source, watermark.Schema, watermark.Table,
+		watermark.Column, watermark.Value, watermark.UpdatedAt)
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
@@ -1,28 +1,140 @@
 package postgresdwh
 
 import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
 	"context"
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 
 	"github.com/lib/pq"
 )
 
-func (p *PostgresDWH) InsertDataToDWH(ctx context.Context, query string) error {
-	const op = "Storage.PostgreSQL.InsertDataToDWH"
+// InsertStream загружает строки потока в таблицу через COPY. Каждые batchSize строк
+// COPY завершается и транзакция фиксируется, поэтому строки не копятся в памяти,
+// а значения передаются типизированными, без сборки SQL-литералов.
+func (p *PostgresDWH) InsertStream(ctx context.Context, view models.View, tableName string, rows models.RowStream, batchSize int) (int64, error) {
+	const op = "Storage.PostgreSQL.InsertStream"
 	log := p.Log.With(
 		slog.String("op", op),
+		slog.String("table", tableName),
 	)
-	_, err := p.Db.ExecContext(ctx, query)
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("%s: некорректный размер порции вставки: %d", op, batchSize)
+	}
+
+	var (
+		inserted    int64
+		columns     []string
+		columnTypes = sqlgenerator.ColumnTypes(view)
+		batch       *copyBatch
+	)
+	fail := func(msg string, err error) (int64, error) {
+		if batch != nil {
+			batch.rollback()
+		}
+		log.Error(msg, slog.Int64("inserted", inserted), slog.String("error", err.Error()))
+		return inserted, err
+	}
+
+	for rows.Next() {
+		row := rows.Row()
+		if columns == nil {
+			var err error
+			if columns, err = sqlgenerator.InsertColumns(view, row); err != nil {
+				return fail("не найдены колонки для вставки", err)
+			}
+		}
+		values, err := sqlgenerator.PostgresCopyValues(row, columns, columnTypes)
+		if err != nil {
+			return fail("ошибка подготовки значений строки", err)
+		}
+
+		if batch == nil {
+			if batch, err = p.beginCopy(ctx, tableName, columns); err != nil {
+				return fail("ошибка запуска COPY", err)
+			}
+		}
+		if err := batch.add(ctx, values); err != nil {
+			return fail("ошибка передачи строки в COPY", err)
+		}
+		if batch.size < batchSize {
+			continue
+		}
+		if err := batch.commit(ctx); err != nil {
+			batch = nil
+			return fail("ошибка завершения COPY", err)
+		}
+		inserted += int64(batch.size)
+		batch = nil
+	}
+	if err := rows.Err(); err != nil {
+		return fail("ошибка чтения строк источника", err)
+	}
+	if batch != nil {
+		if err := batch.commit(ctx); err != nil {
+			batch = nil
+			return fail("ошибка завершения COPY", err)
+		}
+		inserted += int64(batch.size)
+	}
+	return inserted, nil
+}
+
+// copyBatch — открытая транзакция с COPY FROM STDIN в таблицу
+type copyBatch struct {
+	tx   *sql.Tx
+	stmt *sql.Stmt
+	size int
+}
+
+func (p *PostgresDWH) beginCopy(ctx context.Context, tableName string, columns []string) (*copyBatch, error) {
+	query, err := sqlgenerator.GenerateInsertTemplate(tableName, columns, sqlgenerator.DbPostgres)
 	if err != nil {
-		log.Error("ошибка подготовки запроса", slog.String("error", err.Error()))
-		return err
+		return nil, err
+	}
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
 	}
+	return &copyBatch{tx: tx, stmt: stmt}, nil
+}
 
+func (b *copyBatch) add(ctx context.Context, values []interface{}) error {
+	if _, err := b.stmt.ExecContext(ctx, values...); err != nil {
+		return err
+	}
+	b.size++
 	return nil
 }
+
+// commit отправляет остаток буфера COPY и фиксирует транзакцию; при ошибке откатывает её
+func (b *copyBatch) commit(ctx context.Context) error {
+	if _, err := b.stmt.ExecContext(ctx); err != nil {
+		b.rollback()
+		return err
+	}
+	if err := b.stmt.Close(); err != nil {
+		_ = b.tx.Rollback()
+		return err
+	}
+	return b.tx.Commit()
+}
+
+func (b *copyBatch) rollback() {
+	_ = b.stmt.Close()
+	_ = b.tx.Rollback()
+}
+
 func (p *PostgresDWH) MergeTempTables(ctx context.Context, query string) error {
 	const op = "Storage.PostgreSQL.MergeTempTables"
 	log := p.Log.With(
@@ -75,6 +187,21 @@ func (p *PostgresDWH) InsertOrUpdateTransactional(
 		}
 	}()
 
+	if err = upsertWithTx(ctx, tx, schemaName, row, conflictColumns); err != nil {
+		return err
+	}
+
+	// COMMIT
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("ошибка при коммите транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// upsertWithTx обновляет строку таблицы с теми же ключами conflictColumns или вставляет новую
+func upsertWithTx(ctx context.Context, tx *sql.Tx, schemaName string, row map[string]interface{}, conflictColumns []string) error {
 	// Проверка на существование строки
 	where := make([]string, len(conflictColumns))
 	values := make([]interface{}, len(conflictColumns))
@@ -86,7 +213,7 @@ func (p *PostgresDWH) InsertOrUpdateTransactional(
 	selectQuery := fmt.Sprintf(`SELECT 1 FROM %s WHERE %s LIMIT 1`, schemaName, strings.Join(where, " AND "))
 
 	var dummy int
-	err = tx.QueryRowContext(ctx, selectQuery, values...).Scan(&dummy)
+	err := tx.QueryRowContext(ctx, selectQuery, values...).Scan(&dummy)
 	if err != nil && err != sql.ErrNoRows {
 		return fmt.Errorf("ошибка при проверке существования: %w", err)
 	}
@@ -94,24 +221,15 @@ func (p *PostgresDWH) InsertOrUpdateTransactional(
 	// определяем: insert или update
 	if err == sql.ErrNoRows {
 		// INSERT
-		err = insertWithTx(ctx, tx, schemaName, row)
-		if err != nil {
+		if err := insertWithTx(ctx, tx, schemaName, row); err != nil {
 			return fmt.Errorf("ошибка при вставке: %w", err)
 		}
 	} else {
 		// UPDATE
-		err = updateWithTx(ctx, tx, schemaName, row, conflictColumns)
-		if err != nil {
+		if err := updateWithTx(ctx, tx, schemaName, row, conflictColumns); err != nil {
 			return fmt.Errorf("ошибка при обновлении: %w", err)
 		}
 	}
-
-	// COMMIT
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("ошибка при коммите транзакции: %w", err)
-	}
-
 	return nil
 }
 func insertWithTx(ctx context.Context, tx *sql.Tx, table string, row map[string]interface{}) error {
@@ -214,3 +332,123 @@ func convertInterfacesToInt64(arr []interface{}) []int64 {
 	}
 	return ints
 }
+
+func (p *PostgresDWH) DeleteRow(ctx context.Context, tableName string, keys map[string]interface{}) error {
+	const op = "Storage.PostgreSQL.DeleteRow"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("table", tableName),
+	)
+	if len(keys) == 0 {
+		return fmt.Errorf("не заданы ключи для удаления строки из %s", tableName)
+	}
+
+	where, values := buildKeyCondition(keys, 1)
+	query := fmt.Sprintf(`DELETE FROM %s WHERE %s`, tableName, where)
+	res, err := p.Db.ExecContext(ctx, query, values...)
+	if err != nil {
+		log.Error("ошибка удаления строки", slog.String("error", err.Error()))
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil {
+		log.Info("строки удалены", slog.Int64("rows", affected))
+	}
+	return nil
+}
+
+func (p *PostgresDWH) SoftDeleteRow(ctx context.Context, tableName string, keys map[string]interface{}) error {
+	const op = "Storage.PostgreSQL.SoftDeleteRow"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("table", tableName),
+	)
+	if len(keys) == 0 {
+		return fmt.Errorf("не заданы ключи для пометки удаления строки в %s", tableName)
+	}
+
+	where, values := buildKeyCondition(keys, 1)
+	query := fmt.Sprintf(`UPDATE %s SET %s = TRUE, %s = now() WHERE %s`,
+		tableName, models.SoftDeleteFlagColumn, models.SoftDeleteTimeColumn, where)
+	res, err := p.Db.ExecContext(ctx, query, values...)
+	if err != nil {
+		log.Error("ошибка пометки строки удалённой", slog.String("error", err.Error()))
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil {
+		log.Info("строки помечены удалёнными", slog.Int64("rows", affected))
+	}
+	return nil
+}
+
+// ReplaceRows в одной транзакции убирает строки по ключам removeKeys и записывает rows:
+// при ошибке таблица остаётся в прежнем состоянии, без удалённых, но не вставленных строк
+func (p *PostgresDWH) ReplaceRows(
+	ctx context.Context,
+	tableName string,
+	removeKeys []map[string]interface{},
+	softDelete bool,
+	rows []models.RowUpsert,
+) (err error) {
+	const op = "Storage.PostgreSQL.ReplaceRows"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("table", tableName),
+	)
+
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("не удалось начать транзакцию", slog.String("error", err.Error()))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	for _, keys := range removeKeys {
+		if len(keys) == 0 {
+			return fmt.Errorf("не заданы ключи для удаления строки из %s", tableName)
+		}
+		where, values := buildKeyCondition(keys, 1)
+		query := fmt.Sprintf(`DELETE FROM %s WHERE %s`, tableName, where)
+		if softDelete {
+			query = fmt.Sprintf(`UPDATE %s SET %s = TRUE, %s = now() WHERE %s`,
+				tableName, models.SoftDeleteFlagColumn, models.SoftDeleteTimeColumn, where)
+		}
+		if _, err = tx.ExecContext(ctx, query, values...); err != nil {
+			log.Error("ошибка удаления строки", slog.String("error", err.Error()))
+			return err
+		}
+	}
+	for _, row := range rows {
+		if err = upsertWithTx(ctx, tx, tableName, row.Row, row.ConflictColumns); err != nil {
+			log.Error("ошибка вставки/обновления строки", slog.String("error", err.Error()))
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("не удалось зафиксировать транзакцию", slog.String("error", err.Error()))
+		return err
+	}
+	log.Info("строки заменены", slog.Int("removed", len(removeKeys)), slog.Int("rows", len(rows)))
+	return nil
+}
+
+// buildKeyCondition собирает условие "k1 = $n AND k2 = $n+1" в стабильном порядке ключей
+func buildKeyCondition(keys map[string]interface{}, startIdx int) (string, []interface{}) {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	where := make([]string, 0, len(names))
+	values := make([]interface{}, 0, len(names))
+	for i, name := range names {
+		where = append(where, fmt.Sprintf("%s = $%d", name, startIdx+i))
+		values = append(values, normalizeSQLValue(keys[name]))
+	}
+	return strings.Join(where, " AND "), values
+}
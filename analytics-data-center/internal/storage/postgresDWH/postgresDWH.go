@@ -3,11 +3,14 @@ package postgresdwh
 import (
 	"database/sql"
 	"log/slog"
+	"sync"
 )
 
 type PostgresDWH struct {
 	Db  *sql.DB
 	Log *slog.Logger
+	// noConflictIndex — таблицы без уникального индекса под ключи UpsertBatch
+	noConflictIndex sync.Map
 }
 
 func New(connectionString string, log *slog.Logger) (*PostgresDWH, error) {
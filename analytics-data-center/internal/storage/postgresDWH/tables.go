@@ -157,3 +157,97 @@ func (p *PostgresDWH) DropColumn(ctx context.Context, query string) error {
 
 	return nil
 }
+
+// SwapTables подменяет targetTable таблицей shadowTable в одной транзакции: прежняя
+// версия переименовывается, теневая получает её имя, затем прежняя удаляется.
+// При любой ошибке транзакция откатывается и аналитики продолжают видеть прежнюю таблицу.
+func (p *PostgresDWH) SwapTables(ctx context.Context, shadowTable string, targetTable string) error {
+	const op = "Storage.PostgreSQL.SwapTables"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("shadow", shadowTable),
+		slog.String("target", targetTable),
+	)
+
+	oldTable := targetTable + "__old"
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("не удалось начать транзакцию", slog.String("error", err.Error()))
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", pq.QuoteIdentifier(oldTable)),
+		fmt.Sprintf("ALTER TABLE IF EXISTS %s RENAME TO %s", pq.QuoteIdentifier(targetTable), pq.QuoteIdentifier(oldTable)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", pq.QuoteIdentifier(shadowTable), pq.QuoteIdentifier(targetTable)),
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", pq.QuoteIdentifier(oldTable)),
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			log.Error("ошибка подмены таблицы", slog.String("query", query), slog.String("error", err.Error()))
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Error("не удалось зафиксировать подмену таблицы", slog.String("error", err.Error()))
+		return err
+	}
+	// у новой версии таблицы может быть уникальный индекс по ключам
+	p.noConflictIndex.Delete(targetTable)
+	log.Info("таблица view подменена")
+	return nil
+}
+
+// DeleteChunkRows удаляет из временной таблицы строки недогруженного чанка, чтобы его
+// можно было загрузить повторно без дублей
+func (p *PostgresDWH) DeleteChunkRows(ctx context.Context, tableName string, condition string, args ...interface{}) error {
+	const op = "Storage.PostgreSQL.DeleteChunkRows"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("tableName", tableName),
+		slog.String("condition", condition),
+	)
+
+	query := fmt.Sprintf("DELETE FROM %s", tableName)
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+	res, err := p.Db.ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error("не удалось удалить строки чанка", slog.String("error", err.Error()))
+		return err
+	}
+	if count, err := res.RowsAffected(); err == nil {
+		log.Info("строки чанка удалены", slog.Int64("rows", count))
+	}
+	return nil
+}
+
+func (p *PostgresDWH) ListTempTables(ctx context.Context, prefix string) ([]string, error) {
+	const op = "Storage.PostgreSQL.ListTempTables"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("prefix", prefix),
+	)
+
+	query := `SELECT table_name FROM information_schema.tables
+				WHERE table_schema = current_schema() AND left(table_name, length($1)) = $1`
+	rows, err := p.Db.QueryContext(ctx, query, prefix)
+	if err != nil {
+		log.Error("Запрос выполнен с ошибкой", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			log.Error("Ошибка чтения строки", slog.String("error", err.Error()))
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
@@ -0,0 +1,253 @@
+package postgresdwh
+
+import (
+	sqlgenerator "analyticDataCenter/analytics-data-center/internal/lib/SQLGenerator"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// maxQueryParams — предел параметров одного запроса PostgreSQL
+const maxQueryParams = 65535
+
+// UpsertBatch пишет пачку строк в одной транзакции: INSERT ... ON CONFLICT на каждую
+// группу строк с одинаковым набором колонок. ON CONFLICT опирается на уникальный индекс
+// по ключам, который строится при сборке view; если его нет, пачка пишется без него:
+// UPDATE ... FROM (VALUES ...) и INSERT ... WHERE NOT EXISTS в одной транзакции.
+// Таблица без индекса запоминается до следующей подмены, чтобы не повторять заведомо
+// неудачный запрос на каждой пачке.
+func (p *PostgresDWH) UpsertBatch(ctx context.Context, tableName string, rows []map[string]interface{}, conflictColumns []string) error {
+	const op = "Storage.PostgreSQL.UpsertBatch"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("table", tableName),
+		slog.Int("rows", len(rows)),
+	)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var err error
+	if _, noIndex := p.noConflictIndex.Load(tableName); noIndex {
+		err = p.upsertBatchWithoutIndex(ctx, tableName, rows, conflictColumns)
+	} else {
+		err = p.upsertBatchTx(ctx, tableName, rows, conflictColumns)
+		if isNoConflictIndex(err) {
+			log.Warn("ключи не покрыты уникальным индексом, пачки пишутся без ON CONFLICT", slog.String("error", err.Error()))
+			p.noConflictIndex.Store(tableName, struct{}{})
+			err = p.upsertBatchWithoutIndex(ctx, tableName, rows, conflictColumns)
+		}
+	}
+	if err != nil {
+		log.Error("ошибка пакетной вставки/обновления", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *PostgresDWH) upsertBatchTx(ctx context.Context, tableName string, rows []map[string]interface{}, conflictColumns []string) (err error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = forEachRowChunk(rows, func(columns []string, rowCount int, values []interface{}) error {
+		_, err := tx.ExecContext(ctx, upsertQuery(tableName, columns, conflictColumns, rowCount), values...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при коммите транзакции: %w", err)
+	}
+	return nil
+}
+
+// upsertBatchWithoutIndex пишет пачку, когда ON CONFLICT недоступен: строки с уже
+// существующими ключами обновляются одним UPDATE ... FROM (VALUES ...), остальные
+// вставляются одним INSERT ... WHERE NOT EXISTS. Параметры VALUES приводятся к типам
+// колонок таблицы, иначе PostgreSQL считает их текстом.
+func (p *PostgresDWH) upsertBatchWithoutIndex(ctx context.Context, tableName string, rows []map[string]interface{}, conflictColumns []string) (err error) {
+	tx, err := p.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	types, err := columnTypes(ctx, tx, tableName)
+	if err != nil {
+		return err
+	}
+	err = forEachRowChunk(rows, func(columns []string, rowCount int, values []interface{}) error {
+		for _, query := range upsertWithoutIndexQueries(tableName, columns, conflictColumns, types, rowCount) {
+			if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при коммите транзакции: %w", err)
+	}
+	return nil
+}
+
+// forEachRowChunk делит строки на группы с одинаковым набором колонок и порции, которые
+// укладываются в предел параметров запроса, и передаёт значения порции в fn
+func forEachRowChunk(rows []map[string]interface{}, fn func(columns []string, rowCount int, values []interface{}) error) error {
+	for _, group := range sqlgenerator.GroupRowsByColumns(rows) {
+		if len(group.Columns) == 0 {
+			continue
+		}
+		perQuery := maxQueryParams / len(group.Columns)
+		for start := 0; start < len(group.Rows); start += perQuery {
+			end := min(start+perQuery, len(group.Rows))
+			values := make([]interface{}, 0, (end-start)*len(group.Columns))
+			for _, row := range group.Rows[start:end] {
+				for _, col := range group.Columns {
+					values = append(values, normalizeSQLValue(row[col]))
+				}
+			}
+			if err := fn(group.Columns, end-start, values); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// columnTypes возвращает типы колонок таблицы в виде, пригодном для приведения ::type
+func columnTypes(ctx context.Context, tx *sql.Tx, tableName string) (map[string]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT attname, format_type(atttypid, atttypmod)
+		FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped`, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить типы колонок %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	types := make(map[string]string)
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		types[name] = typ
+	}
+	return types, rows.Err()
+}
+
+// isNoConflictIndex — у таблицы нет уникального индекса под ключи ON CONFLICT
+func isNoConflictIndex(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "42P10"
+}
+
+// valuesList собирает VALUES для rowCount строк; параметры приводятся к типам колонок
+func valuesList(columns []string, types map[string]string, rowCount int) string {
+	tuples := make([]string, rowCount)
+	placeholders := make([]string, len(columns))
+	for r := 0; r < rowCount; r++ {
+		for c, col := range columns {
+			placeholders[c] = fmt.Sprintf("$%d", r*len(columns)+c+1)
+			typ, ok := types[col]
+			if !ok {
+				typ, ok = types[strings.ToLower(col)]
+			}
+			if ok {
+				placeholders[c] += "::" + typ
+			}
+		}
+		tuples[r] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+	return "VALUES " + strings.Join(tuples, ", ")
+}
+
+// upsertWithoutIndexQueries собирает UPDATE ... FROM (VALUES ...) для строк с
+// существующими ключами и INSERT ... WHERE NOT EXISTS для остальных; оба запроса
+// принимают одни и те же параметры
+func upsertWithoutIndexQueries(tableName string, columns, conflictColumns []string, types map[string]string, rowCount int) []string {
+	values := valuesList(columns, types, rowCount)
+	insert := fmt.Sprintf(`INSERT INTO %s (%s) %s`, tableName, strings.Join(columns, ", "), values)
+	if len(conflictColumns) == 0 {
+		return []string{insert}
+	}
+
+	keys := make(map[string]struct{}, len(conflictColumns))
+	match := make([]string, 0, len(conflictColumns))
+	for _, col := range sortedColumns(conflictColumns) {
+		keys[col] = struct{}{}
+		match = append(match, fmt.Sprintf("t.%s = v.%s", col, col))
+	}
+	var set, selected []string
+	for _, col := range columns {
+		selected = append(selected, "v."+col)
+		if _, ok := keys[col]; !ok {
+			set = append(set, fmt.Sprintf("%s = v.%s", col, col))
+		}
+	}
+	source := fmt.Sprintf(`(%s) AS v (%s)`, values, strings.Join(columns, ", "))
+	condition := strings.Join(match, " AND ")
+
+	var queries []string
+	if len(set) > 0 {
+		queries = append(queries, fmt.Sprintf(`UPDATE %s AS t SET %s FROM %s WHERE %s`,
+			tableName, strings.Join(set, ", "), source, condition))
+	}
+	queries = append(queries, fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s WHERE NOT EXISTS (SELECT 1 FROM %s AS t WHERE %s)`,
+		tableName, strings.Join(columns, ", "), strings.Join(selected, ", "), source, tableName, condition))
+	return queries
+}
+
+// upsertQuery собирает INSERT ... ON CONFLICT для rowCount строк с колонками columns
+func upsertQuery(tableName string, columns, conflictColumns []string, rowCount int) string {
+	query := fmt.Sprintf(`INSERT INTO %s (%s) %s`,
+		tableName, strings.Join(columns, ", "), valuesList(columns, nil, rowCount))
+	if len(conflictColumns) == 0 {
+		return query
+	}
+
+	keys := make(map[string]struct{}, len(conflictColumns))
+	for _, col := range conflictColumns {
+		keys[col] = struct{}{}
+	}
+	var set []string
+	for _, col := range columns {
+		if _, ok := keys[col]; !ok {
+			set = append(set, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+		}
+	}
+	action := "DO NOTHING"
+	if len(set) > 0 {
+		action = "DO UPDATE SET " + strings.Join(set, ", ")
+	}
+	return fmt.Sprintf(`%s ON CONFLICT (%s) %s`, query, strings.Join(sortedColumns(conflictColumns), ", "), action)
+}
+
+func sortedColumns(columns []string) []string {
+	sorted := append([]string(nil), columns...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,30 @@
+package postgresdwh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertQuery(t *testing.T) {
+	query := upsertQuery("sales", []string{"id", "name"}, []string{"id"}, 2)
+
+	require.Equal(t, "INSERT INTO sales (id, name) VALUES ($1, $2), ($3, $4) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name", query)
+}
+
+func TestUpsertWithoutIndexQueries(t *testing.T) {
+	types := map[string]string{"id": "integer", "tenant_id": "integer", "name": "character varying(50)"}
+
+	queries := upsertWithoutIndexQueries("sales", []string{"id", "Name", "tenant_id"}, []string{"tenant_id", "id"}, types, 2)
+
+	source := "(VALUES ($1::integer, $2::character varying(50), $3::integer), ($4::integer, $5::character varying(50), $6::integer)) AS v (id, Name, tenant_id)"
+	require.Equal(t, []string{
+		"UPDATE sales AS t SET Name = v.Name FROM " + source + " WHERE t.id = v.id AND t.tenant_id = v.tenant_id",
+		"INSERT INTO sales (id, Name, tenant_id) SELECT v.id, v.Name, v.tenant_id FROM " + source +
+			" WHERE NOT EXISTS (SELECT 1 FROM sales AS t WHERE t.id = v.id AND t.tenant_id = v.tenant_id)",
+	}, queries)
+
+	// без ключей обновлять нечего, строки только вставляются
+	require.Equal(t, []string{"INSERT INTO sales (id) VALUES ($1::integer)"},
+		upsertWithoutIndexQueries("sales", []string{"id"}, nil, types, 1))
+}
@@ -1,7 +1,9 @@
 package postgresoltp
 
 import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
 	"context"
+	"database/sql"
 	"log/slog"
 )
 
@@ -13,52 +15,144 @@ func (p *PostgresOLTP) GetCountInsertData(ctx context.Context, query string) (in
 		slog.String("query", query),
 	)
 	log.Info("подсчет количества записей для вставки")
-	err := p.Db.QueryRowContext(ctx, query).Scan(&count)
+	rows, finish, err := p.queryRows(ctx, query)
 	if err != nil {
 		log.Error("ошибка получения количества записей", slog.String("ошибка", err.Error()))
 		return 0, err
 	}
+	defer finish()
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			log.Error("ошибка получения количества записей", slog.String("ошибка", err.Error()))
+			return 0, err
+		}
+		return 0, sql.ErrNoRows
+	}
+	if err := rows.Scan(&count); err != nil {
+		log.Error("ошибка получения количества записей", slog.String("ошибка", err.Error()))
+		return 0, err
+	}
 	return count, nil
 
 }
 
-func (p *PostgresOLTP) SelectDataToInsert(ctx context.Context, query string) ([]map[string]interface{}, error) {
-	const op = "Storage.PostgresOLTP.SelectDataToInsert"
+// StreamRows выполняет выборку данных для вставки. Строки не собираются в память целиком:
+// вызывающий читает их из потока по одной и обязан закрыть поток.
+func (p *PostgresOLTP) StreamRows(ctx context.Context, query string, args ...interface{}) (models.RowStream, error) {
+	const op = "Storage.PostgresOLTP.StreamRows"
 	log := p.Log.With(
 		slog.String("op", op),
 		slog.String("query", query),
 	)
 	log.Info("выборка данных для вставки")
 
-	rows, err := p.Db.QueryContext(ctx, query)
+	rows, finish, err := p.queryRows(ctx, query, args...)
 	if err != nil {
 		log.Error("ошибка получения данных для вставки", slog.String("ошибка", err.Error()))
 		return nil, err
 	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		finish()
+		log.Error("ошибка получения колонок", slog.String("ошибка", err.Error()))
+		return nil, err
+	}
+	return &rowStream{rows: rows, columns: columns, finish: finish}, nil
+}
+
+// rowStream — models.RowStream поверх sql.Rows
+type rowStream struct {
+	rows    *sql.Rows
+	columns []string
+	row     map[string]interface{}
+	err     error
+	// finish закрывает транзакцию снимка, в которой выполнена выборка
+	finish func() error
+}
+
+func (s *rowStream) Next() bool {
+	if s.err != nil || !s.rows.Next() {
+		return false
+	}
+	values := make([]interface{}, len(s.columns))
+	valuesPointers := make([]interface{}, len(s.columns))
+	for i := range values {
+		valuesPointers[i] = &values[i]
+	}
+	if err := s.rows.Scan(valuesPointers...); err != nil {
+		s.err = err
+		return false
+	}
+	row := make(map[string]interface{}, len(s.columns))
+	for i, col := range s.columns {
+		row[col] = values[i]
+	}
+	s.row = row
+	return true
+}
+
+func (s *rowStream) Row() map[string]interface{} {
+	return s.row
+}
+
+func (s *rowStream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.rows.Err()
+}
+
+func (s *rowStream) Close() error {
+	err := s.rows.Close()
+	s.finish()
+	return err
+}
+
+// SelectRows выполняет параметризованную выборку и возвращает строки как map колонка → значение
+func (p *PostgresOLTP) SelectRows(ctx context.Context, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	const op = "Storage.PostgresOLTP.SelectRows"
+	log := p.Log.With(
+		slog.String("op", op),
+		slog.String("query", query),
+	)
+
+	rows, finish, err := p.queryRows(ctx, query, args...)
+	if err != nil {
+		log.Error("ошибка выборки строк", slog.String("ошибка", err.Error()))
+		return nil, err
+	}
+	defer finish()
 	defer rows.Close()
+
 	columns, err := rows.Columns()
-	results := make([]map[string]interface{}, 0)
 	if err != nil {
 		log.Error("ошибка получения колонок", slog.String("ошибка", err.Error()))
 		return nil, err
 	}
+
+	results := make([]map[string]interface{}, 0)
 	for rows.Next() {
 		values := make([]interface{}, len(columns))
 		valuesPointers := make([]interface{}, len(columns))
-
 		for i := range values {
 			valuesPointers[i] = &values[i]
 		}
 		if err := rows.Scan(valuesPointers...); err != nil {
 			log.Error("ошибка сканирования строк", slog.String("ошибка", err.Error()))
+			return nil, err
 		}
-		rowMap := make(map[string]interface{})
+		rowMap := make(map[string]interface{}, len(columns))
 		for i, col := range columns {
 			rowMap[col] = values[i]
 		}
-
 		results = append(results, rowMap)
-
 	}
+	if err := rows.Err(); err != nil {
+		log.Error("ошибка при проходе по строкам", slog.String("ошибка", err.Error()))
+		return nil, err
+	}
+
 	return results, nil
 }
@@ -8,6 +8,9 @@ import (
 type PostgresOLTP struct {
 	Db  *sql.DB
 	Log *slog.Logger
+
+	// snapshotID — экспортированный снимок, который читают выборки данных (см. WithSnapshot)
+	snapshotID string
 }
 
 func New(connectionString string, log *slog.Logger) (*PostgresOLTP, error) {
@@ -0,0 +1,125 @@
+package postgresoltp
+
+import (
+	"analyticDataCenter/analytics-data-center/internal/domain/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ExportSnapshot открывает транзакцию REPEATABLE READ и экспортирует её снимок. Вместе
+// со снимком читаются текущая позиция WAL и границы транзакций снимка: по ним CDC
+// отличает изменения, уже попавшие в снимок. Транзакция держит снимок открытым до release.
+func (p *PostgresOLTP) ExportSnapshot(ctx context.Context) (models.OLTPSnapshot, func() error, error) {
+	const op = "Storage.PostgresOLTP.ExportSnapshot"
+	log := p.Log.With(slog.String("op", op))
+
+	tx, err := p.Db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		log.Error("не удалось открыть транзакцию снимка", slog.String("ошибка", err.Error()))
+		return models.OLTPSnapshot{}, nil, err
+	}
+
+	var snapshotID, lsn, txids string
+	err = tx.QueryRowContext(ctx,
+		"SELECT pg_export_snapshot(), pg_current_wal_lsn()::text, txid_current_snapshot()::text",
+	).Scan(&snapshotID, &lsn, &txids)
+	if err != nil {
+		_ = tx.Rollback()
+		log.Error("не удалось экспортировать снимок", slog.String("ошибка", err.Error()))
+		return models.OLTPSnapshot{}, nil, err
+	}
+	position, err := snapshotPosition(lsn, txids)
+	if err != nil {
+		_ = tx.Rollback()
+		log.Error("не удалось разобрать позицию снимка", slog.String("ошибка", err.Error()))
+		return models.OLTPSnapshot{}, nil, err
+	}
+
+	log.Info("снимок экспортирован", slog.String("snapshot", snapshotID), slog.String("lsn", lsn))
+	return models.OLTPSnapshot{ID: snapshotID, Position: position}, tx.Rollback, nil
+}
+
+// WithSnapshot возвращает хранилище на том же пуле соединений, выборки данных которого
+// выполняются в транзакциях, импортирующих снимок snapshotID
+func (p *PostgresOLTP) WithSnapshot(snapshotID string) *PostgresOLTP {
+	return &PostgresOLTP{Db: p.Db, Log: p.Log, snapshotID: snapshotID}
+}
+
+// snapshotTx открывает транзакцию, читающую экспортированный снимок хранилища
+func (p *PostgresOLTP) snapshotTx(ctx context.Context) (*sql.Tx, error) {
+	tx, err := p.Db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	// SET TRANSACTION SNAPSHOT должен быть первым запросом транзакции
+	if _, err := tx.ExecContext(ctx, "SET TRANSACTION SNAPSHOT "+pq.QuoteLiteral(p.snapshotID)); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// queryRows выполняет выборку; если у хранилища задан снимок — в транзакции, читающей
+// его. finish закрывает транзакцию и вызывается после закрытия строк.
+func (p *PostgresOLTP) queryRows(ctx context.Context, query string, args ...interface{}) (*sql.Rows, func() error, error) {
+	if p.snapshotID == "" {
+		rows, err := p.Db.QueryContext(ctx, query, args...)
+		return rows, func() error { return nil }, err
+	}
+	tx, err := p.snapshotTx(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, nil, err
+	}
+	return rows, tx.Rollback, nil
+}
+
+// snapshotPosition разбирает позицию WAL вида "16/B374D848" и снимок транзакций вида "xmin:xmax:xip,..."
+func snapshotPosition(lsn string, txids string) (models.SnapshotPosition, error) {
+	var position models.SnapshotPosition
+
+	high, low, ok := strings.Cut(lsn, "/")
+	if !ok {
+		return position, fmt.Errorf("неверная позиция WAL %q", lsn)
+	}
+	hi, err := strconv.ParseUint(high, 16, 32)
+	if err != nil {
+		return position, fmt.Errorf("неверная позиция WAL %q: %w", lsn, err)
+	}
+	lo, err := strconv.ParseUint(low, 16, 32)
+	if err != nil {
+		return position, fmt.Errorf("неверная позиция WAL %q: %w", lsn, err)
+	}
+	position.LSN = int64(hi<<32 | lo)
+
+	parts := strings.Split(txids, ":")
+	if len(parts) != 3 {
+		return position, fmt.Errorf("неверный снимок транзакций %q", txids)
+	}
+	if position.Xmin, err = strconv.ParseInt(parts[0], 10, 64); err != nil {
+		return position, fmt.Errorf("неверный снимок транзакций %q: %w", txids, err)
+	}
+	if position.Xmax, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return position, fmt.Errorf("неверный снимок транзакций %q: %w", txids, err)
+	}
+	if parts[2] != "" {
+		for _, value := range strings.Split(parts[2], ",") {
+			txid, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return position, fmt.Errorf("неверный снимок транзакций %q: %w", txids, err)
+			}
+			position.Xip = append(position.Xip, txid)
+		}
+	}
+	return position, nil
+}
@@ -14,6 +14,13 @@ var (
         ErrSchemaNotFound     = errors.New("схема не найдена")
         ErrSuggestionNotFound = errors.New("предложение не найдено")
         ErrMismatchNotFound   = errors.New("группа несоответствий не найдена")
+        ErrTaskNotFound       = errors.New("задача не найдена")
+        ErrQueueFull          = errors.New("очередь ETL переполнена")
+        ErrJobNotFound        = errors.New("задание ETL не найдено")
+        ErrJobLost            = errors.New("аренда задания ETL утрачена")
+        ErrDeadLetterNotFound = errors.New("событие dead letter не найдено")
+        ErrTempTableClaimed   = errors.New("временная таблица закреплена за другой задачей")
+        ErrSnapshotNotSupported = errors.New("источник не поддерживает чтение из снимка")
 )
 
 type Storage struct {
@@ -0,0 +1,18 @@
+-- CDC-события, которые не удалось записать во view
+CREATE TABLE IF NOT EXISTS cdc_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    view_id BIGINT NULL REFERENCES schems(id) ON DELETE SET NULL,
+    database_name TEXT NOT NULL,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    op TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    error TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    status TEXT NOT NULL,
+    next_retry_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS cdc_dead_letters_retry_idx ON cdc_dead_letters (status, next_retry_at);
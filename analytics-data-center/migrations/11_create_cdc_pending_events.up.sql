@@ -0,0 +1,12 @@
+-- CDC-события, отложенные для view до решения по предложению переименования
+CREATE TABLE IF NOT EXISTS cdc_pending_events (
+    id BIGSERIAL PRIMARY KEY,
+    view_id BIGINT NOT NULL REFERENCES schems(id) ON DELETE CASCADE,
+    database_name TEXT NOT NULL,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS cdc_pending_events_table_idx ON cdc_pending_events (view_id, database_name, schema_name, table_name, id);
@@ -0,0 +1,10 @@
+-- Имена временных таблиц содержат идентификатор задачи, поэтому одна таблица DWH
+-- закрепляется не более чем за одной задачей. Общие записи, оставшиеся от прежних
+-- имён temp_<source>_<schema>_<table>, остаются только за последней задачей.
+DELETE FROM task_temp_tables a
+    USING task_temp_tables b
+    WHERE a.temp_table = b.temp_table
+    AND (a.created_at, a.task_id) < (b.created_at, b.task_id);
+
+DROP INDEX IF EXISTS idx_task_temp_tables_temp_table;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_task_temp_tables_temp_table ON task_temp_tables (temp_table);
@@ -0,0 +1,11 @@
+-- Снимки источников, из которых загружена опубликованная view. По ним CDC после
+-- перезапуска пропускает повторно доставленные изменения, уже попавшие в view.
+CREATE TABLE IF NOT EXISTS view_snapshots (
+    view_id BIGINT NOT NULL REFERENCES schems(id) ON DELETE CASCADE,
+    source_name TEXT NOT NULL,
+    lsn BIGINT NOT NULL,
+    xmin BIGINT NOT NULL,
+    xmax BIGINT NOT NULL,
+    xip BIGINT[] NOT NULL DEFAULT '{}',
+    PRIMARY KEY (view_id, source_name)
+);
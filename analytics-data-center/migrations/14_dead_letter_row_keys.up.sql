@@ -0,0 +1,7 @@
+-- Ключ строки источника (ключ сообщения Kafka): следующие события строки ждут за её
+-- событием в dead letter, чтобы повтор не затёр более новые изменения
+ALTER TABLE cdc_dead_letters ADD COLUMN IF NOT EXISTS row_key TEXT NULL;
+
+CREATE INDEX IF NOT EXISTS cdc_dead_letters_row_key_idx
+    ON cdc_dead_letters (database_name, schema_name, table_name, row_key, id)
+    WHERE row_key IS NOT NULL;
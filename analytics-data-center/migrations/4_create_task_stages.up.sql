@@ -0,0 +1,21 @@
+-- Таймлайн этапов ETL-задачи
+CREATE TABLE IF NOT EXISTS task_stages (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    stage TEXT NOT NULL,
+    status TEXT NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NULL,
+    error TEXT NULL,
+    PRIMARY KEY (task_id, stage)
+);
+
+-- Прогресс загрузки таблиц источников в рамках задачи
+CREATE TABLE IF NOT EXISTS task_table_progress (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    source_name TEXT NOT NULL,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    expected_rows BIGINT NOT NULL DEFAULT 0,
+    loaded_rows BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (task_id, source_name, schema_name, table_name)
+);
@@ -0,0 +1,12 @@
+-- Очередь заданий ETL
+CREATE TABLE IF NOT EXISTS etl_jobs (
+    task_id UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    view_id BIGINT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    worker_id TEXT NULL,
+    lease_until TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS etl_jobs_status_created_idx ON etl_jobs (status, created_at);
@@ -0,0 +1,20 @@
+-- Чекпоинты первичной загрузки: план чанков таблицы и отметки о загруженных чанках
+CREATE TABLE IF NOT EXISTS task_chunks (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    source_name TEXT NOT NULL,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    chunk_index INT NOT NULL,
+    temp_table TEXT NOT NULL,
+    bounds JSONB NOT NULL,
+    done BOOLEAN NOT NULL DEFAULT false,
+    loaded_rows BIGINT NOT NULL DEFAULT 0,
+    finished_at TIMESTAMPTZ NULL,
+    PRIMARY KEY (task_id, source_name, schema_name, table_name, chunk_index)
+);
+
+-- view задачи нужен, чтобы возобновить её после сбоя
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS view_id BIGINT NULL;
+
+-- задание возобновления продолжает загрузку по чекпоинтам
+ALTER TABLE etl_jobs ADD COLUMN IF NOT EXISTS resume BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,10 @@
+-- Временные таблицы DWH, созданные задачей: по ним сборщик мусора отличает таблицы
+-- активных задач от брошенных
+CREATE TABLE IF NOT EXISTS task_temp_tables (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    temp_table TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (task_id, temp_table)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_temp_tables_temp_table ON task_temp_tables (temp_table);
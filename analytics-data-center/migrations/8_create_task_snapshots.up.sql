@@ -0,0 +1,12 @@
+-- Снимки источников, из которых задача выполняет первичную загрузку: позиция WAL и
+-- границы транзакций снимка. По ним CDC пропускает изменения, уже попавшие в view.
+CREATE TABLE IF NOT EXISTS task_snapshots (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    source_name TEXT NOT NULL,
+    lsn BIGINT NOT NULL,
+    xmin BIGINT NOT NULL,
+    xmax BIGINT NOT NULL,
+    xip BIGINT[] NOT NULL DEFAULT '{}',
+    taken_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (task_id, source_name)
+);
@@ -0,0 +1,14 @@
+-- Верхние отметки таблиц view для инкрементальной загрузки
+CREATE TABLE IF NOT EXISTS view_watermarks (
+    view_id BIGINT NOT NULL REFERENCES schems(id) ON DELETE CASCADE,
+    source_name TEXT NOT NULL,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (view_id, source_name, schema_name, table_name)
+);
+
+-- режим задания: полная пересборка или инкрементальная загрузка
+ALTER TABLE etl_jobs ADD COLUMN IF NOT EXISTS mode TEXT NOT NULL DEFAULT 'full';